@@ -3,9 +3,13 @@ package main
 import (
 	"GO_Assignment_3/internal/database"
 	service "GO_Assignment_3/internal/service"
+	"GO_Assignment_3/internal/storage"
+	transportGRPC "GO_Assignment_3/internal/transport/Grpc"
 	transportHTTP "GO_Assignment_3/internal/transport/Htt"
 
+	"context"
 	"os"
+	"strconv"
 
 	"github.com/joho/godotenv"
 	log "github.com/sirupsen/logrus"
@@ -31,16 +35,74 @@ func Run() error {
 		return err
 	}
 
+	if err := db.Migrate(context.Background()); err != nil {
+		log.Error("failed to migrate the database")
+		return err
+	}
+
 	studentService := service.NewService(db)
+	if v := os.Getenv("ATTENDANCE_THRESHOLD"); v != "" {
+		threshold, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			log.Errorf("invalid ATTENDANCE_THRESHOLD %q", v)
+			return err
+		}
+		studentService.AttendanceThreshold = threshold
+	}
+	// Transcript digests are signed with TRANSCRIPT_SECRET, or with the JWT
+	// secret when it is not set.
+	transcriptKey := os.Getenv("TRANSCRIPT_SECRET")
+	if transcriptKey == "" {
+		transcriptKey = os.Getenv("JWT_SECRET")
+	}
+	studentService.TranscriptKey = []byte(transcriptKey)
+	if err := studentService.BuildSearchIndex(context.Background()); err != nil {
+		log.Error("failed to build the search index")
+		return err
+	}
 
-	handler := transportHTTP.NewHandler(studentService)
+	files, err := storage.NewStorage()
+	if err != nil {
+		log.Error("failed to set up file storage")
+		return err
+	}
+	studentService.Files = files
 
-	if err := handler.Serve(); err != nil {
-		log.Error("failed to gracefully serve our application")
+	grpcServer := transportGRPC.NewServer(studentService)
+	grpcAddr := os.Getenv("GRPC_ADDR")
+	if grpcAddr == "" {
+		grpcAddr = ":9090"
+	}
+	grpcListener, err := grpcServer.Listen(grpcAddr)
+	if err != nil {
+		log.Errorf("failed to listen for gRPC on %s", grpcAddr)
 		return err
 	}
 
-	return nil
+	handler := transportHTTP.NewHandler(studentService)
+	handler.PublicURL = os.Getenv("PUBLIC_BASE_URL")
+	if handler.PublicURL == "" {
+		handler.PublicURL = "http://localhost:8080"
+	}
+
+	// Both servers run until one of them fails, which stops the app.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stopped := make(chan error, 2)
+	go func() {
+		if err := grpcServer.Serve(ctx, grpcListener); err != nil {
+			log.Error("gRPC server stopped")
+			stopped <- err
+		}
+	}()
+	go func() {
+		if err := handler.Serve(); err != nil {
+			log.Error("failed to gracefully serve our application")
+			stopped <- err
+		}
+	}()
+
+	return <-stopped
 }
 
 func main() {
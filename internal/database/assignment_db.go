@@ -0,0 +1,318 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	student "GO_Assignment_3/internal/service"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const assignmentSelect = `SELECT a.assignment_id, a.course_id, a.term_id, t.code AS term_code, a.title, a.description,
+		a.due_at, a.max_points, a.reject_late, a.created_by, a.created_on, a.updated_by, a.updated_on
+	 FROM assignments a
+	 JOIN terms t ON t.term_id = a.term_id`
+
+const submissionSelect = `SELECT sb.submission_id, sb.assignment_id, a.course_id, sb.user_id, s.name AS student_name,
+		sb.file_name, sb.content_type, sb.size, sb.storage_key, sb.submitted_on, sb.is_late, sb.points,
+		sb.feedback, sb.graded_by, sb.graded_on
+	 FROM submissions sb
+	 JOIN assignments a ON a.assignment_id = sb.assignment_id
+	 JOIN students s ON s.user_id = sb.user_id`
+
+type AssignmentRow struct {
+	AssignmentID int32          `db:"assignment_id"`
+	CourseID     int32          `db:"course_id"`
+	TermID       int32          `db:"term_id"`
+	TermCode     string         `db:"term_code"`
+	Title        string         `db:"title"`
+	Description  sql.NullString `db:"description"`
+	DueAt        time.Time      `db:"due_at"`
+	MaxPoints    float64        `db:"max_points"`
+	RejectLate   bool           `db:"reject_late"`
+	CreatedBy    sql.NullString `db:"created_by"`
+	CreatedOn    sql.NullTime   `db:"created_on"`
+	UpdatedBy    sql.NullString `db:"updated_by"`
+	UpdatedOn    sql.NullTime   `db:"updated_on"`
+}
+
+func convertAssignmentRowToAssignment(a AssignmentRow) student.Assignment {
+	return student.Assignment{
+		ID:          a.AssignmentID,
+		CourseID:    a.CourseID,
+		TermID:      a.TermID,
+		TermCode:    a.TermCode,
+		Title:       a.Title,
+		Description: a.Description.String,
+		DueAt:       a.DueAt.UTC().Format(time.RFC3339),
+		MaxPoints:   a.MaxPoints,
+		RejectLate:  a.RejectLate,
+		CreatedBy:   a.CreatedBy.String,
+		CreatedOn:   formatNullTime(a.CreatedOn),
+		UpdatedBy:   a.UpdatedBy.String,
+		UpdatedOn:   formatNullTime(a.UpdatedOn),
+	}
+}
+
+type SubmissionRow struct {
+	SubmissionID int32           `db:"submission_id"`
+	AssignmentID int32           `db:"assignment_id"`
+	CourseID     int32           `db:"course_id"`
+	UserID       int32           `db:"user_id"`
+	StudentName  sql.NullString  `db:"student_name"`
+	FileName     string          `db:"file_name"`
+	ContentType  string          `db:"content_type"`
+	Size         int64           `db:"size"`
+	StorageKey   string          `db:"storage_key"`
+	SubmittedOn  time.Time       `db:"submitted_on"`
+	IsLate       bool            `db:"is_late"`
+	Points       sql.NullFloat64 `db:"points"`
+	Feedback     sql.NullString  `db:"feedback"`
+	GradedBy     sql.NullString  `db:"graded_by"`
+	GradedOn     sql.NullTime    `db:"graded_on"`
+}
+
+func convertSubmissionRowToSubmission(s SubmissionRow) student.Submission {
+	sub := student.Submission{
+		ID:           s.SubmissionID,
+		AssignmentID: s.AssignmentID,
+		CourseID:     s.CourseID,
+		StudentID:    s.UserID,
+		StudentName:  s.StudentName.String,
+		FileName:     s.FileName,
+		ContentType:  s.ContentType,
+		Size:         s.Size,
+		StorageKey:   s.StorageKey,
+		SubmittedOn:  s.SubmittedOn.UTC().Format(time.RFC3339),
+		Late:         s.IsLate,
+		Feedback:     s.Feedback.String,
+		GradedBy:     s.GradedBy.String,
+		GradedOn:     formatNullTime(s.GradedOn),
+	}
+	if s.Points.Valid {
+		sub.Points = &s.Points.Float64
+	}
+	return sub
+}
+
+func (d *Database) GetAssignments(ctx context.Context, courseID, termID int32) ([]student.Assignment, error) {
+	query, args := assignmentSelect+" WHERE a.course_id = ?", []interface{}{courseID}
+	if termID != 0 {
+		query, args = query+" AND a.term_id = ?", append(args, termID)
+	}
+	var rows []AssignmentRow
+	if err := d.Client.SelectContext(ctx, &rows, query+" ORDER BY a.due_at, a.assignment_id", args...); err != nil {
+		log.Errorf("Error querying assignments of course %d: %v", courseID, err)
+		return nil, fmt.Errorf("error querying assignments: %w", err)
+	}
+	assignments := make([]student.Assignment, 0, len(rows))
+	for _, row := range rows {
+		assignments = append(assignments, convertAssignmentRowToAssignment(row))
+	}
+	return assignments, nil
+}
+
+func (d *Database) GetAssignment(ctx context.Context, assignmentID int32) (student.Assignment, error) {
+	var row AssignmentRow
+	if err := d.Client.GetContext(ctx, &row, assignmentSelect+" WHERE a.assignment_id = ?", assignmentID); err != nil {
+		if err == sql.ErrNoRows {
+			return student.Assignment{}, student.ErrAssignmentNotFound
+		}
+		log.Errorf("Error fetching assignment %d: %v", assignmentID, err)
+		return student.Assignment{}, fmt.Errorf("error fetching assignment: %w", err)
+	}
+	return convertAssignmentRowToAssignment(row), nil
+}
+
+func (d *Database) AddAssignment(ctx context.Context, a student.Assignment) (student.Assignment, error) {
+	userType, _ := ctx.Value("userType").(string)
+	dueAt, err := time.Parse(time.RFC3339, a.DueAt)
+	if err != nil {
+		return a, student.ErrInvalidAssignment
+	}
+
+	result, err := d.Client.ExecContext(
+		ctx,
+		`INSERT INTO assignments (course_id, term_id, title, description, due_at, max_points, reject_late, created_by, updated_by)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		a.CourseID, a.TermID, a.Title, nullString(a.Description), dueAt.UTC(), a.MaxPoints, a.RejectLate,
+		nullString(userType), nullString(userType),
+	)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return a, student.ErrTermNotFound
+		}
+		log.Errorf("Failed to insert assignment, Error: %v", err)
+		return a, fmt.Errorf("failed to insert assignment: %w", err)
+	}
+
+	assignmentID, err := result.LastInsertId()
+	if err != nil {
+		return a, fmt.Errorf("failed to retrieve last inserted ID: %w", err)
+	}
+
+	log.Infof("Successfully added assignment with ID: %d", assignmentID)
+	return d.GetAssignment(ctx, int32(assignmentID))
+}
+
+func (d *Database) UpdateAssignment(ctx context.Context, assignmentID int32, a student.Assignment) (student.Assignment, error) {
+	userType, _ := ctx.Value("userType").(string)
+	dueAt, err := time.Parse(time.RFC3339, a.DueAt)
+	if err != nil {
+		return a, student.ErrInvalidAssignment
+	}
+
+	_, err = d.Client.ExecContext(
+		ctx,
+		`UPDATE assignments SET title = ?, description = ?, due_at = ?, max_points = ?, reject_late = ?, updated_by = ?
+		 WHERE assignment_id = ?`,
+		a.Title, nullString(a.Description), dueAt.UTC(), a.MaxPoints, a.RejectLate, nullString(userType), assignmentID,
+	)
+	if err != nil {
+		log.Errorf("Failed to update assignment %d, Error: %v", assignmentID, err)
+		return a, fmt.Errorf("failed to update assignment: %w", err)
+	}
+
+	log.Infof("Successfully updated assignment with ID: %d", assignmentID)
+	return d.GetAssignment(ctx, assignmentID)
+}
+
+func (d *Database) DeleteAssignment(ctx context.Context, assignmentID int32) ([]string, error) {
+	tx, err := d.Client.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var keys []string
+	if err := tx.SelectContext(ctx, &keys, `SELECT storage_key FROM submissions WHERE assignment_id = ?`, assignmentID); err != nil {
+		return nil, fmt.Errorf("failed to list submission files: %w", err)
+	}
+	result, err := tx.ExecContext(ctx, `DELETE FROM assignments WHERE assignment_id = ?`, assignmentID)
+	if err != nil {
+		log.Errorf("Failed to delete assignment %d, Error: %v", assignmentID, err)
+		return nil, fmt.Errorf("failed to delete assignment: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return nil, student.ErrAssignmentNotFound
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit assignment delete: %w", err)
+	}
+	log.Infof("Successfully deleted assignment with ID: %d", assignmentID)
+	return keys, nil
+}
+
+func (d *Database) selectSubmissions(ctx context.Context, where string, args ...interface{}) ([]student.Submission, error) {
+	var rows []SubmissionRow
+	if err := d.Client.SelectContext(ctx, &rows, submissionSelect+" WHERE "+where+" ORDER BY a.due_at, s.name", args...); err != nil {
+		log.Errorf("Error querying submissions: %v", err)
+		return nil, fmt.Errorf("error querying submissions: %w", err)
+	}
+	submissions := make([]student.Submission, 0, len(rows))
+	for _, row := range rows {
+		submissions = append(submissions, convertSubmissionRowToSubmission(row))
+	}
+	return submissions, nil
+}
+
+func (d *Database) GetSubmission(ctx context.Context, submissionID int32) (student.Submission, error) {
+	var row SubmissionRow
+	if err := d.Client.GetContext(ctx, &row, submissionSelect+" WHERE sb.submission_id = ?", submissionID); err != nil {
+		if err == sql.ErrNoRows {
+			return student.Submission{}, student.ErrSubmissionNotFound
+		}
+		log.Errorf("Error fetching submission %d: %v", submissionID, err)
+		return student.Submission{}, fmt.Errorf("error fetching submission: %w", err)
+	}
+	return convertSubmissionRowToSubmission(row), nil
+}
+
+func (d *Database) GetAssignmentSubmissions(ctx context.Context, assignmentID int32) ([]student.Submission, error) {
+	return d.selectSubmissions(ctx, "sb.assignment_id = ?", assignmentID)
+}
+
+func (d *Database) GetStudentSubmissions(ctx context.Context, userID int32) ([]student.Submission, error) {
+	return d.selectSubmissions(ctx, "sb.user_id = ?", userID)
+}
+
+// SaveSubmission locks the student's earlier submission, if any, so a
+// resubmission cannot race a grade being recorded.
+func (d *Database) SaveSubmission(ctx context.Context, sub student.Submission) (student.Submission, string, error) {
+	submittedOn, err := time.Parse(time.RFC3339, sub.SubmittedOn)
+	if err != nil {
+		return sub, "", student.ErrInvalidSubmission
+	}
+
+	tx, err := d.Client.BeginTxx(ctx, nil)
+	if err != nil {
+		return sub, "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var previous struct {
+		SubmissionID int32        `db:"submission_id"`
+		StorageKey   string       `db:"storage_key"`
+		GradedOn     sql.NullTime `db:"graded_on"`
+	}
+	err = tx.GetContext(ctx, &previous,
+		`SELECT submission_id, storage_key, graded_on FROM submissions WHERE assignment_id = ? AND user_id = ? FOR UPDATE`,
+		sub.AssignmentID, sub.StudentID)
+	switch {
+	case err == sql.ErrNoRows:
+		result, err := tx.ExecContext(ctx,
+			`INSERT INTO submissions (assignment_id, user_id, file_name, content_type, size, storage_key, submitted_on, is_late)
+			 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			sub.AssignmentID, sub.StudentID, sub.FileName, sub.ContentType, sub.Size, sub.StorageKey, submittedOn, sub.Late)
+		if err != nil {
+			if isForeignKeyViolation(err) {
+				return sub, "", student.ErrAssignmentNotFound
+			}
+			log.Errorf("Failed to insert submission, Error: %v", err)
+			return sub, "", fmt.Errorf("failed to insert submission: %w", err)
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return sub, "", fmt.Errorf("failed to retrieve last inserted ID: %w", err)
+		}
+		previous.SubmissionID = int32(id)
+	case err != nil:
+		return sub, "", fmt.Errorf("failed to look up submission: %w", err)
+	case previous.GradedOn.Valid:
+		return sub, "", student.ErrAlreadyGraded
+	default:
+		_, err := tx.ExecContext(ctx,
+			`UPDATE submissions SET file_name = ?, content_type = ?, size = ?, storage_key = ?, submitted_on = ?, is_late = ?
+			 WHERE submission_id = ?`,
+			sub.FileName, sub.ContentType, sub.Size, sub.StorageKey, submittedOn, sub.Late, previous.SubmissionID)
+		if err != nil {
+			log.Errorf("Failed to replace submission %d, Error: %v", previous.SubmissionID, err)
+			return sub, "", fmt.Errorf("failed to replace submission: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return sub, "", fmt.Errorf("failed to commit submission: %w", err)
+	}
+	log.Infof("Successfully saved submission with ID: %d", previous.SubmissionID)
+	saved, err := d.GetSubmission(ctx, previous.SubmissionID)
+	return saved, previous.StorageKey, err
+}
+
+func (d *Database) GradeSubmission(ctx context.Context, submissionID int32, grade student.SubmissionGrade) (student.Submission, error) {
+	userType, _ := ctx.Value("userType").(string)
+	_, err := d.Client.ExecContext(ctx,
+		`UPDATE submissions SET points = ?, feedback = ?, graded_by = ?, graded_on = CURRENT_TIMESTAMP WHERE submission_id = ?`,
+		grade.Points, nullString(grade.Feedback), nullString(userType), submissionID)
+	if err != nil {
+		log.Errorf("Failed to grade submission %d, Error: %v", submissionID, err)
+		return student.Submission{}, fmt.Errorf("failed to grade submission: %w", err)
+	}
+
+	log.Infof("Successfully graded submission with ID: %d", submissionID)
+	return d.GetSubmission(ctx, submissionID)
+}
@@ -0,0 +1,269 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	student "GO_Assignment_3/internal/service"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const sessionSelect = `SELECT cs.session_id, cs.course_id, cs.term_id, t.code AS term_code, cs.session_date,
+		cs.topic, cs.created_by, cs.created_on, cs.updated_by, cs.updated_on
+	 FROM class_sessions cs
+	 JOIN terms t ON t.term_id = cs.term_id`
+
+type SessionRow struct {
+	SessionID   int32          `db:"session_id"`
+	CourseID    int32          `db:"course_id"`
+	TermID      int32          `db:"term_id"`
+	TermCode    string         `db:"term_code"`
+	SessionDate time.Time      `db:"session_date"`
+	Topic       string         `db:"topic"`
+	CreatedBy   sql.NullString `db:"created_by"`
+	CreatedOn   sql.NullTime   `db:"created_on"`
+	UpdatedBy   sql.NullString `db:"updated_by"`
+	UpdatedOn   sql.NullTime   `db:"updated_on"`
+}
+
+func convertSessionRowToSession(s SessionRow) student.ClassSession {
+	return student.ClassSession{
+		ID:        s.SessionID,
+		CourseID:  s.CourseID,
+		TermID:    s.TermID,
+		TermCode:  s.TermCode,
+		Date:      s.SessionDate.Format(student.DateLayout),
+		Topic:     s.Topic,
+		CreatedBy: s.CreatedBy.String,
+		CreatedOn: formatNullTime(s.CreatedOn),
+		UpdatedBy: s.UpdatedBy.String,
+		UpdatedOn: formatNullTime(s.UpdatedOn),
+	}
+}
+
+type AttendanceRow struct {
+	SessionID    int32          `db:"session_id"`
+	EnrollmentID int32          `db:"enrollment_id"`
+	UserID       int32          `db:"user_id"`
+	StudentName  sql.NullString `db:"student_name"`
+	Mark         string         `db:"mark"`
+	Note         sql.NullString `db:"note"`
+	RecordedBy   sql.NullString `db:"recorded_by"`
+	RecordedOn   sql.NullTime   `db:"recorded_on"`
+}
+
+type AttendanceSummaryRow struct {
+	EnrollmentID int32          `db:"enrollment_id"`
+	UserID       int32          `db:"user_id"`
+	StudentName  sql.NullString `db:"student_name"`
+	CourseID     int32          `db:"course_id"`
+	CourseCode   sql.NullString `db:"course_code"`
+	TermID       sql.NullInt32  `db:"term_id"`
+	TermCode     sql.NullString `db:"term_code"`
+	Present      int            `db:"present"`
+	Late         int            `db:"late"`
+	Absent       int            `db:"absent"`
+	Excused      int            `db:"excused"`
+}
+
+func (d *Database) GetSessions(ctx context.Context, courseID, termID int32) ([]student.ClassSession, error) {
+	query, args := sessionSelect+" WHERE cs.course_id = ?", []interface{}{courseID}
+	if termID != 0 {
+		query, args = query+" AND cs.term_id = ?", append(args, termID)
+	}
+	var rows []SessionRow
+	if err := d.Client.SelectContext(ctx, &rows, query+" ORDER BY cs.session_date, cs.session_id", args...); err != nil {
+		log.Errorf("Error querying sessions of course %d: %v", courseID, err)
+		return nil, fmt.Errorf("error querying sessions: %w", err)
+	}
+	sessions := make([]student.ClassSession, 0, len(rows))
+	for _, row := range rows {
+		sessions = append(sessions, convertSessionRowToSession(row))
+	}
+	return sessions, nil
+}
+
+func (d *Database) GetSession(ctx context.Context, sessionID int32) (student.ClassSession, error) {
+	var row SessionRow
+	if err := d.Client.GetContext(ctx, &row, sessionSelect+" WHERE cs.session_id = ?", sessionID); err != nil {
+		if err == sql.ErrNoRows {
+			return student.ClassSession{}, student.ErrSessionNotFound
+		}
+		log.Errorf("Error fetching session %d: %v", sessionID, err)
+		return student.ClassSession{}, fmt.Errorf("error fetching session: %w", err)
+	}
+	return convertSessionRowToSession(row), nil
+}
+
+func (d *Database) AddSession(ctx context.Context, s student.ClassSession) (student.ClassSession, error) {
+	userType, _ := ctx.Value("userType").(string)
+
+	result, err := d.Client.ExecContext(
+		ctx,
+		`INSERT INTO class_sessions (course_id, term_id, session_date, topic, created_by, updated_by) VALUES (?, ?, ?, ?, ?, ?)`,
+		s.CourseID, s.TermID, s.Date, s.Topic, nullString(userType), nullString(userType),
+	)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return s, student.ErrTermNotFound
+		}
+		log.Errorf("Failed to insert session, Error: %v", err)
+		return s, fmt.Errorf("failed to insert session: %w", err)
+	}
+
+	sessionID, err := result.LastInsertId()
+	if err != nil {
+		return s, fmt.Errorf("failed to retrieve last inserted ID: %w", err)
+	}
+
+	log.Infof("Successfully added session with ID: %d", sessionID)
+	return d.GetSession(ctx, int32(sessionID))
+}
+
+func (d *Database) UpdateSession(ctx context.Context, sessionID int32, s student.ClassSession) (student.ClassSession, error) {
+	userType, _ := ctx.Value("userType").(string)
+	_, err := d.Client.ExecContext(
+		ctx,
+		`UPDATE class_sessions SET session_date = ?, topic = ?, updated_by = ? WHERE session_id = ?`,
+		s.Date, s.Topic, nullString(userType), sessionID,
+	)
+	if err != nil {
+		log.Errorf("Failed to update session %d, Error: %v", sessionID, err)
+		return s, fmt.Errorf("failed to update session: %w", err)
+	}
+
+	log.Infof("Successfully updated session with ID: %d", sessionID)
+	return d.GetSession(ctx, sessionID)
+}
+
+func (d *Database) DeleteSession(ctx context.Context, sessionID int32) error {
+	result, err := d.Client.ExecContext(ctx, `DELETE FROM class_sessions WHERE session_id = ?`, sessionID)
+	if err != nil {
+		log.Errorf("Failed to delete session %d, Error: %v", sessionID, err)
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return student.ErrSessionNotFound
+	}
+
+	log.Infof("Successfully deleted session with ID: %d", sessionID)
+	return nil
+}
+
+func (d *Database) GetSessionAttendance(ctx context.Context, sessionID int32) ([]student.AttendanceMark, error) {
+	var rows []AttendanceRow
+	err := d.Client.SelectContext(ctx, &rows,
+		`SELECT a.session_id, a.enrollment_id, e.user_id, s.name AS student_name, a.mark, a.note,
+			a.recorded_by, a.recorded_on
+		 FROM attendance a
+		 JOIN enrollments e ON e.enrollment_id = a.enrollment_id
+		 JOIN students s ON s.user_id = e.user_id
+		 WHERE a.session_id = ?
+		 ORDER BY s.name, e.user_id`, sessionID)
+	if err != nil {
+		log.Errorf("Error querying attendance of session %d: %v", sessionID, err)
+		return nil, fmt.Errorf("error querying attendance: %w", err)
+	}
+	marks := make([]student.AttendanceMark, 0, len(rows))
+	for _, row := range rows {
+		marks = append(marks, student.AttendanceMark{
+			SessionID:    row.SessionID,
+			EnrollmentID: row.EnrollmentID,
+			StudentID:    row.UserID,
+			StudentName:  row.StudentName.String,
+			Mark:         row.Mark,
+			Note:         row.Note.String,
+			RecordedBy:   row.RecordedBy.String,
+			RecordedOn:   formatNullTime(row.RecordedOn),
+		})
+	}
+	return marks, nil
+}
+
+func (d *Database) SetAttendance(ctx context.Context, sessionID int32, marks []student.AttendanceMark) error {
+	userType, _ := ctx.Value("userType").(string)
+
+	tx, err := d.Client.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, m := range marks {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO attendance (session_id, enrollment_id, mark, note, recorded_by) VALUES (?, ?, ?, ?, ?)
+			 ON DUPLICATE KEY UPDATE mark = VALUES(mark), note = VALUES(note), recorded_by = VALUES(recorded_by)`,
+			sessionID, m.EnrollmentID, m.Mark, nullString(m.Note), nullString(userType))
+		if err != nil {
+			if isForeignKeyViolation(err) {
+				return student.ErrEnrollmentNotFound
+			}
+			log.Errorf("Failed to record attendance of session %d, Error: %v", sessionID, err)
+			return fmt.Errorf("failed to record attendance: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit attendance: %w", err)
+	}
+	log.Infof("Successfully recorded %d attendance marks for session %d", len(marks), sessionID)
+	return nil
+}
+
+// GetAttendanceSummaries counts marks per seat-holding enrollment. Only
+// sessions of the enrollment's own course and term are counted, so a
+// student who never got a mark still shows up with zeros.
+func (d *Database) GetAttendanceSummaries(ctx context.Context, q student.AttendanceQuery) ([]student.AttendanceSummary, error) {
+	where, args := "e.status IN "+seatStatuses, []interface{}{}
+	if q.StudentID != 0 {
+		where, args = where+" AND e.user_id = ?", append(args, q.StudentID)
+	}
+	if q.CourseID != 0 {
+		where, args = where+" AND e.course_id = ?", append(args, q.CourseID)
+	}
+	if q.TermID != 0 {
+		where, args = where+" AND e.term_id = ?", append(args, q.TermID)
+	}
+
+	var rows []AttendanceSummaryRow
+	err := d.Client.SelectContext(ctx, &rows,
+		`SELECT e.enrollment_id, e.user_id, s.name AS student_name, e.course_id, c.code AS course_code,
+			e.term_id, t.code AS term_code,
+			COALESCE(SUM(a.mark = 'present'), 0) AS present,
+			COALESCE(SUM(a.mark = 'late'), 0) AS late,
+			COALESCE(SUM(a.mark = 'absent'), 0) AS absent,
+			COALESCE(SUM(a.mark = 'excused'), 0) AS excused
+		 FROM enrollments e
+		 JOIN courses c ON c.course_id = e.course_id
+		 JOIN students s ON s.user_id = e.user_id
+		 LEFT JOIN terms t ON t.term_id = e.term_id
+		 LEFT JOIN class_sessions cs ON cs.course_id = e.course_id AND cs.term_id = e.term_id
+		 LEFT JOIN attendance a ON a.session_id = cs.session_id AND a.enrollment_id = e.enrollment_id
+		 WHERE `+where+`
+		 GROUP BY e.enrollment_id, e.user_id, s.name, e.course_id, c.code, e.term_id, t.code, t.start_date
+		 ORDER BY t.start_date, c.code, s.name`, args...)
+	if err != nil {
+		log.Errorf("Error querying attendance summaries: %v", err)
+		return nil, fmt.Errorf("error querying attendance summaries: %w", err)
+	}
+	summaries := make([]student.AttendanceSummary, 0, len(rows))
+	for _, row := range rows {
+		summaries = append(summaries, student.AttendanceSummary{
+			EnrollmentID: row.EnrollmentID,
+			StudentID:    row.UserID,
+			StudentName:  row.StudentName.String,
+			CourseID:     row.CourseID,
+			CourseCode:   row.CourseCode.String,
+			TermID:       row.TermID.Int32,
+			TermCode:     row.TermCode.String,
+			Present:      row.Present,
+			Late:         row.Late,
+			Absent:       row.Absent,
+			Excused:      row.Excused,
+		})
+	}
+	return summaries, nil
+}
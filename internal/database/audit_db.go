@@ -0,0 +1,161 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	student "GO_Assignment_3/internal/service"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type AuditRow struct {
+	AuditID   int64          `db:"audit_id"`
+	ActorID   sql.NullInt32  `db:"actor_id"`
+	ActorRole string         `db:"actor_role"`
+	Action    string         `db:"action"`
+	TargetID  sql.NullInt32  `db:"target_id"`
+	Diff      sql.NullString `db:"diff"`
+	ClientIP  sql.NullString `db:"client_ip"`
+	RequestID sql.NullString `db:"request_id"`
+	Outcome   string         `db:"outcome"`
+	Message   sql.NullString `db:"message"`
+	CreatedOn time.Time      `db:"created_on"`
+}
+
+func convertAuditRowToAuditEntry(a AuditRow) student.AuditEntry {
+	entry := student.AuditEntry{
+		ID:        a.AuditID,
+		ActorID:   a.ActorID.Int32,
+		ActorRole: a.ActorRole,
+		Action:    a.Action,
+		TargetID:  a.TargetID.Int32,
+		ClientIP:  a.ClientIP.String,
+		RequestID: a.RequestID.String,
+		Outcome:   a.Outcome,
+		Message:   a.Message.String,
+		CreatedOn: a.CreatedOn.Format(time.RFC3339),
+	}
+	if a.Diff.Valid {
+		if err := json.Unmarshal([]byte(a.Diff.String), &entry.Diff); err != nil {
+			log.Warnf("Could not decode diff of audit entry %d: %v", a.AuditID, err)
+		}
+	}
+	return entry
+}
+
+func nullInt32(v int32) sql.NullInt32 {
+	return sql.NullInt32{Int32: v, Valid: v != 0}
+}
+
+func nullString(v string) sql.NullString {
+	return sql.NullString{String: v, Valid: v != ""}
+}
+
+func (d *Database) AddAuditEntry(ctx context.Context, entry student.AuditEntry) error {
+	row := AuditRow{
+		ActorID:   nullInt32(entry.ActorID),
+		ActorRole: entry.ActorRole,
+		Action:    entry.Action,
+		TargetID:  nullInt32(entry.TargetID),
+		ClientIP:  nullString(entry.ClientIP),
+		RequestID: nullString(entry.RequestID),
+		Outcome:   entry.Outcome,
+		Message:   nullString(truncate(entry.Message, 255)),
+	}
+	if len(entry.Diff) > 0 {
+		diff, err := json.Marshal(entry.Diff)
+		if err != nil {
+			return fmt.Errorf("failed to encode audit diff: %w", err)
+		}
+		row.Diff = sql.NullString{String: string(diff), Valid: true}
+	}
+
+	_, err := d.Client.NamedExecContext(
+		ctx,
+		`INSERT INTO audit_log (actor_id, actor_role, action, target_id, diff, client_ip, request_id, outcome, message)
+		 VALUES (:actor_id, :actor_role, :action, :target_id, :diff, :client_ip, :request_id, :outcome, :message)`,
+		row,
+	)
+	if err != nil {
+		log.Errorf("Failed to insert audit entry, Error: %v", err)
+		return fmt.Errorf("failed to insert audit entry: %w", err)
+	}
+	return nil
+}
+
+func (d *Database) GetAuditEntries(ctx context.Context, filter student.AuditFilter) ([]student.AuditEntry, error) {
+	var (
+		where []string
+		args  []interface{}
+	)
+	if filter.ActorID != 0 {
+		where = append(where, "actor_id = ?")
+		args = append(args, filter.ActorID)
+	}
+	if filter.ActorRole != "" {
+		where = append(where, "actor_role = ?")
+		args = append(args, filter.ActorRole)
+	}
+	if filter.Action != "" {
+		where = append(where, "action = ?")
+		args = append(args, filter.Action)
+	}
+	if filter.TargetID != 0 {
+		where = append(where, "target_id = ?")
+		args = append(args, filter.TargetID)
+	}
+	if filter.Outcome != "" {
+		where = append(where, "outcome = ?")
+		args = append(args, filter.Outcome)
+	}
+	if filter.RequestID != "" {
+		where = append(where, "request_id = ?")
+		args = append(args, filter.RequestID)
+	}
+	if !filter.From.IsZero() {
+		where = append(where, "created_on >= ?")
+		args = append(args, filter.From)
+	}
+	if !filter.To.IsZero() {
+		where = append(where, "created_on < ?")
+		args = append(args, filter.To)
+	}
+
+	query := `SELECT audit_id, actor_id, actor_role, action, target_id, diff, client_ip, request_id, outcome, message, created_on
+		 FROM audit_log`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY audit_id DESC LIMIT ? OFFSET ?"
+	args = append(args, filter.Limit, filter.Offset)
+
+	var rows []AuditRow
+	if err := d.Client.SelectContext(ctx, &rows, query, args...); err != nil {
+		log.Errorf("Error querying audit log: %v", err)
+		return nil, fmt.Errorf("error querying audit log: %w", err)
+	}
+
+	entries := make([]student.AuditEntry, 0, len(rows))
+	for _, row := range rows {
+		entries = append(entries, convertAuditRowToAuditEntry(row))
+	}
+	return entries, nil
+}
+
+// truncate cuts s to at most n bytes, on a character boundary so that the
+// result is still valid UTF-8.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
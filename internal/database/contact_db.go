@@ -0,0 +1,386 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	student "GO_Assignment_3/internal/service"
+
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+)
+
+const contactColumns = `contact_id, user_id, name, relationship, phone, email, is_primary, is_emergency, guardian_id,
+	created_by, created_on, updated_by, updated_on`
+
+const guardianColumns = "guardian_id, name, email, password_hash, created_by, created_on, updated_by, updated_on"
+
+type ContactRow struct {
+	ContactID    int32          `db:"contact_id"`
+	UserID       int32          `db:"user_id"`
+	Name         string         `db:"name"`
+	Relationship string         `db:"relationship"`
+	Phone        string         `db:"phone"`
+	Email        sql.NullString `db:"email"`
+	IsPrimary    bool           `db:"is_primary"`
+	IsEmergency  bool           `db:"is_emergency"`
+	GuardianID   sql.NullInt32  `db:"guardian_id"`
+	CreatedBy    sql.NullString `db:"created_by"`
+	CreatedOn    sql.NullTime   `db:"created_on"`
+	UpdatedBy    sql.NullString `db:"updated_by"`
+	UpdatedOn    sql.NullTime   `db:"updated_on"`
+}
+
+func convertContactRowToContact(c ContactRow) student.Contact {
+	return student.Contact{
+		ID:           c.ContactID,
+		StudentID:    c.UserID,
+		Name:         c.Name,
+		Relationship: c.Relationship,
+		Phone:        c.Phone,
+		Email:        c.Email.String,
+		IsPrimary:    c.IsPrimary,
+		IsEmergency:  c.IsEmergency,
+		GuardianID:   c.GuardianID.Int32,
+		CreatedBy:    c.CreatedBy.String,
+		CreatedOn:    formatNullTime(c.CreatedOn),
+		UpdatedBy:    c.UpdatedBy.String,
+		UpdatedOn:    formatNullTime(c.UpdatedOn),
+	}
+}
+
+type GuardianRow struct {
+	GuardianID   int32          `db:"guardian_id"`
+	Name         string         `db:"name"`
+	Email        string         `db:"email"`
+	PasswordHash string         `db:"password_hash"`
+	CreatedBy    sql.NullString `db:"created_by"`
+	CreatedOn    sql.NullTime   `db:"created_on"`
+	UpdatedBy    sql.NullString `db:"updated_by"`
+	UpdatedOn    sql.NullTime   `db:"updated_on"`
+}
+
+// convertGuardianRowToGuardian leaves the password hash out; only
+// GetGuardianByEmail hands it to the service for logins.
+func convertGuardianRowToGuardian(g GuardianRow) student.Guardian {
+	return student.Guardian{
+		ID:        g.GuardianID,
+		Name:      g.Name,
+		Email:     g.Email,
+		CreatedBy: g.CreatedBy.String,
+		CreatedOn: formatNullTime(g.CreatedOn),
+		UpdatedBy: g.UpdatedBy.String,
+		UpdatedOn: formatNullTime(g.UpdatedOn),
+	}
+}
+
+func (d *Database) GetStudentContacts(ctx context.Context, userID int32) ([]student.Contact, error) {
+	var rows []ContactRow
+	err := d.Client.SelectContext(ctx, &rows,
+		"SELECT "+contactColumns+" FROM contacts WHERE user_id = ? ORDER BY is_primary DESC, contact_id", userID)
+	if err != nil {
+		log.Errorf("Error querying contacts of student %d: %v", userID, err)
+		return nil, fmt.Errorf("error querying contacts: %w", err)
+	}
+	contacts := make([]student.Contact, 0, len(rows))
+	for _, row := range rows {
+		contacts = append(contacts, convertContactRowToContact(row))
+	}
+	return contacts, nil
+}
+
+// GetContactsOfStudents lists the contacts of several students in one
+// query, in the order of GetStudentContacts for each student.
+func (d *Database) GetContactsOfStudents(ctx context.Context, userIDs []int32) ([]student.Contact, error) {
+	if len(userIDs) == 0 {
+		return []student.Contact{}, nil
+	}
+	query, args, err := sqlx.In(
+		"SELECT "+contactColumns+" FROM contacts WHERE user_id IN (?) ORDER BY user_id, is_primary DESC, contact_id", userIDs)
+	if err != nil {
+		return nil, err
+	}
+	var rows []ContactRow
+	if err := d.Client.SelectContext(ctx, &rows, d.Client.Rebind(query), args...); err != nil {
+		log.Errorf("Error querying contacts of students: %v", err)
+		return nil, fmt.Errorf("error querying contacts: %w", err)
+	}
+	contacts := make([]student.Contact, 0, len(rows))
+	for _, row := range rows {
+		contacts = append(contacts, convertContactRowToContact(row))
+	}
+	return contacts, nil
+}
+
+func (d *Database) GetContact(ctx context.Context, contactID int32) (student.Contact, error) {
+	var row ContactRow
+	if err := d.Client.GetContext(ctx, &row, "SELECT "+contactColumns+" FROM contacts WHERE contact_id = ?", contactID); err != nil {
+		if err == sql.ErrNoRows {
+			return student.Contact{}, student.ErrContactNotFound
+		}
+		log.Errorf("Error fetching contact %d: %v", contactID, err)
+		return student.Contact{}, fmt.Errorf("error fetching contact: %w", err)
+	}
+	return convertContactRowToContact(row), nil
+}
+
+// settlePrimary makes contactID the only primary contact of the student
+// when primary is set, and otherwise makes sure the student still has one.
+func settlePrimary(ctx context.Context, tx *sqlx.Tx, userID, contactID int32, primary bool) error {
+	if primary {
+		_, err := tx.ExecContext(ctx,
+			`UPDATE contacts SET is_primary = (contact_id = ?) WHERE user_id = ?`, contactID, userID)
+		return err
+	}
+	var hasPrimary bool
+	if err := tx.GetContext(ctx, &hasPrimary,
+		`SELECT EXISTS(SELECT 1 FROM contacts WHERE user_id = ? AND is_primary)`, userID); err != nil || hasPrimary {
+		return err
+	}
+	var oldest sql.NullInt32
+	if err := tx.GetContext(ctx, &oldest, `SELECT MIN(contact_id) FROM contacts WHERE user_id = ?`, userID); err != nil || !oldest.Valid {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, `UPDATE contacts SET is_primary = TRUE WHERE contact_id = ?`, oldest.Int32)
+	return err
+}
+
+func (d *Database) AddContact(ctx context.Context, c student.Contact) (student.Contact, error) {
+	userType, _ := ctx.Value("userType").(string)
+
+	tx, err := d.Client.BeginTxx(ctx, nil)
+	if err != nil {
+		return c, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO contacts (user_id, name, relationship, phone, email, is_primary, is_emergency, guardian_id, created_by, updated_by)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		c.StudentID, c.Name, c.Relationship, c.Phone, nullString(c.Email), c.IsPrimary, c.IsEmergency,
+		nullInt32(c.GuardianID), nullString(userType), nullString(userType),
+	)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return c, student.ErrGuardianNotFound
+		}
+		log.Errorf("Failed to insert contact, Error: %v", err)
+		return c, fmt.Errorf("failed to insert contact: %w", err)
+	}
+	contactID, err := result.LastInsertId()
+	if err != nil {
+		return c, fmt.Errorf("failed to retrieve last inserted ID: %w", err)
+	}
+	if err := settlePrimary(ctx, tx, c.StudentID, int32(contactID), c.IsPrimary); err != nil {
+		return c, fmt.Errorf("failed to set primary contact: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c, fmt.Errorf("failed to commit contact: %w", err)
+	}
+	log.Infof("Successfully added contact with ID: %d", contactID)
+	return d.GetContact(ctx, int32(contactID))
+}
+
+func (d *Database) UpdateContact(ctx context.Context, contactID int32, c student.Contact) (student.Contact, error) {
+	userType, _ := ctx.Value("userType").(string)
+
+	tx, err := d.Client.BeginTxx(ctx, nil)
+	if err != nil {
+		return c, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(
+		ctx,
+		`UPDATE contacts SET name = ?, relationship = ?, phone = ?, email = ?, is_primary = ?, is_emergency = ?,
+			guardian_id = ?, updated_by = ?
+		 WHERE contact_id = ?`,
+		c.Name, c.Relationship, c.Phone, nullString(c.Email), c.IsPrimary, c.IsEmergency,
+		nullInt32(c.GuardianID), nullString(userType), contactID,
+	)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return c, student.ErrGuardianNotFound
+		}
+		log.Errorf("Failed to update contact %d, Error: %v", contactID, err)
+		return c, fmt.Errorf("failed to update contact: %w", err)
+	}
+	if err := settlePrimary(ctx, tx, c.StudentID, contactID, c.IsPrimary); err != nil {
+		return c, fmt.Errorf("failed to set primary contact: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c, fmt.Errorf("failed to commit contact: %w", err)
+	}
+	log.Infof("Successfully updated contact with ID: %d", contactID)
+	return d.GetContact(ctx, contactID)
+}
+
+func (d *Database) DeleteContact(ctx context.Context, contactID int32) error {
+	tx, err := d.Client.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var userID int32
+	if err := tx.GetContext(ctx, &userID, `SELECT user_id FROM contacts WHERE contact_id = ? FOR UPDATE`, contactID); err != nil {
+		if err == sql.ErrNoRows {
+			return student.ErrContactNotFound
+		}
+		return fmt.Errorf("failed to look up contact: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM contacts WHERE contact_id = ?`, contactID); err != nil {
+		log.Errorf("Failed to delete contact %d, Error: %v", contactID, err)
+		return fmt.Errorf("failed to delete contact: %w", err)
+	}
+	if err := settlePrimary(ctx, tx, userID, 0, false); err != nil {
+		return fmt.Errorf("failed to set primary contact: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit contact delete: %w", err)
+	}
+	log.Infof("Successfully deleted contact with ID: %d", contactID)
+	return nil
+}
+
+func (d *Database) getGuardianRow(ctx context.Context, where string, args ...interface{}) (GuardianRow, error) {
+	var row GuardianRow
+	err := d.Client.GetContext(ctx, &row, "SELECT "+guardianColumns+" FROM guardians WHERE "+where, args...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return row, student.ErrGuardianNotFound
+		}
+		log.Errorf("Error fetching guardian: %v", err)
+		return row, fmt.Errorf("error fetching guardian: %w", err)
+	}
+	return row, nil
+}
+
+func (d *Database) GetAllGuardians(ctx context.Context) ([]student.Guardian, error) {
+	var rows []GuardianRow
+	if err := d.Client.SelectContext(ctx, &rows, "SELECT "+guardianColumns+" FROM guardians ORDER BY name"); err != nil {
+		log.Errorf("Error querying guardians: %v", err)
+		return nil, fmt.Errorf("error querying guardians: %w", err)
+	}
+	guardians := make([]student.Guardian, 0, len(rows))
+	for _, row := range rows {
+		guardians = append(guardians, convertGuardianRowToGuardian(row))
+	}
+	return guardians, nil
+}
+
+func (d *Database) GetGuardian(ctx context.Context, guardianID int32) (student.Guardian, error) {
+	row, err := d.getGuardianRow(ctx, "guardian_id = ?", guardianID)
+	if err != nil {
+		return student.Guardian{}, err
+	}
+	return convertGuardianRowToGuardian(row), nil
+}
+
+func (d *Database) GetGuardianByEmail(ctx context.Context, email string) (student.Guardian, error) {
+	row, err := d.getGuardianRow(ctx, "email = ?", email)
+	if err != nil {
+		return student.Guardian{}, err
+	}
+	guardian := convertGuardianRowToGuardian(row)
+	guardian.PasswordHash = row.PasswordHash
+	return guardian, nil
+}
+
+func (d *Database) AddGuardian(ctx context.Context, g student.Guardian) (student.Guardian, error) {
+	userType, _ := ctx.Value("userType").(string)
+
+	result, err := d.Client.ExecContext(
+		ctx,
+		`INSERT INTO guardians (name, email, password_hash, created_by, updated_by) VALUES (?, ?, ?, ?, ?)`,
+		g.Name, g.Email, g.PasswordHash, nullString(userType), nullString(userType),
+	)
+	if err != nil {
+		if isDuplicateKey(err) {
+			return g, student.ErrGuardianEmailUsed
+		}
+		log.Errorf("Failed to insert guardian, Error: %v", err)
+		return g, fmt.Errorf("failed to insert guardian: %w", err)
+	}
+
+	guardianID, err := result.LastInsertId()
+	if err != nil {
+		return g, fmt.Errorf("failed to retrieve last inserted ID: %w", err)
+	}
+
+	log.Infof("Successfully added guardian with ID: %d", guardianID)
+	return d.GetGuardian(ctx, int32(guardianID))
+}
+
+func (d *Database) UpdateGuardian(ctx context.Context, guardianID int32, g student.Guardian) (student.Guardian, error) {
+	if _, err := d.GetGuardian(ctx, guardianID); err != nil {
+		return g, err
+	}
+
+	userType, _ := ctx.Value("userType").(string)
+	_, err := d.Client.ExecContext(
+		ctx,
+		`UPDATE guardians SET name = ?, email = ?, password_hash = COALESCE(?, password_hash), updated_by = ?
+		 WHERE guardian_id = ?`,
+		g.Name, g.Email, nullString(g.PasswordHash), nullString(userType), guardianID,
+	)
+	if err != nil {
+		if isDuplicateKey(err) {
+			return g, student.ErrGuardianEmailUsed
+		}
+		log.Errorf("Failed to update guardian %d, Error: %v", guardianID, err)
+		return g, fmt.Errorf("failed to update guardian: %w", err)
+	}
+
+	log.Infof("Successfully updated guardian with ID: %d", guardianID)
+	return d.GetGuardian(ctx, guardianID)
+}
+
+func (d *Database) DeleteGuardian(ctx context.Context, guardianID int32) error {
+	result, err := d.Client.ExecContext(ctx, `DELETE FROM guardians WHERE guardian_id = ?`, guardianID)
+	if err != nil {
+		log.Errorf("Failed to delete guardian %d, Error: %v", guardianID, err)
+		return fmt.Errorf("failed to delete guardian: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return student.ErrGuardianNotFound
+	}
+
+	log.Infof("Successfully deleted guardian with ID: %d", guardianID)
+	return nil
+}
+
+// GetGuardianStudents leaves the students' passwords out.
+func (d *Database) GetGuardianStudents(ctx context.Context, guardianID int32) ([]student.Student, error) {
+	var rows []StudentRow
+	err := d.Client.SelectContext(ctx, &rows,
+		"SELECT "+studentColumns+` FROM students
+		 WHERE user_id IN (SELECT user_id FROM contacts WHERE guardian_id = ?)
+		 ORDER BY name`, guardianID)
+	if err != nil {
+		log.Errorf("Error querying students of guardian %d: %v", guardianID, err)
+		return nil, fmt.Errorf("error querying students of guardian: %w", err)
+	}
+	students := make([]student.Student, 0, len(rows))
+	for _, row := range rows {
+		s := convertStudentRowToStudent(row)
+		s.Password = ""
+		students = append(students, s)
+	}
+	return students, nil
+}
+
+func (d *Database) IsGuardianOf(ctx context.Context, guardianID, userID int32) (bool, error) {
+	var linked bool
+	err := d.Client.GetContext(ctx, &linked,
+		`SELECT EXISTS(SELECT 1 FROM contacts WHERE guardian_id = ? AND user_id = ?)`, guardianID, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to check guardian link: %w", err)
+	}
+	return linked, nil
+}
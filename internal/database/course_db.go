@@ -0,0 +1,243 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	student "GO_Assignment_3/internal/service"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+)
+
+const courseColumns = "course_id, code, title, credits, capacity, department, grading_scale_id, created_by, created_on, updated_by, updated_on"
+
+type CourseRow struct {
+	CourseID   int32          `db:"course_id"`
+	Code       string         `db:"code"`
+	Title      string         `db:"title"`
+	Credits    float64        `db:"credits"`
+	Capacity   sql.NullInt32  `db:"capacity"`
+	Department sql.NullString `db:"department"`
+	ScaleID    sql.NullInt32  `db:"grading_scale_id"`
+	CreatedBy  sql.NullString `db:"created_by"`
+	CreatedOn  sql.NullTime   `db:"created_on"`
+	UpdatedBy  sql.NullString `db:"updated_by"`
+	UpdatedOn  sql.NullTime   `db:"updated_on"`
+}
+
+func convertCourseRowToCourse(c CourseRow) student.Course {
+	return student.Course{
+		ID:             c.CourseID,
+		Code:           c.Code,
+		Title:          c.Title,
+		Credits:        c.Credits,
+		Capacity:       c.Capacity.Int32,
+		Department:     c.Department.String,
+		GradingScaleID: c.ScaleID.Int32,
+		CreatedBy:      c.CreatedBy.String,
+		CreatedOn:      formatNullTime(c.CreatedOn),
+		UpdatedBy:      c.UpdatedBy.String,
+		UpdatedOn:      formatNullTime(c.UpdatedOn),
+	}
+}
+
+func formatNullTime(t sql.NullTime) string {
+	if !t.Valid {
+		return ""
+	}
+	return t.Time.Format(time.RFC3339)
+}
+
+// isDuplicateKey reports a MySQL unique constraint violation.
+func isDuplicateKey(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1062
+}
+
+// isForeignKeyViolation reports a MySQL foreign key violation, either a
+// missing parent row or a parent that is still referenced.
+func isForeignKeyViolation(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && (mysqlErr.Number == 1451 || mysqlErr.Number == 1452)
+}
+
+func (d *Database) GetAllCourses(ctx context.Context) ([]student.Course, error) {
+	var rows []CourseRow
+	if err := d.Client.SelectContext(ctx, &rows, "SELECT "+courseColumns+" FROM courses ORDER BY code"); err != nil {
+		log.Errorf("Error querying courses: %v", err)
+		return nil, fmt.Errorf("error querying courses: %w", err)
+	}
+
+	courses := make([]student.Course, 0, len(rows))
+	for _, row := range rows {
+		courses = append(courses, convertCourseRowToCourse(row))
+	}
+	return courses, nil
+}
+
+func (d *Database) GetCourse(ctx context.Context, courseID int32) (student.Course, error) {
+	var row CourseRow
+	err := d.Client.GetContext(ctx, &row, "SELECT "+courseColumns+" FROM courses WHERE course_id = ?", courseID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return student.Course{}, student.ErrCourseNotFound
+		}
+		log.Errorf("Error fetching course with ID: %d, Error: %v", courseID, err)
+		return student.Course{}, fmt.Errorf("error fetching course: %w", err)
+	}
+	return convertCourseRowToCourse(row), nil
+}
+
+// GetCoursesByIDs fetches several courses in one query. IDs without a
+// course are left out.
+func (d *Database) GetCoursesByIDs(ctx context.Context, courseIDs []int32) ([]student.Course, error) {
+	if len(courseIDs) == 0 {
+		return []student.Course{}, nil
+	}
+	query, args, err := sqlx.In("SELECT "+courseColumns+" FROM courses WHERE course_id IN (?)", courseIDs)
+	if err != nil {
+		return nil, err
+	}
+	var rows []CourseRow
+	if err := d.Client.SelectContext(ctx, &rows, d.Client.Rebind(query), args...); err != nil {
+		log.Errorf("Error querying courses by ID: %v", err)
+		return nil, fmt.Errorf("error querying courses: %w", err)
+	}
+	courses := make([]student.Course, 0, len(rows))
+	for _, row := range rows {
+		courses = append(courses, convertCourseRowToCourse(row))
+	}
+	return courses, nil
+}
+
+func (d *Database) FindCourse(ctx context.Context, ref string) (student.Course, error) {
+	var row CourseRow
+	err := d.Client.GetContext(
+		ctx,
+		&row,
+		"SELECT "+courseColumns+` FROM courses
+		 WHERE LOWER(code) = LOWER(?) OR LOWER(title) = LOWER(?)
+		 ORDER BY LOWER(code) = LOWER(?) DESC LIMIT 1`,
+		ref, ref, ref,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return student.Course{}, student.ErrCourseNotFound
+		}
+		log.Errorf("Error looking up course %q, Error: %v", ref, err)
+		return student.Course{}, fmt.Errorf("error looking up course: %w", err)
+	}
+	return convertCourseRowToCourse(row), nil
+}
+
+func (d *Database) AddCourse(ctx context.Context, course student.Course) (student.Course, error) {
+	userType, _ := ctx.Value("userType").(string)
+
+	row := CourseRow{
+		Code:       course.Code,
+		Title:      course.Title,
+		Credits:    course.Credits,
+		Capacity:   nullInt32(course.Capacity),
+		Department: nullString(course.Department),
+		ScaleID:    nullInt32(course.GradingScaleID),
+		CreatedBy:  nullString(userType),
+		UpdatedBy:  nullString(userType),
+	}
+
+	result, err := d.Client.NamedExecContext(
+		ctx,
+		`INSERT INTO courses (code, title, credits, capacity, department, grading_scale_id, created_by, updated_by)
+		 VALUES (:code, :title, :credits, :capacity, :department, :grading_scale_id, :created_by, :updated_by)`,
+		row,
+	)
+	if err != nil {
+		if isDuplicateKey(err) {
+			return course, student.ErrCourseCodeTaken
+		}
+		if isForeignKeyViolation(err) {
+			return course, fmt.Errorf("%w: %v", student.ErrInvalidCourse, student.ErrGradingScaleNotFound)
+		}
+		log.Errorf("Failed to insert course, Error: %v", err)
+		return course, fmt.Errorf("failed to insert course: %w", err)
+	}
+
+	courseID, err := result.LastInsertId()
+	if err != nil {
+		return course, fmt.Errorf("failed to retrieve last inserted ID: %w", err)
+	}
+
+	log.Infof("Successfully added course with ID: %d", courseID)
+	return d.GetCourse(ctx, int32(courseID))
+}
+
+func (d *Database) UpdateCourse(ctx context.Context, courseID int32, course student.Course) (student.Course, error) {
+	userType, _ := ctx.Value("userType").(string)
+
+	tx, err := d.Client.BeginTxx(ctx, nil)
+	if err != nil {
+		return course, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(
+		ctx,
+		`UPDATE courses SET code = ?, title = ?, credits = ?, capacity = ?, department = ?, grading_scale_id = ?, updated_by = ?
+		 WHERE course_id = ?`,
+		course.Code, course.Title, course.Credits, nullInt32(course.Capacity), nullString(course.Department), nullInt32(course.GradingScaleID),
+		nullString(userType), courseID,
+	)
+	if err != nil {
+		if isDuplicateKey(err) {
+			return course, student.ErrCourseCodeTaken
+		}
+		if isForeignKeyViolation(err) {
+			return course, fmt.Errorf("%w: %v", student.ErrInvalidCourse, student.ErrGradingScaleNotFound)
+		}
+		log.Errorf("Failed to update course with ID: %d, Error: %v", courseID, err)
+		return course, fmt.Errorf("failed to update course: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		var exists bool
+		if err := tx.GetContext(ctx, &exists, `SELECT EXISTS(SELECT 1 FROM courses WHERE course_id = ?)`, courseID); err != nil {
+			return course, fmt.Errorf("failed to check existence of course: %w", err)
+		}
+		if !exists {
+			return course, student.ErrCourseNotFound
+		}
+	}
+
+	// Students keep a copy of the title for filtering and search.
+	if _, err := tx.ExecContext(ctx, `UPDATE students SET course = ? WHERE course_id = ?`, course.Title, courseID); err != nil {
+		log.Errorf("Failed to update students of course %d, Error: %v", courseID, err)
+		return course, fmt.Errorf("failed to update students of course: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return course, fmt.Errorf("failed to commit course update: %w", err)
+	}
+
+	log.Infof("Successfully updated course with ID: %d", courseID)
+	return d.GetCourse(ctx, courseID)
+}
+
+func (d *Database) DeleteCourse(ctx context.Context, courseID int32) error {
+	result, err := d.Client.ExecContext(ctx, `DELETE FROM courses WHERE course_id = ?`, courseID)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return student.ErrCourseInUse
+		}
+		log.Errorf("Failed to delete course with ID: %d, Error: %v", courseID, err)
+		return fmt.Errorf("failed to delete course: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return student.ErrCourseNotFound
+	}
+
+	log.Infof("Successfully deleted course with ID: %d", courseID)
+	return nil
+}
@@ -0,0 +1,337 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	student "GO_Assignment_3/internal/service"
+
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+)
+
+// enrollmentSelect joins the course and student so that listings can show
+// codes, titles and names without another round trip.
+const enrollmentSelect = `SELECT e.enrollment_id, e.user_id, s.name AS student_name, e.course_id,
+		c.code AS course_code, c.title AS course_title, c.credits, e.term_id, t.code AS term_code,
+		e.grade, e.status, e.created_by, e.created_on, e.updated_by, e.updated_on,
+		CASE WHEN e.status = 'waitlisted' THEN (
+			SELECT COUNT(*) FROM enrollments w
+			WHERE w.course_id = e.course_id AND w.term_id <=> e.term_id
+			  AND w.status = 'waitlisted' AND w.enrollment_id <= e.enrollment_id
+		) END AS waitlist_position
+	 FROM enrollments e
+	 JOIN courses c ON c.course_id = e.course_id
+	 JOIN students s ON s.user_id = e.user_id
+	 LEFT JOIN terms t ON t.term_id = e.term_id`
+
+type EnrollmentRow struct {
+	EnrollmentID int32          `db:"enrollment_id"`
+	UserID       int32          `db:"user_id"`
+	StudentName  sql.NullString `db:"student_name"`
+	CourseID     int32          `db:"course_id"`
+	CourseCode   sql.NullString `db:"course_code"`
+	CourseTitle  sql.NullString `db:"course_title"`
+	Credits      float64        `db:"credits"`
+	TermID       sql.NullInt32  `db:"term_id"`
+	TermCode     sql.NullString `db:"term_code"`
+	Grade        sql.NullString `db:"grade"`
+	Status       string         `db:"status"`
+	CreatedBy    sql.NullString `db:"created_by"`
+	CreatedOn    sql.NullTime   `db:"created_on"`
+	UpdatedBy    sql.NullString `db:"updated_by"`
+	UpdatedOn    sql.NullTime   `db:"updated_on"`
+	Position     sql.NullInt32  `db:"waitlist_position"`
+}
+
+func convertEnrollmentRowToEnrollment(e EnrollmentRow) student.Enrollment {
+	return student.Enrollment{
+		ID:               e.EnrollmentID,
+		StudentID:        e.UserID,
+		StudentName:      e.StudentName.String,
+		CourseID:         e.CourseID,
+		CourseCode:       e.CourseCode.String,
+		CourseTitle:      e.CourseTitle.String,
+		Credits:          e.Credits,
+		TermID:           e.TermID.Int32,
+		TermCode:         e.TermCode.String,
+		Grade:            e.Grade.String,
+		Status:           e.Status,
+		CreatedBy:        e.CreatedBy.String,
+		WaitlistPosition: e.Position.Int32,
+		CreatedOn:        formatNullTime(e.CreatedOn),
+		UpdatedBy:        e.UpdatedBy.String,
+		UpdatedOn:        formatNullTime(e.UpdatedOn),
+	}
+}
+
+func (d *Database) selectEnrollments(ctx context.Context, where string, args ...interface{}) ([]student.Enrollment, error) {
+	var rows []EnrollmentRow
+	if err := d.Client.SelectContext(ctx, &rows, enrollmentSelect+" WHERE "+where+" ORDER BY t.start_date, e.enrollment_id", args...); err != nil {
+		log.Errorf("Error querying enrollments: %v", err)
+		return nil, fmt.Errorf("error querying enrollments: %w", err)
+	}
+	enrollments := make([]student.Enrollment, 0, len(rows))
+	for _, row := range rows {
+		enrollments = append(enrollments, convertEnrollmentRowToEnrollment(row))
+	}
+	return enrollments, nil
+}
+
+func (d *Database) GetEnrollment(ctx context.Context, enrollmentID int32) (student.Enrollment, error) {
+	enrollments, err := d.selectEnrollments(ctx, "e.enrollment_id = ?", enrollmentID)
+	if err != nil {
+		return student.Enrollment{}, err
+	}
+	if len(enrollments) == 0 {
+		return student.Enrollment{}, student.ErrEnrollmentNotFound
+	}
+	return enrollments[0], nil
+}
+
+func (d *Database) GetStudentEnrollments(ctx context.Context, userID int32, termID int32) ([]student.Enrollment, error) {
+	if termID != 0 {
+		return d.selectEnrollments(ctx, "e.user_id = ? AND e.term_id = ?", userID, termID)
+	}
+	return d.selectEnrollments(ctx, "e.user_id = ?", userID)
+}
+
+// GetEnrollmentsOfStudents lists the enrollments of several students in
+// one query, every term when the term ID is zero.
+func (d *Database) GetEnrollmentsOfStudents(ctx context.Context, userIDs []int32, termID int32) ([]student.Enrollment, error) {
+	if len(userIDs) == 0 {
+		return []student.Enrollment{}, nil
+	}
+	where, args, err := sqlx.In("e.user_id IN (?)", userIDs)
+	if err != nil {
+		return nil, err
+	}
+	if termID != 0 {
+		where += " AND e.term_id = ?"
+		args = append(args, termID)
+	}
+	return d.selectEnrollments(ctx, d.Client.Rebind(where), args...)
+}
+
+func (d *Database) GetCourseEnrollments(ctx context.Context, courseID int32, termID int32) ([]student.Enrollment, error) {
+	if termID != 0 {
+		return d.selectEnrollments(ctx, "e.course_id = ? AND e.term_id = ?", courseID, termID)
+	}
+	return d.selectEnrollments(ctx, "e.course_id = ?", courseID)
+}
+
+// seatStatuses are the enrollment statuses that take up a seat, matching
+// service.HoldsSeat.
+const seatStatuses = "('enrolled', 'completed', 'failed')"
+
+// seatFor decides whether an enrollment asking for a seat in a course
+// offering gets one or goes on the waitlist. It locks the course row, so
+// concurrent enrollments in the same course are decided one at a time.
+// enrollmentID is zero for a new enrollment; an existing one only has to
+// wait for those ahead of it.
+func seatFor(ctx context.Context, tx *sqlx.Tx, courseID, termID, enrollmentID int32) (string, error) {
+	var capacity sql.NullInt32
+	if err := tx.GetContext(ctx, &capacity, `SELECT capacity FROM courses WHERE course_id = ? FOR UPDATE`, courseID); err != nil {
+		if err == sql.ErrNoRows {
+			return "", student.ErrCourseNotFound
+		}
+		return "", fmt.Errorf("failed to lock course: %w", err)
+	}
+	if !capacity.Valid || capacity.Int32 <= 0 {
+		return student.EnrollmentEnrolled, nil
+	}
+
+	var taken, waiting int
+	err := tx.GetContext(ctx, &taken,
+		`SELECT COUNT(*) FROM enrollments WHERE course_id = ? AND term_id <=> ? AND status IN `+seatStatuses,
+		courseID, nullInt32(termID))
+	if err != nil {
+		return "", fmt.Errorf("failed to count seats: %w", err)
+	}
+	err = tx.GetContext(ctx, &waiting,
+		`SELECT COUNT(*) FROM enrollments WHERE course_id = ? AND term_id <=> ? AND status = 'waitlisted'
+		   AND (? = 0 OR enrollment_id < ?)`,
+		courseID, nullInt32(termID), enrollmentID, enrollmentID)
+	if err != nil {
+		return "", fmt.Errorf("failed to count waitlist: %w", err)
+	}
+	// Nobody skips the queue, even when a seat is free.
+	if taken >= int(capacity.Int32) || waiting > 0 {
+		return student.EnrollmentWaitlisted, nil
+	}
+	return student.EnrollmentEnrolled, nil
+}
+
+func (d *Database) AddEnrollment(ctx context.Context, e student.Enrollment) (student.Enrollment, error) {
+	userType, _ := ctx.Value("userType").(string)
+
+	tx, err := d.Client.BeginTxx(ctx, nil)
+	if err != nil {
+		return e, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if e.Status == student.EnrollmentEnrolled {
+		if e.Status, err = seatFor(ctx, tx, e.CourseID, e.TermID, 0); err != nil {
+			return e, err
+		}
+	}
+
+	result, err := tx.ExecContext(
+		ctx,
+		`INSERT INTO enrollments (user_id, course_id, term_id, grade, status, created_by, updated_by)
+		 VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		e.StudentID, e.CourseID, nullInt32(e.TermID), nullString(e.Grade), e.Status, nullString(userType), nullString(userType),
+	)
+	if err != nil {
+		if isDuplicateKey(err) {
+			return e, student.ErrAlreadyEnrolled
+		}
+		if isForeignKeyViolation(err) {
+			return e, student.ErrStudentNotFound
+		}
+		log.Errorf("Failed to insert enrollment, Error: %v", err)
+		return e, fmt.Errorf("failed to insert enrollment: %w", err)
+	}
+
+	enrollmentID, err := result.LastInsertId()
+	if err != nil {
+		return e, fmt.Errorf("failed to retrieve last inserted ID: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return e, fmt.Errorf("failed to commit enrollment: %w", err)
+	}
+
+	log.Infof("Successfully added enrollment with ID: %d", enrollmentID)
+	return d.GetEnrollment(ctx, int32(enrollmentID))
+}
+
+func (d *Database) UpdateEnrollment(ctx context.Context, enrollmentID int32, e student.Enrollment) (student.Enrollment, error) {
+	userType, _ := ctx.Value("userType").(string)
+
+	tx, err := d.Client.BeginTxx(ctx, nil)
+	if err != nil {
+		return e, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Taking a seat again, e.g. after a drop, goes through the same check
+	// as a new enrollment.
+	if e.Status == student.EnrollmentEnrolled {
+		var current string
+		err := tx.GetContext(ctx, &current, `SELECT status FROM enrollments WHERE enrollment_id = ?`, enrollmentID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return e, student.ErrEnrollmentNotFound
+			}
+			return e, fmt.Errorf("failed to read enrollment: %w", err)
+		}
+		if !student.HoldsSeat(current) {
+			if e.Status, err = seatFor(ctx, tx, e.CourseID, e.TermID, enrollmentID); err != nil {
+				return e, err
+			}
+		}
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		`UPDATE enrollments SET grade = ?, status = ?, updated_by = ? WHERE enrollment_id = ?`,
+		nullString(e.Grade), e.Status, nullString(userType), enrollmentID,
+	)
+	if err != nil {
+		log.Errorf("Failed to update enrollment with ID: %d, Error: %v", enrollmentID, err)
+		return e, fmt.Errorf("failed to update enrollment: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return e, fmt.Errorf("failed to commit enrollment update: %w", err)
+	}
+
+	log.Infof("Successfully updated enrollment with ID: %d", enrollmentID)
+	return d.GetEnrollment(ctx, enrollmentID)
+}
+
+func (d *Database) PromoteWaitlisted(ctx context.Context, courseID int32) ([]student.Enrollment, error) {
+	tx, err := d.Client.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var capacity sql.NullInt32
+	if err := tx.GetContext(ctx, &capacity, `SELECT capacity FROM courses WHERE course_id = ? FOR UPDATE`, courseID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, student.ErrCourseNotFound
+		}
+		return nil, fmt.Errorf("failed to lock course: %w", err)
+	}
+
+	var waiting []struct {
+		EnrollmentID int32         `db:"enrollment_id"`
+		TermID       sql.NullInt32 `db:"term_id"`
+	}
+	err = tx.SelectContext(ctx, &waiting,
+		`SELECT enrollment_id, term_id FROM enrollments
+		 WHERE course_id = ? AND status = 'waitlisted' ORDER BY enrollment_id`, courseID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read waitlist: %w", err)
+	}
+
+	// Free seats per term, counted once per term seen on the waitlist.
+	free := map[int32]int{}
+	var promoted []int32
+	for _, w := range waiting {
+		term := w.TermID.Int32
+		if _, counted := free[term]; !counted {
+			free[term] = len(waiting) // no capacity, everyone gets a seat
+			if capacity.Valid && capacity.Int32 > 0 {
+				var taken int
+				err := tx.GetContext(ctx, &taken,
+					`SELECT COUNT(*) FROM enrollments WHERE course_id = ? AND term_id <=> ? AND status IN `+seatStatuses,
+					courseID, w.TermID)
+				if err != nil {
+					return nil, fmt.Errorf("failed to count seats: %w", err)
+				}
+				free[term] = int(capacity.Int32) - taken
+			}
+		}
+		if free[term] <= 0 {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE enrollments SET status = 'enrolled', updated_by = 'waitlist' WHERE enrollment_id = ?`,
+			w.EnrollmentID); err != nil {
+			return nil, fmt.Errorf("failed to promote enrollment: %w", err)
+		}
+		free[term]--
+		promoted = append(promoted, w.EnrollmentID)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit waitlist promotion: %w", err)
+	}
+
+	enrollments := make([]student.Enrollment, 0, len(promoted))
+	for _, id := range promoted {
+		e, err := d.GetEnrollment(ctx, id)
+		if err != nil {
+			return enrollments, err
+		}
+		enrollments = append(enrollments, e)
+	}
+	return enrollments, nil
+}
+
+func (d *Database) DeleteEnrollment(ctx context.Context, enrollmentID int32) error {
+	result, err := d.Client.ExecContext(ctx, `DELETE FROM enrollments WHERE enrollment_id = ?`, enrollmentID)
+	if err != nil {
+		log.Errorf("Failed to delete enrollment with ID: %d, Error: %v", enrollmentID, err)
+		return fmt.Errorf("failed to delete enrollment: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return student.ErrEnrollmentNotFound
+	}
+
+	log.Infof("Successfully deleted enrollment with ID: %d", enrollmentID)
+	return nil
+}
@@ -0,0 +1,114 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+
+	student "GO_Assignment_3/internal/service"
+)
+
+// filterColumns maps filterable fields onto columns. The service has already
+// rejected unknown fields, but only names from this map reach the SQL text.
+var filterColumns = map[string]string{
+	"id":         "user_id",
+	"name":       "name",
+	"course":     "course",
+	"course_id":  "course_id",
+	"grade":      "grade",
+	"created_by": "created_by",
+	"updated_by": "updated_by",
+	"created_on": "created_on",
+	"updated_on": "updated_on",
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// compileFilter turns a parsed filter into parameterized WHERE conditions
+// with the same semantics as student.Filter.Match.
+func compileFilter(f student.Filter) ([]string, []interface{}) {
+	var (
+		where []string
+		args  []interface{}
+	)
+	for _, c := range f.Conditions {
+		column, ok := filterColumns[c.Field]
+		if !ok {
+			continue
+		}
+		clause, clauseArgs := compileCondition(column, c)
+		where = append(where, clause)
+		args = append(args, clauseArgs...)
+	}
+	return where, args
+}
+
+func compileCondition(column string, c student.Condition) (string, []interface{}) {
+	if student.FilterFieldKind(c.Field) == student.KindTime {
+		return compileTimeCondition(column, c)
+	}
+
+	switch c.Op {
+	case student.OpEq:
+		return column + " = ?", []interface{}{c.Values[0]}
+	case student.OpNe:
+		// Match reads a NULL column as "" or 0, so it is not equal to any
+		// other value; a bare <> would drop the row instead.
+		zero := "''"
+		if student.FilterFieldKind(c.Field) == student.KindInt {
+			zero = "0"
+		}
+		return fmt.Sprintf("COALESCE(%s, %s) <> ?", column, zero), []interface{}{c.Values[0]}
+	case student.OpGt:
+		return column + " > ?", []interface{}{c.Values[0]}
+	case student.OpGe:
+		return column + " >= ?", []interface{}{c.Values[0]}
+	case student.OpLt:
+		return column + " < ?", []interface{}{c.Values[0]}
+	case student.OpLe:
+		return column + " <= ?", []interface{}{c.Values[0]}
+	case student.OpPrefix:
+		return column + " LIKE ?", []interface{}{likeEscaper.Replace(c.Values[0]) + "%"}
+	case student.OpContains:
+		return column + " LIKE ?", []interface{}{"%" + likeEscaper.Replace(c.Values[0]) + "%"}
+	case student.OpIn:
+		args := make([]interface{}, len(c.Values))
+		for i, v := range c.Values {
+			args[i] = v
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(c.Values)), ", ")
+		return fmt.Sprintf("%s IN (%s)", column, placeholders), args
+	}
+	return "FALSE", nil
+}
+
+// compileTimeCondition compares against the [start, end) interval a date or
+// timestamp value covers.
+func compileTimeCondition(column string, c student.Condition) (string, []interface{}) {
+	start, end, _ := student.DateBounds(c.Values[0])
+	switch c.Op {
+	case student.OpEq:
+		return fmt.Sprintf("(%s >= ? AND %s < ?)", column, column), []interface{}{start, end}
+	case student.OpNe:
+		return fmt.Sprintf("(%s < ? OR %s >= ?)", column, column), []interface{}{start, end}
+	case student.OpGt:
+		return column + " >= ?", []interface{}{end}
+	case student.OpGe:
+		return column + " >= ?", []interface{}{start}
+	case student.OpLt:
+		return column + " < ?", []interface{}{start}
+	case student.OpLe:
+		return column + " < ?", []interface{}{end}
+	case student.OpIn:
+		var (
+			parts []string
+			args  []interface{}
+		)
+		for _, v := range c.Values {
+			s, e, _ := student.DateBounds(v)
+			parts = append(parts, fmt.Sprintf("(%s >= ? AND %s < ?)", column, column))
+			args = append(args, s, e)
+		}
+		return "(" + strings.Join(parts, " OR ") + ")", args
+	}
+	return "FALSE", nil
+}
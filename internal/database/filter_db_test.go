@@ -0,0 +1,45 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	student "GO_Assignment_3/internal/service"
+)
+
+func TestCompileFilter(t *testing.T) {
+	day := time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)
+	next := day.AddDate(0, 0, 1)
+
+	tests := []struct {
+		expr  string
+		where []string
+		args  []interface{}
+	}{
+		{"", nil, nil},
+		{"name eq Jo", []string{"name = ?"}, []interface{}{"Jo"}},
+		{"grade ne A", []string{"COALESCE(grade, '') <> ?"}, []interface{}{"A"}},
+		{"course_id ne 3", []string{"COALESCE(course_id, 0) <> ?"}, []interface{}{"3"}},
+		{"id ge 5", []string{"user_id >= ?"}, []interface{}{"5"}},
+		{"name prefix 50%_", []string{"name LIKE ?"}, []interface{}{`50\%\_%`}},
+		{`name contains a\b`, []string{"name LIKE ?"}, []interface{}{`%a\\b%`}},
+		{"grade in (A, B)", []string{"grade IN (?, ?)"}, []interface{}{"A", "B"}},
+		{"created_on eq 2024-10-01", []string{"(created_on >= ? AND created_on < ?)"}, []interface{}{day, next}},
+		{"created_on ne 2024-10-01", []string{"(created_on < ? OR created_on >= ?)"}, []interface{}{day, next}},
+		{"updated_on gt 2024-10-01", []string{"updated_on >= ?"}, []interface{}{next}},
+		{"updated_on le 2024-10-01", []string{"updated_on < ?"}, []interface{}{next}},
+		{"created_on in (2024-10-01)", []string{"((created_on >= ? AND created_on < ?))"}, []interface{}{day, next}},
+		{"name eq a and id lt 9", []string{"name = ?", "user_id < ?"}, []interface{}{"a", "9"}},
+	}
+	for _, tt := range tests {
+		f, err := student.ParseFilter(tt.expr)
+		if err != nil {
+			t.Fatalf("ParseFilter(%q): %v", tt.expr, err)
+		}
+		where, args := compileFilter(f)
+		if !reflect.DeepEqual(where, tt.where) || !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("compileFilter(%q) = %q %v, want %q %v", tt.expr, where, args, tt.where, tt.args)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	student "GO_Assignment_3/internal/service"
+
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+)
+
+const gradingScaleColumns = "scale_id, name, is_default, created_by, created_on, updated_by, updated_on"
+
+type GradingScaleRow struct {
+	ScaleID   int32          `db:"scale_id"`
+	Name      string         `db:"name"`
+	IsDefault bool           `db:"is_default"`
+	CreatedBy sql.NullString `db:"created_by"`
+	CreatedOn sql.NullTime   `db:"created_on"`
+	UpdatedBy sql.NullString `db:"updated_by"`
+	UpdatedOn sql.NullTime   `db:"updated_on"`
+}
+
+type GradePointRow struct {
+	ScaleID int32   `db:"scale_id"`
+	Letter  string  `db:"letter"`
+	Points  float64 `db:"points"`
+}
+
+func convertGradingScaleRow(g GradingScaleRow, grades []student.GradePoint) student.GradingScale {
+	if grades == nil {
+		grades = []student.GradePoint{}
+	}
+	return student.GradingScale{
+		ID:        g.ScaleID,
+		Name:      g.Name,
+		IsDefault: g.IsDefault,
+		Grades:    grades,
+		CreatedBy: g.CreatedBy.String,
+		CreatedOn: formatNullTime(g.CreatedOn),
+		UpdatedBy: g.UpdatedBy.String,
+		UpdatedOn: formatNullTime(g.UpdatedOn),
+	}
+}
+
+// loadScales attaches the grades to each scale row, in their defined order.
+func (d *Database) loadScales(ctx context.Context, rows []GradingScaleRow) ([]student.GradingScale, error) {
+	scales := make([]student.GradingScale, 0, len(rows))
+	if len(rows) == 0 {
+		return scales, nil
+	}
+
+	ids := make([]int32, len(rows))
+	for i, row := range rows {
+		ids[i] = row.ScaleID
+	}
+	query, args, err := sqlx.In(
+		`SELECT scale_id, letter, points FROM grading_scale_grades WHERE scale_id IN (?) ORDER BY scale_id, position`, ids)
+	if err != nil {
+		return nil, err
+	}
+	var gradeRows []GradePointRow
+	if err := d.Client.SelectContext(ctx, &gradeRows, d.Client.Rebind(query), args...); err != nil {
+		log.Errorf("Error querying grading scale grades: %v", err)
+		return nil, fmt.Errorf("error querying grading scale grades: %w", err)
+	}
+
+	grades := map[int32][]student.GradePoint{}
+	for _, g := range gradeRows {
+		grades[g.ScaleID] = append(grades[g.ScaleID], student.GradePoint{Letter: g.Letter, Points: g.Points})
+	}
+	for _, row := range rows {
+		scales = append(scales, convertGradingScaleRow(row, grades[row.ScaleID]))
+	}
+	return scales, nil
+}
+
+func (d *Database) getGradingScaleWhere(ctx context.Context, where string, args ...interface{}) (student.GradingScale, error) {
+	var rows []GradingScaleRow
+	if err := d.Client.SelectContext(ctx, &rows, "SELECT "+gradingScaleColumns+" FROM grading_scales WHERE "+where+" LIMIT 1", args...); err != nil {
+		log.Errorf("Error fetching grading scale: %v", err)
+		return student.GradingScale{}, fmt.Errorf("error fetching grading scale: %w", err)
+	}
+	if len(rows) == 0 {
+		return student.GradingScale{}, student.ErrGradingScaleNotFound
+	}
+	scales, err := d.loadScales(ctx, rows)
+	if err != nil {
+		return student.GradingScale{}, err
+	}
+	return scales[0], nil
+}
+
+func (d *Database) GetAllGradingScales(ctx context.Context) ([]student.GradingScale, error) {
+	var rows []GradingScaleRow
+	if err := d.Client.SelectContext(ctx, &rows, "SELECT "+gradingScaleColumns+" FROM grading_scales ORDER BY name"); err != nil {
+		log.Errorf("Error querying grading scales: %v", err)
+		return nil, fmt.Errorf("error querying grading scales: %w", err)
+	}
+	return d.loadScales(ctx, rows)
+}
+
+func (d *Database) GetGradingScale(ctx context.Context, scaleID int32) (student.GradingScale, error) {
+	return d.getGradingScaleWhere(ctx, "scale_id = ?", scaleID)
+}
+
+func (d *Database) GetDefaultGradingScale(ctx context.Context) (student.GradingScale, error) {
+	return d.getGradingScaleWhere(ctx, "is_default = TRUE")
+}
+
+// writeScale stores the default flag and the grades of a scale inside tx.
+// Only one scale can be the default, so setting it clears it elsewhere.
+func writeScale(ctx context.Context, tx *sqlx.Tx, scaleID int32, scale student.GradingScale) error {
+	if scale.IsDefault {
+		if _, err := tx.ExecContext(ctx, `UPDATE grading_scales SET is_default = FALSE WHERE scale_id <> ?`, scaleID); err != nil {
+			return fmt.Errorf("failed to clear default grading scale: %w", err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM grading_scale_grades WHERE scale_id = ?`, scaleID); err != nil {
+		return fmt.Errorf("failed to clear grades: %w", err)
+	}
+	for i, gp := range scale.Grades {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO grading_scale_grades (scale_id, letter, points, position) VALUES (?, ?, ?, ?)`,
+			scaleID, gp.Letter, gp.Points, i); err != nil {
+			return fmt.Errorf("failed to insert grade %q: %w", gp.Letter, err)
+		}
+	}
+	return nil
+}
+
+func (d *Database) AddGradingScale(ctx context.Context, scale student.GradingScale) (student.GradingScale, error) {
+	userType, _ := ctx.Value("userType").(string)
+
+	tx, err := d.Client.BeginTxx(ctx, nil)
+	if err != nil {
+		return scale, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx,
+		`INSERT INTO grading_scales (name, is_default, created_by, updated_by) VALUES (?, ?, ?, ?)`,
+		scale.Name, scale.IsDefault, nullString(userType), nullString(userType))
+	if err != nil {
+		if isDuplicateKey(err) {
+			return scale, student.ErrGradingScaleTaken
+		}
+		log.Errorf("Failed to insert grading scale, Error: %v", err)
+		return scale, fmt.Errorf("failed to insert grading scale: %w", err)
+	}
+	scaleID, err := result.LastInsertId()
+	if err != nil {
+		return scale, fmt.Errorf("failed to retrieve last inserted ID: %w", err)
+	}
+
+	if err := writeScale(ctx, tx, int32(scaleID), scale); err != nil {
+		log.Errorf("Failed to write grading scale %d, Error: %v", scaleID, err)
+		return scale, err
+	}
+	if err := tx.Commit(); err != nil {
+		return scale, fmt.Errorf("failed to commit grading scale: %w", err)
+	}
+
+	log.Infof("Successfully added grading scale with ID: %d", scaleID)
+	return d.GetGradingScale(ctx, int32(scaleID))
+}
+
+func (d *Database) UpdateGradingScale(ctx context.Context, scaleID int32, scale student.GradingScale) (student.GradingScale, error) {
+	userType, _ := ctx.Value("userType").(string)
+
+	tx, err := d.Client.BeginTxx(ctx, nil)
+	if err != nil {
+		return scale, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.GetContext(ctx, &exists, `SELECT EXISTS(SELECT 1 FROM grading_scales WHERE scale_id = ?)`, scaleID); err != nil {
+		return scale, fmt.Errorf("failed to check existence of grading scale: %w", err)
+	}
+	if !exists {
+		return scale, student.ErrGradingScaleNotFound
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE grading_scales SET name = ?, is_default = ?, updated_by = ? WHERE scale_id = ?`,
+		scale.Name, scale.IsDefault, nullString(userType), scaleID); err != nil {
+		if isDuplicateKey(err) {
+			return scale, student.ErrGradingScaleTaken
+		}
+		log.Errorf("Failed to update grading scale %d, Error: %v", scaleID, err)
+		return scale, fmt.Errorf("failed to update grading scale: %w", err)
+	}
+	if err := writeScale(ctx, tx, scaleID, scale); err != nil {
+		log.Errorf("Failed to write grading scale %d, Error: %v", scaleID, err)
+		return scale, err
+	}
+	if err := tx.Commit(); err != nil {
+		return scale, fmt.Errorf("failed to commit grading scale: %w", err)
+	}
+
+	log.Infof("Successfully updated grading scale with ID: %d", scaleID)
+	return d.GetGradingScale(ctx, scaleID)
+}
+
+func (d *Database) DeleteGradingScale(ctx context.Context, scaleID int32) error {
+	result, err := d.Client.ExecContext(ctx, `DELETE FROM grading_scales WHERE scale_id = ?`, scaleID)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return student.ErrGradingScaleInUse
+		}
+		log.Errorf("Failed to delete grading scale %d, Error: %v", scaleID, err)
+		return fmt.Errorf("failed to delete grading scale: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return student.ErrGradingScaleNotFound
+	}
+
+	log.Infof("Successfully deleted grading scale with ID: %d", scaleID)
+	return nil
+}
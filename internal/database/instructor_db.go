@@ -0,0 +1,198 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	student "GO_Assignment_3/internal/service"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const instructorColumns = "instructor_id, name, email, password_hash, created_by, created_on, updated_by, updated_on"
+
+type InstructorRow struct {
+	InstructorID int32          `db:"instructor_id"`
+	Name         string         `db:"name"`
+	Email        string         `db:"email"`
+	PasswordHash string         `db:"password_hash"`
+	CreatedBy    sql.NullString `db:"created_by"`
+	CreatedOn    sql.NullTime   `db:"created_on"`
+	UpdatedBy    sql.NullString `db:"updated_by"`
+	UpdatedOn    sql.NullTime   `db:"updated_on"`
+}
+
+// convertInstructorRowToInstructor leaves the password hash out; only
+// GetInstructorByEmail hands it to the service for logins.
+func convertInstructorRowToInstructor(i InstructorRow) student.Instructor {
+	return student.Instructor{
+		ID:        i.InstructorID,
+		Name:      i.Name,
+		Email:     i.Email,
+		CreatedBy: i.CreatedBy.String,
+		CreatedOn: formatNullTime(i.CreatedOn),
+		UpdatedBy: i.UpdatedBy.String,
+		UpdatedOn: formatNullTime(i.UpdatedOn),
+	}
+}
+
+func (d *Database) getInstructorRow(ctx context.Context, where string, args ...interface{}) (InstructorRow, error) {
+	var row InstructorRow
+	err := d.Client.GetContext(ctx, &row, "SELECT "+instructorColumns+" FROM instructors WHERE "+where, args...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return row, student.ErrInstructorNotFound
+		}
+		log.Errorf("Error fetching instructor: %v", err)
+		return row, fmt.Errorf("error fetching instructor: %w", err)
+	}
+	return row, nil
+}
+
+func (d *Database) GetAllInstructors(ctx context.Context) ([]student.Instructor, error) {
+	var rows []InstructorRow
+	if err := d.Client.SelectContext(ctx, &rows, "SELECT "+instructorColumns+" FROM instructors ORDER BY name"); err != nil {
+		log.Errorf("Error querying instructors: %v", err)
+		return nil, fmt.Errorf("error querying instructors: %w", err)
+	}
+	instructors := make([]student.Instructor, 0, len(rows))
+	for _, row := range rows {
+		instructors = append(instructors, convertInstructorRowToInstructor(row))
+	}
+	return instructors, nil
+}
+
+func (d *Database) GetInstructor(ctx context.Context, instructorID int32) (student.Instructor, error) {
+	row, err := d.getInstructorRow(ctx, "instructor_id = ?", instructorID)
+	if err != nil {
+		return student.Instructor{}, err
+	}
+	return convertInstructorRowToInstructor(row), nil
+}
+
+func (d *Database) GetInstructorByEmail(ctx context.Context, email string) (student.Instructor, error) {
+	row, err := d.getInstructorRow(ctx, "email = ?", email)
+	if err != nil {
+		return student.Instructor{}, err
+	}
+	instructor := convertInstructorRowToInstructor(row)
+	instructor.PasswordHash = row.PasswordHash
+	return instructor, nil
+}
+
+func (d *Database) AddInstructor(ctx context.Context, i student.Instructor) (student.Instructor, error) {
+	userType, _ := ctx.Value("userType").(string)
+
+	result, err := d.Client.ExecContext(
+		ctx,
+		`INSERT INTO instructors (name, email, password_hash, created_by, updated_by) VALUES (?, ?, ?, ?, ?)`,
+		i.Name, i.Email, i.PasswordHash, nullString(userType), nullString(userType),
+	)
+	if err != nil {
+		if isDuplicateKey(err) {
+			return i, student.ErrInstructorEmailUsed
+		}
+		log.Errorf("Failed to insert instructor, Error: %v", err)
+		return i, fmt.Errorf("failed to insert instructor: %w", err)
+	}
+
+	instructorID, err := result.LastInsertId()
+	if err != nil {
+		return i, fmt.Errorf("failed to retrieve last inserted ID: %w", err)
+	}
+
+	log.Infof("Successfully added instructor with ID: %d", instructorID)
+	return d.GetInstructor(ctx, int32(instructorID))
+}
+
+func (d *Database) UpdateInstructor(ctx context.Context, instructorID int32, i student.Instructor) (student.Instructor, error) {
+	if _, err := d.GetInstructor(ctx, instructorID); err != nil {
+		return i, err
+	}
+
+	userType, _ := ctx.Value("userType").(string)
+	_, err := d.Client.ExecContext(
+		ctx,
+		`UPDATE instructors SET name = ?, email = ?, password_hash = COALESCE(?, password_hash), updated_by = ?
+		 WHERE instructor_id = ?`,
+		i.Name, i.Email, nullString(i.PasswordHash), nullString(userType), instructorID,
+	)
+	if err != nil {
+		if isDuplicateKey(err) {
+			return i, student.ErrInstructorEmailUsed
+		}
+		log.Errorf("Failed to update instructor %d, Error: %v", instructorID, err)
+		return i, fmt.Errorf("failed to update instructor: %w", err)
+	}
+
+	log.Infof("Successfully updated instructor with ID: %d", instructorID)
+	return d.GetInstructor(ctx, instructorID)
+}
+
+func (d *Database) DeleteInstructor(ctx context.Context, instructorID int32) error {
+	result, err := d.Client.ExecContext(ctx, `DELETE FROM instructors WHERE instructor_id = ?`, instructorID)
+	if err != nil {
+		log.Errorf("Failed to delete instructor %d, Error: %v", instructorID, err)
+		return fmt.Errorf("failed to delete instructor: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return student.ErrInstructorNotFound
+	}
+
+	log.Infof("Successfully deleted instructor with ID: %d", instructorID)
+	return nil
+}
+
+func (d *Database) GetInstructorCourses(ctx context.Context, instructorID int32) ([]student.Course, error) {
+	var rows []CourseRow
+	err := d.Client.SelectContext(ctx, &rows,
+		"SELECT "+courseColumns+` FROM courses
+		 WHERE course_id IN (SELECT course_id FROM course_instructors WHERE instructor_id = ?)
+		 ORDER BY code`, instructorID)
+	if err != nil {
+		log.Errorf("Error querying courses of instructor %d: %v", instructorID, err)
+		return nil, fmt.Errorf("error querying courses of instructor: %w", err)
+	}
+	courses := make([]student.Course, 0, len(rows))
+	for _, row := range rows {
+		courses = append(courses, convertCourseRowToCourse(row))
+	}
+	return courses, nil
+}
+
+func (d *Database) AssignCourse(ctx context.Context, instructorID, courseID int32) error {
+	_, err := d.Client.ExecContext(ctx,
+		`INSERT IGNORE INTO course_instructors (course_id, instructor_id) VALUES (?, ?)`, courseID, instructorID)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return student.ErrCourseNotFound
+		}
+		log.Errorf("Failed to assign course %d to instructor %d, Error: %v", courseID, instructorID, err)
+		return fmt.Errorf("failed to assign course: %w", err)
+	}
+	return nil
+}
+
+func (d *Database) UnassignCourse(ctx context.Context, instructorID, courseID int32) error {
+	result, err := d.Client.ExecContext(ctx,
+		`DELETE FROM course_instructors WHERE course_id = ? AND instructor_id = ?`, courseID, instructorID)
+	if err != nil {
+		log.Errorf("Failed to unassign course %d from instructor %d, Error: %v", courseID, instructorID, err)
+		return fmt.Errorf("failed to unassign course: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return student.ErrCourseNotFound
+	}
+	return nil
+}
+
+func (d *Database) Teaches(ctx context.Context, instructorID, courseID int32) (bool, error) {
+	var teaches bool
+	err := d.Client.GetContext(ctx, &teaches,
+		`SELECT EXISTS(SELECT 1 FROM course_instructors WHERE course_id = ? AND instructor_id = ?)`, courseID, instructorID)
+	if err != nil {
+		return false, fmt.Errorf("failed to check course instructor: %w", err)
+	}
+	return teaches, nil
+}
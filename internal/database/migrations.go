@@ -0,0 +1,465 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type migration struct {
+	Version int
+	Name    string
+	// Check, when set, runs before the statements and stops the migration
+	// with its error, for data the statements cannot fix on their own.
+	Check      func(ctx context.Context, d *Database) error
+	Statements []string
+}
+
+// migrations are applied in order and recorded in schema_migrations, so a
+// new schema change is added by appending to this list, never by editing an
+// entry that has already shipped.
+var migrations = []migration{
+	{
+		Version: 1,
+		Name:    "create audit_log",
+		Statements: []string{
+			`CREATE TABLE IF NOT EXISTS audit_log (
+				audit_id    BIGINT       NOT NULL AUTO_INCREMENT PRIMARY KEY,
+				actor_id    INT          NULL,
+				actor_role  VARCHAR(50)  NOT NULL,
+				action      VARCHAR(50)  NOT NULL,
+				target_id   INT          NULL,
+				diff        JSON         NULL,
+				client_ip   VARCHAR(64)  NULL,
+				request_id  VARCHAR(64)  NULL,
+				outcome     VARCHAR(20)  NOT NULL,
+				message     VARCHAR(255) NULL,
+				created_on  TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+				INDEX idx_audit_actor (actor_id),
+				INDEX idx_audit_target (target_id),
+				INDEX idx_audit_action (action),
+				INDEX idx_audit_created_on (created_on)
+			)`,
+		},
+	},
+	{
+		Version: 2,
+		Name:    "create courses and link students",
+		Statements: []string{
+			`CREATE TABLE IF NOT EXISTS courses (
+				course_id   INT          NOT NULL AUTO_INCREMENT PRIMARY KEY,
+				code        VARCHAR(20)  NOT NULL,
+				title       VARCHAR(100) NOT NULL,
+				credits     DECIMAL(4,1) NOT NULL,
+				department  VARCHAR(100) NULL,
+				created_by  VARCHAR(50)  NULL,
+				created_on  TIMESTAMP    NULL DEFAULT CURRENT_TIMESTAMP,
+				updated_by  VARCHAR(50)  NULL,
+				updated_on  TIMESTAMP    NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+				UNIQUE KEY uq_courses_code (code)
+			)`,
+			// Existing free-text courses are kept as they are with a NULL
+			// course_id; they have to be matched to catalog entries by hand.
+			`ALTER TABLE students
+				ADD COLUMN course_id INT NULL AFTER course,
+				ADD CONSTRAINT fk_students_course FOREIGN KEY (course_id) REFERENCES courses (course_id)`,
+		},
+	},
+	{
+		Version: 3,
+		Name:    "create enrollments",
+		Statements: []string{
+			`CREATE TABLE IF NOT EXISTS enrollments (
+				enrollment_id INT          NOT NULL AUTO_INCREMENT PRIMARY KEY,
+				user_id       INT          NOT NULL,
+				course_id     INT          NOT NULL,
+				grade         VARCHAR(10)  NULL,
+				status        VARCHAR(20)  NOT NULL DEFAULT 'enrolled',
+				created_by    VARCHAR(50)  NULL,
+				created_on    TIMESTAMP    NULL DEFAULT CURRENT_TIMESTAMP,
+				updated_by    VARCHAR(50)  NULL,
+				updated_on    TIMESTAMP    NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+				UNIQUE KEY uq_enrollments_student_course (user_id, course_id),
+				INDEX idx_enrollments_course (course_id),
+				CONSTRAINT fk_enrollments_student FOREIGN KEY (user_id) REFERENCES students (user_id) ON DELETE CASCADE,
+				CONSTRAINT fk_enrollments_course FOREIGN KEY (course_id) REFERENCES courses (course_id)
+			)`,
+		},
+	},
+	{
+		Version: 4,
+		Name:    "create grading scales",
+		Statements: []string{
+			`CREATE TABLE IF NOT EXISTS grading_scales (
+				scale_id    INT          NOT NULL AUTO_INCREMENT PRIMARY KEY,
+				name        VARCHAR(100) NOT NULL,
+				is_default  BOOLEAN      NOT NULL DEFAULT FALSE,
+				created_by  VARCHAR(50)  NULL,
+				created_on  TIMESTAMP    NULL DEFAULT CURRENT_TIMESTAMP,
+				updated_by  VARCHAR(50)  NULL,
+				updated_on  TIMESTAMP    NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+				UNIQUE KEY uq_grading_scales_name (name)
+			)`,
+			`CREATE TABLE IF NOT EXISTS grading_scale_grades (
+				scale_id INT          NOT NULL,
+				letter   VARCHAR(10)  NOT NULL,
+				points   DECIMAL(4,2) NOT NULL,
+				position INT          NOT NULL,
+				PRIMARY KEY (scale_id, letter),
+				CONSTRAINT fk_scale_grades_scale FOREIGN KEY (scale_id) REFERENCES grading_scales (scale_id) ON DELETE CASCADE
+			)`,
+			`ALTER TABLE courses
+				ADD COLUMN grading_scale_id INT NULL AFTER department,
+				ADD CONSTRAINT fk_courses_grading_scale FOREIGN KEY (grading_scale_id) REFERENCES grading_scales (scale_id)`,
+		},
+	},
+	{
+		Version: 5,
+		Name:    "create terms and scope enrollments",
+		Statements: []string{
+			`CREATE TABLE IF NOT EXISTS terms (
+				term_id     INT          NOT NULL AUTO_INCREMENT PRIMARY KEY,
+				code        VARCHAR(20)  NOT NULL,
+				name        VARCHAR(100) NOT NULL,
+				start_date  DATE         NOT NULL,
+				end_date    DATE         NOT NULL,
+				created_by  VARCHAR(50)  NULL,
+				created_on  TIMESTAMP    NULL DEFAULT CURRENT_TIMESTAMP,
+				updated_by  VARCHAR(50)  NULL,
+				updated_on  TIMESTAMP    NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+				UNIQUE KEY uq_terms_code (code),
+				INDEX idx_terms_dates (start_date, end_date)
+			)`,
+			// Enrollments made before terms existed keep a NULL term_id.
+			`ALTER TABLE enrollments
+				ADD COLUMN term_id INT NULL AFTER course_id,
+				ADD CONSTRAINT fk_enrollments_term FOREIGN KEY (term_id) REFERENCES terms (term_id),
+				ADD UNIQUE KEY uq_enrollments_student_course_term (user_id, course_id, term_id)`,
+			`ALTER TABLE enrollments DROP INDEX uq_enrollments_student_course`,
+		},
+	},
+	{
+		Version: 6,
+		Name:    "create transcripts",
+		Statements: []string{
+			`CREATE TABLE IF NOT EXISTS transcripts (
+				code          VARCHAR(24)  NOT NULL PRIMARY KEY,
+				user_id       INT          NOT NULL,
+				student_name  VARCHAR(100) NOT NULL,
+				gpa           DECIMAL(5,2) NOT NULL,
+				credits       DECIMAL(6,1) NOT NULL,
+				digest        CHAR(64)     NOT NULL,
+				issued_by     VARCHAR(50)  NULL,
+				issued_on     DATETIME     NOT NULL,
+				INDEX idx_transcripts_student (user_id)
+			)`,
+		},
+	},
+	{
+		Version: 7,
+		Name:    "add course capacity",
+		Statements: []string{
+			// NULL capacity means the course has no seat limit.
+			`ALTER TABLE courses ADD COLUMN capacity INT NULL AFTER credits`,
+			`CREATE INDEX idx_enrollments_offering ON enrollments (course_id, term_id, status)`,
+		},
+	},
+	{
+		Version: 8,
+		Name:    "create prerequisites and overrides",
+		Statements: []string{
+			// Rows with the same group_no are alternatives; a course requires
+			// every one of its groups.
+			`CREATE TABLE IF NOT EXISTS course_prerequisites (
+				course_id          INT         NOT NULL,
+				group_no           INT         NOT NULL,
+				required_course_id INT         NOT NULL,
+				min_grade          VARCHAR(10) NULL,
+				PRIMARY KEY (course_id, group_no, required_course_id),
+				CONSTRAINT fk_prerequisites_course FOREIGN KEY (course_id) REFERENCES courses (course_id) ON DELETE CASCADE,
+				CONSTRAINT fk_prerequisites_required FOREIGN KEY (required_course_id) REFERENCES courses (course_id)
+			)`,
+			`CREATE TABLE IF NOT EXISTS prerequisite_overrides (
+				override_id INT          NOT NULL AUTO_INCREMENT PRIMARY KEY,
+				user_id     INT          NOT NULL,
+				course_id   INT          NOT NULL,
+				reason      VARCHAR(255) NOT NULL,
+				created_by  VARCHAR(50)  NULL,
+				created_on  TIMESTAMP    NULL DEFAULT CURRENT_TIMESTAMP,
+				UNIQUE KEY uq_overrides_student_course (user_id, course_id),
+				CONSTRAINT fk_overrides_student FOREIGN KEY (user_id) REFERENCES students (user_id) ON DELETE CASCADE,
+				CONSTRAINT fk_overrides_course FOREIGN KEY (course_id) REFERENCES courses (course_id) ON DELETE CASCADE
+			)`,
+		},
+	},
+	{
+		Version: 9,
+		Name:    "create instructors",
+		Statements: []string{
+			`CREATE TABLE IF NOT EXISTS instructors (
+				instructor_id INT          NOT NULL AUTO_INCREMENT PRIMARY KEY,
+				name          VARCHAR(100) NOT NULL,
+				email         VARCHAR(255) NOT NULL,
+				password_hash VARCHAR(100) NOT NULL,
+				created_by    VARCHAR(50)  NULL,
+				created_on    TIMESTAMP    NULL DEFAULT CURRENT_TIMESTAMP,
+				updated_by    VARCHAR(50)  NULL,
+				updated_on    TIMESTAMP    NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+				UNIQUE KEY uq_instructors_email (email)
+			)`,
+			`CREATE TABLE IF NOT EXISTS course_instructors (
+				course_id     INT NOT NULL,
+				instructor_id INT NOT NULL,
+				PRIMARY KEY (course_id, instructor_id),
+				INDEX idx_course_instructors_instructor (instructor_id),
+				CONSTRAINT fk_course_instructors_course FOREIGN KEY (course_id) REFERENCES courses (course_id) ON DELETE CASCADE,
+				CONSTRAINT fk_course_instructors_instructor FOREIGN KEY (instructor_id) REFERENCES instructors (instructor_id) ON DELETE CASCADE
+			)`,
+		},
+	},
+	{
+		Version: 10,
+		Name:    "create class sessions and attendance",
+		Statements: []string{
+			`CREATE TABLE IF NOT EXISTS class_sessions (
+				session_id    INT AUTO_INCREMENT PRIMARY KEY,
+				course_id     INT          NOT NULL,
+				term_id       INT          NOT NULL,
+				session_date  DATE         NOT NULL,
+				topic         VARCHAR(255) NOT NULL DEFAULT '',
+				created_by    VARCHAR(50)  NULL,
+				created_on    TIMESTAMP    NULL DEFAULT CURRENT_TIMESTAMP,
+				updated_by    VARCHAR(50)  NULL,
+				updated_on    TIMESTAMP    NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+				INDEX idx_class_sessions_offering (course_id, term_id, session_date),
+				CONSTRAINT fk_class_sessions_course FOREIGN KEY (course_id) REFERENCES courses (course_id) ON DELETE CASCADE,
+				CONSTRAINT fk_class_sessions_term FOREIGN KEY (term_id) REFERENCES terms (term_id)
+			)`,
+			`CREATE TABLE IF NOT EXISTS attendance (
+				session_id    INT          NOT NULL,
+				enrollment_id INT          NOT NULL,
+				mark          VARCHAR(10)  NOT NULL,
+				note          VARCHAR(255) NULL,
+				recorded_by   VARCHAR(50)  NULL,
+				recorded_on   TIMESTAMP    NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+				PRIMARY KEY (session_id, enrollment_id),
+				INDEX idx_attendance_enrollment (enrollment_id),
+				CONSTRAINT fk_attendance_session FOREIGN KEY (session_id) REFERENCES class_sessions (session_id) ON DELETE CASCADE,
+				CONSTRAINT fk_attendance_enrollment FOREIGN KEY (enrollment_id) REFERENCES enrollments (enrollment_id) ON DELETE CASCADE
+			)`,
+		},
+	},
+	{
+		Version: 11,
+		Name:    "create assignments and submissions",
+		Statements: []string{
+			`CREATE TABLE IF NOT EXISTS assignments (
+				assignment_id INT AUTO_INCREMENT PRIMARY KEY,
+				course_id     INT           NOT NULL,
+				term_id       INT           NOT NULL,
+				title         VARCHAR(200)  NOT NULL,
+				description   TEXT          NULL,
+				due_at        DATETIME      NOT NULL,
+				max_points    DECIMAL(7,2)  NOT NULL,
+				reject_late   BOOLEAN       NOT NULL DEFAULT FALSE,
+				created_by    VARCHAR(50)   NULL,
+				created_on    TIMESTAMP     NULL DEFAULT CURRENT_TIMESTAMP,
+				updated_by    VARCHAR(50)   NULL,
+				updated_on    TIMESTAMP     NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+				INDEX idx_assignments_offering (course_id, term_id, due_at),
+				CONSTRAINT fk_assignments_course FOREIGN KEY (course_id) REFERENCES courses (course_id) ON DELETE CASCADE,
+				CONSTRAINT fk_assignments_term FOREIGN KEY (term_id) REFERENCES terms (term_id)
+			)`,
+			`CREATE TABLE IF NOT EXISTS submissions (
+				submission_id INT AUTO_INCREMENT PRIMARY KEY,
+				assignment_id INT           NOT NULL,
+				user_id       INT           NOT NULL,
+				file_name     VARCHAR(255)  NOT NULL,
+				content_type  VARCHAR(100)  NOT NULL,
+				size          BIGINT        NOT NULL,
+				storage_key   VARCHAR(500)  NOT NULL,
+				submitted_on  DATETIME      NOT NULL,
+				is_late       BOOLEAN       NOT NULL DEFAULT FALSE,
+				points        DECIMAL(7,2)  NULL,
+				feedback      TEXT          NULL,
+				graded_by     VARCHAR(50)   NULL,
+				graded_on     TIMESTAMP     NULL,
+				UNIQUE KEY uq_submissions_assignment_student (assignment_id, user_id),
+				INDEX idx_submissions_student (user_id),
+				CONSTRAINT fk_submissions_assignment FOREIGN KEY (assignment_id) REFERENCES assignments (assignment_id) ON DELETE CASCADE,
+				CONSTRAINT fk_submissions_student FOREIGN KEY (user_id) REFERENCES students (user_id) ON DELETE CASCADE
+			)`,
+		},
+	},
+	{
+		Version: 12,
+		Name:    "create guardians and contacts",
+		Statements: []string{
+			`CREATE TABLE IF NOT EXISTS guardians (
+				guardian_id   INT AUTO_INCREMENT PRIMARY KEY,
+				name          VARCHAR(100) NOT NULL,
+				email         VARCHAR(255) NOT NULL,
+				password_hash VARCHAR(100) NOT NULL,
+				created_by    VARCHAR(50)  NULL,
+				created_on    TIMESTAMP    NULL DEFAULT CURRENT_TIMESTAMP,
+				updated_by    VARCHAR(50)  NULL,
+				updated_on    TIMESTAMP    NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+				UNIQUE KEY uq_guardians_email (email)
+			)`,
+			`CREATE TABLE IF NOT EXISTS contacts (
+				contact_id    INT AUTO_INCREMENT PRIMARY KEY,
+				user_id       INT          NOT NULL,
+				name          VARCHAR(100) NOT NULL,
+				relationship  VARCHAR(20)  NOT NULL,
+				phone         VARCHAR(30)  NOT NULL,
+				email         VARCHAR(255) NULL,
+				is_primary    BOOLEAN      NOT NULL DEFAULT FALSE,
+				is_emergency  BOOLEAN      NOT NULL DEFAULT FALSE,
+				guardian_id   INT          NULL,
+				created_by    VARCHAR(50)  NULL,
+				created_on    TIMESTAMP    NULL DEFAULT CURRENT_TIMESTAMP,
+				updated_by    VARCHAR(50)  NULL,
+				updated_on    TIMESTAMP    NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+				INDEX idx_contacts_student (user_id),
+				INDEX idx_contacts_guardian (guardian_id),
+				CONSTRAINT fk_contacts_student FOREIGN KEY (user_id) REFERENCES students (user_id) ON DELETE CASCADE,
+				CONSTRAINT fk_contacts_guardian FOREIGN KEY (guardian_id) REFERENCES guardians (guardian_id) ON DELETE SET NULL
+			)`,
+		},
+	},
+	{
+		Version: 13,
+		Name:    "seed the default grading scale",
+		Statements: []string{
+			// The grades of service.StandardGradingScale. Installs that
+			// already have a default scale keep it.
+			`INSERT INTO grading_scales (name, is_default, created_by, updated_by)
+				SELECT 'Standard letter grades', TRUE, 'system', 'system' FROM DUAL
+				WHERE NOT EXISTS (SELECT 1 FROM grading_scales WHERE is_default = TRUE OR name = 'Standard letter grades')`,
+			`INSERT IGNORE INTO grading_scale_grades (scale_id, letter, points, position)
+				SELECT s.scale_id, g.letter, g.points, g.position
+				FROM grading_scales s
+				JOIN (
+					SELECT 'A' AS letter, 4.0 AS points, 0 AS position
+					UNION ALL SELECT 'A-', 3.7, 1
+					UNION ALL SELECT 'B+', 3.3, 2
+					UNION ALL SELECT 'B', 3.0, 3
+					UNION ALL SELECT 'B-', 2.7, 4
+					UNION ALL SELECT 'C+', 2.3, 5
+					UNION ALL SELECT 'C', 2.0, 6
+					UNION ALL SELECT 'C-', 1.7, 7
+					UNION ALL SELECT 'D+', 1.3, 8
+					UNION ALL SELECT 'D', 1.0, 9
+					UNION ALL SELECT 'F', 0.0, 10
+				) g
+				WHERE s.name = 'Standard letter grades' AND s.created_by = 'system'`,
+		},
+	},
+	{
+		Version: 14,
+		Name:    "require a term on every enrollment",
+		Check:   checkLegacyEnrollmentDuplicates,
+		Statements: []string{
+			// MySQL treats NULLs as distinct in unique keys, so enrollments
+			// without a term escaped uq_enrollments_student_course_term.
+			// They move to a LEGACY term whose dates keep it from ever
+			// being the current term. Duplicates among them would break
+			// the key, and are left to checkLegacyEnrollmentDuplicates.
+			`INSERT INTO terms (code, name, start_date, end_date, created_by, updated_by)
+				SELECT 'LEGACY', 'Before terms', '1970-01-01', '1970-01-01', 'system', 'system' FROM DUAL
+				WHERE EXISTS (SELECT 1 FROM enrollments WHERE term_id IS NULL)
+				AND NOT EXISTS (SELECT 1 FROM terms WHERE code = 'LEGACY')`,
+			`UPDATE enrollments SET term_id = (SELECT term_id FROM terms WHERE code = 'LEGACY') WHERE term_id IS NULL`,
+			`ALTER TABLE enrollments MODIFY term_id INT NOT NULL`,
+		},
+	},
+	{
+		Version: 15,
+		Name:    "keep the body of issued transcripts",
+		Statements: []string{
+			// The transcript as issued, so that verification can recompute
+			// its digest. NULL for transcripts issued before this column.
+			`ALTER TABLE transcripts ADD COLUMN body MEDIUMTEXT NULL`,
+		},
+	},
+}
+
+// checkLegacyEnrollmentDuplicates stops migration 14 while a student has
+// several enrollments in a course without a term. Which of them to keep
+// depends on their grades and attendance, so they are listed for an admin to
+// resolve rather than deleted.
+func checkLegacyEnrollmentDuplicates(ctx context.Context, d *Database) error {
+	var duplicates []struct {
+		UserID      int32  `db:"user_id"`
+		CourseID    int32  `db:"course_id"`
+		Enrollments string `db:"enrollments"`
+	}
+	err := d.Client.SelectContext(ctx, &duplicates, `SELECT user_id, course_id,
+			GROUP_CONCAT(enrollment_id ORDER BY enrollment_id SEPARATOR ', ') AS enrollments
+		FROM enrollments WHERE term_id IS NULL
+		GROUP BY user_id, course_id HAVING COUNT(*) > 1`)
+	if err != nil {
+		return fmt.Errorf("failed to look for duplicate enrollments: %w", err)
+	}
+	if len(duplicates) == 0 {
+		return nil
+	}
+
+	groups := make([]string, len(duplicates))
+	for i, dup := range duplicates {
+		groups[i] = fmt.Sprintf("student %d in course %d (enrollments %s)", dup.UserID, dup.CourseID, dup.Enrollments)
+	}
+	return fmt.Errorf("enrollments without a term are duplicated for %s; delete all but one enrollment of each student and course, then restart",
+		strings.Join(groups, "; "))
+}
+
+func (d *Database) Migrate(ctx context.Context) error {
+	log.Info("Running database migrations")
+
+	_, err := d.Client.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INT          NOT NULL PRIMARY KEY,
+		name       VARCHAR(100) NOT NULL,
+		applied_on TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		log.Errorf("Failed to create schema_migrations table, Error: %v", err)
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	var applied []int
+	if err := d.Client.SelectContext(ctx, &applied, `SELECT version FROM schema_migrations`); err != nil {
+		log.Errorf("Failed to read applied migrations, Error: %v", err)
+		return fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	done := make(map[int]bool, len(applied))
+	for _, v := range applied {
+		done[v] = true
+	}
+
+	for _, m := range migrations {
+		if done[m.Version] {
+			continue
+		}
+		if m.Check != nil {
+			if err := m.Check(ctx, d); err != nil {
+				log.Errorf("Migration %d (%s) cannot be applied, Error: %v", m.Version, m.Name, err)
+				return fmt.Errorf("migration %d (%s) cannot be applied: %w", m.Version, m.Name, err)
+			}
+		}
+		for _, stmt := range m.Statements {
+			if _, err := d.Client.ExecContext(ctx, stmt); err != nil {
+				log.Errorf("Migration %d (%s) failed, Error: %v", m.Version, m.Name, err)
+				return fmt.Errorf("migration %d (%s) failed: %w", m.Version, m.Name, err)
+			}
+		}
+		if _, err := d.Client.ExecContext(ctx,
+			`INSERT INTO schema_migrations (version, name) VALUES (?, ?)`, m.Version, m.Name); err != nil {
+			return fmt.Errorf("failed to record migration %d: %w", m.Version, err)
+		}
+		log.Infof("Applied migration %d (%s)", m.Version, m.Name)
+	}
+
+	return nil
+}
@@ -0,0 +1,171 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	student "GO_Assignment_3/internal/service"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type PrerequisiteRow struct {
+	GroupNo    int32          `db:"group_no"`
+	CourseID   int32          `db:"required_course_id"`
+	CourseCode string         `db:"code"`
+	MinGrade   sql.NullString `db:"min_grade"`
+}
+
+type OverrideRow struct {
+	OverrideID int32          `db:"override_id"`
+	UserID     int32          `db:"user_id"`
+	CourseID   int32          `db:"course_id"`
+	CourseCode string         `db:"code"`
+	Reason     string         `db:"reason"`
+	CreatedBy  sql.NullString `db:"created_by"`
+	CreatedOn  sql.NullTime   `db:"created_on"`
+}
+
+func (d *Database) GetPrerequisites(ctx context.Context, courseID int32) ([]student.PrerequisiteGroup, error) {
+	var rows []PrerequisiteRow
+	err := d.Client.SelectContext(ctx, &rows,
+		`SELECT p.group_no, p.required_course_id, c.code, p.min_grade
+		 FROM course_prerequisites p
+		 JOIN courses c ON c.course_id = p.required_course_id
+		 WHERE p.course_id = ?
+		 ORDER BY p.group_no, c.code`, courseID)
+	if err != nil {
+		log.Errorf("Error querying prerequisites of course %d: %v", courseID, err)
+		return nil, fmt.Errorf("error querying prerequisites: %w", err)
+	}
+
+	groups := []student.PrerequisiteGroup{}
+	for i, row := range rows {
+		if i == 0 || row.GroupNo != rows[i-1].GroupNo {
+			groups = append(groups, student.PrerequisiteGroup{})
+		}
+		g := &groups[len(groups)-1]
+		g.AnyOf = append(g.AnyOf, student.Prerequisite{
+			CourseID:   row.CourseID,
+			CourseCode: row.CourseCode,
+			MinGrade:   row.MinGrade.String,
+		})
+	}
+	return groups, nil
+}
+
+func (d *Database) SetPrerequisites(ctx context.Context, courseID int32, groups []student.PrerequisiteGroup) error {
+	tx, err := d.Client.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM course_prerequisites WHERE course_id = ?`, courseID); err != nil {
+		return fmt.Errorf("failed to clear prerequisites: %w", err)
+	}
+	for i, g := range groups {
+		for _, p := range g.AnyOf {
+			_, err := tx.ExecContext(ctx,
+				`INSERT INTO course_prerequisites (course_id, group_no, required_course_id, min_grade) VALUES (?, ?, ?, ?)`,
+				courseID, i+1, p.CourseID, nullString(p.MinGrade))
+			if err != nil {
+				if isDuplicateKey(err) {
+					return fmt.Errorf("%w: course %d is listed twice in group %d", student.ErrInvalidPrerequisites, p.CourseID, i+1)
+				}
+				if isForeignKeyViolation(err) {
+					return student.ErrUnknownCourse
+				}
+				log.Errorf("Failed to insert prerequisite of course %d, Error: %v", courseID, err)
+				return fmt.Errorf("failed to insert prerequisite: %w", err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit prerequisites: %w", err)
+	}
+	log.Infof("Successfully set prerequisites of course %d", courseID)
+	return nil
+}
+
+func (d *Database) GetStudentOverrides(ctx context.Context, userID int32) ([]student.PrerequisiteOverride, error) {
+	var rows []OverrideRow
+	err := d.Client.SelectContext(ctx, &rows,
+		`SELECT o.override_id, o.user_id, o.course_id, c.code, o.reason, o.created_by, o.created_on
+		 FROM prerequisite_overrides o
+		 JOIN courses c ON c.course_id = o.course_id
+		 WHERE o.user_id = ?
+		 ORDER BY o.override_id`, userID)
+	if err != nil {
+		log.Errorf("Error querying overrides of student %d: %v", userID, err)
+		return nil, fmt.Errorf("error querying overrides: %w", err)
+	}
+
+	overrides := make([]student.PrerequisiteOverride, 0, len(rows))
+	for _, row := range rows {
+		overrides = append(overrides, student.PrerequisiteOverride{
+			ID:         row.OverrideID,
+			StudentID:  row.UserID,
+			CourseID:   row.CourseID,
+			CourseCode: row.CourseCode,
+			Reason:     row.Reason,
+			CreatedBy:  row.CreatedBy.String,
+			CreatedOn:  formatNullTime(row.CreatedOn),
+		})
+	}
+	return overrides, nil
+}
+
+func (d *Database) HasOverride(ctx context.Context, userID, courseID int32) (bool, error) {
+	var exists bool
+	err := d.Client.GetContext(ctx, &exists,
+		`SELECT EXISTS(SELECT 1 FROM prerequisite_overrides WHERE user_id = ? AND course_id = ?)`, userID, courseID)
+	if err != nil {
+		return false, fmt.Errorf("failed to check override: %w", err)
+	}
+	return exists, nil
+}
+
+func (d *Database) AddOverride(ctx context.Context, o student.PrerequisiteOverride) (student.PrerequisiteOverride, error) {
+	userType, _ := ctx.Value("userType").(string)
+
+	result, err := d.Client.ExecContext(ctx,
+		`INSERT INTO prerequisite_overrides (user_id, course_id, reason, created_by) VALUES (?, ?, ?, ?)`,
+		o.StudentID, o.CourseID, o.Reason, nullString(userType))
+	if err != nil {
+		if isDuplicateKey(err) {
+			return o, student.ErrOverrideExists
+		}
+		log.Errorf("Failed to insert override, Error: %v", err)
+		return o, fmt.Errorf("failed to insert override: %w", err)
+	}
+
+	overrideID, err := result.LastInsertId()
+	if err != nil {
+		return o, fmt.Errorf("failed to retrieve last inserted ID: %w", err)
+	}
+	overrides, err := d.GetStudentOverrides(ctx, o.StudentID)
+	if err != nil {
+		return o, err
+	}
+	for _, created := range overrides {
+		if created.ID == int32(overrideID) {
+			return created, nil
+		}
+	}
+	return o, student.ErrOverrideNotFound
+}
+
+func (d *Database) DeleteOverride(ctx context.Context, overrideID int32) error {
+	result, err := d.Client.ExecContext(ctx, `DELETE FROM prerequisite_overrides WHERE override_id = ?`, overrideID)
+	if err != nil {
+		log.Errorf("Failed to delete override %d, Error: %v", overrideID, err)
+		return fmt.Errorf("failed to delete override: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return student.ErrOverrideNotFound
+	}
+	return nil
+}
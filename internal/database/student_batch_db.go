@@ -0,0 +1,181 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	student "GO_Assignment_3/internal/service"
+
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+)
+
+// studentInsertChunk is how many students one INSERT statement carries, to
+// keep statements well under max_allowed_packet.
+const studentInsertChunk = 500
+
+// insertStudents inserts students with multi-row INSERTs and returns their
+// IDs in order. MySQL gives the rows of one simple multi-row INSERT
+// consecutive auto-increment IDs starting at LAST_INSERT_ID().
+func insertStudents(ctx context.Context, e sqlx.ExtContext, students []student.Student, createdBy string) ([]int32, error) {
+	ids := make([]int32, 0, len(students))
+	for start := 0; start < len(students); start += studentInsertChunk {
+		end := min(start+studentInsertChunk, len(students))
+		rows := make([]StudentRow, 0, end-start)
+		for _, s := range students[start:end] {
+			rows = append(rows, StudentRow{
+				Password:  sql.NullString{String: s.Password, Valid: true},
+				Name:      sql.NullString{String: s.Name, Valid: true},
+				Course:    sql.NullString{String: s.Course, Valid: true},
+				CourseID:  nullInt32(s.CourseID),
+				Grade:     sql.NullString{String: s.Grade, Valid: true},
+				CreatedBy: sql.NullString{String: createdBy, Valid: true},
+				UpdatedBy: sql.NullString{String: s.UpdatedBy, Valid: true},
+			})
+		}
+
+		result, err := sqlx.NamedExecContext(
+			ctx,
+			e,
+			`INSERT INTO students (password, name, course, course_id, grade, created_by, updated_by)
+			 VALUES (:password, :name, :course, :course_id, :grade, :created_by, :updated_by)`,
+			rows,
+		)
+		if err != nil {
+			log.Errorf("Failed to insert %d students, Error: %v", len(rows), err)
+			return ids, fmt.Errorf("failed to insert students: %w", err)
+		}
+		first, err := result.LastInsertId()
+		if err != nil {
+			return ids, fmt.Errorf("failed to retrieve last inserted ID: %w", err)
+		}
+		for i := range rows {
+			ids = append(ids, int32(first)+int32(i))
+		}
+	}
+	return ids, nil
+}
+
+// selectStudentsByID reads students back by ID, keyed by ID.
+func selectStudentsByID(ctx context.Context, e sqlx.ExtContext, ids []int32) (map[int32]student.Student, error) {
+	students := make(map[int32]student.Student, len(ids))
+	if len(ids) == 0 {
+		return students, nil
+	}
+	query, args, err := sqlx.In("SELECT "+studentColumns+" FROM students WHERE user_id IN (?)", ids)
+	if err != nil {
+		return nil, err
+	}
+	var rows []StudentRow
+	if err := sqlx.SelectContext(ctx, e, &rows, e.Rebind(query), args...); err != nil {
+		log.Errorf("Error querying students by ID: %v", err)
+		return nil, fmt.Errorf("error querying students: %w", err)
+	}
+	for _, row := range rows {
+		students[row.User_ID] = convertStudentRowToStudent(row)
+	}
+	return students, nil
+}
+
+// AddStudents inserts several students in one transaction and returns them
+// as stored, in order.
+func (d *Database) AddStudents(ctx context.Context, students []student.Student) ([]student.Student, error) {
+	userType, ok := ctx.Value("userType").(string)
+	if !ok {
+		log.Error("User type not found in context")
+		return nil, errors.New("context Error")
+	}
+
+	tx, err := d.Client.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	ids, err := insertStudents(ctx, tx, students, userType)
+	if err != nil {
+		return nil, err
+	}
+	stored, err := selectStudentsByID(ctx, tx, ids)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit student insert: %w", err)
+	}
+
+	added := make([]student.Student, len(ids))
+	for i, id := range ids {
+		added[i] = stored[id]
+	}
+	log.Infof("Successfully added %d students", len(added))
+	return added, nil
+}
+
+// ApplyStudentBatch runs a batch in one transaction: the creates first, all
+// together, then the updates and deletes in order. The first failure rolls
+// everything back and is returned as a *StudentOpError; a failed INSERT is
+// blamed on the first create it carried.
+func (d *Database) ApplyStudentBatch(ctx context.Context, ops []student.StudentOp) ([]student.Student, error) {
+	userType, ok := ctx.Value("userType").(string)
+	if !ok {
+		log.Error("User type not found in context")
+		return nil, errors.New("context Error")
+	}
+
+	tx, err := d.Client.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var creates []int
+	var students []student.Student
+	for i, op := range ops {
+		if op.Op == student.BatchCreate {
+			creates = append(creates, i)
+			students = append(students, *op.Student)
+		}
+	}
+	ids, err := insertStudents(ctx, tx, students, userType)
+	if err != nil {
+		return nil, &student.StudentOpError{Index: creates[len(ids)], Err: err}
+	}
+
+	written := make([]int32, len(ops))
+	for n, i := range creates {
+		written[i] = ids[n]
+	}
+	for i, op := range ops {
+		switch op.Op {
+		case student.BatchUpdate:
+			if _, err := updateStudent(ctx, tx, op.ID, *op.Student); err != nil {
+				return nil, &student.StudentOpError{Index: i, Err: err}
+			}
+			written[i] = op.ID
+		case student.BatchDelete:
+			if err := deleteStudent(ctx, tx, op.ID); err != nil {
+				return nil, &student.StudentOpError{Index: i, Err: err}
+			}
+		}
+	}
+
+	stored, err := selectStudentsByID(ctx, tx, written)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit student batch: %w", err)
+	}
+
+	results := make([]student.Student, len(ops))
+	for i, id := range written {
+		if id != 0 {
+			results[i] = stored[id]
+		}
+	}
+	log.Infof("Successfully applied a batch of %d student operations", len(ops))
+	return results, nil
+}
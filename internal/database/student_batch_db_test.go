@@ -0,0 +1,83 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	student "GO_Assignment_3/internal/service"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// fakeInserts answers each INSERT with the next of firstIDs as
+// LAST_INSERT_ID(), or with err once firstIDs runs out.
+type fakeInserts struct {
+	sqlx.ExtContext
+
+	firstIDs []int64
+	err      error
+	rows     []int
+}
+
+func (f *fakeInserts) DriverName() string { return "mysql" }
+
+func (f *fakeInserts) ExecContext(_ context.Context, _ string, args ...interface{}) (sql.Result, error) {
+	if len(f.firstIDs) == 0 {
+		return nil, f.err
+	}
+	f.rows = append(f.rows, len(args)/7)
+	first := f.firstIDs[0]
+	f.firstIDs = f.firstIDs[1:]
+	return fakeResult(first), nil
+}
+
+type fakeResult int64
+
+func (r fakeResult) LastInsertId() (int64, error) { return int64(r), nil }
+func (r fakeResult) RowsAffected() (int64, error) { return 0, nil }
+
+func batchOfStudents(n int) []student.Student {
+	students := make([]student.Student, n)
+	for i := range students {
+		students[i] = student.Student{Name: fmt.Sprintf("Student %d", i)}
+	}
+	return students
+}
+
+func TestInsertStudentsIDs(t *testing.T) {
+	// Other writers may take IDs between the chunks, so each chunk starts
+	// from its own LAST_INSERT_ID().
+	e := &fakeInserts{firstIDs: []int64{10, 700, 2000}}
+	ids, err := insertStudents(context.Background(), e, batchOfStudents(1201), "admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(e.rows) != 3 || e.rows[0] != 500 || e.rows[1] != 500 || e.rows[2] != 201 {
+		t.Errorf("inserted rows per statement %v, want [500 500 201]", e.rows)
+	}
+	if len(ids) != 1201 {
+		t.Fatalf("got %d IDs, want 1201", len(ids))
+	}
+	for i, want := range map[int]int32{0: 10, 499: 509, 500: 700, 999: 1199, 1000: 2000, 1200: 2200} {
+		if ids[i] != want {
+			t.Errorf("ID %d = %d, want %d", i, ids[i], want)
+		}
+	}
+}
+
+func TestInsertStudentsFailedChunk(t *testing.T) {
+	// The IDs of the chunks before a failed one are returned, so that
+	// ApplyStudentBatch blames the first create of the failed chunk.
+	failed := errors.New("duplicate entry")
+	e := &fakeInserts{firstIDs: []int64{10}, err: failed}
+	ids, err := insertStudents(context.Background(), e, batchOfStudents(600), "admin")
+	if !errors.Is(err, failed) {
+		t.Fatalf("got %v, want the insert error", err)
+	}
+	if len(ids) != 500 || ids[499] != 509 {
+		t.Errorf("got %d IDs ending in %d, want 500 ending in 509", len(ids), ids[len(ids)-1])
+	}
+}
@@ -5,15 +5,19 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	student "GO_Assignment_3/internal/service"
 
+	"github.com/jmoiron/sqlx"
 	log "github.com/sirupsen/logrus"
 )
 
 var (
-	ErrStudentNotFound = errors.New("student not found")
+	// ErrStudentNotFound is the service's error, so that callers can match
+	// it with errors.Is whichever layer returned it.
+	ErrStudentNotFound = student.ErrStudentNotFound
 	ErrStudentUpdate   = errors.New("unable to update student details")
 	ErrStudentDelete   = errors.New("unable to delete student")
 )
@@ -23,6 +27,7 @@ type StudentRow struct {
 	Password  sql.NullString `db:"password"`
 	Name      sql.NullString `db:"name"`
 	Course    sql.NullString `db:"course"`
+	CourseID  sql.NullInt32  `db:"course_id"`
 	Grade     sql.NullString `db:"grade"`
 	CreatedBy sql.NullString `db:"created_by"`
 	CreatedOn time.Time      `db:"created_on"`
@@ -36,6 +41,7 @@ func convertStudentRowToStudent(s StudentRow) student.Student {
 		Password:  s.Password.String,
 		Name:      s.Name.String,
 		Course:    s.Course.String,
+		CourseID:  s.CourseID.Int32,
 		Grade:     s.Grade.String,
 		CreatedBy: s.CreatedBy.String,
 		CreatedOn: s.CreatedOn.Format(time.RFC3339),
@@ -44,14 +50,105 @@ func convertStudentRowToStudent(s StudentRow) student.Student {
 	}
 }
 
-func (db *Database) GetAllStudents(ctx context.Context) ([]student.Student, error) {
+const studentColumns = "user_id, password, name, course, course_id, grade, created_by, created_on, updated_by, updated_on"
+
+// sortColumns maps the service sort keys onto columns. Only these values are
+// ever interpolated into a query.
+var sortColumns = map[string]string{
+	student.SortByID:        "user_id",
+	student.SortByName:      "name",
+	student.SortByCreatedOn: "created_on",
+	student.SortByUpdatedOn: "updated_on",
+}
+
+// keysetClause builds the ORDER BY and the "after the cursor" condition for
+// keyset pagination. Rows are ordered by the sort column, then user_id.
+func keysetClause(opts student.ListOptions) (where string, args []interface{}, orderBy string, err error) {
+	column, ok := sortColumns[opts.Sort]
+	if !ok {
+		return "", nil, "", student.ErrInvalidSort
+	}
+
+	cmp, dir := ">", "ASC"
+	if opts.Desc {
+		cmp, dir = "<", "DESC"
+	}
+
+	if column == "user_id" {
+		orderBy = fmt.Sprintf("user_id %s", dir)
+	} else {
+		orderBy = fmt.Sprintf("%s %s, user_id %s", column, dir, dir)
+	}
+
+	if opts.After == nil {
+		return "", nil, orderBy, nil
+	}
+	if column == "user_id" {
+		return fmt.Sprintf("user_id %s ?", cmp), []interface{}{opts.After.ID}, orderBy, nil
+	}
+
+	var value interface{} = opts.After.Value
+	if column == "created_on" || column == "updated_on" {
+		t, err := time.Parse(time.RFC3339, opts.After.Value)
+		if err != nil {
+			return "", nil, "", student.ErrInvalidCursor
+		}
+		value = t
+	}
+	where = fmt.Sprintf("(%s %s ? OR (%s = ? AND user_id %s ?))", column, cmp, column, cmp)
+	return where, []interface{}{value, value, opts.After.ID}, orderBy, nil
+}
+
+// studentListQuery builds the SELECT for a filtered, ordered listing. A
+// zero limit means no LIMIT clause.
+func studentListQuery(opts student.ListOptions) (string, []interface{}, error) {
+	keyset, args, orderBy, err := keysetClause(opts)
+	if err != nil {
+		return "", nil, err
+	}
+	where, filterArgs := compileFilter(opts.Filter)
+	if keyset != "" {
+		where = append(where, keyset)
+	}
+	args = append(filterArgs, args...)
+
+	query := "SELECT " + studentColumns + " FROM students"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY " + orderBy
+	if opts.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, opts.Limit)
+	}
+	return query, args, nil
+}
+
+func (db *Database) GetAllStudents(ctx context.Context, opts student.ListOptions) ([]student.Student, error) {
 	var students []student.Student
-	query := "SELECT * FROM students"
+	err := db.StreamStudents(ctx, opts, func(s student.Student) error {
+		students = append(students, s)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return students, nil
+}
+
+// StreamStudents scans the listing row by row and hands each student to fn
+// as soon as it is read. Iteration stops at the first error from fn or when
+// ctx is cancelled.
+func (db *Database) StreamStudents(ctx context.Context, opts student.ListOptions, fn func(student.Student) error) error {
+	query, args, err := studentListQuery(opts)
+	if err != nil {
+		return err
+	}
 
-	rows, err := db.Client.QueryContext(ctx, query)
+	rows, err := db.Client.QueryContext(ctx, query, args...)
 	if err != nil {
 		log.Errorf("Error querying students: %s", err.Error())
-		return nil, err
+		return err
 	}
 	defer rows.Close()
 
@@ -62,6 +159,7 @@ func (db *Database) GetAllStudents(ctx context.Context) ([]student.Student, erro
 			&studentRow.Password,
 			&studentRow.Name,
 			&studentRow.Course,
+			&studentRow.CourseID,
 			&studentRow.Grade,
 			&studentRow.CreatedBy,
 			&studentRow.CreatedOn,
@@ -69,30 +167,34 @@ func (db *Database) GetAllStudents(ctx context.Context) ([]student.Student, erro
 			&studentRow.UpdatedOn,
 		); err != nil {
 			log.Errorf("Error scanning student row: %s", err.Error())
-			return nil, err
+			return err
+		}
+		if err := fn(convertStudentRowToStudent(studentRow)); err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
 		}
-		students = append(students, convertStudentRowToStudent(studentRow))
 	}
 
 	if err := rows.Err(); err != nil {
 		log.Errorf("Error iterating over student rows: %s", err.Error())
-		return nil, err
+		return err
 	}
 
-	return students, nil
+	return nil
 }
 
 func (d *Database) GetStudent(ctx context.Context, userID int32) (student.Student, error) {
 	var studentRow StudentRow
 	row := d.Client.QueryRowContext(
 		ctx,
-		`SELECT user_id, password, name, course, grade, created_by, created_on, updated_by, updated_on
-		 FROM students WHERE user_id = ?`,
+		"SELECT "+studentColumns+" FROM students WHERE user_id = ?",
 		userID,
 	)
 
 	err := row.Scan(&studentRow.User_ID, &studentRow.Password, &studentRow.Name, &studentRow.Course,
-		&studentRow.Grade, &studentRow.CreatedBy, &studentRow.CreatedOn, &studentRow.UpdatedBy, &studentRow.UpdatedOn)
+		&studentRow.CourseID, &studentRow.Grade, &studentRow.CreatedBy, &studentRow.CreatedOn, &studentRow.UpdatedBy, &studentRow.UpdatedOn)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -119,6 +221,7 @@ func (d *Database) AddStudent(ctx context.Context, student student.Student) (stu
 		Password:  sql.NullString{String: student.Password, Valid: true},
 		Name:      sql.NullString{String: student.Name, Valid: true},
 		Course:    sql.NullString{String: student.Course, Valid: true},
+		CourseID:  nullInt32(student.CourseID),
 		Grade:     sql.NullString{String: student.Grade, Valid: true},
 		CreatedBy: sql.NullString{String: userType, Valid: true},
 		UpdatedBy: sql.NullString{String: student.UpdatedBy, Valid: true},
@@ -126,8 +229,8 @@ func (d *Database) AddStudent(ctx context.Context, student student.Student) (stu
 
 	result, err := d.Client.NamedExecContext(
 		ctx,
-		`INSERT INTO students (password, name, course, grade, created_by, updated_by) 
-		 VALUES (:password, :name, :course, :grade, :created_by, :updated_by)`,
+		`INSERT INTO students (password, name, course, course_id, grade, created_by, updated_by) 
+		 VALUES (:password, :name, :course, :course_id, :grade, :created_by, :updated_by)`,
 		studentRow,
 	)
 
@@ -147,9 +250,50 @@ func (d *Database) AddStudent(ctx context.Context, student student.Student) (stu
 	return student, nil
 }
 
-func (d *Database) UpdateStudent(ctx context.Context, userID int32, student student.Student) (student.Student, error) {
+func (d *Database) UpdateStudent(ctx context.Context, userID int32, s student.Student) (student.Student, error) {
+	check, ok := ctx.Value("precondition").(student.Precondition)
+	if !ok {
+		return updateStudent(ctx, d.Client, userID, s)
+	}
+
+	tx, err := d.Client.BeginTxx(ctx, nil)
+	if err != nil {
+		return s, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := checkPrecondition(ctx, tx, userID, check); err != nil {
+		return s, err
+	}
+	updated, err := updateStudent(ctx, tx, userID, s)
+	if err != nil {
+		return s, err
+	}
+	if err := tx.Commit(); err != nil {
+		return s, fmt.Errorf("failed to commit student update: %w", err)
+	}
+	return updated, nil
+}
+
+// checkPrecondition locks a student for the rest of the transaction and
+// checks the precondition of a conditional write against it.
+func checkPrecondition(ctx context.Context, tx *sqlx.Tx, userID int32, check student.Precondition) error {
+	var row StudentRow
+	err := tx.GetContext(ctx, &row, "SELECT "+studentColumns+" FROM students WHERE user_id = ? FOR UPDATE", userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrStudentNotFound
+		}
+		log.Errorf("Failed to lock student with user ID: %d, Error: %v", userID, err)
+		return fmt.Errorf("failed to lock student: %w", err)
+	}
+	return check(convertStudentRowToStudent(row))
+}
+
+// updateStudent is UpdateStudent on a connection or a transaction.
+func updateStudent(ctx context.Context, e sqlx.ExtContext, userID int32, student student.Student) (student.Student, error) {
 	var exists bool
-	err := d.Client.QueryRowContext(
+	err := e.QueryRowxContext(
 		ctx,
 		`SELECT EXISTS(SELECT 1 FROM students WHERE user_id = ?)`,
 		userID,
@@ -162,7 +306,7 @@ func (d *Database) UpdateStudent(ctx context.Context, userID int32, student stud
 
 	if !exists {
 		log.Warnf("Attempted to update student with user ID: %d, but no such student exists", userID)
-		return student, fmt.Errorf("student with user ID %d does not exist", userID)
+		return student, ErrStudentNotFound
 	}
 
 	userType, ok := ctx.Value("userType").(string)
@@ -176,13 +320,15 @@ func (d *Database) UpdateStudent(ctx context.Context, userID int32, student stud
 		Password:  sql.NullString{String: student.Password, Valid: true},
 		Name:      sql.NullString{String: student.Name, Valid: true},
 		Course:    sql.NullString{String: student.Course, Valid: true},
+		CourseID:  nullInt32(student.CourseID),
 		Grade:     sql.NullString{String: student.Grade, Valid: true},
 		UpdatedBy: sql.NullString{String: userType, Valid: true},
 	}
 
-	_, err = d.Client.NamedExecContext(
+	_, err = sqlx.NamedExecContext(
 		ctx,
-		`UPDATE students SET password = :password, name = :name, course = :course, 
+		e,
+		`UPDATE students SET password = :password, name = :name, course = :course, course_id = :course_id,
 		 grade = :grade, updated_by = :updated_by WHERE user_id = :user_id`,
 		studentRow,
 	)
@@ -197,8 +343,33 @@ func (d *Database) UpdateStudent(ctx context.Context, userID int32, student stud
 }
 
 func (d *Database) DeleteStudent(ctx context.Context, userID int32) error {
+	check, ok := ctx.Value("precondition").(student.Precondition)
+	if !ok {
+		return deleteStudent(ctx, d.Client, userID)
+	}
+
+	tx, err := d.Client.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := checkPrecondition(ctx, tx, userID, check); err != nil {
+		return err
+	}
+	if err := deleteStudent(ctx, tx, userID); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit student delete: %w", err)
+	}
+	return nil
+}
+
+// deleteStudent is DeleteStudent on a connection or a transaction.
+func deleteStudent(ctx context.Context, e sqlx.ExtContext, userID int32) error {
 	var exists bool
-	err := d.Client.QueryRowContext(
+	err := e.QueryRowxContext(
 		ctx,
 		`SELECT EXISTS(SELECT 1 FROM students WHERE user_id = ?)`,
 		userID,
@@ -211,10 +382,10 @@ func (d *Database) DeleteStudent(ctx context.Context, userID int32) error {
 
 	if !exists {
 		log.Warnf("Attempted to delete student with user ID: %d, but no such student exists", userID)
-		return fmt.Errorf("student with user ID %d does not exist", userID)
+		return ErrStudentNotFound
 	}
 
-	_, err = d.Client.ExecContext(
+	_, err = e.ExecContext(
 		ctx,
 		`DELETE FROM students WHERE user_id = ?`,
 		userID,
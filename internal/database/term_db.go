@@ -0,0 +1,144 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	student "GO_Assignment_3/internal/service"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const termColumns = "term_id, code, name, start_date, end_date, created_by, created_on, updated_by, updated_on"
+
+type TermRow struct {
+	TermID    int32          `db:"term_id"`
+	Code      string         `db:"code"`
+	Name      string         `db:"name"`
+	StartDate time.Time      `db:"start_date"`
+	EndDate   time.Time      `db:"end_date"`
+	CreatedBy sql.NullString `db:"created_by"`
+	CreatedOn sql.NullTime   `db:"created_on"`
+	UpdatedBy sql.NullString `db:"updated_by"`
+	UpdatedOn sql.NullTime   `db:"updated_on"`
+}
+
+func convertTermRowToTerm(t TermRow) student.Term {
+	return student.Term{
+		ID:        t.TermID,
+		Code:      t.Code,
+		Name:      t.Name,
+		StartDate: t.StartDate.Format(student.DateLayout),
+		EndDate:   t.EndDate.Format(student.DateLayout),
+		CreatedBy: t.CreatedBy.String,
+		CreatedOn: formatNullTime(t.CreatedOn),
+		UpdatedBy: t.UpdatedBy.String,
+		UpdatedOn: formatNullTime(t.UpdatedOn),
+	}
+}
+
+func (d *Database) getTermWhere(ctx context.Context, where string, args ...interface{}) (student.Term, error) {
+	var row TermRow
+	err := d.Client.GetContext(ctx, &row, "SELECT "+termColumns+" FROM terms WHERE "+where, args...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return student.Term{}, student.ErrTermNotFound
+		}
+		log.Errorf("Error fetching term: %v", err)
+		return student.Term{}, fmt.Errorf("error fetching term: %w", err)
+	}
+	return convertTermRowToTerm(row), nil
+}
+
+func (d *Database) GetAllTerms(ctx context.Context) ([]student.Term, error) {
+	var rows []TermRow
+	if err := d.Client.SelectContext(ctx, &rows, "SELECT "+termColumns+" FROM terms ORDER BY start_date"); err != nil {
+		log.Errorf("Error querying terms: %v", err)
+		return nil, fmt.Errorf("error querying terms: %w", err)
+	}
+	terms := make([]student.Term, 0, len(rows))
+	for _, row := range rows {
+		terms = append(terms, convertTermRowToTerm(row))
+	}
+	return terms, nil
+}
+
+func (d *Database) GetTerm(ctx context.Context, termID int32) (student.Term, error) {
+	return d.getTermWhere(ctx, "term_id = ?", termID)
+}
+
+func (d *Database) GetTermByCode(ctx context.Context, code string) (student.Term, error) {
+	return d.getTermWhere(ctx, "code = ?", code)
+}
+
+func (d *Database) GetTermOn(ctx context.Context, day time.Time) (student.Term, error) {
+	date := day.Format(student.DateLayout)
+	return d.getTermWhere(ctx, "start_date <= ? AND end_date >= ? ORDER BY start_date DESC LIMIT 1", date, date)
+}
+
+func (d *Database) AddTerm(ctx context.Context, term student.Term) (student.Term, error) {
+	userType, _ := ctx.Value("userType").(string)
+
+	result, err := d.Client.ExecContext(
+		ctx,
+		`INSERT INTO terms (code, name, start_date, end_date, created_by, updated_by) VALUES (?, ?, ?, ?, ?, ?)`,
+		term.Code, term.Name, term.StartDate, term.EndDate, nullString(userType), nullString(userType),
+	)
+	if err != nil {
+		if isDuplicateKey(err) {
+			return term, student.ErrTermCodeTaken
+		}
+		log.Errorf("Failed to insert term, Error: %v", err)
+		return term, fmt.Errorf("failed to insert term: %w", err)
+	}
+
+	termID, err := result.LastInsertId()
+	if err != nil {
+		return term, fmt.Errorf("failed to retrieve last inserted ID: %w", err)
+	}
+
+	log.Infof("Successfully added term with ID: %d", termID)
+	return d.GetTerm(ctx, int32(termID))
+}
+
+func (d *Database) UpdateTerm(ctx context.Context, termID int32, term student.Term) (student.Term, error) {
+	if _, err := d.GetTerm(ctx, termID); err != nil {
+		return term, err
+	}
+
+	userType, _ := ctx.Value("userType").(string)
+	_, err := d.Client.ExecContext(
+		ctx,
+		`UPDATE terms SET code = ?, name = ?, start_date = ?, end_date = ?, updated_by = ? WHERE term_id = ?`,
+		term.Code, term.Name, term.StartDate, term.EndDate, nullString(userType), termID,
+	)
+	if err != nil {
+		if isDuplicateKey(err) {
+			return term, student.ErrTermCodeTaken
+		}
+		log.Errorf("Failed to update term %d, Error: %v", termID, err)
+		return term, fmt.Errorf("failed to update term: %w", err)
+	}
+
+	log.Infof("Successfully updated term with ID: %d", termID)
+	return d.GetTerm(ctx, termID)
+}
+
+func (d *Database) DeleteTerm(ctx context.Context, termID int32) error {
+	result, err := d.Client.ExecContext(ctx, `DELETE FROM terms WHERE term_id = ?`, termID)
+	if err != nil {
+		if isForeignKeyViolation(err) {
+			return student.ErrTermInUse
+		}
+		log.Errorf("Failed to delete term %d, Error: %v", termID, err)
+		return fmt.Errorf("failed to delete term: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return student.ErrTermNotFound
+	}
+
+	log.Infof("Successfully deleted term with ID: %d", termID)
+	return nil
+}
@@ -0,0 +1,70 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	student "GO_Assignment_3/internal/service"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Transcripts have no foreign key to students: a record of an issued
+// transcript stays verifiable after the student is deleted.
+type TranscriptRow struct {
+	Code        string         `db:"code"`
+	UserID      int32          `db:"user_id"`
+	StudentName string         `db:"student_name"`
+	GPA         float64        `db:"gpa"`
+	Credits     float64        `db:"credits"`
+	Digest      string         `db:"digest"`
+	IssuedBy    sql.NullString `db:"issued_by"`
+	IssuedOn    time.Time      `db:"issued_on"`
+	Body        sql.NullString `db:"body"`
+}
+
+func (d *Database) AddTranscript(ctx context.Context, t student.TranscriptRecord) error {
+	issuedOn, err := time.Parse(time.RFC3339, t.IssuedOn)
+	if err != nil {
+		return fmt.Errorf("invalid issue time: %w", err)
+	}
+
+	_, err = d.Client.ExecContext(
+		ctx,
+		`INSERT INTO transcripts (code, user_id, student_name, gpa, credits, digest, issued_by, issued_on, body)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		t.Code, t.StudentID, t.StudentName, t.GPA, t.Credits, t.Digest, nullString(t.IssuedBy), issuedOn.UTC(), nullString(t.Body),
+	)
+	if err != nil {
+		log.Errorf("Failed to insert transcript for student %d, Error: %v", t.StudentID, err)
+		return fmt.Errorf("failed to insert transcript: %w", err)
+	}
+	return nil
+}
+
+func (d *Database) GetTranscriptByCode(ctx context.Context, code string) (student.TranscriptRecord, error) {
+	var row TranscriptRow
+	err := d.Client.GetContext(ctx, &row,
+		`SELECT code, user_id, student_name, gpa, credits, digest, issued_by, issued_on, body
+		 FROM transcripts WHERE code = ?`, code)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return student.TranscriptRecord{}, student.ErrTranscriptNotFound
+		}
+		log.Errorf("Error fetching transcript %s: %v", code, err)
+		return student.TranscriptRecord{}, fmt.Errorf("error fetching transcript: %w", err)
+	}
+	return student.TranscriptRecord{
+		Code:        row.Code,
+		StudentID:   row.UserID,
+		StudentName: row.StudentName,
+		GPA:         row.GPA,
+		Credits:     row.Credits,
+		Digest:      row.Digest,
+		IssuedBy:    row.IssuedBy.String,
+		IssuedOn:    row.IssuedOn.UTC().Format(time.RFC3339),
+		Body:        row.Body.String,
+	}, nil
+}
@@ -0,0 +1,490 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// MaxSubmissionSize is the largest file a student can upload.
+const MaxSubmissionSize = 10 << 20
+
+// Assignment is coursework of a course offering. Late submissions are
+// accepted and flagged unless RejectLate is set.
+type Assignment struct {
+	ID          int32   `json:"id"`
+	CourseID    int32   `json:"course_id"`
+	TermID      int32   `json:"term_id" validate:"gte=0"`
+	TermCode    string  `json:"term_code,omitempty"`
+	Term        string  `json:"term,omitempty"`
+	Title       string  `json:"title" validate:"required,max=200"`
+	Description string  `json:"description"`
+	DueAt       string  `json:"due_at" validate:"required,datetime=2006-01-02T15:04:05Z07:00"`
+	MaxPoints   float64 `json:"max_points" validate:"gt=0"`
+	RejectLate  bool    `json:"reject_late"`
+	CreatedBy   string  `json:"created_by"`
+	CreatedOn   string  `json:"created_on"`
+	UpdatedBy   string  `json:"updated_by"`
+	UpdatedOn   string  `json:"updated_on"`
+}
+
+// Submission is a student's upload for an assignment. A student has at most
+// one submission per assignment; submitting again replaces it until it is
+// graded. Points is nil until graded.
+type Submission struct {
+	ID           int32    `json:"id"`
+	AssignmentID int32    `json:"assignment_id"`
+	CourseID     int32    `json:"course_id"`
+	StudentID    int32    `json:"student_id"`
+	StudentName  string   `json:"student_name,omitempty"`
+	FileName     string   `json:"file_name"`
+	ContentType  string   `json:"content_type"`
+	Size         int64    `json:"size"`
+	StorageKey   string   `json:"-"`
+	SubmittedOn  string   `json:"submitted_on"`
+	Late         bool     `json:"late"`
+	Points       *float64 `json:"points"`
+	Feedback     string   `json:"feedback,omitempty"`
+	GradedBy     string   `json:"graded_by,omitempty"`
+	GradedOn     string   `json:"graded_on,omitempty"`
+}
+
+// Upload is a file sent with a submission.
+type Upload struct {
+	FileName    string
+	ContentType string
+	Size        int64
+	Body        io.Reader
+}
+
+// SubmissionGrade is the body of a grading request.
+type SubmissionGrade struct {
+	Points   float64 `json:"points" validate:"gte=0"`
+	Feedback string  `json:"feedback" validate:"omitempty,max=2000"`
+}
+
+var (
+	ErrAssignmentNotFound = errors.New("assignment not found")
+	ErrInvalidAssignment  = errors.New("invalid assignment")
+	ErrSubmissionNotFound = errors.New("submission not found")
+	ErrInvalidSubmission  = errors.New("invalid submission")
+	ErrSubmissionClosed   = errors.New("the assignment is past due and does not accept late submissions")
+	ErrAlreadyGraded      = errors.New("the submission has already been graded")
+)
+
+const (
+	AuditAssignmentCreate = "assignment.create"
+	AuditAssignmentUpdate = "assignment.update"
+	AuditAssignmentDelete = "assignment.delete"
+	AuditSubmissionCreate = "submission.create"
+	AuditSubmissionGrade  = "submission.grade"
+)
+
+type AssignmentStore interface {
+	GetAssignments(ctx context.Context, courseID, termID int32) ([]Assignment, error)
+	GetAssignment(context.Context, int32) (Assignment, error)
+	AddAssignment(context.Context, Assignment) (Assignment, error)
+	UpdateAssignment(context.Context, int32, Assignment) (Assignment, error)
+	// DeleteAssignment also deletes its submissions and returns their
+	// storage keys so the files can be removed.
+	DeleteAssignment(context.Context, int32) ([]string, error)
+	GetSubmission(context.Context, int32) (Submission, error)
+	GetAssignmentSubmissions(context.Context, int32) ([]Submission, error)
+	GetStudentSubmissions(context.Context, int32) ([]Submission, error)
+	// SaveSubmission adds a submission or replaces the student's earlier one
+	// for the same assignment, returning the storage key it replaced.
+	SaveSubmission(context.Context, Submission) (Submission, string, error)
+	GradeSubmission(context.Context, int32, SubmissionGrade) (Submission, error)
+}
+
+func validateAssignment(a Assignment) error {
+	switch {
+	case strings.TrimSpace(a.Title) == "":
+		return fmt.Errorf("%w: title is required", ErrInvalidAssignment)
+	case a.MaxPoints <= 0:
+		return fmt.Errorf("%w: max_points must be positive", ErrInvalidAssignment)
+	}
+	if _, err := time.Parse(time.RFC3339, a.DueAt); err != nil {
+		return fmt.Errorf("%w: due_at must be an RFC 3339 time, e.g. 2024-09-30T23:59:00Z", ErrInvalidAssignment)
+	}
+	return nil
+}
+
+func (s *Service) GetAssignments(ctx context.Context, courseID int32, termRef string) ([]Assignment, error) {
+	if _, err := s.Store.GetCourse(ctx, courseID); err != nil {
+		return nil, err
+	}
+	termID, err := s.termFilter(ctx, termRef)
+	if err != nil {
+		return nil, err
+	}
+	return s.Store.GetAssignments(ctx, courseID, termID)
+}
+
+// getCourseAssignment fetches an assignment and makes sure it belongs to
+// the course in the request path.
+func (s *Service) getCourseAssignment(ctx context.Context, courseID, assignmentID int32) (Assignment, error) {
+	a, err := s.Store.GetAssignment(ctx, assignmentID)
+	if err != nil {
+		return Assignment{}, err
+	}
+	if a.CourseID != courseID {
+		return Assignment{}, ErrAssignmentNotFound
+	}
+	return a, nil
+}
+
+func (s *Service) GetAssignment(ctx context.Context, courseID, assignmentID int32) (Assignment, error) {
+	return s.getCourseAssignment(ctx, courseID, assignmentID)
+}
+
+// AddAssignment creates an assignment for a course offering. The term is
+// given by term_id or term and defaults to the current term.
+func (s *Service) AddAssignment(ctx context.Context, courseID int32, a Assignment) (Assignment, error) {
+	if _, err := s.Store.GetCourse(ctx, courseID); err != nil {
+		return Assignment{}, err
+	}
+	if err := s.checkStaff(ctx, courseID); err != nil {
+		return Assignment{}, err
+	}
+	if err := validateAssignment(a); err != nil {
+		return Assignment{}, err
+	}
+	termRef := a.Term
+	if a.TermID == 0 && termRef == "" {
+		termRef = CurrentTermRef
+	}
+	term, err := s.ResolveTerm(ctx, a.TermID, termRef)
+	if err != nil {
+		return Assignment{}, err
+	}
+	a.CourseID, a.TermID, a.Term = courseID, term.ID, ""
+
+	created, err := s.Store.AddAssignment(ctx, a)
+	s.RecordAudit(ctx, AuditEntry{
+		Action:   AuditAssignmentCreate,
+		TargetID: created.ID,
+		Diff:     diffAssignments(Assignment{}, a),
+		Outcome:  outcomeOf(err),
+		Message:  messageOf(err),
+	})
+	if err != nil {
+		log.Errorf("Failed to add assignment to course %d, Error: %v", courseID, err)
+		return Assignment{}, err
+	}
+	return created, nil
+}
+
+// UpdateAssignment changes an assignment; its course and term stay.
+func (s *Service) UpdateAssignment(ctx context.Context, courseID, assignmentID int32, a Assignment) (Assignment, error) {
+	before, err := s.getCourseAssignment(ctx, courseID, assignmentID)
+	if err != nil {
+		return Assignment{}, err
+	}
+	if err := s.checkStaff(ctx, courseID); err != nil {
+		return Assignment{}, err
+	}
+	if err := validateAssignment(a); err != nil {
+		return Assignment{}, err
+	}
+	a.CourseID, a.TermID = before.CourseID, before.TermID
+
+	updated, err := s.Store.UpdateAssignment(ctx, assignmentID, a)
+	s.RecordAudit(ctx, AuditEntry{
+		Action:   AuditAssignmentUpdate,
+		TargetID: assignmentID,
+		Diff:     diffAssignments(before, a),
+		Outcome:  outcomeOf(err),
+		Message:  messageOf(err),
+	})
+	if err != nil {
+		log.Errorf("Failed to update assignment %d, Error: %v", assignmentID, err)
+		return Assignment{}, err
+	}
+	return updated, nil
+}
+
+// DeleteAssignment removes an assignment with its submissions and their
+// files. Files that cannot be removed are only logged.
+func (s *Service) DeleteAssignment(ctx context.Context, courseID, assignmentID int32) error {
+	before, err := s.getCourseAssignment(ctx, courseID, assignmentID)
+	if err != nil {
+		return err
+	}
+	if err := s.checkStaff(ctx, courseID); err != nil {
+		return err
+	}
+	keys, err := s.Store.DeleteAssignment(ctx, assignmentID)
+	entry := AuditEntry{
+		Action:   AuditAssignmentDelete,
+		TargetID: assignmentID,
+		Outcome:  outcomeOf(err),
+		Message:  messageOf(err),
+	}
+	if err == nil {
+		entry.Diff = diffAssignments(before, Assignment{})
+	}
+	s.RecordAudit(ctx, entry)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		s.removeFile(ctx, key)
+	}
+	return nil
+}
+
+// submissionFiles returns the storage keys of a student's submissions. It
+// is read before the student is deleted, since the submissions go with
+// them, so that the files can be removed afterwards.
+func (s *Service) submissionFiles(ctx context.Context, studentID int32) []string {
+	submissions, err := s.Store.GetStudentSubmissions(ctx, studentID)
+	if err != nil {
+		log.Errorf("Failed to read the submissions of student %d, Error: %v", studentID, err)
+		return nil
+	}
+	keys := make([]string, 0, len(submissions))
+	for _, sub := range submissions {
+		keys = append(keys, sub.StorageKey)
+	}
+	return keys
+}
+
+func (s *Service) removeFile(ctx context.Context, key string) {
+	if s.Files == nil || key == "" {
+		return
+	}
+	if err := s.Files.Delete(ctx, key); err != nil {
+		log.Errorf("Failed to delete file %s: %v", key, err)
+	}
+}
+
+// GetAssignmentSubmissions lists the submissions of an assignment for the
+// admin and the course's instructors.
+func (s *Service) GetAssignmentSubmissions(ctx context.Context, courseID, assignmentID int32) ([]Submission, error) {
+	if _, err := s.getCourseAssignment(ctx, courseID, assignmentID); err != nil {
+		return nil, err
+	}
+	if err := s.checkStaff(ctx, courseID); err != nil {
+		return nil, err
+	}
+	return s.Store.GetAssignmentSubmissions(ctx, assignmentID)
+}
+
+// GetStudentSubmissions lists a student's submissions. Students only see
+// their own, instructors only those in the courses they teach.
+func (s *Service) GetStudentSubmissions(ctx context.Context, studentID int32) ([]Submission, error) {
+	if callerRole(ctx) == RoleUser && callerID(ctx) != studentID {
+		return nil, fmt.Errorf("%w: students can only read their own submissions", ErrForbidden)
+	}
+	if _, err := s.Store.GetStudent(ctx, studentID); err != nil {
+		return nil, ErrStudentNotFound
+	}
+	submissions, err := s.Store.GetStudentSubmissions(ctx, studentID)
+	if err != nil || callerRole(ctx) != RoleInstructor {
+		return submissions, err
+	}
+	visible := []Submission{}
+	for _, sub := range submissions {
+		if s.checkTeaches(ctx, sub.CourseID) == nil {
+			visible = append(visible, sub)
+		}
+	}
+	return visible, nil
+}
+
+// getStudentSubmission fetches a submission the caller may see: the
+// student's own, or any in a course an instructor teaches.
+func (s *Service) getStudentSubmission(ctx context.Context, studentID, submissionID int32) (Submission, error) {
+	if callerRole(ctx) == RoleUser && callerID(ctx) != studentID {
+		return Submission{}, fmt.Errorf("%w: students can only read their own submissions", ErrForbidden)
+	}
+	sub, err := s.Store.GetSubmission(ctx, submissionID)
+	if err != nil {
+		return Submission{}, err
+	}
+	if sub.StudentID != studentID {
+		return Submission{}, ErrSubmissionNotFound
+	}
+	if err := s.checkTeaches(ctx, sub.CourseID); err != nil {
+		return Submission{}, err
+	}
+	return sub, nil
+}
+
+func (s *Service) GetSubmission(ctx context.Context, studentID, submissionID int32) (Submission, error) {
+	return s.getStudentSubmission(ctx, studentID, submissionID)
+}
+
+// OpenSubmissionFile returns the uploaded file of a submission. The caller
+// closes it.
+func (s *Service) OpenSubmissionFile(ctx context.Context, studentID, submissionID int32) (Submission, io.ReadCloser, error) {
+	sub, err := s.getStudentSubmission(ctx, studentID, submissionID)
+	if err != nil {
+		return Submission{}, nil, err
+	}
+	if s.Files == nil {
+		return Submission{}, nil, ErrStorageUnavailable
+	}
+	file, err := s.Files.Get(ctx, sub.StorageKey)
+	if err != nil {
+		log.Errorf("Failed to open file of submission %d: %v", submissionID, err)
+		return Submission{}, nil, err
+	}
+	return sub, file, nil
+}
+
+// Submit uploads a student's work for an assignment. The student must hold
+// a seat in the assignment's course offering. Work handed in after the due
+// time is flagged late, or refused when the assignment rejects late work.
+func (s *Service) Submit(ctx context.Context, studentID, assignmentID int32, upload Upload) (Submission, error) {
+	if err := denyInstructor(ctx); err != nil {
+		return Submission{}, err
+	}
+	if s.Files == nil {
+		return Submission{}, ErrStorageUnavailable
+	}
+	a, err := s.Store.GetAssignment(ctx, assignmentID)
+	if err != nil {
+		return Submission{}, err
+	}
+	if upload.Size <= 0 {
+		return Submission{}, fmt.Errorf("%w: the file is empty", ErrInvalidSubmission)
+	}
+	if upload.Size > MaxSubmissionSize {
+		return Submission{}, fmt.Errorf("%w: the file is larger than %d MB", ErrInvalidSubmission, MaxSubmissionSize>>20)
+	}
+
+	enrollments, err := s.Store.GetStudentEnrollments(ctx, studentID, a.TermID)
+	if err != nil {
+		return Submission{}, err
+	}
+	enrolled := false
+	for _, e := range enrollments {
+		enrolled = enrolled || (e.CourseID == a.CourseID && HoldsSeat(e.Status))
+	}
+	if !enrolled {
+		return Submission{}, fmt.Errorf("%w: the student is not enrolled in this course", ErrInvalidSubmission)
+	}
+
+	now := time.Now().UTC()
+	due, _ := time.Parse(time.RFC3339, a.DueAt)
+	late := now.After(due)
+	if late && a.RejectLate {
+		return Submission{}, ErrSubmissionClosed
+	}
+
+	fileName := path.Base(strings.ReplaceAll(upload.FileName, "\\", "/"))
+	if fileName == "." || fileName == "/" {
+		fileName = "submission"
+	}
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return Submission{}, err
+	}
+	key := fmt.Sprintf("submissions/%d/%d/%s-%s", assignmentID, studentID, hex.EncodeToString(suffix), fileName)
+	if err := s.Files.Put(ctx, key, upload.Body, upload.Size, upload.ContentType); err != nil {
+		log.Errorf("Failed to store submission of student %d for assignment %d: %v", studentID, assignmentID, err)
+		return Submission{}, err
+	}
+
+	sub := Submission{
+		AssignmentID: assignmentID,
+		StudentID:    studentID,
+		FileName:     fileName,
+		ContentType:  upload.ContentType,
+		Size:         upload.Size,
+		StorageKey:   key,
+		SubmittedOn:  now.Format(time.RFC3339),
+		Late:         late,
+	}
+	saved, replaced, err := s.Store.SaveSubmission(ctx, sub)
+	s.RecordAudit(ctx, AuditEntry{
+		Action:   AuditSubmissionCreate,
+		TargetID: studentID,
+		Diff: map[string]FieldChange{
+			"assignment_id": {To: fmt.Sprint(assignmentID)},
+			"file_name":     {To: fileName},
+			"late":          {To: fmt.Sprint(late)},
+		},
+		Outcome: outcomeOf(err),
+		Message: messageOf(err),
+	})
+	if err != nil {
+		s.removeFile(ctx, key)
+		return Submission{}, err
+	}
+	s.removeFile(ctx, replaced)
+	return saved, nil
+}
+
+// GradeSubmission records points and feedback on a submission. Only the
+// admin and the course's instructors can grade.
+func (s *Service) GradeSubmission(ctx context.Context, studentID, submissionID int32, grade SubmissionGrade) (Submission, error) {
+	before, err := s.getStudentSubmission(ctx, studentID, submissionID)
+	if err != nil {
+		return Submission{}, err
+	}
+	if err := s.checkStaff(ctx, before.CourseID); err != nil {
+		return Submission{}, err
+	}
+	a, err := s.Store.GetAssignment(ctx, before.AssignmentID)
+	if err != nil {
+		return Submission{}, err
+	}
+	if grade.Points < 0 || grade.Points > a.MaxPoints {
+		return Submission{}, fmt.Errorf("%w: points must be between 0 and %g", ErrInvalidSubmission, a.MaxPoints)
+	}
+	grade.Feedback = strings.TrimSpace(grade.Feedback)
+
+	graded, err := s.Store.GradeSubmission(ctx, submissionID, grade)
+	from := ""
+	if before.Points != nil {
+		from = fmt.Sprint(*before.Points)
+	}
+	s.RecordAudit(ctx, AuditEntry{
+		Action:   AuditSubmissionGrade,
+		TargetID: studentID,
+		Diff: map[string]FieldChange{
+			"submission_id": {To: fmt.Sprint(submissionID)},
+			"points":        {From: from, To: fmt.Sprint(grade.Points)},
+		},
+		Outcome: outcomeOf(err),
+		Message: messageOf(err),
+	})
+	if err != nil {
+		log.Errorf("Failed to grade submission %d, Error: %v", submissionID, err)
+		return Submission{}, err
+	}
+	return graded, nil
+}
+
+func diffAssignments(before, after Assignment) map[string]FieldChange {
+	diff := map[string]FieldChange{}
+	add := func(field, from, to string) {
+		if from != to {
+			diff[field] = FieldChange{From: from, To: to}
+		}
+	}
+	add("title", before.Title, after.Title)
+	add("description", before.Description, after.Description)
+	add("due_at", before.DueAt, after.DueAt)
+	if before.MaxPoints != after.MaxPoints {
+		diff["max_points"] = FieldChange{From: fmt.Sprint(before.MaxPoints), To: fmt.Sprint(after.MaxPoints)}
+	}
+	if before.RejectLate != after.RejectLate {
+		diff["reject_late"] = FieldChange{From: fmt.Sprint(before.RejectLate), To: fmt.Sprint(after.RejectLate)}
+	}
+	if before.TermID != after.TermID {
+		diff["term_id"] = FieldChange{From: fmt.Sprint(before.TermID), To: fmt.Sprint(after.TermID)}
+	}
+	return diff
+}
@@ -0,0 +1,455 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Attendance marks.
+const (
+	MarkPresent = "present"
+	MarkAbsent  = "absent"
+	MarkLate    = "late"
+	MarkExcused = "excused"
+)
+
+var attendanceMarks = map[string]bool{
+	MarkPresent: true,
+	MarkAbsent:  true,
+	MarkLate:    true,
+	MarkExcused: true,
+}
+
+// DefaultAttendanceThreshold is the attendance percentage below which a
+// student shows up in attendance reports, unless configured otherwise.
+const DefaultAttendanceThreshold = 75.0
+
+// ClassSession is one meeting of a course in a term.
+type ClassSession struct {
+	ID        int32  `json:"id"`
+	CourseID  int32  `json:"course_id"`
+	TermID    int32  `json:"term_id" validate:"gte=0"`
+	TermCode  string `json:"term_code,omitempty"`
+	Term      string `json:"term,omitempty"`
+	Date      string `json:"date" validate:"required,datetime=2006-01-02"`
+	Topic     string `json:"topic" validate:"omitempty,max=255"`
+	CreatedBy string `json:"created_by"`
+	CreatedOn string `json:"created_on"`
+	UpdatedBy string `json:"updated_by"`
+	UpdatedOn string `json:"updated_on"`
+}
+
+// AttendanceMark is the attendance of one enrollment at one session.
+type AttendanceMark struct {
+	SessionID    int32  `json:"session_id"`
+	EnrollmentID int32  `json:"enrollment_id"`
+	StudentID    int32  `json:"student_id" validate:"required_without=EnrollmentID"`
+	StudentName  string `json:"student_name,omitempty"`
+	Mark         string `json:"mark" validate:"required,oneof=present absent late excused"`
+	Note         string `json:"note,omitempty" validate:"omitempty,max=255"`
+	RecordedBy   string `json:"recorded_by,omitempty"`
+	RecordedOn   string `json:"recorded_on,omitempty"`
+}
+
+// BulkAttendance marks a whole session at once: every student of the
+// session's course and term gets Default, except those listed in Marks.
+type BulkAttendance struct {
+	Default string           `json:"default" validate:"omitempty,oneof=present absent late excused"`
+	Marks   []AttendanceMark `json:"marks" validate:"dive"`
+}
+
+// AttendanceSummary counts the marks of one enrollment. Late counts as
+// attended, and excused sessions are left out of the percentage.
+type AttendanceSummary struct {
+	EnrollmentID int32   `json:"enrollment_id"`
+	StudentID    int32   `json:"student_id"`
+	StudentName  string  `json:"student_name,omitempty"`
+	CourseID     int32   `json:"course_id"`
+	CourseCode   string  `json:"course_code,omitempty"`
+	TermID       int32   `json:"term_id"`
+	TermCode     string  `json:"term_code,omitempty"`
+	Present      int     `json:"present"`
+	Late         int     `json:"late"`
+	Absent       int     `json:"absent"`
+	Excused      int     `json:"excused"`
+	Percentage   float64 `json:"percentage"`
+}
+
+// AttendanceQuery selects enrollments to summarize. Zero values are
+// ignored.
+type AttendanceQuery struct {
+	StudentID int32
+	CourseID  int32
+	TermID    int32
+}
+
+// StudentAttendance is a student's attendance per enrollment and overall.
+type StudentAttendance struct {
+	StudentID  int32               `json:"student_id"`
+	Percentage float64             `json:"percentage"`
+	Courses    []AttendanceSummary `json:"courses"`
+}
+
+var (
+	ErrSessionNotFound   = errors.New("class session not found")
+	ErrInvalidSession    = errors.New("invalid class session")
+	ErrInvalidAttendance = errors.New("invalid attendance")
+)
+
+const (
+	AuditSessionCreate  = "session.create"
+	AuditSessionUpdate  = "session.update"
+	AuditSessionDelete  = "session.delete"
+	AuditAttendanceMark = "attendance.mark"
+)
+
+type AttendanceStore interface {
+	GetSessions(ctx context.Context, courseID, termID int32) ([]ClassSession, error)
+	GetSession(context.Context, int32) (ClassSession, error)
+	AddSession(context.Context, ClassSession) (ClassSession, error)
+	UpdateSession(context.Context, int32, ClassSession) (ClassSession, error)
+	DeleteSession(context.Context, int32) error
+	GetSessionAttendance(context.Context, int32) ([]AttendanceMark, error)
+	// SetAttendance records or replaces the given marks of a session in one
+	// transaction.
+	SetAttendance(context.Context, int32, []AttendanceMark) error
+	// GetAttendanceSummaries counts the marks of every seat-holding
+	// enrollment the query selects.
+	GetAttendanceSummaries(context.Context, AttendanceQuery) ([]AttendanceSummary, error)
+}
+
+// checkStaff lets the admin and the instructors of a course through.
+func (s *Service) checkStaff(ctx context.Context, courseID int32) error {
+	switch callerRole(ctx) {
+	case RoleAdmin:
+		return nil
+	case RoleInstructor:
+		return s.checkTeaches(ctx, courseID)
+	}
+	return fmt.Errorf("%w: only staff can manage attendance", ErrForbidden)
+}
+
+func attendancePercentage(present, late, absent int) float64 {
+	if present+late+absent == 0 {
+		return 100
+	}
+	return math.Round(float64(present+late)/float64(present+late+absent)*10000) / 100
+}
+
+func (s *Service) GetSessions(ctx context.Context, courseID int32, termRef string) ([]ClassSession, error) {
+	if _, err := s.Store.GetCourse(ctx, courseID); err != nil {
+		return nil, err
+	}
+	termID, err := s.termFilter(ctx, termRef)
+	if err != nil {
+		return nil, err
+	}
+	return s.Store.GetSessions(ctx, courseID, termID)
+}
+
+// getCourseSession fetches a session and makes sure it belongs to the
+// course in the request path.
+func (s *Service) getCourseSession(ctx context.Context, courseID, sessionID int32) (ClassSession, error) {
+	session, err := s.Store.GetSession(ctx, sessionID)
+	if err != nil {
+		return ClassSession{}, err
+	}
+	if session.CourseID != courseID {
+		return ClassSession{}, ErrSessionNotFound
+	}
+	return session, nil
+}
+
+func (s *Service) GetSession(ctx context.Context, courseID, sessionID int32) (ClassSession, error) {
+	return s.getCourseSession(ctx, courseID, sessionID)
+}
+
+// AddSession schedules a class session. The term is given by term_id or
+// term and defaults to the current term.
+func (s *Service) AddSession(ctx context.Context, courseID int32, session ClassSession) (ClassSession, error) {
+	if _, err := s.Store.GetCourse(ctx, courseID); err != nil {
+		return ClassSession{}, err
+	}
+	if err := s.checkStaff(ctx, courseID); err != nil {
+		return ClassSession{}, err
+	}
+	if _, err := time.Parse(DateLayout, session.Date); err != nil {
+		return ClassSession{}, fmt.Errorf("%w: date must be YYYY-MM-DD", ErrInvalidSession)
+	}
+	termRef := session.Term
+	if session.TermID == 0 && termRef == "" {
+		termRef = CurrentTermRef
+	}
+	term, err := s.ResolveTerm(ctx, session.TermID, termRef)
+	if err != nil {
+		return ClassSession{}, err
+	}
+	session.CourseID, session.TermID, session.Term = courseID, term.ID, ""
+	session.Topic = strings.TrimSpace(session.Topic)
+
+	created, err := s.Store.AddSession(ctx, session)
+	s.RecordAudit(ctx, AuditEntry{
+		Action:   AuditSessionCreate,
+		TargetID: courseID,
+		Diff:     diffSessions(ClassSession{}, session),
+		Outcome:  outcomeOf(err),
+		Message:  messageOf(err),
+	})
+	if err != nil {
+		log.Errorf("Failed to add session to course %d, Error: %v", courseID, err)
+		return ClassSession{}, err
+	}
+	return created, nil
+}
+
+// UpdateSession changes the date and topic of a session.
+func (s *Service) UpdateSession(ctx context.Context, courseID, sessionID int32, session ClassSession) (ClassSession, error) {
+	before, err := s.getCourseSession(ctx, courseID, sessionID)
+	if err != nil {
+		return ClassSession{}, err
+	}
+	if err := s.checkStaff(ctx, courseID); err != nil {
+		return ClassSession{}, err
+	}
+	if _, err := time.Parse(DateLayout, session.Date); err != nil {
+		return ClassSession{}, fmt.Errorf("%w: date must be YYYY-MM-DD", ErrInvalidSession)
+	}
+	session.CourseID, session.TermID = before.CourseID, before.TermID
+	session.Topic = strings.TrimSpace(session.Topic)
+
+	updated, err := s.Store.UpdateSession(ctx, sessionID, session)
+	s.RecordAudit(ctx, AuditEntry{
+		Action:   AuditSessionUpdate,
+		TargetID: courseID,
+		Diff:     diffSessions(before, session),
+		Outcome:  outcomeOf(err),
+		Message:  messageOf(err),
+	})
+	if err != nil {
+		log.Errorf("Failed to update session %d, Error: %v", sessionID, err)
+		return ClassSession{}, err
+	}
+	return updated, nil
+}
+
+// DeleteSession removes a session together with its attendance marks.
+func (s *Service) DeleteSession(ctx context.Context, courseID, sessionID int32) error {
+	before, err := s.getCourseSession(ctx, courseID, sessionID)
+	if err != nil {
+		return err
+	}
+	if err := s.checkStaff(ctx, courseID); err != nil {
+		return err
+	}
+	err = s.Store.DeleteSession(ctx, sessionID)
+	entry := AuditEntry{
+		Action:   AuditSessionDelete,
+		TargetID: courseID,
+		Outcome:  outcomeOf(err),
+		Message:  messageOf(err),
+	}
+	if err == nil {
+		entry.Diff = diffSessions(before, ClassSession{})
+	}
+	s.RecordAudit(ctx, entry)
+	return err
+}
+
+func (s *Service) GetSessionAttendance(ctx context.Context, courseID, sessionID int32) ([]AttendanceMark, error) {
+	if _, err := s.getCourseSession(ctx, courseID, sessionID); err != nil {
+		return nil, err
+	}
+	if err := s.checkStaff(ctx, courseID); err != nil {
+		return nil, err
+	}
+	return s.Store.GetSessionAttendance(ctx, sessionID)
+}
+
+// MarkAttendance records the attendance of a session in bulk. Marks are
+// matched to the session's students by enrollment_id or student_id. When a
+// default mark is given, every other student of the course in the
+// session's term gets it; otherwise only the listed students are marked.
+func (s *Service) MarkAttendance(ctx context.Context, courseID, sessionID int32, bulk BulkAttendance) ([]AttendanceMark, error) {
+	session, err := s.getCourseSession(ctx, courseID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkStaff(ctx, courseID); err != nil {
+		return nil, err
+	}
+	if bulk.Default != "" && !attendanceMarks[bulk.Default] {
+		return nil, fmt.Errorf("%w: unknown mark %q", ErrInvalidAttendance, bulk.Default)
+	}
+
+	enrollments, err := s.Store.GetCourseEnrollments(ctx, courseID, session.TermID)
+	if err != nil {
+		return nil, err
+	}
+	byEnrollment := map[int32]Enrollment{}
+	byStudent := map[int32]Enrollment{}
+	for _, e := range enrollments {
+		if HoldsSeat(e.Status) && e.TermID == session.TermID {
+			byEnrollment[e.ID] = e
+			byStudent[e.StudentID] = e
+		}
+	}
+
+	marks := make([]AttendanceMark, 0, len(byEnrollment))
+	listed := map[int32]bool{}
+	for _, m := range bulk.Marks {
+		e, ok := byEnrollment[m.EnrollmentID]
+		if m.EnrollmentID == 0 {
+			e, ok = byStudent[m.StudentID]
+		}
+		if !ok {
+			return nil, fmt.Errorf("%w: enrollment %d / student %d is not in this session", ErrInvalidAttendance, m.EnrollmentID, m.StudentID)
+		}
+		if !attendanceMarks[m.Mark] {
+			return nil, fmt.Errorf("%w: unknown mark %q", ErrInvalidAttendance, m.Mark)
+		}
+		if listed[e.ID] {
+			return nil, fmt.Errorf("%w: student %d is marked twice", ErrInvalidAttendance, e.StudentID)
+		}
+		listed[e.ID] = true
+		marks = append(marks, AttendanceMark{SessionID: sessionID, EnrollmentID: e.ID, StudentID: e.StudentID, Mark: m.Mark, Note: m.Note})
+	}
+	if bulk.Default != "" {
+		for _, e := range enrollments {
+			if _, ok := byEnrollment[e.ID]; ok && !listed[e.ID] {
+				marks = append(marks, AttendanceMark{SessionID: sessionID, EnrollmentID: e.ID, StudentID: e.StudentID, Mark: bulk.Default})
+			}
+		}
+	}
+	if len(marks) == 0 {
+		return nil, fmt.Errorf("%w: nothing to mark", ErrInvalidAttendance)
+	}
+
+	err = s.Store.SetAttendance(ctx, sessionID, marks)
+	counts := map[string]int{}
+	for _, m := range marks {
+		counts[m.Mark]++
+	}
+	diff := map[string]FieldChange{}
+	for mark, n := range counts {
+		diff[mark] = FieldChange{To: fmt.Sprint(n)}
+	}
+	s.RecordAudit(ctx, AuditEntry{
+		Action:   AuditAttendanceMark,
+		TargetID: sessionID,
+		Diff:     diff,
+		Outcome:  outcomeOf(err),
+		Message:  messageOf(err),
+	})
+	if err != nil {
+		log.Errorf("Failed to mark attendance of session %d, Error: %v", sessionID, err)
+		return nil, err
+	}
+	return s.Store.GetSessionAttendance(ctx, sessionID)
+}
+
+// GetStudentAttendance returns the attendance of a student per enrollment
+// and overall. Students can only read their own.
+func (s *Service) GetStudentAttendance(ctx context.Context, studentID int32, termRef string) (StudentAttendance, error) {
+	if callerRole(ctx) == RoleUser && callerID(ctx) != studentID {
+		return StudentAttendance{}, fmt.Errorf("%w: students can only read their own attendance", ErrForbidden)
+	}
+	if _, err := s.Store.GetStudent(ctx, studentID); err != nil {
+		return StudentAttendance{}, ErrStudentNotFound
+	}
+	termID, err := s.termFilter(ctx, termRef)
+	if err != nil {
+		return StudentAttendance{}, err
+	}
+	summaries, err := s.Store.GetAttendanceSummaries(ctx, AttendanceQuery{StudentID: studentID, TermID: termID})
+	if err != nil {
+		return StudentAttendance{}, err
+	}
+
+	result := StudentAttendance{StudentID: studentID, Courses: summaries}
+	var present, late, absent int
+	for i := range summaries {
+		sum := &result.Courses[i]
+		sum.Percentage = attendancePercentage(sum.Present, sum.Late, sum.Absent)
+		present, late, absent = present+sum.Present, late+sum.Late, absent+sum.Absent
+	}
+	result.Percentage = attendancePercentage(present, late, absent)
+	return result, nil
+}
+
+// GetAttendanceReport lists the enrollments whose attendance is below the
+// threshold, lowest first. A threshold of zero uses the configured one.
+// Instructors only see the courses they teach.
+func (s *Service) GetAttendanceReport(ctx context.Context, courseID int32, termRef string, threshold float64) ([]AttendanceSummary, error) {
+	role := callerRole(ctx)
+	if role != RoleAdmin && role != RoleInstructor {
+		return nil, fmt.Errorf("%w: only staff can read attendance reports", ErrForbidden)
+	}
+	if courseID != 0 {
+		if _, err := s.Store.GetCourse(ctx, courseID); err != nil {
+			return nil, err
+		}
+		if err := s.checkStaff(ctx, courseID); err != nil {
+			return nil, err
+		}
+	}
+	if threshold <= 0 {
+		threshold = s.AttendanceThreshold
+	}
+	termID, err := s.termFilter(ctx, termRef)
+	if err != nil {
+		return nil, err
+	}
+
+	taught := map[int32]bool{}
+	if role == RoleInstructor {
+		courses, err := s.Store.GetInstructorCourses(ctx, callerID(ctx))
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range courses {
+			taught[c.ID] = true
+		}
+	}
+
+	summaries, err := s.Store.GetAttendanceSummaries(ctx, AttendanceQuery{CourseID: courseID, TermID: termID})
+	if err != nil {
+		return nil, err
+	}
+	report := []AttendanceSummary{}
+	for _, sum := range summaries {
+		if role == RoleInstructor && !taught[sum.CourseID] {
+			continue
+		}
+		sum.Percentage = attendancePercentage(sum.Present, sum.Late, sum.Absent)
+		if sum.Present+sum.Late+sum.Absent > 0 && sum.Percentage < threshold {
+			report = append(report, sum)
+		}
+	}
+	// Lowest attendance first; ties keep the order of the store.
+	sort.SliceStable(report, func(i, j int) bool {
+		return report[i].Percentage < report[j].Percentage
+	})
+	return report, nil
+}
+
+func diffSessions(before, after ClassSession) map[string]FieldChange {
+	diff := map[string]FieldChange{}
+	add := func(field, from, to string) {
+		if from != to {
+			diff[field] = FieldChange{From: from, To: to}
+		}
+	}
+	add("date", before.Date, after.Date)
+	add("topic", before.Topic, after.Topic)
+	if before.TermID != after.TermID {
+		diff["term_id"] = FieldChange{From: fmt.Sprint(before.TermID), To: fmt.Sprint(after.TermID)}
+	}
+	return diff
+}
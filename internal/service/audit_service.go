@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Audit actions recorded for every mutating or auth-related operation.
+const (
+	AuditStudentCreate = "student.create"
+	AuditStudentUpdate = "student.update"
+	AuditStudentDelete = "student.delete"
+	AuditAuthRegister  = "auth.register"
+	AuditAuthRejected  = "auth.rejected"
+	AuditAuthDenied    = "auth.denied"
+)
+
+// Audit outcomes.
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+	OutcomeDenied  = "denied"
+)
+
+// FieldChange is the before and after value of a single field.
+type FieldChange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type AuditEntry struct {
+	ID        int64                  `json:"id"`
+	ActorID   int32                  `json:"actor_id"`
+	ActorRole string                 `json:"actor_role"`
+	Action    string                 `json:"action"`
+	TargetID  int32                  `json:"target_id"`
+	Diff      map[string]FieldChange `json:"diff,omitempty"`
+	ClientIP  string                 `json:"client_ip"`
+	RequestID string                 `json:"request_id"`
+	Outcome   string                 `json:"outcome"`
+	Message   string                 `json:"message,omitempty"`
+	CreatedOn string                 `json:"created_on"`
+}
+
+// AuditFilter narrows a search of the audit log. Zero values are ignored.
+type AuditFilter struct {
+	ActorID   int32
+	ActorRole string
+	Action    string
+	TargetID  int32
+	Outcome   string
+	RequestID string
+	From      time.Time
+	To        time.Time
+	Limit     int
+	Offset    int
+}
+
+const (
+	DefaultAuditLimit = 50
+	MaxAuditLimit     = 500
+)
+
+type AuditStore interface {
+	AddAuditEntry(context.Context, AuditEntry) error
+	GetAuditEntries(context.Context, AuditFilter) ([]AuditEntry, error)
+}
+
+// RecordAudit fills in the actor, client IP and request ID from the context
+// and stores the entry. A failed audit write is logged but never fails the
+// operation being audited.
+func (s *Service) RecordAudit(ctx context.Context, entry AuditEntry) {
+	if entry.ActorRole == "" {
+		entry.ActorRole, _ = ctx.Value("userType").(string)
+	}
+	if entry.ActorID == 0 {
+		entry.ActorID, _ = ctx.Value("userID").(int32)
+	}
+	if entry.ActorRole == "" {
+		entry.ActorRole = "anonymous"
+	}
+	entry.ClientIP, _ = ctx.Value("clientIP").(string)
+	entry.RequestID, _ = ctx.Value("requestID").(string)
+
+	if err := s.Store.AddAuditEntry(ctx, entry); err != nil {
+		log.Errorf("Failed to record audit entry for action %s, Error: %v", entry.Action, err)
+	}
+}
+
+func (s *Service) GetAuditEntries(ctx context.Context, filter AuditFilter) ([]AuditEntry, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultAuditLimit
+	}
+	if filter.Limit > MaxAuditLimit {
+		filter.Limit = MaxAuditLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	entries, err := s.Store.GetAuditEntries(ctx, filter)
+	if err != nil {
+		log.Errorf("Error fetching audit entries: %v", err)
+		return nil, err
+	}
+	return entries, nil
+}
+
+// diffStudents lists the fields that differ between two versions of a
+// student. Passwords are never written to the audit log in clear text.
+func diffStudents(before, after Student) map[string]FieldChange {
+	diff := map[string]FieldChange{}
+	add := func(field, from, to string) {
+		if from != to {
+			diff[field] = FieldChange{From: from, To: to}
+		}
+	}
+	add("name", before.Name, after.Name)
+	add("course", before.Course, after.Course)
+	add("grade", before.Grade, after.Grade)
+	if before.Password != after.Password {
+		diff["password"] = FieldChange{From: maskSecret(before.Password), To: maskSecret(after.Password)}
+	}
+	return diff
+}
+
+func maskSecret(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "********"
+}
+
+func outcomeOf(err error) string {
+	if err != nil {
+		return OutcomeFailure
+	}
+	return OutcomeSuccess
+}
+
+func messageOf(err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return ""
+}
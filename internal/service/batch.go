@@ -0,0 +1,310 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Operations of a student batch.
+const (
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchDelete = "delete"
+)
+
+// MaxBatchSize is the largest number of operations in one batch.
+const MaxBatchSize = 1000
+
+var (
+	ErrBatchTooLarge = fmt.Errorf("a batch can have at most %d operations", MaxBatchSize)
+	// ErrBatchAborted is the result of the operations of an atomic batch
+	// that were rolled back, or never tried, because another one failed.
+	ErrBatchAborted = errors.New("not applied because another operation of the atomic batch failed")
+)
+
+// StudentOp is one operation of a batch. ID names the student to update or
+// delete; Student is the new record for creates and updates.
+type StudentOp struct {
+	Op      string   `json:"op" validate:"required,oneof=create update delete"`
+	ID      int32    `json:"id,omitempty" validate:"required_unless=Op create"`
+	Student *Student `json:"student,omitempty" validate:"required_unless=Op delete"`
+	// Fields limits an update to these fields of Student, by JSON name; the
+	// others keep their stored values. An update with nil Fields replaces
+	// the whole record; with empty Fields it changes nothing.
+	Fields []string `json:"-"`
+}
+
+// StudentOpResult is the outcome of one operation: the created or updated
+// student, or why the operation failed.
+type StudentOpResult struct {
+	Student Student
+	Err     error
+}
+
+// StudentOpError is the operation that failed an atomic batch, by its index
+// in the batch.
+type StudentOpError struct {
+	Index int
+	Err   error
+}
+
+func (e *StudentOpError) Error() string {
+	return fmt.Sprintf("operation %d: %v", e.Index, e.Err)
+}
+
+func (e *StudentOpError) Unwrap() error {
+	return e.Err
+}
+
+// ApplyStudentBatch runs a batch of creates, updates and deletes with the
+// rules of AddStudent, UpdateStudent and DeleteStudent, and returns a result
+// per operation. The new students are inserted together with multi-row
+// inserts. An atomic batch runs in one transaction and is either applied
+// whole or not at all; otherwise each operation stands on its own.
+func (s *Service) ApplyStudentBatch(ctx context.Context, ops []StudentOp, atomic bool) ([]StudentOpResult, error) {
+	if len(ops) > MaxBatchSize {
+		return nil, ErrBatchTooLarge
+	}
+	if err := denyInstructor(ctx); err != nil {
+		return nil, err
+	}
+
+	results := make([]StudentOpResult, len(ops))
+	before := make([]Student, len(ops))
+	prepared := make([]StudentOp, len(ops))
+	seats := make([][]int32, len(ops))
+	files := make([][]string, len(ops))
+	for i, op := range ops {
+		prepared[i], before[i], results[i].Err = s.prepareStudentOp(ctx, op)
+		if op.Op == BatchDelete && results[i].Err == nil {
+			seats[i] = s.seatCourses(ctx, op.ID)
+			files[i] = s.submissionFiles(ctx, op.ID)
+		}
+	}
+
+	if atomic {
+		s.applyAtomic(ctx, prepared, before, results)
+	} else {
+		s.applyEach(ctx, prepared, before, results)
+	}
+
+	// Each course freed by a delete is promoted once, after the batch.
+	var freed []int32
+	promote := map[int32]bool{}
+	for i, op := range ops {
+		if results[i].Err != nil {
+			continue
+		}
+		switch op.Op {
+		case BatchCreate:
+			s.Search.Put(results[i].Student)
+		case BatchUpdate:
+			s.reindexStudent(ctx, op.ID)
+		case BatchDelete:
+			s.Search.Remove(op.ID)
+			for _, key := range files[i] {
+				s.removeFile(ctx, key)
+			}
+			for _, courseID := range seats[i] {
+				if !promote[courseID] {
+					promote[courseID] = true
+					freed = append(freed, courseID)
+				}
+			}
+		}
+	}
+	for _, courseID := range freed {
+		s.promoteWaitlist(ctx, courseID)
+	}
+	log.Infof("Applied student batch of %d operations (atomic: %t)", len(ops), atomic)
+	return results, nil
+}
+
+// CheckStudentBatch runs the checks of ApplyStudentBatch without writing
+// anything. Each result holds the student as it would be stored, or why the
+// operation would fail.
+func (s *Service) CheckStudentBatch(ctx context.Context, ops []StudentOp) ([]StudentOpResult, error) {
+	if len(ops) > MaxBatchSize {
+		return nil, ErrBatchTooLarge
+	}
+	if err := denyInstructor(ctx); err != nil {
+		return nil, err
+	}
+
+	results := make([]StudentOpResult, len(ops))
+	for i, op := range ops {
+		var prepared StudentOp
+		prepared, results[i].Student, results[i].Err = s.prepareStudentOp(ctx, op)
+		if results[i].Err == nil && prepared.Student != nil {
+			results[i].Student = *prepared.Student
+			results[i].Student.ID = op.ID
+		}
+	}
+	return results, nil
+}
+
+// prepareStudentOp checks an operation before anything is written: the
+// course of the student must exist and the grade must be on its scale, and
+// the student to update or delete must exist. It returns the operation with
+// the course resolved and the stored student.
+func (s *Service) prepareStudentOp(ctx context.Context, op StudentOp) (StudentOp, Student, error) {
+	var before Student
+	if op.Op != BatchCreate {
+		stored, err := s.Store.GetStudent(ctx, op.ID)
+		if err != nil {
+			return op, before, err
+		}
+		before = stored
+	}
+	if op.Op == BatchDelete {
+		return op, before, nil
+	}
+
+	student := *op.Student
+	if op.Op == BatchUpdate && op.Fields != nil {
+		student = mergeStudent(before, student, op.Fields)
+	}
+	if err := s.resolveCourse(ctx, &student); err != nil {
+		return op, before, err
+	}
+	if err := s.validateGrade(ctx, student.CourseID, student.Grade); err != nil {
+		return op, before, err
+	}
+	op.Student = &student
+	return op, before, nil
+}
+
+// mergeStudent copies the named fields of update onto stored. A new course
+// name without a new course ID is looked up again, as in PatchStudent.
+func mergeStudent(stored, update Student, fields []string) Student {
+	merged := stored
+	for _, field := range fields {
+		switch field {
+		case "password":
+			merged.Password = update.Password
+		case "name":
+			merged.Name = update.Name
+		case "course":
+			merged.Course = update.Course
+		case "course_id":
+			merged.CourseID = update.CourseID
+		case "grade":
+			merged.Grade = update.Grade
+		}
+	}
+	if merged.Course != stored.Course && merged.CourseID == stored.CourseID {
+		merged.CourseID = 0
+	}
+	return merged
+}
+
+// applyAtomic writes a batch in one transaction if every operation passed
+// its checks. The operations that did not fail are reported as aborted.
+func (s *Service) applyAtomic(ctx context.Context, ops []StudentOp, before []Student, results []StudentOpResult) {
+	checked := true
+	for i := range results {
+		if results[i].Err != nil {
+			checked = false
+			s.auditStudentOp(ctx, ops[i], before[i], Student{}, results[i].Err)
+		}
+	}
+
+	if checked {
+		stored, err := s.Store.ApplyStudentBatch(ctx, ops)
+		if err == nil {
+			for i, op := range ops {
+				results[i].Student = stored[i]
+				s.auditStudentOp(ctx, op, before[i], stored[i], nil)
+			}
+			return
+		}
+		var opErr *StudentOpError
+		if !errors.As(err, &opErr) {
+			log.Errorf("Failed to apply student batch: %v", err)
+			for i := range results {
+				results[i].Err = err
+			}
+			return
+		}
+		results[opErr.Index].Err = opErr.Err
+		s.auditStudentOp(ctx, ops[opErr.Index], before[opErr.Index], Student{}, opErr.Err)
+	}
+
+	for i := range results {
+		if results[i].Err == nil {
+			results[i].Err = ErrBatchAborted
+		}
+	}
+}
+
+// applyEach writes the operations that passed their checks one by one,
+// except the creates, which are inserted together before the rest. If the
+// multi-row insert fails, the creates are retried one at a time to find the
+// failing rows.
+func (s *Service) applyEach(ctx context.Context, ops []StudentOp, before []Student, results []StudentOpResult) {
+	var creates []int
+	var students []Student
+	for i, op := range ops {
+		switch {
+		case results[i].Err != nil:
+			s.auditStudentOp(ctx, op, before[i], Student{}, results[i].Err)
+		case op.Op == BatchCreate:
+			creates = append(creates, i)
+			students = append(students, *op.Student)
+		}
+	}
+
+	if len(students) > 0 {
+		created, err := s.Store.AddStudents(ctx, students)
+		if err != nil {
+			log.Warnf("Multi-row insert of %d students failed, inserting them one at a time: %v", len(students), err)
+		}
+		for n, i := range creates {
+			if err == nil {
+				results[i].Student = created[n]
+			} else {
+				results[i].Student, results[i].Err = s.Store.AddStudent(ctx, students[n])
+			}
+			s.auditStudentOp(ctx, ops[i], Student{}, results[i].Student, results[i].Err)
+		}
+	}
+
+	for i, op := range ops {
+		if results[i].Err != nil || op.Op == BatchCreate {
+			continue
+		}
+		switch op.Op {
+		case BatchUpdate:
+			results[i].Student, results[i].Err = s.Store.UpdateStudent(ctx, op.ID, *op.Student)
+		case BatchDelete:
+			results[i].Err = s.Store.DeleteStudent(ctx, op.ID)
+		}
+		s.auditStudentOp(ctx, op, before[i], results[i].Student, results[i].Err)
+	}
+}
+
+// auditStudentOp records an operation of a batch like the single writes
+// record theirs.
+func (s *Service) auditStudentOp(ctx context.Context, op StudentOp, before, after Student, err error) {
+	entry := AuditEntry{
+		TargetID: op.ID,
+		Outcome:  outcomeOf(err),
+		Message:  messageOf(err),
+	}
+	switch op.Op {
+	case BatchCreate:
+		entry.Action = AuditStudentCreate
+		entry.TargetID = after.ID
+	case BatchUpdate:
+		entry.Action = AuditStudentUpdate
+	case BatchDelete:
+		entry.Action = AuditStudentDelete
+	}
+	if err == nil {
+		entry.Diff = diffStudents(before, after)
+	}
+	s.RecordAudit(ctx, entry)
+}
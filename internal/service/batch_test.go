@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// batchFixture has Ann as student 1 and Bob as student 2.
+func batchFixture() (*fakeStore, *Service, context.Context) {
+	store := newFakeStore()
+	store.students[1] = Student{ID: 1, Name: "Ann"}
+	store.students[2] = Student{ID: 2, Name: "Bob"}
+	store.lastID = 2
+	ctx := context.WithValue(context.Background(), "userType", RoleAdmin)
+	return store, NewService(store), ctx
+}
+
+func create(name string) StudentOp {
+	return StudentOp{Op: BatchCreate, Student: &Student{Name: name}}
+}
+
+func update(id int32, name string) StudentOp {
+	return StudentOp{Op: BatchUpdate, ID: id, Student: &Student{Name: name}}
+}
+
+func remove(id int32) StudentOp {
+	return StudentOp{Op: BatchDelete, ID: id}
+}
+
+// names lists the stored students by ID.
+func names(store *fakeStore) map[int32]string {
+	out := map[int32]string{}
+	for id, s := range store.students {
+		out[id] = s.Name
+	}
+	return out
+}
+
+func TestApplyStudentBatchAtomic(t *testing.T) {
+	store, s, ctx := batchFixture()
+	results, err := s.ApplyStudentBatch(ctx, []StudentOp{update(1, "Ann B"), create("Cy"), remove(2), create("Di")}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []Student{{ID: 1, Name: "Ann B"}, {ID: 3, Name: "Cy"}, {}, {ID: 4, Name: "Di"}} {
+		if results[i].Err != nil || results[i].Student != want {
+			t.Errorf("operation %d: got %+v, %v, want %+v", i, results[i].Student, results[i].Err, want)
+		}
+	}
+	if got := names(store); len(got) != 3 || got[1] != "Ann B" || got[3] != "Cy" || got[4] != "Di" {
+		t.Errorf("stored %v", got)
+	}
+	if len(store.audit) != 4 {
+		t.Errorf("got %d audit entries, want 4", len(store.audit))
+	}
+}
+
+func TestApplyStudentBatchAtomicFailure(t *testing.T) {
+	tests := []struct {
+		name   string
+		ops    []StudentOp
+		failed int
+		err    error
+	}{
+		{"failed check", []StudentOp{create("Cy"), update(9, "Nobody"), remove(2)}, 1, ErrStudentNotFound},
+		{"failed create", []StudentOp{remove(2), create("Cy"), create("Bad")}, 2, errFakeInsert},
+		{"failed update", []StudentOp{create("Cy"), update(1, "Bad"), remove(2)}, 1, errFakeInsert},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, s, ctx := batchFixture()
+			store.failName = "Bad"
+			results, err := s.ApplyStudentBatch(ctx, tt.ops, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, r := range results {
+				want := ErrBatchAborted
+				if i == tt.failed {
+					want = tt.err
+				}
+				if !errors.Is(r.Err, want) {
+					t.Errorf("operation %d: got %v, want %v", i, r.Err, want)
+				}
+			}
+			if got := names(store); len(got) != 2 || got[1] != "Ann" || got[2] != "Bob" {
+				t.Errorf("a failed batch changed the students: %v", got)
+			}
+		})
+	}
+}
+
+func TestApplyStudentBatchPerItem(t *testing.T) {
+	store, s, ctx := batchFixture()
+	store.failName = "Bad"
+	ops := []StudentOp{create("Cy"), update(9, "Nobody"), update(1, "Bad"), remove(2), create("Di")}
+	results, err := s.ApplyStudentBatch(ctx, ops, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []StudentOpResult{
+		{Student: Student{ID: 3, Name: "Cy"}},
+		{Err: ErrStudentNotFound},
+		{Err: errFakeInsert},
+		{},
+		{Student: Student{ID: 4, Name: "Di"}},
+	}
+	for i, r := range results {
+		if r.Student != want[i].Student || !errors.Is(r.Err, want[i].Err) {
+			t.Errorf("operation %d: got %+v, %v, want %+v, %v", i, r.Student, r.Err, want[i].Student, want[i].Err)
+		}
+	}
+	if got := names(store); len(got) != 3 || got[1] != "Ann" || got[3] != "Cy" || got[4] != "Di" {
+		t.Errorf("stored %v", got)
+	}
+}
+
+func TestApplyStudentBatchSingleInsertFallback(t *testing.T) {
+	// When the multi-row insert fails, each create is retried alone and
+	// only the failing one is reported.
+	store, s, ctx := batchFixture()
+	store.failName = "Bad"
+	store.failMultiRow = true
+	results, err := s.ApplyStudentBatch(ctx, []StudentOp{create("Cy"), create("Bad"), create("Di")}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Err != nil || results[0].Student.ID != 3 {
+		t.Errorf("Cy: got %+v, %v", results[0].Student, results[0].Err)
+	}
+	if !errors.Is(results[1].Err, errFakeInsert) {
+		t.Errorf("Bad: got %v, want the insert error", results[1].Err)
+	}
+	if results[2].Err != nil || results[2].Student.ID != 4 {
+		t.Errorf("Di: got %+v, %v", results[2].Student, results[2].Err)
+	}
+}
@@ -0,0 +1,528 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// RoleGuardian is the role of a guardian's token. Guardians can only read
+// the contacts of the students they are linked to.
+const RoleGuardian = "guardian"
+
+var contactRelationships = map[string]bool{
+	"parent":   true,
+	"guardian": true,
+	"sibling":  true,
+	"spouse":   true,
+	"relative": true,
+	"friend":   true,
+	"other":    true,
+}
+
+// phonePattern accepts an optional leading + and 7 to 15 digits, which may
+// be grouped with spaces, dashes, dots or parentheses.
+var phonePattern = regexp.MustCompile(`^\+?[0-9 ()\-.]+$`)
+
+// Contact is a guardian or emergency contact of a student. Every student
+// with contacts has exactly one primary contact. GuardianID links the
+// contact to a guardian account, which can then read the student's
+// contacts.
+type Contact struct {
+	ID           int32  `json:"id"`
+	StudentID    int32  `json:"student_id"`
+	Name         string `json:"name" validate:"required,max=100"`
+	Relationship string `json:"relationship" validate:"required,max=20"`
+	Phone        string `json:"phone" validate:"required,max=30"`
+	Email        string `json:"email,omitempty" validate:"omitempty,email,max=100"`
+	IsPrimary    bool   `json:"is_primary"`
+	IsEmergency  bool   `json:"is_emergency"`
+	GuardianID   int32  `json:"guardian_id,omitempty" validate:"gte=0"`
+	CreatedBy    string `json:"created_by"`
+	CreatedOn    string `json:"created_on"`
+	UpdatedBy    string `json:"updated_by"`
+	UpdatedOn    string `json:"updated_on"`
+}
+
+// Guardian is an account that lets a parent or guardian sign in. Password
+// is only read from requests; the store keeps a bcrypt hash.
+type Guardian struct {
+	ID           int32  `json:"id"`
+	Name         string `json:"name" validate:"required,max=100"`
+	Email        string `json:"email" validate:"required,email,max=100"`
+	Password     string `json:"password,omitempty" validate:"omitempty,min=8,max=72"`
+	PasswordHash string `json:"-"`
+	CreatedBy    string `json:"created_by"`
+	CreatedOn    string `json:"created_on"`
+	UpdatedBy    string `json:"updated_by"`
+	UpdatedOn    string `json:"updated_on"`
+}
+
+var (
+	ErrContactNotFound   = errors.New("contact not found")
+	ErrInvalidContact    = errors.New("invalid contact")
+	ErrGuardianNotFound  = errors.New("guardian not found")
+	ErrGuardianEmailUsed = errors.New("a guardian with that email already exists")
+	ErrInvalidGuardian   = errors.New("invalid guardian")
+)
+
+const (
+	AuditContactCreate  = "contact.create"
+	AuditContactUpdate  = "contact.update"
+	AuditContactDelete  = "contact.delete"
+	AuditGuardianCreate = "guardian.create"
+	AuditGuardianUpdate = "guardian.update"
+	AuditGuardianDelete = "guardian.delete"
+)
+
+type ContactStore interface {
+	GetStudentContacts(context.Context, int32) ([]Contact, error)
+	GetContactsOfStudents(context.Context, []int32) ([]Contact, error)
+	GetContact(context.Context, int32) (Contact, error)
+	// AddContact, UpdateContact and DeleteContact keep one primary contact
+	// per student: marking a contact primary clears the others, and when no
+	// primary is left the oldest contact becomes primary.
+	AddContact(context.Context, Contact) (Contact, error)
+	UpdateContact(context.Context, int32, Contact) (Contact, error)
+	DeleteContact(context.Context, int32) error
+	GetAllGuardians(context.Context) ([]Guardian, error)
+	GetGuardian(context.Context, int32) (Guardian, error)
+	// GetGuardianByEmail also returns the password hash.
+	GetGuardianByEmail(context.Context, string) (Guardian, error)
+	AddGuardian(context.Context, Guardian) (Guardian, error)
+	// UpdateGuardian keeps the stored hash when PasswordHash is empty.
+	UpdateGuardian(context.Context, int32, Guardian) (Guardian, error)
+	DeleteGuardian(context.Context, int32) error
+	GetGuardianStudents(context.Context, int32) ([]Student, error)
+	IsGuardianOf(ctx context.Context, guardianID, studentID int32) (bool, error)
+}
+
+func validateContact(c Contact) error {
+	switch {
+	case strings.TrimSpace(c.Name) == "":
+		return fmt.Errorf("%w: name is required", ErrInvalidContact)
+	case !contactRelationships[c.Relationship]:
+		return fmt.Errorf("%w: relationship must be one of parent, guardian, sibling, spouse, relative, friend or other", ErrInvalidContact)
+	case c.Phone == "":
+		return fmt.Errorf("%w: phone is required", ErrInvalidContact)
+	}
+	digits := 0
+	for _, r := range c.Phone {
+		if r >= '0' && r <= '9' {
+			digits++
+		}
+	}
+	if !phonePattern.MatchString(c.Phone) || digits < 7 || digits > 15 {
+		return fmt.Errorf("%w: phone must have 7 to 15 digits, optionally starting with +", ErrInvalidContact)
+	}
+	if c.Email != "" {
+		if _, err := mail.ParseAddress(c.Email); err != nil {
+			return fmt.Errorf("%w: email is not valid", ErrInvalidContact)
+		}
+	}
+	return nil
+}
+
+func normalizeContact(c *Contact) {
+	c.Name = strings.TrimSpace(c.Name)
+	c.Relationship = strings.ToLower(strings.TrimSpace(c.Relationship))
+	c.Phone = strings.TrimSpace(c.Phone)
+	c.Email = strings.ToLower(strings.TrimSpace(c.Email))
+}
+
+// checkContactsReadable lets the student, the admin, the instructors of the
+// student's courses and the student's linked guardians read contacts.
+func (s *Service) checkContactsReadable(ctx context.Context, studentID int32) error {
+	switch callerRole(ctx) {
+	case RoleAdmin:
+		return nil
+	case RoleInstructor:
+		taught, err := s.taughtStudents(ctx, []int32{studentID})
+		if err != nil || taught[studentID] {
+			return err
+		}
+	case RoleUser:
+		if callerID(ctx) == studentID {
+			return nil
+		}
+	case RoleGuardian:
+		linked, err := s.Store.IsGuardianOf(ctx, callerID(ctx), studentID)
+		if err != nil || linked {
+			return err
+		}
+	}
+	return fmt.Errorf("%w: contacts are only visible to the student, their instructors, the admin and linked guardians", ErrForbidden)
+}
+
+// taughtStudents reports which of the students have an enrollment in a
+// course the instructor caller teaches.
+func (s *Service) taughtStudents(ctx context.Context, studentIDs []int32) (map[int32]bool, error) {
+	enrollments, err := s.Store.GetEnrollmentsOfStudents(ctx, studentIDs, 0)
+	if err != nil {
+		return nil, err
+	}
+	if enrollments, err = s.visibleEnrollments(ctx, enrollments); err != nil {
+		return nil, err
+	}
+	taught := map[int32]bool{}
+	for _, e := range enrollments {
+		taught[e.StudentID] = true
+	}
+	return taught, nil
+}
+
+// checkContactsWritable lets only the student and the admin change
+// contacts.
+func checkContactsWritable(ctx context.Context, studentID int32) error {
+	switch callerRole(ctx) {
+	case RoleAdmin:
+		return nil
+	case RoleUser:
+		if callerID(ctx) == studentID {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: only the student and the admin can change contacts", ErrForbidden)
+}
+
+func (s *Service) GetStudentContacts(ctx context.Context, studentID int32) ([]Contact, error) {
+	if err := s.checkContactsReadable(ctx, studentID); err != nil {
+		return nil, err
+	}
+	if _, err := s.Store.GetStudent(ctx, studentID); err != nil {
+		return nil, ErrStudentNotFound
+	}
+	return s.Store.GetStudentContacts(ctx, studentID)
+}
+
+// GetContactsOfStudents lists the contacts of several students at once,
+// keyed by student ID. Students whose contacts the caller may not read, by
+// the rules of checkContactsReadable, are missing from the map.
+func (s *Service) GetContactsOfStudents(ctx context.Context, studentIDs []int32) (map[int32][]Contact, error) {
+	var readable []int32
+	switch callerRole(ctx) {
+	case RoleAdmin:
+		readable = studentIDs
+	case RoleInstructor:
+		taught, err := s.taughtStudents(ctx, studentIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range studentIDs {
+			if taught[id] {
+				readable = append(readable, id)
+			}
+		}
+	case RoleUser:
+		for _, id := range studentIDs {
+			if id == callerID(ctx) {
+				readable = append(readable, id)
+			}
+		}
+	case RoleGuardian:
+		linked, err := s.Store.GetGuardianStudents(ctx, callerID(ctx))
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range studentIDs {
+			for _, st := range linked {
+				if st.ID == id {
+					readable = append(readable, id)
+					break
+				}
+			}
+		}
+	}
+
+	byStudent := make(map[int32][]Contact, len(readable))
+	if len(readable) == 0 {
+		return byStudent, nil
+	}
+	contacts, err := s.Store.GetContactsOfStudents(ctx, readable)
+	if err != nil {
+		log.Errorf("Error fetching contacts of students %v: %v", readable, err)
+		return nil, err
+	}
+	for _, id := range readable {
+		byStudent[id] = []Contact{}
+	}
+	for _, c := range contacts {
+		byStudent[c.StudentID] = append(byStudent[c.StudentID], c)
+	}
+	return byStudent, nil
+}
+
+// getStudentContact fetches a contact and makes sure it belongs to the
+// student in the request path.
+func (s *Service) getStudentContact(ctx context.Context, studentID, contactID int32) (Contact, error) {
+	c, err := s.Store.GetContact(ctx, contactID)
+	if err != nil {
+		return Contact{}, err
+	}
+	if c.StudentID != studentID {
+		return Contact{}, ErrContactNotFound
+	}
+	return c, nil
+}
+
+func (s *Service) GetContact(ctx context.Context, studentID, contactID int32) (Contact, error) {
+	if err := s.checkContactsReadable(ctx, studentID); err != nil {
+		return Contact{}, err
+	}
+	return s.getStudentContact(ctx, studentID, contactID)
+}
+
+// AddContact adds a contact to a student. The first contact becomes
+// primary. Only the admin can link a guardian account.
+func (s *Service) AddContact(ctx context.Context, studentID int32, c Contact) (Contact, error) {
+	if err := checkContactsWritable(ctx, studentID); err != nil {
+		return Contact{}, err
+	}
+	if _, err := s.Store.GetStudent(ctx, studentID); err != nil {
+		return Contact{}, ErrStudentNotFound
+	}
+	normalizeContact(&c)
+	if err := validateContact(c); err != nil {
+		return Contact{}, err
+	}
+	if c.GuardianID != 0 && callerRole(ctx) != RoleAdmin {
+		return Contact{}, fmt.Errorf("%w: only the admin can link a guardian account", ErrForbidden)
+	}
+	c.StudentID = studentID
+
+	created, err := s.Store.AddContact(ctx, c)
+	s.RecordAudit(ctx, AuditEntry{
+		Action:   AuditContactCreate,
+		TargetID: studentID,
+		Diff:     diffContacts(Contact{}, c),
+		Outcome:  outcomeOf(err),
+		Message:  messageOf(err),
+	})
+	if err != nil {
+		log.Errorf("Failed to add contact to student %d, Error: %v", studentID, err)
+		return Contact{}, err
+	}
+	return created, nil
+}
+
+// UpdateContact replaces a contact. A student cannot change its guardian
+// link; the admin can.
+func (s *Service) UpdateContact(ctx context.Context, studentID, contactID int32, c Contact) (Contact, error) {
+	if err := checkContactsWritable(ctx, studentID); err != nil {
+		return Contact{}, err
+	}
+	before, err := s.getStudentContact(ctx, studentID, contactID)
+	if err != nil {
+		return Contact{}, err
+	}
+	normalizeContact(&c)
+	if err := validateContact(c); err != nil {
+		return Contact{}, err
+	}
+	if callerRole(ctx) != RoleAdmin {
+		c.GuardianID = before.GuardianID
+	}
+	c.StudentID = studentID
+
+	updated, err := s.Store.UpdateContact(ctx, contactID, c)
+	s.RecordAudit(ctx, AuditEntry{
+		Action:   AuditContactUpdate,
+		TargetID: studentID,
+		Diff:     diffContacts(before, c),
+		Outcome:  outcomeOf(err),
+		Message:  messageOf(err),
+	})
+	if err != nil {
+		log.Errorf("Failed to update contact %d, Error: %v", contactID, err)
+		return Contact{}, err
+	}
+	return updated, nil
+}
+
+func (s *Service) DeleteContact(ctx context.Context, studentID, contactID int32) error {
+	if err := checkContactsWritable(ctx, studentID); err != nil {
+		return err
+	}
+	before, err := s.getStudentContact(ctx, studentID, contactID)
+	if err != nil {
+		return err
+	}
+	err = s.Store.DeleteContact(ctx, contactID)
+	entry := AuditEntry{
+		Action:   AuditContactDelete,
+		TargetID: studentID,
+		Outcome:  outcomeOf(err),
+		Message:  messageOf(err),
+	}
+	if err == nil {
+		entry.Diff = diffContacts(before, Contact{})
+	}
+	s.RecordAudit(ctx, entry)
+	return err
+}
+
+func validateGuardian(g Guardian, requirePassword bool) error {
+	switch {
+	case strings.TrimSpace(g.Name) == "":
+		return fmt.Errorf("%w: name is required", ErrInvalidGuardian)
+	case strings.TrimSpace(g.Email) == "":
+		return fmt.Errorf("%w: email is required", ErrInvalidGuardian)
+	}
+	if _, err := mail.ParseAddress(g.Email); err != nil {
+		return fmt.Errorf("%w: email is not valid", ErrInvalidGuardian)
+	}
+	if (requirePassword || g.Password != "") && len(g.Password) < MinPasswordLength {
+		return fmt.Errorf("%w: password must be at least %d characters", ErrInvalidGuardian, MinPasswordLength)
+	}
+	return nil
+}
+
+func (s *Service) GetAllGuardians(ctx context.Context) ([]Guardian, error) {
+	return s.Store.GetAllGuardians(ctx)
+}
+
+func (s *Service) GetGuardian(ctx context.Context, guardianID int32) (Guardian, error) {
+	return s.Store.GetGuardian(ctx, guardianID)
+}
+
+func (s *Service) AddGuardian(ctx context.Context, g Guardian) (Guardian, error) {
+	g.Email = strings.ToLower(strings.TrimSpace(g.Email))
+	if err := validateGuardian(g, true); err != nil {
+		return Guardian{}, err
+	}
+	hash, err := hashPassword(g.Password)
+	if err != nil {
+		return Guardian{}, err
+	}
+	g.PasswordHash, g.Password = hash, ""
+
+	created, err := s.Store.AddGuardian(ctx, g)
+	s.RecordAudit(ctx, AuditEntry{
+		Action:   AuditGuardianCreate,
+		TargetID: created.ID,
+		Diff:     diffGuardians(Guardian{}, g),
+		Outcome:  outcomeOf(err),
+		Message:  messageOf(err),
+	})
+	if err != nil {
+		log.Errorf("Failed to add guardian %s, Error: %v", g.Email, err)
+		return Guardian{}, err
+	}
+	return created, nil
+}
+
+// UpdateGuardian changes the name, email and, when one is given, the
+// password of a guardian.
+func (s *Service) UpdateGuardian(ctx context.Context, guardianID int32, g Guardian) (Guardian, error) {
+	g.Email = strings.ToLower(strings.TrimSpace(g.Email))
+	if err := validateGuardian(g, false); err != nil {
+		return Guardian{}, err
+	}
+	hash, err := hashPassword(g.Password)
+	if err != nil {
+		return Guardian{}, err
+	}
+	g.PasswordHash, g.Password = hash, ""
+
+	before, _ := s.Store.GetGuardian(ctx, guardianID)
+	updated, err := s.Store.UpdateGuardian(ctx, guardianID, g)
+	s.RecordAudit(ctx, AuditEntry{
+		Action:   AuditGuardianUpdate,
+		TargetID: guardianID,
+		Diff:     diffGuardians(before, g),
+		Outcome:  outcomeOf(err),
+		Message:  messageOf(err),
+	})
+	if err != nil {
+		log.Errorf("Failed to update guardian %d, Error: %v", guardianID, err)
+		return Guardian{}, err
+	}
+	return updated, nil
+}
+
+// DeleteGuardian removes a guardian account. Contacts linked to it stay,
+// without the link.
+func (s *Service) DeleteGuardian(ctx context.Context, guardianID int32) error {
+	before, _ := s.Store.GetGuardian(ctx, guardianID)
+	err := s.Store.DeleteGuardian(ctx, guardianID)
+	entry := AuditEntry{
+		Action:   AuditGuardianDelete,
+		TargetID: guardianID,
+		Outcome:  outcomeOf(err),
+		Message:  messageOf(err),
+	}
+	if err == nil {
+		entry.Diff = diffGuardians(before, Guardian{})
+	}
+	s.RecordAudit(ctx, entry)
+	return err
+}
+
+// GetGuardianStudents lists the students a guardian is linked to, for the
+// admin and the guardian themselves.
+func (s *Service) GetGuardianStudents(ctx context.Context, guardianID int32) ([]Student, error) {
+	role := callerRole(ctx)
+	if role != RoleAdmin && !(role == RoleGuardian && callerID(ctx) == guardianID) {
+		return nil, fmt.Errorf("%w: guardians can only list their own students", ErrForbidden)
+	}
+	if _, err := s.Store.GetGuardian(ctx, guardianID); err != nil {
+		return nil, err
+	}
+	return s.Store.GetGuardianStudents(ctx, guardianID)
+}
+
+// AuthenticateGuardian checks a guardian's email and password.
+func (s *Service) AuthenticateGuardian(ctx context.Context, email, password string) (Guardian, error) {
+	guardian, err := s.Store.GetGuardianByEmail(ctx, strings.ToLower(strings.TrimSpace(email)))
+	err = checkPassword(guardian.PasswordHash, password, err, ErrGuardianNotFound)
+	s.RecordAudit(ctx, AuditEntry{
+		ActorID:   guardian.ID,
+		ActorRole: RoleGuardian,
+		Action:    AuditAuthLogin,
+		TargetID:  guardian.ID,
+		Outcome:   outcomeOf(err),
+		Message:   messageOf(err),
+	})
+	if err != nil {
+		return Guardian{}, err
+	}
+	guardian.PasswordHash = ""
+	return guardian, nil
+}
+
+func diffContacts(before, after Contact) map[string]FieldChange {
+	diff := map[string]FieldChange{}
+	add := func(field, from, to string) {
+		if from != to {
+			diff[field] = FieldChange{From: from, To: to}
+		}
+	}
+	add("name", before.Name, after.Name)
+	add("relationship", before.Relationship, after.Relationship)
+	add("phone", before.Phone, after.Phone)
+	add("email", before.Email, after.Email)
+	add("is_primary", fmt.Sprint(before.IsPrimary), fmt.Sprint(after.IsPrimary))
+	add("is_emergency", fmt.Sprint(before.IsEmergency), fmt.Sprint(after.IsEmergency))
+	add("guardian_id", fmt.Sprint(before.GuardianID), fmt.Sprint(after.GuardianID))
+	return diff
+}
+
+func diffGuardians(before, after Guardian) map[string]FieldChange {
+	diff := map[string]FieldChange{}
+	add := func(field, from, to string) {
+		if from != to {
+			diff[field] = FieldChange{From: from, To: to}
+		}
+	}
+	add("name", before.Name, after.Name)
+	add("email", before.Email, after.Email)
+	if after.PasswordHash != "" && before.PasswordHash != after.PasswordHash {
+		diff["password"] = FieldChange{From: maskSecret(before.PasswordHash), To: maskSecret(after.PasswordHash)}
+	}
+	return diff
+}
@@ -0,0 +1,239 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type Course struct {
+	ID      int32   `json:"id"`
+	Code    string  `json:"code" validate:"required,max=20"`
+	Title   string  `json:"title" validate:"required,max=100"`
+	Credits float64 `json:"credits" validate:"gt=0,lte=999"`
+	// Capacity is the number of seats per term; zero means unlimited.
+	Capacity   int32  `json:"capacity" validate:"gte=0"`
+	Department string `json:"department" validate:"omitempty,max=100"`
+	// GradingScaleID names the scale grades of this course are checked
+	// against; zero means the default scale.
+	GradingScaleID int32  `json:"grading_scale_id" validate:"gte=0"`
+	CreatedBy      string `json:"created_by"`
+	CreatedOn      string `json:"created_on"`
+	UpdatedBy      string `json:"updated_by"`
+	UpdatedOn      string `json:"updated_on"`
+}
+
+var (
+	ErrCourseNotFound  = errors.New("course not found")
+	ErrUnknownCourse   = errors.New("unknown course: not in the course catalog")
+	ErrCourseCodeTaken = errors.New("a course with that code already exists")
+	ErrCourseInUse     = errors.New("course is still referenced by students, enrollments or prerequisites")
+	ErrInvalidCourse   = errors.New("invalid course")
+)
+
+const (
+	AuditCourseCreate = "course.create"
+	AuditCourseUpdate = "course.update"
+	AuditCourseDelete = "course.delete"
+)
+
+type CourseStore interface {
+	GetAllCourses(context.Context) ([]Course, error)
+	GetCourse(context.Context, int32) (Course, error)
+	// GetCoursesByIDs leaves out IDs without a course.
+	GetCoursesByIDs(context.Context, []int32) ([]Course, error)
+	// FindCourse looks a course up by code or by title, ignoring case.
+	FindCourse(context.Context, string) (Course, error)
+	AddCourse(context.Context, Course) (Course, error)
+	UpdateCourse(context.Context, int32, Course) (Course, error)
+	DeleteCourse(context.Context, int32) error
+}
+
+func validateCourse(c Course) error {
+	switch {
+	case strings.TrimSpace(c.Code) == "":
+		return fmt.Errorf("%w: code is required", ErrInvalidCourse)
+	case strings.TrimSpace(c.Title) == "":
+		return fmt.Errorf("%w: title is required", ErrInvalidCourse)
+	case c.Credits <= 0:
+		return fmt.Errorf("%w: credits must be positive", ErrInvalidCourse)
+	case c.Capacity < 0:
+		return fmt.Errorf("%w: capacity cannot be negative", ErrInvalidCourse)
+	}
+	return nil
+}
+
+// lookupCourse finds a catalog course by ID or, failing that, by a
+// reference that may be either a course code or a title.
+func (s *Service) lookupCourse(ctx context.Context, courseID int32, ref string) (Course, error) {
+	var (
+		course Course
+		err    error
+	)
+	if courseID != 0 {
+		course, err = s.Store.GetCourse(ctx, courseID)
+	} else {
+		course, err = s.Store.FindCourse(ctx, strings.TrimSpace(ref))
+	}
+	if err != nil {
+		if errors.Is(err, ErrCourseNotFound) {
+			return Course{}, ErrUnknownCourse
+		}
+		return Course{}, err
+	}
+	return course, nil
+}
+
+// resolveCourse points a student at a catalog course. A course ID wins over
+// the course text. On success the student carries the course ID and the
+// canonical title.
+func (s *Service) resolveCourse(ctx context.Context, student *Student) error {
+	if student.CourseID == 0 && strings.TrimSpace(student.Course) == "" {
+		return nil
+	}
+	course, err := s.lookupCourse(ctx, student.CourseID, student.Course)
+	if err != nil {
+		return err
+	}
+	student.CourseID = course.ID
+	student.Course = course.Title
+	return nil
+}
+
+func (s *Service) GetAllCourses(ctx context.Context) ([]Course, error) {
+	courses, err := s.Store.GetAllCourses(ctx)
+	if err != nil {
+		log.Errorf("Error fetching courses: %v", err)
+		return nil, err
+	}
+	return courses, nil
+}
+
+func (s *Service) GetCourse(ctx context.Context, courseID int32) (Course, error) {
+	course, err := s.Store.GetCourse(ctx, courseID)
+	if err != nil {
+		log.Warnf("Error fetching course with ID: %d, Error: %v", courseID, err)
+		return Course{}, err
+	}
+	return course, nil
+}
+
+// GetCoursesByIDs fetches several courses at once, keyed by ID. IDs without
+// a course are missing from the map.
+func (s *Service) GetCoursesByIDs(ctx context.Context, courseIDs []int32) (map[int32]Course, error) {
+	courses, err := s.Store.GetCoursesByIDs(ctx, courseIDs)
+	if err != nil {
+		log.Errorf("Error fetching courses %v: %v", courseIDs, err)
+		return nil, err
+	}
+	byID := make(map[int32]Course, len(courses))
+	for _, c := range courses {
+		byID[c.ID] = c
+	}
+	return byID, nil
+}
+
+func (s *Service) AddCourse(ctx context.Context, course Course) (Course, error) {
+	if err := validateCourse(course); err != nil {
+		return Course{}, err
+	}
+	course, err := s.Store.AddCourse(ctx, course)
+	s.RecordAudit(ctx, AuditEntry{
+		Action:   AuditCourseCreate,
+		TargetID: course.ID,
+		Diff:     diffCourses(Course{}, course),
+		Outcome:  outcomeOf(err),
+		Message:  messageOf(err),
+	})
+	if err != nil {
+		log.Errorf("Failed to add course %s, Error: %v", course.Code, err)
+		return Course{}, err
+	}
+	log.Infof("Successfully added course with ID: %d", course.ID)
+	return course, nil
+}
+
+// UpdateCourse also rewrites the course title held by every student on the
+// course, in the same transaction.
+func (s *Service) UpdateCourse(ctx context.Context, courseID int32, course Course) (Course, error) {
+	if err := validateCourse(course); err != nil {
+		return Course{}, err
+	}
+	before, _ := s.Store.GetCourse(ctx, courseID)
+	course, err := s.Store.UpdateCourse(ctx, courseID, course)
+	s.RecordAudit(ctx, AuditEntry{
+		Action:   AuditCourseUpdate,
+		TargetID: courseID,
+		Diff:     diffCourses(before, course),
+		Outcome:  outcomeOf(err),
+		Message:  messageOf(err),
+	})
+	if err != nil {
+		log.Errorf("Failed to update course with ID: %d, Error: %v", courseID, err)
+		return Course{}, err
+	}
+	if before.Title != course.Title {
+		s.reindexCourseStudents(ctx, courseID)
+	}
+	if course.Capacity == 0 || course.Capacity > before.Capacity {
+		s.promoteWaitlist(ctx, courseID)
+	}
+	log.Infof("Successfully updated course with ID: %d", courseID)
+	return course, nil
+}
+
+func (s *Service) DeleteCourse(ctx context.Context, courseID int32) error {
+	before, _ := s.Store.GetCourse(ctx, courseID)
+	err := s.Store.DeleteCourse(ctx, courseID)
+	entry := AuditEntry{
+		Action:   AuditCourseDelete,
+		TargetID: courseID,
+		Outcome:  outcomeOf(err),
+		Message:  messageOf(err),
+	}
+	if err == nil {
+		entry.Diff = diffCourses(before, Course{})
+	}
+	s.RecordAudit(ctx, entry)
+	if err != nil {
+		log.Errorf("Failed to delete course with ID: %d, Error: %v", courseID, err)
+		return err
+	}
+	log.Infof("Successfully deleted course with ID: %d", courseID)
+	return nil
+}
+
+// reindexCourseStudents refreshes the search index after a course title
+// changed underneath its students.
+func (s *Service) reindexCourseStudents(ctx context.Context, courseID int32) {
+	filter := Filter{Conditions: []Condition{{Field: "course_id", Op: OpEq, Values: []string{fmt.Sprint(courseID)}}}}
+	err := s.Store.StreamStudents(ctx, ListOptions{Sort: SortByID, Filter: filter}, func(student Student) error {
+		s.Search.Put(student)
+		return nil
+	})
+	if err != nil {
+		log.Warnf("Could not reindex students of course %d: %v", courseID, err)
+	}
+}
+
+func diffCourses(before, after Course) map[string]FieldChange {
+	diff := map[string]FieldChange{}
+	add := func(field, from, to string) {
+		if from != to {
+			diff[field] = FieldChange{From: from, To: to}
+		}
+	}
+	add("code", before.Code, after.Code)
+	add("title", before.Title, after.Title)
+	add("department", before.Department, after.Department)
+	if before.Credits != after.Credits {
+		diff["credits"] = FieldChange{From: fmt.Sprint(before.Credits), To: fmt.Sprint(after.Credits)}
+	}
+	if before.Capacity != after.Capacity {
+		diff["capacity"] = FieldChange{From: fmt.Sprint(before.Capacity), To: fmt.Sprint(after.Capacity)}
+	}
+	return diff
+}
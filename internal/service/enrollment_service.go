@@ -0,0 +1,504 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Enrollment statuses.
+const (
+	EnrollmentEnrolled   = "enrolled"
+	EnrollmentCompleted  = "completed"
+	EnrollmentDropped    = "dropped"
+	EnrollmentFailed     = "failed"
+	EnrollmentWaitlisted = "waitlisted"
+)
+
+var enrollmentStatuses = map[string]bool{
+	EnrollmentEnrolled:   true,
+	EnrollmentCompleted:  true,
+	EnrollmentDropped:    true,
+	EnrollmentFailed:     true,
+	EnrollmentWaitlisted: true,
+}
+
+// HoldsSeat reports whether an enrollment with the given status counts
+// against the capacity of its course.
+func HoldsSeat(status string) bool {
+	return status == EnrollmentEnrolled || status == EnrollmentCompleted || status == EnrollmentFailed
+}
+
+type Enrollment struct {
+	ID          int32   `json:"id"`
+	StudentID   int32   `json:"student_id"`
+	StudentName string  `json:"student_name,omitempty"`
+	CourseID    int32   `json:"course_id" validate:"gte=0"`
+	CourseCode  string  `json:"course_code,omitempty"`
+	CourseTitle string  `json:"course_title,omitempty"`
+	Credits     float64 `json:"credits,omitempty"`
+	TermID      int32   `json:"term_id" validate:"gte=0"`
+	TermCode    string  `json:"term_code,omitempty"`
+	Term        string  `json:"term,omitempty"`
+	Course      string  `json:"course,omitempty"`
+	Grade       string  `json:"grade" validate:"omitempty,grade"`
+	Status      string  `json:"status" validate:"omitempty,oneof=enrolled completed dropped failed waitlisted"`
+	// WaitlistPosition is the place in the waitlist of the course offering,
+	// starting at 1, for waitlisted enrollments.
+	WaitlistPosition int32  `json:"waitlist_position,omitempty"`
+	CreatedBy        string `json:"created_by"`
+	CreatedOn        string `json:"created_on"`
+	UpdatedBy        string `json:"updated_by"`
+	UpdatedOn        string `json:"updated_on"`
+}
+
+var (
+	ErrEnrollmentNotFound = errors.New("enrollment not found")
+	ErrAlreadyEnrolled    = errors.New("student is already enrolled in this course for this term")
+	ErrInvalidEnrollment  = errors.New("invalid enrollment")
+)
+
+const (
+	AuditEnrollmentCreate  = "enrollment.create"
+	AuditEnrollmentUpdate  = "enrollment.update"
+	AuditEnrollmentDelete  = "enrollment.delete"
+	AuditEnrollmentPromote = "enrollment.promote"
+)
+
+type EnrollmentStore interface {
+	GetEnrollment(context.Context, int32) (Enrollment, error)
+	// GetStudentEnrollments and GetCourseEnrollments list every term when
+	// the term ID is zero.
+	GetStudentEnrollments(ctx context.Context, studentID int32, termID int32) ([]Enrollment, error)
+	GetCourseEnrollments(ctx context.Context, courseID int32, termID int32) ([]Enrollment, error)
+	GetEnrollmentsOfStudents(ctx context.Context, studentIDs []int32, termID int32) ([]Enrollment, error)
+	// AddEnrollment and UpdateEnrollment give a seat to an enrollment that
+	// asks for one only while the course offering has free seats and nobody
+	// waiting; otherwise it is stored as waitlisted.
+	AddEnrollment(context.Context, Enrollment) (Enrollment, error)
+	UpdateEnrollment(context.Context, int32, Enrollment) (Enrollment, error)
+	DeleteEnrollment(context.Context, int32) error
+	// PromoteWaitlisted moves waitlisted enrollments of a course into free
+	// seats, first come first served within each term, and returns them.
+	PromoteWaitlisted(ctx context.Context, courseID int32) ([]Enrollment, error)
+}
+
+// checkEnrollmentWrite applies the ownership rules for enrollments. The
+// transport layer has already made sure a user only reaches their own
+// student ID; on top of that a user may enroll or drop but never grade, and
+// their new enrollments start as enrolled. An instructor may grade and
+// change the status of enrollments in the courses they teach.
+func (s *Service) checkEnrollmentWrite(ctx context.Context, before, after Enrollment) error {
+	switch callerRole(ctx) {
+	case RoleAdmin:
+		return nil
+	case RoleInstructor:
+		if before.ID == 0 {
+			return denyInstructor(ctx)
+		}
+		return s.checkTeaches(ctx, before.CourseID)
+	}
+	if after.Grade != before.Grade {
+		return fmt.Errorf("%w: only staff can record grades", ErrForbidden)
+	}
+	if before.ID == 0 && after.Status != EnrollmentEnrolled {
+		return fmt.Errorf("%w: students can only enroll", ErrForbidden)
+	}
+	if after.Status != before.Status && after.Status != EnrollmentEnrolled && after.Status != EnrollmentDropped {
+		return fmt.Errorf("%w: students can only enroll or drop", ErrForbidden)
+	}
+	if before.Status == EnrollmentWaitlisted && after.Status == EnrollmentEnrolled {
+		return fmt.Errorf("%w: waitlisted students get a seat when one frees up", ErrForbidden)
+	}
+	return nil
+}
+
+// checkRecordsReadable guards a student's enrollments and grades: staff
+// read everyone's, students only their own and guardians those of the
+// students they are linked to. Instructors are further limited to the
+// courses they teach with visibleEnrollments.
+func (s *Service) checkRecordsReadable(ctx context.Context, studentID int32) error {
+	switch callerRole(ctx) {
+	case RoleAdmin, RoleInstructor:
+		return nil
+	case RoleUser:
+		if callerID(ctx) == studentID {
+			return nil
+		}
+	case RoleGuardian:
+		linked, err := s.Store.IsGuardianOf(ctx, callerID(ctx), studentID)
+		if err != nil || linked {
+			return err
+		}
+	}
+	return fmt.Errorf("%w: students can only read their own records", ErrForbidden)
+}
+
+// checkStaff keeps course-wide listings to the admin and instructors.
+func checkStaff(ctx context.Context) error {
+	switch callerRole(ctx) {
+	case RoleAdmin, RoleInstructor:
+		return nil
+	}
+	return fmt.Errorf("%w: only staff can list a course", ErrForbidden)
+}
+
+// termFilter turns an optional term reference from a listing request into
+// a term ID, zero meaning every term.
+func (s *Service) termFilter(ctx context.Context, termRef string) (int32, error) {
+	if termRef == "" {
+		return 0, nil
+	}
+	term, err := s.ResolveTerm(ctx, 0, termRef)
+	if err != nil {
+		return 0, err
+	}
+	return term.ID, nil
+}
+
+// GetStudentEnrollments lists a student's enrollments, optionally only
+// those of one term ("current", a term code or an ID). Instructors only see
+// the enrollments in their own courses.
+func (s *Service) GetStudentEnrollments(ctx context.Context, studentID int32, termRef string) ([]Enrollment, error) {
+	if err := s.checkRecordsReadable(ctx, studentID); err != nil {
+		return nil, err
+	}
+	if _, err := s.Store.GetStudent(ctx, studentID); err != nil {
+		return nil, ErrStudentNotFound
+	}
+	termID, err := s.termFilter(ctx, termRef)
+	if err != nil {
+		return nil, err
+	}
+	enrollments, err := s.Store.GetStudentEnrollments(ctx, studentID, termID)
+	if err != nil {
+		log.Errorf("Error fetching enrollments of student %d: %v", studentID, err)
+		return nil, err
+	}
+	return s.visibleEnrollments(ctx, enrollments)
+}
+
+// GetEnrollmentsOfStudents lists the enrollments of several students at
+// once, keyed by student ID, optionally only those of one term. Unlike
+// GetStudentEnrollments it does not check that the students exist. Students
+// whose records the caller may not read, by the rules of
+// checkRecordsReadable, are missing from the map.
+func (s *Service) GetEnrollmentsOfStudents(ctx context.Context, studentIDs []int32, termRef string) (map[int32][]Enrollment, error) {
+	termID, err := s.termFilter(ctx, termRef)
+	if err != nil {
+		return nil, err
+	}
+	var readable []int32
+	for _, id := range studentIDs {
+		if err := s.checkRecordsReadable(ctx, id); err == nil {
+			readable = append(readable, id)
+		} else if !errors.Is(err, ErrForbidden) {
+			return nil, err
+		}
+	}
+	byStudent := make(map[int32][]Enrollment, len(readable))
+	if len(readable) == 0 {
+		return byStudent, nil
+	}
+	enrollments, err := s.Store.GetEnrollmentsOfStudents(ctx, readable, termID)
+	if err != nil {
+		log.Errorf("Error fetching enrollments of students %v: %v", readable, err)
+		return nil, err
+	}
+	if enrollments, err = s.visibleEnrollments(ctx, enrollments); err != nil {
+		return nil, err
+	}
+	for _, id := range readable {
+		byStudent[id] = []Enrollment{}
+	}
+	for _, e := range enrollments {
+		byStudent[e.StudentID] = append(byStudent[e.StudentID], e)
+	}
+	return byStudent, nil
+}
+
+// GetCourseRoster lists the enrollments of a course with student names,
+// optionally only those of one term. Only staff see rosters, and
+// instructors only those of their own courses.
+func (s *Service) GetCourseRoster(ctx context.Context, courseID int32, termRef string) ([]Enrollment, error) {
+	if err := checkStaff(ctx); err != nil {
+		return nil, err
+	}
+	if _, err := s.Store.GetCourse(ctx, courseID); err != nil {
+		return nil, err
+	}
+	if err := s.checkTeaches(ctx, courseID); err != nil {
+		return nil, err
+	}
+	termID, err := s.termFilter(ctx, termRef)
+	if err != nil {
+		return nil, err
+	}
+	enrollments, err := s.Store.GetCourseEnrollments(ctx, courseID, termID)
+	if err != nil {
+		log.Errorf("Error fetching roster of course %d: %v", courseID, err)
+		return nil, err
+	}
+	return enrollments, nil
+}
+
+// GetCourseWaitlist lists the waitlisted enrollments of a course in the
+// order they will be promoted, optionally only those of one term.
+func (s *Service) GetCourseWaitlist(ctx context.Context, courseID int32, termRef string) ([]Enrollment, error) {
+	roster, err := s.GetCourseRoster(ctx, courseID, termRef)
+	if err != nil {
+		return nil, err
+	}
+	waitlist := []Enrollment{}
+	for _, e := range roster {
+		if e.Status == EnrollmentWaitlisted {
+			waitlist = append(waitlist, e)
+		}
+	}
+	return waitlist, nil
+}
+
+// getStudentEnrollment fetches an enrollment and makes sure it belongs to
+// the student in the request path, and to a course an instructor caller
+// teaches.
+func (s *Service) getStudentEnrollment(ctx context.Context, studentID, enrollmentID int32) (Enrollment, error) {
+	if err := s.checkRecordsReadable(ctx, studentID); err != nil {
+		return Enrollment{}, err
+	}
+	enrollment, err := s.Store.GetEnrollment(ctx, enrollmentID)
+	if err != nil {
+		return Enrollment{}, err
+	}
+	if enrollment.StudentID != studentID {
+		return Enrollment{}, ErrEnrollmentNotFound
+	}
+	if err := s.checkTeaches(ctx, enrollment.CourseID); err != nil {
+		return Enrollment{}, err
+	}
+	return enrollment, nil
+}
+
+func (s *Service) GetEnrollment(ctx context.Context, studentID, enrollmentID int32) (Enrollment, error) {
+	return s.getStudentEnrollment(ctx, studentID, enrollmentID)
+}
+
+// Enroll adds a student to a course, given by course_id or by code or title
+// in the course field, for a term given by term_id or term. Without a term
+// the current term is used. The student has to meet the prerequisites of
+// the course. When the course is full the student is put on its waitlist
+// instead.
+func (s *Service) Enroll(ctx context.Context, studentID int32, e Enrollment) (Enrollment, error) {
+	e.StudentID = studentID
+	if e.Status == "" {
+		e.Status = EnrollmentEnrolled
+	}
+	if !enrollmentStatuses[e.Status] {
+		return Enrollment{}, fmt.Errorf("%w: unknown status %q", ErrInvalidEnrollment, e.Status)
+	}
+	if err := s.checkEnrollmentWrite(ctx, Enrollment{}, e); err != nil {
+		return Enrollment{}, err
+	}
+	if _, err := s.Store.GetStudent(ctx, studentID); err != nil {
+		return Enrollment{}, ErrStudentNotFound
+	}
+	if e.CourseID == 0 && e.Course == "" {
+		return Enrollment{}, fmt.Errorf("%w: course_id or course is required", ErrInvalidEnrollment)
+	}
+	course, err := s.lookupCourse(ctx, e.CourseID, e.Course)
+	if err != nil {
+		return Enrollment{}, err
+	}
+	e.CourseID = course.ID
+	if err := s.validateGrade(ctx, e.CourseID, e.Grade); err != nil {
+		return Enrollment{}, err
+	}
+	// Past results recorded by staff are not held to today's rules.
+	if e.Status == EnrollmentEnrolled || e.Status == EnrollmentWaitlisted {
+		if err := s.checkPrerequisites(ctx, studentID, e.CourseID); err != nil {
+			return Enrollment{}, err
+		}
+	}
+	termRef := e.Term
+	if e.TermID == 0 && termRef == "" {
+		termRef = CurrentTermRef
+	}
+	term, err := s.ResolveTerm(ctx, e.TermID, termRef)
+	if err != nil {
+		return Enrollment{}, err
+	}
+	e.TermID = term.ID
+
+	created, err := s.Store.AddEnrollment(ctx, e)
+	s.RecordAudit(ctx, AuditEntry{
+		Action:   AuditEnrollmentCreate,
+		TargetID: studentID,
+		Diff:     diffEnrollments(Enrollment{}, e),
+		Outcome:  outcomeOf(err),
+		Message:  messageOf(err),
+	})
+	if err != nil {
+		log.Errorf("Failed to enroll student %d in course %d, Error: %v", studentID, course.ID, err)
+		return Enrollment{}, err
+	}
+	log.Infof("Enrolled student %d in course %d with status %s", studentID, course.ID, created.Status)
+	return created, nil
+}
+
+// UpdateEnrollment changes the grade and status of an enrollment. The
+// course and term cannot be changed; drop the enrollment and enroll again
+// instead.
+func (s *Service) UpdateEnrollment(ctx context.Context, studentID, enrollmentID int32, e Enrollment) (Enrollment, error) {
+	before, err := s.getStudentEnrollment(ctx, studentID, enrollmentID)
+	if err != nil {
+		return Enrollment{}, err
+	}
+	if e.Status == "" {
+		e.Status = before.Status
+	}
+	if !enrollmentStatuses[e.Status] {
+		return Enrollment{}, fmt.Errorf("%w: unknown status %q", ErrInvalidEnrollment, e.Status)
+	}
+	if callerRole(ctx) == RoleUser && e.Grade == "" {
+		// Students do not send the grade back; keep the recorded one.
+		e.Grade = before.Grade
+	}
+	if err := s.checkEnrollmentWrite(ctx, before, e); err != nil {
+		return Enrollment{}, err
+	}
+	e.StudentID = before.StudentID
+	e.CourseID = before.CourseID
+	e.TermID = before.TermID
+	if e.Grade != before.Grade {
+		if err := s.validateGrade(ctx, e.CourseID, e.Grade); err != nil {
+			return Enrollment{}, err
+		}
+	}
+	// Taking a seat or a place on the waitlist again is held to the
+	// prerequisites, as when enrolling.
+	if e.Status != before.Status && !HoldsSeat(before.Status) &&
+		(e.Status == EnrollmentEnrolled || e.Status == EnrollmentWaitlisted) {
+		if err := s.checkPrerequisites(ctx, e.StudentID, e.CourseID); err != nil {
+			return Enrollment{}, err
+		}
+	}
+
+	updated, err := s.Store.UpdateEnrollment(ctx, enrollmentID, e)
+	s.RecordAudit(ctx, AuditEntry{
+		Action:   AuditEnrollmentUpdate,
+		TargetID: studentID,
+		Diff:     diffEnrollments(before, e),
+		Outcome:  outcomeOf(err),
+		Message:  messageOf(err),
+	})
+	if err != nil {
+		log.Errorf("Failed to update enrollment %d, Error: %v", enrollmentID, err)
+		return Enrollment{}, err
+	}
+	if HoldsSeat(before.Status) && !HoldsSeat(updated.Status) {
+		s.promoteWaitlist(ctx, before.CourseID)
+	}
+	return updated, nil
+}
+
+func (s *Service) DeleteEnrollment(ctx context.Context, studentID, enrollmentID int32) error {
+	if err := denyInstructor(ctx); err != nil {
+		return err
+	}
+	before, err := s.getStudentEnrollment(ctx, studentID, enrollmentID)
+	if err != nil {
+		return err
+	}
+
+	err = s.Store.DeleteEnrollment(ctx, enrollmentID)
+	entry := AuditEntry{
+		Action:   AuditEnrollmentDelete,
+		TargetID: studentID,
+		Outcome:  outcomeOf(err),
+		Message:  messageOf(err),
+	}
+	if err == nil {
+		entry.Diff = diffEnrollments(before, Enrollment{})
+	}
+	s.RecordAudit(ctx, entry)
+	if err != nil {
+		log.Errorf("Failed to delete enrollment %d, Error: %v", enrollmentID, err)
+		return err
+	}
+	if HoldsSeat(before.Status) {
+		s.promoteWaitlist(ctx, before.CourseID)
+	}
+	return nil
+}
+
+// promoteWaitlist fills the free seats of a course from its waitlist and
+// tells every promoted student. Failures are logged, not returned: the
+// change that freed the seat has already been made, and the next one will
+// promote again.
+func (s *Service) promoteWaitlist(ctx context.Context, courseID int32) {
+	promoted, err := s.Store.PromoteWaitlisted(ctx, courseID)
+	if err != nil {
+		log.Errorf("Failed to promote waitlist of course %d, Error: %v", courseID, err)
+		return
+	}
+	for _, e := range promoted {
+		s.RecordAudit(ctx, AuditEntry{
+			Action:   AuditEnrollmentPromote,
+			TargetID: e.StudentID,
+			Diff:     map[string]FieldChange{"status": {From: EnrollmentWaitlisted, To: e.Status}},
+			Outcome:  OutcomeSuccess,
+		})
+		s.Notifier.Notify(ctx, Event{
+			Type:         EventEnrollmentPromoted,
+			StudentID:    e.StudentID,
+			CourseID:     e.CourseID,
+			TermID:       e.TermID,
+			EnrollmentID: e.ID,
+			Message:      fmt.Sprintf("You have been moved from the waitlist into %s %s", e.CourseCode, e.TermCode),
+			At:           time.Now().UTC(),
+		})
+		log.Infof("Promoted enrollment %d of student %d from the waitlist", e.ID, e.StudentID)
+	}
+}
+
+// seatCourses returns the courses in which a student holds a seat. It is
+// read before the student is deleted, since the enrollments go with them,
+// so that the waitlists of those courses can be promoted afterwards.
+func (s *Service) seatCourses(ctx context.Context, studentID int32) []int32 {
+	enrollments, err := s.Store.GetStudentEnrollments(ctx, studentID, 0)
+	if err != nil {
+		log.Errorf("Failed to read the enrollments of student %d, Error: %v", studentID, err)
+		return nil
+	}
+	var courses []int32
+	seen := map[int32]bool{}
+	for _, e := range enrollments {
+		if HoldsSeat(e.Status) && !seen[e.CourseID] {
+			seen[e.CourseID] = true
+			courses = append(courses, e.CourseID)
+		}
+	}
+	return courses
+}
+
+func diffEnrollments(before, after Enrollment) map[string]FieldChange {
+	diff := map[string]FieldChange{}
+	add := func(field, from, to string) {
+		if from != to {
+			diff[field] = FieldChange{From: from, To: to}
+		}
+	}
+	formatID := func(id int32) string {
+		if id == 0 {
+			return ""
+		}
+		return fmt.Sprint(id)
+	}
+	add("course_id", formatID(before.CourseID), formatID(after.CourseID))
+	add("term_id", formatID(before.TermID), formatID(after.TermID))
+	add("grade", before.Grade, after.Grade)
+	add("status", before.Status, after.Status)
+	return diff
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"errors"
+)
+
+// errFakeInsert is how the fake store fails a write of a student named
+// fakeStore.failName.
+var errFakeInsert = errors.New("duplicate entry")
+
+// fakeStore keeps just enough in memory for the service tests. Store
+// methods it does not override panic through the nil embedded interface,
+// which points at the method a test is missing.
+type fakeStore struct {
+	Store
+
+	courses       map[int32]Course
+	scales        map[int32]GradingScale
+	prerequisites map[int32][]PrerequisiteGroup
+	overrides     map[[2]int32]bool
+	enrollments   []Enrollment
+	audit         []AuditEntry
+
+	students map[int32]Student
+	lastID   int32
+	// failName fails every write of a student with this name, and
+	// failMultiRow fails AddStudents as a whole.
+	failName     string
+	failMultiRow bool
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{
+		courses:       map[int32]Course{},
+		scales:        map[int32]GradingScale{},
+		prerequisites: map[int32][]PrerequisiteGroup{},
+		overrides:     map[[2]int32]bool{},
+		students:      map[int32]Student{},
+	}
+}
+
+func (f *fakeStore) GetCourse(_ context.Context, id int32) (Course, error) {
+	c, ok := f.courses[id]
+	if !ok {
+		return Course{}, ErrCourseNotFound
+	}
+	return c, nil
+}
+
+func (f *fakeStore) GetGradingScale(_ context.Context, id int32) (GradingScale, error) {
+	g, ok := f.scales[id]
+	if !ok {
+		return GradingScale{}, ErrGradingScaleNotFound
+	}
+	return g, nil
+}
+
+func (f *fakeStore) GetDefaultGradingScale(context.Context) (GradingScale, error) {
+	return GradingScale{}, ErrGradingScaleNotFound
+}
+
+func (f *fakeStore) GetPrerequisites(_ context.Context, courseID int32) ([]PrerequisiteGroup, error) {
+	return f.prerequisites[courseID], nil
+}
+
+func (f *fakeStore) SetPrerequisites(_ context.Context, courseID int32, groups []PrerequisiteGroup) error {
+	f.prerequisites[courseID] = groups
+	return nil
+}
+
+func (f *fakeStore) HasOverride(_ context.Context, studentID, courseID int32) (bool, error) {
+	return f.overrides[[2]int32{studentID, courseID}], nil
+}
+
+func (f *fakeStore) GetStudentEnrollments(_ context.Context, studentID, termID int32) ([]Enrollment, error) {
+	var out []Enrollment
+	for _, e := range f.enrollments {
+		if e.StudentID == studentID && (termID == 0 || e.TermID == termID) {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeStore) AddAuditEntry(_ context.Context, entry AuditEntry) error {
+	f.audit = append(f.audit, entry)
+	return nil
+}
+
+func (f *fakeStore) GetStudent(_ context.Context, id int32) (Student, error) {
+	s, ok := f.students[id]
+	if !ok {
+		return Student{}, ErrStudentNotFound
+	}
+	return s, nil
+}
+
+func (f *fakeStore) AddStudent(_ context.Context, s Student) (Student, error) {
+	if s.Name == f.failName {
+		return Student{}, errFakeInsert
+	}
+	f.lastID++
+	s.ID = f.lastID
+	f.students[s.ID] = s
+	return s, nil
+}
+
+func (f *fakeStore) AddStudents(ctx context.Context, students []Student) ([]Student, error) {
+	if f.failMultiRow {
+		return nil, errFakeInsert
+	}
+	var added []Student
+	err := f.inTransaction(func() error {
+		for _, s := range students {
+			stored, err := f.AddStudent(ctx, s)
+			if err != nil {
+				return err
+			}
+			added = append(added, stored)
+		}
+		return nil
+	})
+	return added, err
+}
+
+func (f *fakeStore) UpdateStudent(_ context.Context, id int32, s Student) (Student, error) {
+	if _, ok := f.students[id]; !ok {
+		return Student{}, ErrStudentNotFound
+	}
+	if s.Name == f.failName {
+		return Student{}, errFakeInsert
+	}
+	s.ID = id
+	f.students[id] = s
+	return s, nil
+}
+
+func (f *fakeStore) DeleteStudent(_ context.Context, id int32) error {
+	if _, ok := f.students[id]; !ok {
+		return ErrStudentNotFound
+	}
+	delete(f.students, id)
+	return nil
+}
+
+// ApplyStudentBatch writes the creates first, then the rest in order, and
+// keeps nothing if one fails, like the database does.
+func (f *fakeStore) ApplyStudentBatch(ctx context.Context, ops []StudentOp) ([]Student, error) {
+	results := make([]Student, len(ops))
+	err := f.inTransaction(func() error {
+		for i, op := range ops {
+			if op.Op != BatchCreate {
+				continue
+			}
+			stored, err := f.AddStudent(ctx, *op.Student)
+			if err != nil {
+				return &StudentOpError{Index: i, Err: err}
+			}
+			results[i] = stored
+		}
+		for i, op := range ops {
+			var err error
+			switch op.Op {
+			case BatchUpdate:
+				results[i], err = f.UpdateStudent(ctx, op.ID, *op.Student)
+			case BatchDelete:
+				err = f.DeleteStudent(ctx, op.ID)
+			}
+			if err != nil {
+				return &StudentOpError{Index: i, Err: err}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// inTransaction restores the students and the ID counter if fn fails.
+func (f *fakeStore) inTransaction(fn func() error) error {
+	saved := make(map[int32]Student, len(f.students))
+	for id, s := range f.students {
+		saved[id] = s
+	}
+	lastID := f.lastID
+	if err := fn(); err != nil {
+		f.students, f.lastID = saved, lastID
+		return err
+	}
+	return nil
+}
+
+func (f *fakeStore) GetStudentSubmissions(context.Context, int32) ([]Submission, error) {
+	return nil, nil
+}
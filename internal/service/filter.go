@@ -0,0 +1,391 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Filter operators.
+const (
+	OpEq       = "eq"
+	OpNe       = "ne"
+	OpIn       = "in"
+	OpPrefix   = "prefix"
+	OpContains = "contains"
+	OpGt       = "gt"
+	OpGe       = "ge"
+	OpLt       = "lt"
+	OpLe       = "le"
+)
+
+// FieldKind is the type of a filterable field, which decides the operators
+// and values it accepts.
+type FieldKind int
+
+const (
+	KindString FieldKind = iota
+	KindInt
+	KindTime
+)
+
+// filterFields lists the Student fields that may be filtered on.
+var filterFields = map[string]FieldKind{
+	"id":         KindInt,
+	"name":       KindString,
+	"course":     KindString,
+	"course_id":  KindInt,
+	"grade":      KindString,
+	"created_by": KindString,
+	"updated_by": KindString,
+	"created_on": KindTime,
+	"updated_on": KindTime,
+}
+
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// Condition is a single "field op value" clause of a filter expression.
+type Condition struct {
+	Field  string
+	Op     string
+	Values []string
+}
+
+// Filter is a conjunction of conditions; a student matches when every
+// condition holds.
+type Filter struct {
+	Conditions []Condition
+}
+
+// ParseFilter parses a filter expression such as
+//
+//	course eq 'Data Science' and grade in ('A', 'A+') and updated_on ge 2024-10-01
+//
+// Clauses are joined with "and". String fields support eq, ne, in, prefix
+// and contains; id and course_id support eq, ne, in, gt, ge, lt and le; created_on and
+// updated_on accept the same comparisons with a YYYY-MM-DD or RFC 3339 value,
+// where a plain date covers the whole day. Values containing spaces must be
+// quoted with ' or ", and a quote is escaped by doubling it.
+func ParseFilter(expr string) (Filter, error) {
+	var f Filter
+	if strings.TrimSpace(expr) == "" {
+		return f, nil
+	}
+
+	tokens, err := tokenizeFilter(expr)
+	if err != nil {
+		return f, err
+	}
+
+	p := &filterParser{tokens: tokens}
+	for {
+		cond, err := p.condition()
+		if err != nil {
+			return Filter{}, err
+		}
+		f.Conditions = append(f.Conditions, cond)
+
+		if p.done() {
+			return f, nil
+		}
+		if tok := p.next(); !tok.is("and") {
+			return Filter{}, filterError("expected 'and' but found %q", tok.text)
+		}
+	}
+}
+
+func filterError(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidFilter, fmt.Sprintf(format, args...))
+}
+
+type filterToken struct {
+	text   string
+	quoted bool
+}
+
+func (t filterToken) is(word string) bool {
+	return !t.quoted && strings.EqualFold(t.text, word)
+}
+
+func tokenizeFilter(expr string) ([]filterToken, error) {
+	var tokens []filterToken
+	runes := []rune(expr)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')' || r == ',':
+			tokens = append(tokens, filterToken{text: string(r)})
+			i++
+		case r == '\'' || r == '"':
+			quote := r
+			var sb strings.Builder
+			i++
+			closed := false
+			for i < len(runes) {
+				if runes[i] == quote {
+					if i+1 < len(runes) && runes[i+1] == quote {
+						sb.WriteRune(quote)
+						i += 2
+						continue
+					}
+					i++
+					closed = true
+					break
+				}
+				sb.WriteRune(runes[i])
+				i++
+			}
+			if !closed {
+				return nil, filterError("unterminated quoted value")
+			}
+			tokens = append(tokens, filterToken{text: sb.String(), quoted: true})
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && !strings.ContainsRune("(),'\"", runes[i]) {
+				i++
+			}
+			tokens = append(tokens, filterToken{text: string(runes[start:i])})
+		}
+	}
+	return tokens, nil
+}
+
+type filterParser struct {
+	tokens []filterToken
+	pos    int
+}
+
+func (p *filterParser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *filterParser) next() filterToken {
+	if p.done() {
+		return filterToken{}
+	}
+	tok := p.tokens[p.pos]
+	p.pos++
+	return tok
+}
+
+func (p *filterParser) value() (string, error) {
+	tok := p.next()
+	if tok.text == "" && !tok.quoted {
+		return "", filterError("missing value")
+	}
+	if !tok.quoted && (tok.text == "(" || tok.text == ")" || tok.text == ",") {
+		return "", filterError("unexpected %q", tok.text)
+	}
+	return tok.text, nil
+}
+
+func (p *filterParser) condition() (Condition, error) {
+	fieldTok := p.next()
+	field := strings.ToLower(fieldTok.text)
+	kind, ok := filterFields[field]
+	if fieldTok.quoted || !ok {
+		return Condition{}, filterError("unknown field %q", fieldTok.text)
+	}
+
+	op := strings.ToLower(p.next().text)
+	cond := Condition{Field: field, Op: op}
+
+	if op == OpIn {
+		if tok := p.next(); tok.text != "(" || tok.quoted {
+			return Condition{}, filterError("expected '(' after in")
+		}
+		for {
+			v, err := p.value()
+			if err != nil {
+				return Condition{}, err
+			}
+			cond.Values = append(cond.Values, v)
+			tok := p.next()
+			if tok.text == ")" && !tok.quoted {
+				break
+			}
+			if tok.text != "," || tok.quoted {
+				return Condition{}, filterError("expected ',' or ')' in value list")
+			}
+		}
+	} else {
+		v, err := p.value()
+		if err != nil {
+			return Condition{}, err
+		}
+		cond.Values = []string{v}
+	}
+
+	if err := validateCondition(cond, kind); err != nil {
+		return Condition{}, err
+	}
+	return cond, nil
+}
+
+func validateCondition(c Condition, kind FieldKind) error {
+	allowed := map[FieldKind][]string{
+		KindString: {OpEq, OpNe, OpIn, OpPrefix, OpContains},
+		KindInt:    {OpEq, OpNe, OpIn, OpGt, OpGe, OpLt, OpLe},
+		KindTime:   {OpEq, OpNe, OpIn, OpGt, OpGe, OpLt, OpLe},
+	}[kind]
+	ok := false
+	for _, op := range allowed {
+		if c.Op == op {
+			ok = true
+		}
+	}
+	if !ok {
+		return filterError("operator %q is not supported for field %s", c.Op, c.Field)
+	}
+
+	for _, v := range c.Values {
+		switch kind {
+		case KindInt:
+			if _, err := strconv.ParseInt(v, 10, 32); err != nil {
+				return filterError("%s expects an integer, got %q", c.Field, v)
+			}
+		case KindTime:
+			if _, _, err := DateBounds(v); err != nil {
+				return filterError("%s expects YYYY-MM-DD or an RFC 3339 timestamp, got %q", c.Field, v)
+			}
+		}
+	}
+	return nil
+}
+
+// DateBounds returns the half-open interval [start, end) a filter value
+// covers: a whole day for YYYY-MM-DD, or one second for a timestamp, which is
+// the precision of the stored columns.
+func DateBounds(v string) (time.Time, time.Time, error) {
+	if t, err := time.Parse("2006-01-02", v); err == nil {
+		return t, t.AddDate(0, 0, 1), nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	t = t.Truncate(time.Second)
+	return t, t.Add(time.Second), nil
+}
+
+// FilterFieldKind returns the kind of a filterable field, for stores that
+// compile conditions themselves.
+func FilterFieldKind(field string) FieldKind {
+	return filterFields[field]
+}
+
+// Match evaluates the filter against a student in memory. It is the
+// reference behaviour for stores that cannot push the filter down to SQL,
+// and compares strings case-insensitively like the default MySQL collation.
+func (f Filter) Match(s Student) bool {
+	for _, c := range f.Conditions {
+		if !c.match(s) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c Condition) match(s Student) bool {
+	switch filterFields[c.Field] {
+	case KindInt:
+		if c.Field == "course_id" {
+			return c.matchInt(int64(s.CourseID))
+		}
+		return c.matchInt(int64(s.ID))
+	case KindTime:
+		raw := map[string]string{"created_on": s.CreatedOn, "updated_on": s.UpdatedOn}[c.Field]
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return false
+		}
+		return c.matchTime(t)
+	}
+	value := map[string]string{
+		"name":       s.Name,
+		"course":     s.Course,
+		"grade":      s.Grade,
+		"created_by": s.CreatedBy,
+		"updated_by": s.UpdatedBy,
+	}[c.Field]
+	return c.matchString(value)
+}
+
+func (c Condition) matchString(v string) bool {
+	v = strings.ToLower(v)
+	want := strings.ToLower(c.Values[0])
+	switch c.Op {
+	case OpEq:
+		return v == want
+	case OpNe:
+		return v != want
+	case OpPrefix:
+		return strings.HasPrefix(v, want)
+	case OpContains:
+		return strings.Contains(v, want)
+	case OpIn:
+		for _, w := range c.Values {
+			if v == strings.ToLower(w) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c Condition) matchInt(v int64) bool {
+	want, _ := strconv.ParseInt(c.Values[0], 10, 32)
+	switch c.Op {
+	case OpEq:
+		return v == want
+	case OpNe:
+		return v != want
+	case OpGt:
+		return v > want
+	case OpGe:
+		return v >= want
+	case OpLt:
+		return v < want
+	case OpLe:
+		return v <= want
+	case OpIn:
+		for _, w := range c.Values {
+			if n, _ := strconv.ParseInt(w, 10, 32); n == v {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c Condition) matchTime(t time.Time) bool {
+	start, end, _ := DateBounds(c.Values[0])
+	within := !t.Before(start) && t.Before(end)
+	switch c.Op {
+	case OpEq:
+		return within
+	case OpNe:
+		return !within
+	case OpGt:
+		return !t.Before(end)
+	case OpGe:
+		return !t.Before(start)
+	case OpLt:
+		return t.Before(start)
+	case OpLe:
+		return t.Before(end)
+	case OpIn:
+		for _, w := range c.Values {
+			s, e, _ := DateBounds(w)
+			if !t.Before(s) && t.Before(e) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestTokenizeFilter(t *testing.T) {
+	tests := []struct {
+		expr string
+		want []filterToken
+	}{
+		{"grade eq A", []filterToken{{text: "grade"}, {text: "eq"}, {text: "A"}}},
+		{"course eq 'Data Science'", []filterToken{{text: "course"}, {text: "eq"}, {text: "Data Science", quoted: true}}},
+		{`name eq "O""Brien"`, []filterToken{{text: "name"}, {text: "eq"}, {text: `O"Brien`, quoted: true}}},
+		{"name eq 'it''s'", []filterToken{{text: "name"}, {text: "eq"}, {text: "it's", quoted: true}}},
+		{"name eq ''", []filterToken{{text: "name"}, {text: "eq"}, {text: "", quoted: true}}},
+		{"id in (1,2)", []filterToken{{text: "id"}, {text: "in"}, {text: "("}, {text: "1"}, {text: ","}, {text: "2"}, {text: ")"}}},
+	}
+	for _, tt := range tests {
+		got, err := tokenizeFilter(tt.expr)
+		if err != nil {
+			t.Errorf("tokenizeFilter(%q): %v", tt.expr, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("tokenizeFilter(%q) = %+v, want %+v", tt.expr, got, tt.want)
+		}
+	}
+
+	if _, err := tokenizeFilter("name eq 'open"); !errors.Is(err, ErrInvalidFilter) {
+		t.Errorf("unterminated quote: got %v, want ErrInvalidFilter", err)
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		expr string
+		want []Condition
+	}{
+		{"", nil},
+		{"  ", nil},
+		{"Grade EQ A", []Condition{{Field: "grade", Op: OpEq, Values: []string{"A"}}}},
+		{
+			"course eq 'Data Science' and grade in ('A', 'A+') and updated_on ge 2024-10-01",
+			[]Condition{
+				{Field: "course", Op: OpEq, Values: []string{"Data Science"}},
+				{Field: "grade", Op: OpIn, Values: []string{"A", "A+"}},
+				{Field: "updated_on", Op: OpGe, Values: []string{"2024-10-01"}},
+			},
+		},
+		{"id lt 10 AND name prefix Jo", []Condition{
+			{Field: "id", Op: OpLt, Values: []string{"10"}},
+			{Field: "name", Op: OpPrefix, Values: []string{"Jo"}},
+		}},
+		{"created_on eq 2024-10-01T08:30:00Z", []Condition{{Field: "created_on", Op: OpEq, Values: []string{"2024-10-01T08:30:00Z"}}}},
+		{"name eq 'and'", []Condition{{Field: "name", Op: OpEq, Values: []string{"and"}}}},
+	}
+	for _, tt := range tests {
+		got, err := ParseFilter(tt.expr)
+		if err != nil {
+			t.Errorf("ParseFilter(%q): %v", tt.expr, err)
+			continue
+		}
+		if !reflect.DeepEqual(got.Conditions, tt.want) {
+			t.Errorf("ParseFilter(%q) = %+v, want %+v", tt.expr, got.Conditions, tt.want)
+		}
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	for _, expr := range []string{
+		"password eq x",           // not filterable
+		"'name' eq x",             // quoted field
+		"name",                    // no operator or value
+		"name eq",                 // no value
+		"name gt b",               // comparison on a string
+		"id prefix 1",             // prefix on an integer
+		"id eq abc",               // not an integer
+		"created_on eq yesterday", // not a date
+		"name eq a or id eq 1",    // only "and" joins clauses
+		"name eq a and",           // dangling "and"
+		"id in 1, 2",              // missing (
+		"id in (1 2)",             // missing ,
+		"id in (1,",               // unterminated list
+		"name eq (",               // punctuation as a value
+	} {
+		if _, err := ParseFilter(expr); !errors.Is(err, ErrInvalidFilter) {
+			t.Errorf("ParseFilter(%q) = %v, want ErrInvalidFilter", expr, err)
+		}
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	s := Student{
+		ID:        7,
+		Name:      "Jo Smith",
+		Course:    "Data Science",
+		CourseID:  3,
+		Grade:     "",
+		CreatedOn: "2024-10-01T08:30:00Z",
+		UpdatedOn: "2024-10-02T00:00:00Z",
+	}
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{"name eq 'jo smith'", true},
+		{"name prefix JO", true},
+		{"name contains smi", true},
+		{"name contains xyz", false},
+		{"course eq 'Data Science' and course_id eq 3", true},
+		{"course_id ne 3", false},
+		{"id in (1, 7)", true},
+		{"id gt 7", false},
+		{"id ge 7", true},
+		{"grade ne A", true}, // an empty grade is not A
+		{"grade ne ''", false},
+		{"grade in (A, B)", false},
+		{"created_on eq 2024-10-01", true},
+		{"created_on ne 2024-10-01", false},
+		{"created_on gt 2024-10-01", false},
+		{"created_on lt 2024-10-02", true},
+		{"updated_on ge 2024-10-02 and updated_on le 2024-10-02", true},
+		{"created_on eq 2024-10-01T08:30:00Z", true},
+		{"created_on eq 2024-10-01T08:30:01Z", false},
+	}
+	for _, tt := range tests {
+		f, err := ParseFilter(tt.expr)
+		if err != nil {
+			t.Fatalf("ParseFilter(%q): %v", tt.expr, err)
+		}
+		if got := f.Match(s); got != tt.want {
+			t.Errorf("%q matched %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
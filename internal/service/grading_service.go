@@ -0,0 +1,363 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// GradePoint maps one letter grade of a scale to its grade points.
+type GradePoint struct {
+	Letter string  `json:"letter" validate:"required,max=10"`
+	Points float64 `json:"points" validate:"gte=0,lte=99"`
+}
+
+// GradingScale is an admin-defined set of valid letter grades. A course may
+// name its own scale; otherwise the default (institution-wide) scale applies.
+type GradingScale struct {
+	ID        int32        `json:"id"`
+	Name      string       `json:"name" validate:"required,max=100"`
+	IsDefault bool         `json:"is_default"`
+	Grades    []GradePoint `json:"grades" validate:"required,min=1,dive"`
+	CreatedBy string       `json:"created_by"`
+	CreatedOn string       `json:"created_on"`
+	UpdatedBy string       `json:"updated_by"`
+	UpdatedOn string       `json:"updated_on"`
+}
+
+var (
+	ErrGradingScaleNotFound = errors.New("grading scale not found")
+	ErrGradingScaleInUse    = errors.New("grading scale is still used by courses")
+	ErrGradingScaleTaken    = errors.New("a grading scale with that name already exists")
+	ErrInvalidGradingScale  = errors.New("invalid grading scale")
+	ErrInvalidGrade         = errors.New("invalid grade")
+)
+
+const (
+	AuditGradingScaleCreate = "grading_scale.create"
+	AuditGradingScaleUpdate = "grading_scale.update"
+	AuditGradingScaleDelete = "grading_scale.delete"
+)
+
+type GradingScaleStore interface {
+	GetAllGradingScales(context.Context) ([]GradingScale, error)
+	GetGradingScale(context.Context, int32) (GradingScale, error)
+	// GetDefaultGradingScale returns ErrGradingScaleNotFound when no scale
+	// is marked as the default.
+	GetDefaultGradingScale(context.Context) (GradingScale, error)
+	AddGradingScale(context.Context, GradingScale) (GradingScale, error)
+	UpdateGradingScale(context.Context, int32, GradingScale) (GradingScale, error)
+	DeleteGradingScale(context.Context, int32) error
+}
+
+// StandardGradingScale applies when no scale is marked as the default. It
+// is also what migration 13 seeds as the default scale of a new install.
+var StandardGradingScale = GradingScale{
+	Name:      "Standard letter grades",
+	IsDefault: true,
+	Grades: []GradePoint{
+		{"A", 4.0}, {"A-", 3.7},
+		{"B+", 3.3}, {"B", 3.0}, {"B-", 2.7},
+		{"C+", 2.3}, {"C", 2.0}, {"C-", 1.7},
+		{"D+", 1.3}, {"D", 1.0},
+		{"F", 0},
+	},
+}
+
+// Points returns the grade points of a letter, matched exactly.
+func (g GradingScale) Points(letter string) (float64, bool) {
+	for _, gp := range g.Grades {
+		if gp.Letter == letter {
+			return gp.Points, true
+		}
+	}
+	return 0, false
+}
+
+func (g GradingScale) letters() []string {
+	letters := make([]string, len(g.Grades))
+	for i, gp := range g.Grades {
+		letters[i] = gp.Letter
+	}
+	return letters
+}
+
+func validateGradingScale(g GradingScale) error {
+	if strings.TrimSpace(g.Name) == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidGradingScale)
+	}
+	if len(g.Grades) == 0 {
+		return fmt.Errorf("%w: at least one grade is required", ErrInvalidGradingScale)
+	}
+	seen := map[string]bool{}
+	for _, gp := range g.Grades {
+		if strings.TrimSpace(gp.Letter) == "" || len(gp.Letter) > 10 {
+			return fmt.Errorf("%w: grade letters must be 1 to 10 characters", ErrInvalidGradingScale)
+		}
+		if seen[gp.Letter] {
+			return fmt.Errorf("%w: grade %q is listed twice", ErrInvalidGradingScale, gp.Letter)
+		}
+		if gp.Points < 0 || gp.Points > 99 {
+			return fmt.Errorf("%w: points for %q must be between 0 and 99", ErrInvalidGradingScale, gp.Letter)
+		}
+		seen[gp.Letter] = true
+	}
+	return nil
+}
+
+// scaleForCourse returns the scale that grades of a course are checked
+// against: the course's own scale, else the default scale, else
+// StandardGradingScale.
+func (s *Service) scaleForCourse(ctx context.Context, courseID int32) (GradingScale, error) {
+	if courseID != 0 {
+		course, err := s.Store.GetCourse(ctx, courseID)
+		if err != nil {
+			return GradingScale{}, err
+		}
+		if course.GradingScaleID != 0 {
+			return s.Store.GetGradingScale(ctx, course.GradingScaleID)
+		}
+	}
+
+	scale, err := s.Store.GetDefaultGradingScale(ctx)
+	if errors.Is(err, ErrGradingScaleNotFound) {
+		return StandardGradingScale, nil
+	}
+	if err != nil {
+		return GradingScale{}, err
+	}
+	return scale, nil
+}
+
+// validateGrade rejects a grade that is not on the applicable scale. An
+// empty grade is always allowed.
+func (s *Service) validateGrade(ctx context.Context, courseID int32, grade string) error {
+	if grade == "" {
+		return nil
+	}
+	scale, err := s.scaleForCourse(ctx, courseID)
+	if err != nil {
+		return err
+	}
+	if _, valid := scale.Points(grade); !valid {
+		return fmt.Errorf("%w: %q is not on grading scale %q (allowed: %s)",
+			ErrInvalidGrade, grade, scale.Name, strings.Join(scale.letters(), ", "))
+	}
+	return nil
+}
+
+func (s *Service) GetAllGradingScales(ctx context.Context) ([]GradingScale, error) {
+	scales, err := s.Store.GetAllGradingScales(ctx)
+	if err != nil {
+		log.Errorf("Error fetching grading scales: %v", err)
+		return nil, err
+	}
+	return scales, nil
+}
+
+func (s *Service) GetGradingScale(ctx context.Context, scaleID int32) (GradingScale, error) {
+	return s.Store.GetGradingScale(ctx, scaleID)
+}
+
+func (s *Service) AddGradingScale(ctx context.Context, scale GradingScale) (GradingScale, error) {
+	if err := validateGradingScale(scale); err != nil {
+		return GradingScale{}, err
+	}
+	created, err := s.Store.AddGradingScale(ctx, scale)
+	s.RecordAudit(ctx, AuditEntry{
+		Action:   AuditGradingScaleCreate,
+		TargetID: created.ID,
+		Diff:     diffGradingScales(GradingScale{}, scale),
+		Outcome:  outcomeOf(err),
+		Message:  messageOf(err),
+	})
+	if err != nil {
+		log.Errorf("Failed to add grading scale %s, Error: %v", scale.Name, err)
+		return GradingScale{}, err
+	}
+	return created, nil
+}
+
+// UpdateGradingScale replaces the name, default flag and grades of a scale.
+// Grades already stored are not re-validated.
+func (s *Service) UpdateGradingScale(ctx context.Context, scaleID int32, scale GradingScale) (GradingScale, error) {
+	if err := validateGradingScale(scale); err != nil {
+		return GradingScale{}, err
+	}
+	before, _ := s.Store.GetGradingScale(ctx, scaleID)
+	updated, err := s.Store.UpdateGradingScale(ctx, scaleID, scale)
+	s.RecordAudit(ctx, AuditEntry{
+		Action:   AuditGradingScaleUpdate,
+		TargetID: scaleID,
+		Diff:     diffGradingScales(before, scale),
+		Outcome:  outcomeOf(err),
+		Message:  messageOf(err),
+	})
+	if err != nil {
+		log.Errorf("Failed to update grading scale %d, Error: %v", scaleID, err)
+		return GradingScale{}, err
+	}
+	return updated, nil
+}
+
+func (s *Service) DeleteGradingScale(ctx context.Context, scaleID int32) error {
+	before, _ := s.Store.GetGradingScale(ctx, scaleID)
+	err := s.Store.DeleteGradingScale(ctx, scaleID)
+	entry := AuditEntry{
+		Action:   AuditGradingScaleDelete,
+		TargetID: scaleID,
+		Outcome:  outcomeOf(err),
+		Message:  messageOf(err),
+	}
+	if err == nil {
+		entry.Diff = diffGradingScales(before, GradingScale{})
+	}
+	s.RecordAudit(ctx, entry)
+	if err != nil {
+		log.Errorf("Failed to delete grading scale %d, Error: %v", scaleID, err)
+		return err
+	}
+	return nil
+}
+
+func diffGradingScales(before, after GradingScale) map[string]FieldChange {
+	diff := map[string]FieldChange{}
+	if before.Name != after.Name {
+		diff["name"] = FieldChange{From: before.Name, To: after.Name}
+	}
+	if before.IsDefault != after.IsDefault {
+		diff["is_default"] = FieldChange{From: fmt.Sprint(before.IsDefault), To: fmt.Sprint(after.IsDefault)}
+	}
+	format := func(g GradingScale) string {
+		parts := make([]string, len(g.Grades))
+		for i, gp := range g.Grades {
+			parts[i] = fmt.Sprintf("%s=%g", gp.Letter, gp.Points)
+		}
+		return strings.Join(parts, " ")
+	}
+	if from, to := format(before), format(after); from != to {
+		diff["grades"] = FieldChange{From: from, To: to}
+	}
+	return diff
+}
+
+// CourseGrade is one graded enrollment counted towards a GPA.
+type CourseGrade struct {
+	EnrollmentID int32   `json:"enrollment_id"`
+	CourseID     int32   `json:"course_id"`
+	CourseCode   string  `json:"course_code"`
+	TermID       int32   `json:"term_id"`
+	TermCode     string  `json:"term_code,omitempty"`
+	Credits      float64 `json:"credits"`
+	Grade        string  `json:"grade"`
+	Points       float64 `json:"points"`
+}
+
+// TermGPA is the GPA of the grades earned in one term.
+type TermGPA struct {
+	TermID        int32   `json:"term_id"`
+	TermCode      string  `json:"term_code,omitempty"`
+	GPA           float64 `json:"gpa"`
+	Credits       float64 `json:"credits"`
+	QualityPoints float64 `json:"quality_points"`
+}
+
+// GPA is a credit-weighted grade point average, cumulative and per term.
+type GPA struct {
+	StudentID     int32         `json:"student_id"`
+	GPA           float64       `json:"gpa"`
+	Credits       float64       `json:"credits"`
+	QualityPoints float64       `json:"quality_points"`
+	Terms         []TermGPA     `json:"terms"`
+	Courses       []CourseGrade `json:"courses"`
+	Unscored      []int32       `json:"unscored_enrollments,omitempty"`
+}
+
+// countsTowardsGPA reports whether an enrollment has a final grade.
+func countsTowardsGPA(e Enrollment) bool {
+	return e.Grade != "" && (e.Status == EnrollmentCompleted || e.Status == EnrollmentFailed)
+}
+
+// GetStudentGPA computes the GPA over completed and failed enrollments,
+// weighting each grade's points by the course credits, both cumulatively and
+// for every term. Grades that are not on the course's scale are listed as
+// unscored. Students only see their own GPA, and for instructors it only
+// covers the courses they teach.
+func (s *Service) GetStudentGPA(ctx context.Context, studentID int32) (GPA, error) {
+	if err := s.checkRecordsReadable(ctx, studentID); err != nil {
+		return GPA{}, err
+	}
+	enrollments, err := s.GetStudentEnrollments(ctx, studentID, "")
+	if err != nil {
+		return GPA{}, err
+	}
+
+	result := GPA{StudentID: studentID, Terms: []TermGPA{}, Courses: []CourseGrade{}}
+	scales := map[int32]GradingScale{}
+	termIndex := map[int32]int{}
+	for _, e := range enrollments {
+		if !countsTowardsGPA(e) {
+			continue
+		}
+		scale, cached := scales[e.CourseID]
+		if !cached {
+			scale, err = s.scaleForCourse(ctx, e.CourseID)
+			if err != nil {
+				return GPA{}, err
+			}
+			scales[e.CourseID] = scale
+		}
+		points, ok := scale.Points(e.Grade)
+		if !ok {
+			result.Unscored = append(result.Unscored, e.ID)
+			continue
+		}
+		result.Courses = append(result.Courses, CourseGrade{
+			EnrollmentID: e.ID,
+			CourseID:     e.CourseID,
+			CourseCode:   e.CourseCode,
+			TermID:       e.TermID,
+			TermCode:     e.TermCode,
+			Credits:      e.Credits,
+			Grade:        e.Grade,
+			Points:       points,
+		})
+		result.Credits += e.Credits
+		result.QualityPoints += points * e.Credits
+
+		// Enrollments from before terms existed only count cumulatively.
+		if e.TermID == 0 {
+			continue
+		}
+		i, seen := termIndex[e.TermID]
+		if !seen {
+			i = len(result.Terms)
+			termIndex[e.TermID] = i
+			result.Terms = append(result.Terms, TermGPA{TermID: e.TermID, TermCode: e.TermCode})
+		}
+		result.Terms[i].Credits += e.Credits
+		result.Terms[i].QualityPoints += points * e.Credits
+	}
+
+	result.GPA, result.QualityPoints = weightedAverage(result.QualityPoints, result.Credits)
+	for i := range result.Terms {
+		t := &result.Terms[i]
+		t.GPA, t.QualityPoints = weightedAverage(t.QualityPoints, t.Credits)
+	}
+	return result, nil
+}
+
+func weightedAverage(qualityPoints, credits float64) (gpa float64, rounded float64) {
+	if credits > 0 {
+		gpa = round2(qualityPoints / credits)
+	}
+	return gpa, round2(qualityPoints)
+}
+
+func round2(f float64) float64 {
+	return math.Round(f*100) / 100
+}
@@ -0,0 +1,333 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Caller roles, as carried in the "userType" context value.
+const (
+	RoleAdmin      = "admin"
+	RoleUser       = "user"
+	RoleInstructor = "instructor"
+)
+
+// MinPasswordLength applies to instructor passwords.
+const MinPasswordLength = 8
+
+// Instructor is a teacher with their own login. Password is only read from
+// requests; the store keeps a bcrypt hash.
+type Instructor struct {
+	ID           int32  `json:"id"`
+	Name         string `json:"name" validate:"required,max=100"`
+	Email        string `json:"email" validate:"required,email,max=100"`
+	Password     string `json:"password,omitempty" validate:"omitempty,min=8,max=72"`
+	PasswordHash string `json:"-"`
+	CreatedBy    string `json:"created_by"`
+	CreatedOn    string `json:"created_on"`
+	UpdatedBy    string `json:"updated_by"`
+	UpdatedOn    string `json:"updated_on"`
+}
+
+var (
+	ErrInstructorNotFound  = errors.New("instructor not found")
+	ErrInstructorEmailUsed = errors.New("an instructor with that email already exists")
+	ErrInvalidInstructor   = errors.New("invalid instructor")
+	ErrInvalidCredentials  = errors.New("invalid email or password")
+)
+
+const (
+	AuditInstructorCreate   = "instructor.create"
+	AuditInstructorUpdate   = "instructor.update"
+	AuditInstructorDelete   = "instructor.delete"
+	AuditInstructorAssign   = "instructor.assign"
+	AuditInstructorUnassign = "instructor.unassign"
+	AuditAuthLogin          = "auth.login"
+)
+
+type InstructorStore interface {
+	GetAllInstructors(context.Context) ([]Instructor, error)
+	GetInstructor(context.Context, int32) (Instructor, error)
+	// GetInstructorByEmail also returns the password hash.
+	GetInstructorByEmail(context.Context, string) (Instructor, error)
+	AddInstructor(context.Context, Instructor) (Instructor, error)
+	// UpdateInstructor keeps the stored hash when PasswordHash is empty.
+	UpdateInstructor(context.Context, int32, Instructor) (Instructor, error)
+	DeleteInstructor(context.Context, int32) error
+	GetInstructorCourses(context.Context, int32) ([]Course, error)
+	AssignCourse(ctx context.Context, instructorID, courseID int32) error
+	UnassignCourse(ctx context.Context, instructorID, courseID int32) error
+	Teaches(ctx context.Context, instructorID, courseID int32) (bool, error)
+}
+
+// callerRole returns the role of the authenticated caller.
+func callerRole(ctx context.Context) string {
+	role, _ := ctx.Value("userType").(string)
+	return role
+}
+
+func callerID(ctx context.Context) int32 {
+	id, _ := ctx.Value("userID").(int32)
+	return id
+}
+
+// denyInstructor stops instructors from the writes that are reserved for
+// students and the admin. Instructors pass the transport's ownership check
+// so that the service can scope them to their courses.
+func denyInstructor(ctx context.Context) error {
+	if callerRole(ctx) == RoleInstructor {
+		return fmt.Errorf("%w: instructors can only grade enrollments in their own courses", ErrForbidden)
+	}
+	return nil
+}
+
+// checkTeaches makes sure an instructor caller teaches a course. Other
+// callers are not affected.
+func (s *Service) checkTeaches(ctx context.Context, courseID int32) error {
+	if callerRole(ctx) != RoleInstructor {
+		return nil
+	}
+	ok, err := s.Store.Teaches(ctx, callerID(ctx), courseID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: you do not teach this course", ErrForbidden)
+	}
+	return nil
+}
+
+// taughtCourses returns the courses an instructor caller teaches, or nil for
+// other callers, who are not scoped to courses.
+func (s *Service) taughtCourses(ctx context.Context) (map[int32]bool, error) {
+	if callerRole(ctx) != RoleInstructor {
+		return nil, nil
+	}
+	courses, err := s.Store.GetInstructorCourses(ctx, callerID(ctx))
+	if err != nil {
+		return nil, err
+	}
+	taught := make(map[int32]bool, len(courses))
+	for _, c := range courses {
+		taught[c.ID] = true
+	}
+	return taught, nil
+}
+
+// visibleEnrollments leaves an instructor caller only the enrollments in
+// the courses they teach, like checkTeaches does for single enrollments.
+func (s *Service) visibleEnrollments(ctx context.Context, enrollments []Enrollment) ([]Enrollment, error) {
+	taught, err := s.taughtCourses(ctx)
+	if err != nil || taught == nil {
+		return enrollments, err
+	}
+	visible := []Enrollment{}
+	for _, e := range enrollments {
+		if taught[e.CourseID] {
+			visible = append(visible, e)
+		}
+	}
+	return visible, nil
+}
+
+func validateInstructor(i Instructor, requirePassword bool) error {
+	switch {
+	case strings.TrimSpace(i.Name) == "":
+		return fmt.Errorf("%w: name is required", ErrInvalidInstructor)
+	case strings.TrimSpace(i.Email) == "":
+		return fmt.Errorf("%w: email is required", ErrInvalidInstructor)
+	}
+	if _, err := mail.ParseAddress(i.Email); err != nil {
+		return fmt.Errorf("%w: email is not valid", ErrInvalidInstructor)
+	}
+	if (requirePassword || i.Password != "") && len(i.Password) < MinPasswordLength {
+		return fmt.Errorf("%w: password must be at least %d characters", ErrInvalidInstructor, MinPasswordLength)
+	}
+	return nil
+}
+
+// hashPassword returns the bcrypt hash of a clear-text password, or an
+// empty hash for an empty password.
+func hashPassword(password string) (string, error) {
+	if password == "" {
+		return "", nil
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(hash), err
+}
+
+// checkPassword compares a password with a stored hash. Unknown accounts and
+// wrong passwords both give ErrInvalidCredentials.
+func checkPassword(hash, password string, lookupErr error, notFound error) error {
+	err := lookupErr
+	if err == nil {
+		err = bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	}
+	if errors.Is(err, notFound) || errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return ErrInvalidCredentials
+	}
+	return err
+}
+
+func (s *Service) GetAllInstructors(ctx context.Context) ([]Instructor, error) {
+	instructors, err := s.Store.GetAllInstructors(ctx)
+	if err != nil {
+		log.Errorf("Error fetching instructors: %v", err)
+		return nil, err
+	}
+	return instructors, nil
+}
+
+func (s *Service) GetInstructor(ctx context.Context, instructorID int32) (Instructor, error) {
+	return s.Store.GetInstructor(ctx, instructorID)
+}
+
+func (s *Service) AddInstructor(ctx context.Context, instructor Instructor) (Instructor, error) {
+	instructor.Email = strings.ToLower(strings.TrimSpace(instructor.Email))
+	if err := validateInstructor(instructor, true); err != nil {
+		return Instructor{}, err
+	}
+	hash, err := hashPassword(instructor.Password)
+	if err != nil {
+		return Instructor{}, err
+	}
+	instructor.PasswordHash, instructor.Password = hash, ""
+	created, err := s.Store.AddInstructor(ctx, instructor)
+	s.RecordAudit(ctx, AuditEntry{
+		Action:   AuditInstructorCreate,
+		TargetID: created.ID,
+		Diff:     diffInstructors(Instructor{}, instructor),
+		Outcome:  outcomeOf(err),
+		Message:  messageOf(err),
+	})
+	if err != nil {
+		log.Errorf("Failed to add instructor %s, Error: %v", instructor.Email, err)
+		return Instructor{}, err
+	}
+	return created, nil
+}
+
+// UpdateInstructor changes the name, email and, when one is given, the
+// password of an instructor.
+func (s *Service) UpdateInstructor(ctx context.Context, instructorID int32, instructor Instructor) (Instructor, error) {
+	instructor.Email = strings.ToLower(strings.TrimSpace(instructor.Email))
+	if err := validateInstructor(instructor, false); err != nil {
+		return Instructor{}, err
+	}
+	hash, err := hashPassword(instructor.Password)
+	if err != nil {
+		return Instructor{}, err
+	}
+	instructor.PasswordHash, instructor.Password = hash, ""
+	before, _ := s.Store.GetInstructor(ctx, instructorID)
+	updated, err := s.Store.UpdateInstructor(ctx, instructorID, instructor)
+	s.RecordAudit(ctx, AuditEntry{
+		Action:   AuditInstructorUpdate,
+		TargetID: instructorID,
+		Diff:     diffInstructors(before, instructor),
+		Outcome:  outcomeOf(err),
+		Message:  messageOf(err),
+	})
+	if err != nil {
+		log.Errorf("Failed to update instructor %d, Error: %v", instructorID, err)
+		return Instructor{}, err
+	}
+	return updated, nil
+}
+
+func (s *Service) DeleteInstructor(ctx context.Context, instructorID int32) error {
+	before, _ := s.Store.GetInstructor(ctx, instructorID)
+	err := s.Store.DeleteInstructor(ctx, instructorID)
+	entry := AuditEntry{
+		Action:   AuditInstructorDelete,
+		TargetID: instructorID,
+		Outcome:  outcomeOf(err),
+		Message:  messageOf(err),
+	}
+	if err == nil {
+		entry.Diff = diffInstructors(before, Instructor{})
+	}
+	s.RecordAudit(ctx, entry)
+	if err != nil {
+		log.Errorf("Failed to delete instructor %d, Error: %v", instructorID, err)
+	}
+	return err
+}
+
+func (s *Service) GetInstructorCourses(ctx context.Context, instructorID int32) ([]Course, error) {
+	if _, err := s.Store.GetInstructor(ctx, instructorID); err != nil {
+		return nil, err
+	}
+	return s.Store.GetInstructorCourses(ctx, instructorID)
+}
+
+func (s *Service) AssignCourse(ctx context.Context, instructorID, courseID int32) error {
+	if _, err := s.Store.GetInstructor(ctx, instructorID); err != nil {
+		return err
+	}
+	if _, err := s.Store.GetCourse(ctx, courseID); err != nil {
+		return err
+	}
+	err := s.Store.AssignCourse(ctx, instructorID, courseID)
+	s.RecordAudit(ctx, AuditEntry{
+		Action:   AuditInstructorAssign,
+		TargetID: instructorID,
+		Diff:     map[string]FieldChange{"course_id": {To: fmt.Sprint(courseID)}},
+		Outcome:  outcomeOf(err),
+		Message:  messageOf(err),
+	})
+	return err
+}
+
+func (s *Service) UnassignCourse(ctx context.Context, instructorID, courseID int32) error {
+	err := s.Store.UnassignCourse(ctx, instructorID, courseID)
+	s.RecordAudit(ctx, AuditEntry{
+		Action:   AuditInstructorUnassign,
+		TargetID: instructorID,
+		Diff:     map[string]FieldChange{"course_id": {From: fmt.Sprint(courseID)}},
+		Outcome:  outcomeOf(err),
+		Message:  messageOf(err),
+	})
+	return err
+}
+
+// AuthenticateInstructor checks an instructor's email and password. Both
+// an unknown email and a wrong password give ErrInvalidCredentials.
+func (s *Service) AuthenticateInstructor(ctx context.Context, email, password string) (Instructor, error) {
+	instructor, err := s.Store.GetInstructorByEmail(ctx, strings.ToLower(strings.TrimSpace(email)))
+	err = checkPassword(instructor.PasswordHash, password, err, ErrInstructorNotFound)
+	s.RecordAudit(ctx, AuditEntry{
+		ActorID:   instructor.ID,
+		ActorRole: RoleInstructor,
+		Action:    AuditAuthLogin,
+		TargetID:  instructor.ID,
+		Outcome:   outcomeOf(err),
+		Message:   messageOf(err),
+	})
+	if err != nil {
+		return Instructor{}, err
+	}
+	instructor.PasswordHash = ""
+	return instructor, nil
+}
+
+func diffInstructors(before, after Instructor) map[string]FieldChange {
+	diff := map[string]FieldChange{}
+	add := func(field, from, to string) {
+		if from != to {
+			diff[field] = FieldChange{From: from, To: to}
+		}
+	}
+	add("name", before.Name, after.Name)
+	add("email", before.Email, after.Email)
+	if after.PasswordHash != "" && before.PasswordHash != after.PasswordHash {
+		diff["password"] = FieldChange{From: maskSecret(before.PasswordHash), To: maskSecret(after.PasswordHash)}
+	}
+	return diff
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Notification event types.
+const (
+	EventEnrollmentPromoted = "enrollment.promoted"
+)
+
+// Event is something a student should be told about.
+type Event struct {
+	Type         string    `json:"type"`
+	StudentID    int32     `json:"student_id"`
+	CourseID     int32     `json:"course_id"`
+	TermID       int32     `json:"term_id"`
+	EnrollmentID int32     `json:"enrollment_id"`
+	Message      string    `json:"message"`
+	At           time.Time `json:"at"`
+}
+
+// Notifier delivers events to students. Delivery is best effort: a failed
+// notification never fails the operation that raised it.
+type Notifier interface {
+	Notify(context.Context, Event)
+}
+
+// LogNotifier writes events to the log. It is the default until a mail or
+// message queue notifier is configured.
+type LogNotifier struct{}
+
+func (LogNotifier) Notify(_ context.Context, e Event) {
+	log.WithFields(log.Fields{
+		"event":         e.Type,
+		"student_id":    e.StudentID,
+		"course_id":     e.CourseID,
+		"term_id":       e.TermID,
+		"enrollment_id": e.EnrollmentID,
+	}).Info(e.Message)
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 200
+)
+
+// Sort keys accepted when listing students.
+const (
+	SortByID        = "id"
+	SortByName      = "name"
+	SortByCreatedOn = "created_on"
+	SortByUpdatedOn = "updated_on"
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidSort   = errors.New("invalid sort: must be one of id, name, created_on, updated_on, optionally prefixed with -")
+)
+
+// Cursor marks the last row of a page. Rows are always ordered by the sort
+// key and then by ID, so (Value, ID) identifies a unique position.
+type Cursor struct {
+	Sort  string `json:"s"`
+	Desc  bool   `json:"d,omitempty"`
+	Value string `json:"v,omitempty"`
+	ID    int32  `json:"id"`
+}
+
+// ListOptions controls filtering, keyset pagination and ordering of student
+// listings.
+type ListOptions struct {
+	Limit  int
+	Sort   string
+	Desc   bool
+	After  *Cursor
+	Filter Filter
+}
+
+// StudentPage is one page of a student listing.
+type StudentPage struct {
+	Students   []Student `json:"data"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+}
+
+// ParseSort turns "name" or "-updated_on" into a sort key and direction.
+func ParseSort(s string) (string, bool, error) {
+	if s == "" {
+		return SortByID, false, nil
+	}
+	desc := strings.HasPrefix(s, "-")
+	key := strings.TrimPrefix(s, "-")
+	switch key {
+	case SortByID, SortByName, SortByCreatedOn, SortByUpdatedOn:
+		return key, desc, nil
+	}
+	return "", false, ErrInvalidSort
+}
+
+func EncodeCursor(c Cursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func DecodeCursor(s string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// SortValue returns the value of the sort key for a student, as stored in a
+// cursor.
+func SortValue(s Student, sort string) string {
+	switch sort {
+	case SortByName:
+		return s.Name
+	case SortByCreatedOn:
+		return s.CreatedOn
+	case SortByUpdatedOn:
+		return s.UpdatedOn
+	}
+	return ""
+}
@@ -0,0 +1,328 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Prerequisite is a course that has to be completed first, optionally with
+// at least MinGrade on that course's grading scale.
+type Prerequisite struct {
+	CourseID   int32  `json:"course_id" validate:"gte=0"`
+	CourseCode string `json:"course_code,omitempty"`
+	Course     string `json:"course,omitempty"`
+	MinGrade   string `json:"min_grade,omitempty" validate:"omitempty,grade"`
+}
+
+// PrerequisiteGroup is met by completing any one of its courses. A course
+// requires every one of its groups, so a single-course group is a plain
+// requirement and a larger group is an OR.
+type PrerequisiteGroup struct {
+	AnyOf []Prerequisite `json:"any_of" validate:"required,min=1,dive"`
+}
+
+// PrerequisiteOverride lets a student enroll in a course without meeting
+// its prerequisites.
+type PrerequisiteOverride struct {
+	ID         int32  `json:"id"`
+	StudentID  int32  `json:"student_id"`
+	CourseID   int32  `json:"course_id" validate:"gte=0"`
+	CourseCode string `json:"course_code,omitempty"`
+	Course     string `json:"course,omitempty"`
+	Reason     string `json:"reason" validate:"required,max=255"`
+	CreatedBy  string `json:"created_by"`
+	CreatedOn  string `json:"created_on"`
+}
+
+var (
+	ErrPrerequisitesNotMet  = errors.New("prerequisites not met")
+	ErrInvalidPrerequisites = errors.New("invalid prerequisites")
+	ErrOverrideNotFound     = errors.New("prerequisite override not found")
+	ErrOverrideExists       = errors.New("student already has an override for this course")
+)
+
+const (
+	AuditPrerequisitesUpdate = "course.prerequisites"
+	AuditOverrideGrant       = "prerequisite.override.grant"
+	AuditOverrideRevoke      = "prerequisite.override.revoke"
+)
+
+// PrerequisiteError lists the groups a student does not meet. It matches
+// ErrPrerequisitesNotMet with errors.Is.
+type PrerequisiteError struct {
+	Unmet []PrerequisiteGroup
+}
+
+func (e *PrerequisiteError) Error() string {
+	rules := make([]string, len(e.Unmet))
+	for i, g := range e.Unmet {
+		options := make([]string, len(g.AnyOf))
+		for j, p := range g.AnyOf {
+			options[j] = p.CourseCode
+			if p.MinGrade != "" {
+				options[j] += " with at least " + p.MinGrade
+			}
+		}
+		if len(options) == 1 {
+			rules[i] = options[0]
+		} else {
+			rules[i] = "one of " + strings.Join(options, ", ")
+		}
+	}
+	return fmt.Sprintf("%s: requires %s", ErrPrerequisitesNotMet, strings.Join(rules, "; and "))
+}
+
+func (e *PrerequisiteError) Is(target error) bool {
+	return target == ErrPrerequisitesNotMet
+}
+
+type PrerequisiteStore interface {
+	GetPrerequisites(context.Context, int32) ([]PrerequisiteGroup, error)
+	// SetPrerequisites replaces every prerequisite group of a course.
+	SetPrerequisites(context.Context, int32, []PrerequisiteGroup) error
+	GetStudentOverrides(context.Context, int32) ([]PrerequisiteOverride, error)
+	HasOverride(ctx context.Context, studentID, courseID int32) (bool, error)
+	AddOverride(context.Context, PrerequisiteOverride) (PrerequisiteOverride, error)
+	DeleteOverride(context.Context, int32) error
+}
+
+func (s *Service) GetPrerequisites(ctx context.Context, courseID int32) ([]PrerequisiteGroup, error) {
+	if _, err := s.Store.GetCourse(ctx, courseID); err != nil {
+		return nil, err
+	}
+	groups, err := s.Store.GetPrerequisites(ctx, courseID)
+	if err != nil {
+		log.Errorf("Error fetching prerequisites of course %d: %v", courseID, err)
+		return nil, err
+	}
+	return groups, nil
+}
+
+// SetPrerequisites replaces the prerequisites of a course. Required courses
+// are given by course_id or by code or title in course, and a minimum grade
+// must be on the required course's grading scale. A required course may not
+// itself require the course, directly or through its own prerequisites.
+func (s *Service) SetPrerequisites(ctx context.Context, courseID int32, groups []PrerequisiteGroup) ([]PrerequisiteGroup, error) {
+	if _, err := s.Store.GetCourse(ctx, courseID); err != nil {
+		return nil, err
+	}
+	for i := range groups {
+		if len(groups[i].AnyOf) == 0 {
+			return nil, fmt.Errorf("%w: group %d is empty", ErrInvalidPrerequisites, i+1)
+		}
+		for j := range groups[i].AnyOf {
+			p := &groups[i].AnyOf[j]
+			course, err := s.lookupCourse(ctx, p.CourseID, p.Course)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidPrerequisites, err)
+			}
+			if course.ID == courseID {
+				return nil, fmt.Errorf("%w: a course cannot require itself", ErrInvalidPrerequisites)
+			}
+			cycle, err := s.requiresCourse(ctx, course.ID, courseID)
+			if err != nil {
+				return nil, err
+			}
+			if cycle {
+				return nil, fmt.Errorf("%w: %s already requires this course", ErrInvalidPrerequisites, course.Code)
+			}
+			if err := s.validateGrade(ctx, course.ID, p.MinGrade); err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidPrerequisites, err)
+			}
+			p.CourseID, p.CourseCode, p.Course = course.ID, course.Code, ""
+		}
+	}
+
+	before, _ := s.Store.GetPrerequisites(ctx, courseID)
+	err := s.Store.SetPrerequisites(ctx, courseID, groups)
+	s.RecordAudit(ctx, AuditEntry{
+		Action:   AuditPrerequisitesUpdate,
+		TargetID: courseID,
+		Diff:     diffPrerequisites(before, groups),
+		Outcome:  outcomeOf(err),
+		Message:  messageOf(err),
+	})
+	if err != nil {
+		log.Errorf("Failed to set prerequisites of course %d, Error: %v", courseID, err)
+		return nil, err
+	}
+	return s.Store.GetPrerequisites(ctx, courseID)
+}
+
+// requiresCourse reports whether course from requires course target,
+// directly or through the prerequisites of its prerequisites.
+func (s *Service) requiresCourse(ctx context.Context, from, target int32) (bool, error) {
+	seen := map[int32]bool{from: true}
+	pending := []int32{from}
+	for len(pending) > 0 {
+		id := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		groups, err := s.Store.GetPrerequisites(ctx, id)
+		if err != nil {
+			return false, err
+		}
+		for _, g := range groups {
+			for _, p := range g.AnyOf {
+				if p.CourseID == target {
+					return true, nil
+				}
+				if !seen[p.CourseID] {
+					seen[p.CourseID] = true
+					pending = append(pending, p.CourseID)
+				}
+			}
+		}
+	}
+	return false, nil
+}
+
+// checkPrerequisites makes sure a student meets every prerequisite group of
+// a course, or holds an override for it. Only completed enrollments count.
+func (s *Service) checkPrerequisites(ctx context.Context, studentID, courseID int32) error {
+	groups, err := s.Store.GetPrerequisites(ctx, courseID)
+	if err != nil || len(groups) == 0 {
+		return err
+	}
+	overridden, err := s.Store.HasOverride(ctx, studentID, courseID)
+	if err != nil || overridden {
+		return err
+	}
+
+	enrollments, err := s.Store.GetStudentEnrollments(ctx, studentID, 0)
+	if err != nil {
+		return err
+	}
+	completed := map[int32][]string{}
+	for _, e := range enrollments {
+		if e.Status == EnrollmentCompleted {
+			completed[e.CourseID] = append(completed[e.CourseID], e.Grade)
+		}
+	}
+
+	var unmet []PrerequisiteGroup
+	for _, g := range groups {
+		met := false
+		for _, p := range g.AnyOf {
+			for _, grade := range completed[p.CourseID] {
+				ok, err := s.meetsMinGrade(ctx, p.CourseID, grade, p.MinGrade)
+				if err != nil {
+					return err
+				}
+				met = met || ok
+			}
+		}
+		if !met {
+			unmet = append(unmet, g)
+		}
+	}
+	if len(unmet) > 0 {
+		return &PrerequisiteError{Unmet: unmet}
+	}
+	return nil
+}
+
+// meetsMinGrade compares grades by their points on the course's scale.
+func (s *Service) meetsMinGrade(ctx context.Context, courseID int32, grade, minGrade string) (bool, error) {
+	if minGrade == "" {
+		return true, nil
+	}
+	scale, err := s.scaleForCourse(ctx, courseID)
+	if err != nil {
+		return false, err
+	}
+	got, okGot := scale.Points(grade)
+	want, okWant := scale.Points(minGrade)
+	return okGot && okWant && got >= want, nil
+}
+
+func (s *Service) GetStudentOverrides(ctx context.Context, studentID int32) ([]PrerequisiteOverride, error) {
+	if _, err := s.Store.GetStudent(ctx, studentID); err != nil {
+		return nil, ErrStudentNotFound
+	}
+	return s.Store.GetStudentOverrides(ctx, studentID)
+}
+
+// GrantOverride lets a student enroll in a course whatever its
+// prerequisites. The course is given by course_id or by code or title.
+func (s *Service) GrantOverride(ctx context.Context, studentID int32, o PrerequisiteOverride) (PrerequisiteOverride, error) {
+	if _, err := s.Store.GetStudent(ctx, studentID); err != nil {
+		return PrerequisiteOverride{}, ErrStudentNotFound
+	}
+	course, err := s.lookupCourse(ctx, o.CourseID, o.Course)
+	if err != nil {
+		return PrerequisiteOverride{}, err
+	}
+	if strings.TrimSpace(o.Reason) == "" {
+		return PrerequisiteOverride{}, fmt.Errorf("%w: a reason is required for an override", ErrInvalidPrerequisites)
+	}
+	o.StudentID, o.CourseID, o.Course = studentID, course.ID, ""
+
+	created, err := s.Store.AddOverride(ctx, o)
+	s.RecordAudit(ctx, AuditEntry{
+		Action:   AuditOverrideGrant,
+		TargetID: studentID,
+		Diff: map[string]FieldChange{
+			"course_id": {To: fmt.Sprint(course.ID)},
+			"reason":    {To: o.Reason},
+		},
+		Outcome: outcomeOf(err),
+		Message: messageOf(err),
+	})
+	if err != nil {
+		log.Errorf("Failed to grant override to student %d for course %d, Error: %v", studentID, course.ID, err)
+		return PrerequisiteOverride{}, err
+	}
+	return created, nil
+}
+
+func (s *Service) RevokeOverride(ctx context.Context, studentID, overrideID int32) error {
+	overrides, err := s.Store.GetStudentOverrides(ctx, studentID)
+	if err != nil {
+		return err
+	}
+	var before *PrerequisiteOverride
+	for i := range overrides {
+		if overrides[i].ID == overrideID {
+			before = &overrides[i]
+		}
+	}
+	if before == nil {
+		return ErrOverrideNotFound
+	}
+
+	err = s.Store.DeleteOverride(ctx, overrideID)
+	s.RecordAudit(ctx, AuditEntry{
+		Action:   AuditOverrideRevoke,
+		TargetID: studentID,
+		Diff:     map[string]FieldChange{"course_id": {From: fmt.Sprint(before.CourseID)}},
+		Outcome:  outcomeOf(err),
+		Message:  messageOf(err),
+	})
+	return err
+}
+
+func diffPrerequisites(before, after []PrerequisiteGroup) map[string]FieldChange {
+	format := func(groups []PrerequisiteGroup) string {
+		parts := make([]string, len(groups))
+		for i, g := range groups {
+			options := make([]string, len(g.AnyOf))
+			for j, p := range g.AnyOf {
+				options[j] = fmt.Sprint(p.CourseID)
+				if p.MinGrade != "" {
+					options[j] += ">=" + p.MinGrade
+				}
+			}
+			parts[i] = "(" + strings.Join(options, " or ") + ")"
+		}
+		return strings.Join(parts, " and ")
+	}
+	diff := map[string]FieldChange{}
+	if from, to := format(before), format(after); from != to {
+		diff["prerequisites"] = FieldChange{From: from, To: to}
+	}
+	return diff
+}
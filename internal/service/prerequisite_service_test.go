@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// prerequisiteFixture has CS101 and CS102 on the standard scale, MA101 on a
+// pass/fail scale, and CS201, the course the tests enroll student 7 in.
+func prerequisiteFixture() *fakeStore {
+	store := newFakeStore()
+	store.scales[9] = GradingScale{ID: 9, Name: "Pass/fail", Grades: []GradePoint{{"P", 1}, {"F", 0}}}
+	for _, c := range []Course{
+		{ID: 1, Code: "CS101"},
+		{ID: 2, Code: "CS102"},
+		{ID: 3, Code: "MA101", GradingScaleID: 9},
+		{ID: 4, Code: "CS201"},
+	} {
+		store.courses[c.ID] = c
+	}
+	return store
+}
+
+func TestCheckPrerequisites(t *testing.T) {
+	completed := func(courseID int32, grade string) Enrollment {
+		return Enrollment{StudentID: 7, CourseID: courseID, Grade: grade, Status: EnrollmentCompleted}
+	}
+	one := func(courseID int32, minGrade string) PrerequisiteGroup {
+		return PrerequisiteGroup{AnyOf: []Prerequisite{{CourseID: courseID, MinGrade: minGrade}}}
+	}
+	either := PrerequisiteGroup{AnyOf: []Prerequisite{{CourseID: 1, MinGrade: "B"}, {CourseID: 2}}}
+
+	tests := []struct {
+		name        string
+		groups      []PrerequisiteGroup
+		enrollments []Enrollment
+		override    bool
+		unmet       int
+	}{
+		{name: "no prerequisites"},
+		{name: "completed", groups: []PrerequisiteGroup{one(1, "")}, enrollments: []Enrollment{completed(1, "C")}},
+		{name: "not taken", groups: []PrerequisiteGroup{one(1, "")}, unmet: 1},
+		{
+			name:        "still enrolled",
+			groups:      []PrerequisiteGroup{one(1, "")},
+			enrollments: []Enrollment{{StudentID: 7, CourseID: 1, Status: EnrollmentEnrolled}},
+			unmet:       1,
+		},
+		{
+			name:        "failed",
+			groups:      []PrerequisiteGroup{one(1, "")},
+			enrollments: []Enrollment{{StudentID: 7, CourseID: 1, Grade: "F", Status: EnrollmentFailed}},
+			unmet:       1,
+		},
+		{name: "grade at threshold", groups: []PrerequisiteGroup{one(1, "B")}, enrollments: []Enrollment{completed(1, "B")}},
+		{name: "grade above threshold", groups: []PrerequisiteGroup{one(1, "B")}, enrollments: []Enrollment{completed(1, "A-")}},
+		{name: "grade below threshold", groups: []PrerequisiteGroup{one(1, "B")}, enrollments: []Enrollment{completed(1, "B-")}, unmet: 1},
+		{name: "grade off the scale", groups: []PrerequisiteGroup{one(1, "B")}, enrollments: []Enrollment{completed(1, "P")}, unmet: 1},
+		{name: "retake meets threshold", groups: []PrerequisiteGroup{one(1, "B")}, enrollments: []Enrollment{completed(1, "D"), completed(1, "A")}},
+		{name: "course scale", groups: []PrerequisiteGroup{one(3, "P")}, enrollments: []Enrollment{completed(3, "P")}},
+		{name: "course scale below threshold", groups: []PrerequisiteGroup{one(3, "P")}, enrollments: []Enrollment{completed(3, "F")}, unmet: 1},
+		{name: "or group first option", groups: []PrerequisiteGroup{either}, enrollments: []Enrollment{completed(1, "A")}},
+		{name: "or group second option", groups: []PrerequisiteGroup{either}, enrollments: []Enrollment{completed(2, "D")}},
+		{name: "or group neither", groups: []PrerequisiteGroup{either}, enrollments: []Enrollment{completed(1, "C")}, unmet: 1},
+		{
+			name:        "every group required",
+			groups:      []PrerequisiteGroup{either, one(3, "")},
+			enrollments: []Enrollment{completed(2, "B")},
+			unmet:       1,
+		},
+		{name: "override", groups: []PrerequisiteGroup{either, one(3, "")}, override: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := prerequisiteFixture()
+			store.prerequisites[4] = tt.groups
+			store.enrollments = tt.enrollments
+			store.overrides[[2]int32{7, 4}] = tt.override
+			s := NewService(store)
+
+			err := s.checkPrerequisites(context.Background(), 7, 4)
+			if tt.unmet == 0 {
+				if err != nil {
+					t.Fatalf("got %v, want the prerequisites met", err)
+				}
+				return
+			}
+			var perr *PrerequisiteError
+			if !errors.As(err, &perr) || !errors.Is(err, ErrPrerequisitesNotMet) {
+				t.Fatalf("got %v, want a PrerequisiteError", err)
+			}
+			if len(perr.Unmet) != tt.unmet {
+				t.Errorf("got %d unmet groups, want %d", len(perr.Unmet), tt.unmet)
+			}
+		})
+	}
+}
+
+func TestSetPrerequisitesRejectsCycles(t *testing.T) {
+	ctx := context.Background()
+	requires := func(ids ...int32) []PrerequisiteGroup {
+		groups := make([]PrerequisiteGroup, len(ids))
+		for i, id := range ids {
+			groups[i] = PrerequisiteGroup{AnyOf: []Prerequisite{{CourseID: id}}}
+		}
+		return groups
+	}
+
+	store := prerequisiteFixture()
+	s := NewService(store)
+	// CS201 requires CS102, which requires CS101.
+	if _, err := s.SetPrerequisites(ctx, 2, requires(1)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.SetPrerequisites(ctx, 4, requires(2)); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		course int32
+		groups []PrerequisiteGroup
+	}{
+		{1, requires(1)}, // itself
+		{2, requires(4)}, // directly
+		{1, requires(4)}, // through CS102
+		{1, []PrerequisiteGroup{{AnyOf: []Prerequisite{{CourseID: 3}, {CourseID: 4}}}}}, // in an OR group
+	} {
+		if _, err := s.SetPrerequisites(ctx, tt.course, tt.groups); !errors.Is(err, ErrInvalidPrerequisites) {
+			t.Errorf("course %d requiring %+v: got %v, want ErrInvalidPrerequisites", tt.course, tt.groups, err)
+		}
+	}
+	if got := store.prerequisites[1]; len(got) != 0 {
+		t.Errorf("a rejected update was stored: %+v", got)
+	}
+
+	// A shared prerequisite is not a cycle.
+	if _, err := s.SetPrerequisites(ctx, 3, requires(1)); err != nil {
+		t.Errorf("MA101 requiring CS101: %v", err)
+	}
+}
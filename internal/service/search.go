@@ -0,0 +1,435 @@
+package service
+
+import (
+	"context"
+	"html"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+)
+
+// Field weights: a hit on the name ranks above the same hit on the course.
+var searchFields = []struct {
+	name   string
+	weight float64
+	value  func(Student) string
+}{
+	{"name", 2.0, func(s Student) string { return s.Name }},
+	{"course", 1.0, func(s Student) string { return s.Course }},
+}
+
+// Match quality, multiplied by the field weight.
+const (
+	scoreExact  = 1.0
+	scorePrefix = 0.8
+	scoreFuzzy1 = 0.6
+	scoreFuzzy2 = 0.4
+)
+
+// SearchResult is a ranked hit. Highlights holds each matched field with the
+// matching words wrapped in <em></em>.
+type SearchResult struct {
+	Student    Student           `json:"student"`
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights"`
+}
+
+// SearchIndex is an in-process inverted index over student names and
+// courses. The service keeps it in sync with every write; it is rebuilt from
+// the store on startup.
+//
+// Query words find their terms without scanning the vocabulary: exact
+// matches through postings, prefixes through the sorted terms, and fuzzy
+// candidates through the bigrams they share with the word.
+type SearchIndex struct {
+	mu       sync.RWMutex
+	docs     map[int32]Student
+	postings map[string]map[int32]map[string]bool // term -> student -> fields
+	terms    []string                             // sorted keys of postings
+	grams    map[string]map[string]bool           // bigram -> terms
+}
+
+func NewSearchIndex() *SearchIndex {
+	return &SearchIndex{
+		docs:     map[int32]Student{},
+		postings: map[string]map[int32]map[string]bool{},
+		grams:    map[string]map[string]bool{},
+	}
+}
+
+// bigrams returns the pairs of adjacent runes of a term, padded with ^ and
+// $ so that the first and last letters count as well. Terms never contain
+// either mark.
+func bigrams(term string) []string {
+	runes := append(append([]rune{'^'}, []rune(term)...), '$')
+	grams := make([]string, 0, len(runes)-1)
+	for i := 1; i < len(runes); i++ {
+		grams = append(grams, string(runes[i-1:i+1]))
+	}
+	return grams
+}
+
+// addTerm records a term that just entered postings.
+func (idx *SearchIndex) addTerm(term string) {
+	i := sort.SearchStrings(idx.terms, term)
+	idx.terms = append(idx.terms, "")
+	copy(idx.terms[i+1:], idx.terms[i:])
+	idx.terms[i] = term
+	for _, g := range bigrams(term) {
+		if idx.grams[g] == nil {
+			idx.grams[g] = map[string]bool{}
+		}
+		idx.grams[g][term] = true
+	}
+}
+
+// dropTerm forgets a term that just left postings.
+func (idx *SearchIndex) dropTerm(term string) {
+	if i := sort.SearchStrings(idx.terms, term); i < len(idx.terms) && idx.terms[i] == term {
+		idx.terms = append(idx.terms[:i], idx.terms[i+1:]...)
+	}
+	for _, g := range bigrams(term) {
+		delete(idx.grams[g], term)
+		if len(idx.grams[g]) == 0 {
+			delete(idx.grams, g)
+		}
+	}
+}
+
+type token struct {
+	term       string
+	start, end int
+}
+
+// tokenize splits text into lower-cased words, keeping byte offsets into the
+// original text for highlighting.
+func tokenize(text string) []token {
+	var tokens []token
+	start := -1
+	for i, r := range text {
+		isWord := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if isWord && start < 0 {
+			start = i
+		}
+		if !isWord && start >= 0 {
+			tokens = append(tokens, token{strings.ToLower(text[start:i]), start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, token{strings.ToLower(text[start:]), start, len(text)})
+	}
+	return tokens
+}
+
+// Put adds or replaces a student in the index.
+func (idx *SearchIndex) Put(s Student) {
+	s.Password = ""
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.remove(s.ID)
+	idx.docs[s.ID] = s
+	for _, f := range searchFields {
+		for _, tok := range tokenize(f.value(s)) {
+			docs, ok := idx.postings[tok.term]
+			if !ok {
+				docs = map[int32]map[string]bool{}
+				idx.postings[tok.term] = docs
+				idx.addTerm(tok.term)
+			}
+			if docs[s.ID] == nil {
+				docs[s.ID] = map[string]bool{}
+			}
+			docs[s.ID][f.name] = true
+		}
+	}
+}
+
+// Remove drops a student from the index.
+func (idx *SearchIndex) Remove(id int32) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.remove(id)
+}
+
+func (idx *SearchIndex) remove(id int32) {
+	old, ok := idx.docs[id]
+	if !ok {
+		return
+	}
+	delete(idx.docs, id)
+	for _, f := range searchFields {
+		for _, tok := range tokenize(f.value(old)) {
+			if docs, ok := idx.postings[tok.term]; ok {
+				delete(docs, id)
+				if len(docs) == 0 {
+					delete(idx.postings, tok.term)
+					idx.dropTerm(tok.term)
+				}
+			}
+		}
+	}
+}
+
+// matchScore rates how well an indexed term matches a query word, or returns
+// 0 when it does not match at all. Fuzzy matching allows one edit for words
+// of four or more characters and two edits from eight characters.
+func matchScore(query, term string) float64 {
+	if query == term {
+		return scoreExact
+	}
+	if utf8.RuneCountInString(query) >= 2 && strings.HasPrefix(term, query) {
+		return scorePrefix
+	}
+	maxEdits := fuzzyEdits(query)
+	if maxEdits == 0 {
+		return 0
+	}
+	d := editDistance(query, term, maxEdits)
+	switch {
+	case d > maxEdits:
+		return 0
+	case d == 1:
+		return scoreFuzzy1
+	}
+	return scoreFuzzy2
+}
+
+// fuzzyEdits is how many edits a term may be away from a query word.
+func fuzzyEdits(query string) int {
+	switch n := utf8.RuneCountInString(query); {
+	case n >= 8:
+		return 2
+	case n >= 4:
+		return 1
+	}
+	return 0
+}
+
+// candidates returns the terms that can match a query word: the word
+// itself, the terms it prefixes and, for fuzzy matching, the terms that
+// share enough bigrams with it. One edit changes at most three padded
+// bigrams (a transposition), so a term within k edits of a word of n runes
+// shares at least n+1-3k of its bigrams.
+func (idx *SearchIndex) candidates(query string) map[string]bool {
+	found := map[string]bool{}
+	if _, ok := idx.postings[query]; ok {
+		found[query] = true
+	}
+	if utf8.RuneCountInString(query) >= 2 {
+		for i := sort.SearchStrings(idx.terms, query); i < len(idx.terms) && strings.HasPrefix(idx.terms[i], query); i++ {
+			found[idx.terms[i]] = true
+		}
+	}
+
+	maxEdits := fuzzyEdits(query)
+	if maxEdits == 0 {
+		return found
+	}
+	n := utf8.RuneCountInString(query)
+	need := n + 1 - 3*maxEdits
+	if need < 1 {
+		need = 1
+	}
+	shared := map[string]int{}
+	for _, g := range bigrams(query) {
+		for term := range idx.grams[g] {
+			shared[term]++
+		}
+	}
+	for term, count := range shared {
+		if d := utf8.RuneCountInString(term) - n; count >= need && d <= maxEdits && -d <= maxEdits {
+			found[term] = true
+		}
+	}
+	return found
+}
+
+// editDistance returns the optimal string alignment distance between a and
+// b, where swapping two adjacent letters counts as one edit, or max+1 as soon
+// as the distance is known to exceed max.
+func editDistance(a, b string, max int) int {
+	ra, rb := []rune(a), []rune(b)
+	if d := len(ra) - len(rb); d > max || -d > max {
+		return max + 1
+	}
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				curr[j] = minInt(curr[j], prev2[j-2]+1)
+			}
+			if curr[j] < rowMin {
+				rowMin = curr[j]
+			}
+		}
+		if rowMin > max {
+			return max + 1
+		}
+		prev2, prev, curr = prev, curr, prev2
+	}
+	return prev[len(rb)]
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
+
+// Search ranks students against the words of q. For every query word the
+// best matching term in each field counts once, weighted by the field.
+func (idx *SearchIndex) Search(q string, limit int) []SearchResult {
+	words := tokenize(q)
+	if len(words) == 0 {
+		return []SearchResult{}
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	scores := map[int32]float64{}
+	// matched[student][field] is the set of indexed terms that matched.
+	matched := map[int32]map[string]map[string]bool{}
+
+	for _, w := range words {
+		best := map[int32]map[string]float64{}
+		for term := range idx.candidates(w.term) {
+			score := matchScore(w.term, term)
+			if score == 0 {
+				continue
+			}
+			for id, fields := range idx.postings[term] {
+				if best[id] == nil {
+					best[id] = map[string]float64{}
+				}
+				if matched[id] == nil {
+					matched[id] = map[string]map[string]bool{}
+				}
+				for field := range fields {
+					if score > best[id][field] {
+						best[id][field] = score
+					}
+					if matched[id][field] == nil {
+						matched[id][field] = map[string]bool{}
+					}
+					matched[id][field][term] = true
+				}
+			}
+		}
+		for id, fields := range best {
+			for _, f := range searchFields {
+				scores[id] += fields[f.name] * f.weight
+			}
+		}
+	}
+
+	results := make([]SearchResult, 0, len(scores))
+	for id, score := range scores {
+		doc := idx.docs[id]
+		highlights := map[string]string{}
+		for _, f := range searchFields {
+			if terms := matched[id][f.name]; len(terms) > 0 {
+				highlights[f.name] = highlight(f.value(doc), terms)
+			}
+		}
+		results = append(results, SearchResult{Student: doc, Score: score, Highlights: highlights})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Student.ID < results[j].Student.ID
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// highlight wraps the matched words of text in <em></em>. The rest of the
+// text is HTML-escaped so the result is safe to render.
+func highlight(text string, terms map[string]bool) string {
+	var sb strings.Builder
+	last := 0
+	for _, tok := range tokenize(text) {
+		if !terms[tok.term] {
+			continue
+		}
+		sb.WriteString(html.EscapeString(text[last:tok.start]))
+		sb.WriteString("<em>")
+		sb.WriteString(html.EscapeString(text[tok.start:tok.end]))
+		sb.WriteString("</em>")
+		last = tok.end
+	}
+	sb.WriteString(html.EscapeString(text[last:]))
+	return sb.String()
+}
+
+// BuildSearchIndex loads every student from the store into the index.
+func (s *Service) BuildSearchIndex(ctx context.Context) error {
+	log.Info("Building student search index")
+
+	count := 0
+	err := s.StreamStudents(ctx, ListOptions{Sort: SortByID}, func(student Student) error {
+		s.Search.Put(student)
+		count++
+		return nil
+	})
+	if err != nil {
+		log.Errorf("Failed to build search index: %v", err)
+		return err
+	}
+
+	log.Infof("Indexed %d students for search", count)
+	return nil
+}
+
+// reindexStudent refreshes one student in the search index from the store,
+// so the indexed copy carries the stored timestamps.
+func (s *Service) reindexStudent(ctx context.Context, userID int32) {
+	student, err := s.Store.GetStudent(ctx, userID)
+	if err != nil {
+		log.Warnf("Could not reindex student with user ID: %d, Error: %v", userID, err)
+		return
+	}
+	s.Search.Put(student)
+}
+
+func (s *Service) SearchStudents(ctx context.Context, q string, limit int) []SearchResult {
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+	if limit > MaxSearchLimit {
+		limit = MaxSearchLimit
+	}
+	return s.Search.Search(q, limit)
+}
@@ -0,0 +1,21 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+var (
+	ErrFileNotFound       = errors.New("file not found")
+	ErrStorageUnavailable = errors.New("file storage is not configured")
+)
+
+// FileStorage keeps uploaded files by key. Keys are slash separated paths
+// chosen by the service, e.g. "submissions/3/11/report.pdf".
+type FileStorage interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get returns ErrFileNotFound for an unknown key.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
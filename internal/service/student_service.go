@@ -4,7 +4,7 @@ import (
 	"context"
 	//"database/sql"
 	"errors"
-	//"fmt"
+	"fmt"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -12,10 +12,11 @@ import (
 // Define the Student struct
 type Student struct {
 	ID        int32  `json:"id"`
-	Password  string `json:"password"`
-	Name      string `json:"name"`
-	Course    string `json:"course"`
-	Grade     string `json:"grade"`
+	Password  string `json:"password" validate:"omitempty,max=100"`
+	Name      string `json:"name" validate:"required,max=100"`
+	Course    string `json:"course" validate:"omitempty,max=100"`
+	CourseID  int32  `json:"course_id"`
+	Grade     string `json:"grade" validate:"omitempty,grade"`
 	CreatedBy string `json:"created_by"`
 	CreatedOn string `json:"created_on"`
 	UpdatedBy string `json:"updated_by"`
@@ -26,13 +27,37 @@ var (
 	ErrFetchingStudent = errors.New("no student with that ID found")
 	ErrUpdatingStudent = errors.New("update unsuccessful")
 	ErrDeletingStudent = errors.New("could not delete student")
-	ErrStudentNotFound = errors.New("not found")
+	ErrStudentNotFound = errors.New("student not found")
+	ErrForbidden       = errors.New("not allowed to perform this action")
+	// ErrInvalidPatch is a partial update that cannot be applied.
+	ErrInvalidPatch = errors.New("invalid patch")
+	// ErrPreconditionFailed is a conditional write whose condition no longer
+	// holds, such as an If-Match with an outdated ETag.
+	ErrPreconditionFailed = errors.New("the resource has changed since it was read")
 )
 
+// Precondition is the condition of a conditional write to a student, such
+// as an If-Match. The transport puts it in the context under
+// "precondition"; the store checks it against the stored student in the
+// transaction of the write, with the row locked, so that no other write
+// can come in between.
+type Precondition func(current Student) error
+
 type StudentStore interface {
-	GetAllStudents(context.Context) ([]Student, error)
+	GetAllStudents(context.Context, ListOptions) ([]Student, error)
+	StreamStudents(context.Context, ListOptions, func(Student) error) error
 	GetStudent(context.Context, int32) (Student, error)
 	AddStudent(context.Context, Student) (Student, error)
+	// AddStudents inserts students with multi-row inserts and returns them
+	// as stored, in order.
+	AddStudents(context.Context, []Student) ([]Student, error)
+	// ApplyStudentBatch runs the operations of a batch in one transaction,
+	// the creates first and together, and returns the created and updated
+	// students by operation. If one fails nothing is kept and the error is
+	// a *StudentOpError.
+	ApplyStudentBatch(context.Context, []StudentOp) ([]Student, error)
+	// UpdateStudent and DeleteStudent check the Precondition in the
+	// context, if there is one, before they write.
 	UpdateStudent(context.Context, int32, Student) (Student, error)
 	DeleteStudent(context.Context, int32) error
 	Ping(context.Context) error
@@ -40,23 +65,106 @@ type StudentStore interface {
 	GetLastInsertedStudentID() (int32, error)
 }
 
+// Store is everything the service layer needs from persistence.
+type Store interface {
+	StudentStore
+	AuditStore
+	CourseStore
+	EnrollmentStore
+	GradingScaleStore
+	TermStore
+	TranscriptStore
+	PrerequisiteStore
+	InstructorStore
+	AttendanceStore
+	AssignmentStore
+	ContactStore
+}
+
 type Service struct {
-	Store StudentStore
+	Store    Store
+	Search   *SearchIndex
+	Notifier Notifier
+	// Files keeps uploaded coursework. Uploads fail with
+	// ErrStorageUnavailable while it is nil.
+	Files FileStorage
+	// AttendanceThreshold is the attendance percentage below which students
+	// are listed in attendance reports.
+	AttendanceThreshold float64
+	// TranscriptKey signs the digests of issued transcripts. Transcripts
+	// cannot be issued or verified while it is empty.
+	TranscriptKey []byte
 }
 
-func NewService(store StudentStore) *Service {
+func NewService(store Store) *Service {
 	return &Service{
-		Store: store,
+		Store:    store,
+		Search:   NewSearchIndex(),
+		Notifier: LogNotifier{},
+
+		AttendanceThreshold: DefaultAttendanceThreshold,
 	}
 }
 
-func (s *Service) GetAllStudents(ctx context.Context) ([]Student, error) {
-	students, err := s.Store.GetAllStudents(ctx)
+// GetAllStudents returns one page of students. The store is asked for one
+// row more than the limit to find out whether another page follows.
+func (s *Service) GetAllStudents(ctx context.Context, opts ListOptions) (StudentPage, error) {
+	if opts.Limit <= 0 {
+		opts.Limit = DefaultPageLimit
+	}
+	if opts.Limit > MaxPageLimit {
+		opts.Limit = MaxPageLimit
+	}
+	if opts.Sort == "" {
+		opts.Sort = SortByID
+	}
+	if opts.After != nil && (opts.After.Sort != opts.Sort || opts.After.Desc != opts.Desc) {
+		return StudentPage{}, ErrInvalidCursor
+	}
+
+	limit := opts.Limit
+	opts.Limit++
+	students, err := s.Store.GetAllStudents(ctx, opts)
 	if err != nil {
+		if errors.Is(err, ErrInvalidCursor) || errors.Is(err, ErrInvalidSort) {
+			return StudentPage{}, err
+		}
 		log.Errorf("Error fetching all students: %s", err.Error())
-		return nil, ErrFetchingStudent
+		return StudentPage{}, ErrFetchingStudent
+	}
+
+	page := StudentPage{Students: students}
+	if len(students) > limit {
+		page.Students = students[:limit]
+		last := page.Students[limit-1]
+		page.NextCursor = EncodeCursor(Cursor{
+			Sort:  opts.Sort,
+			Desc:  opts.Desc,
+			Value: SortValue(last, opts.Sort),
+			ID:    last.ID,
+		})
+	}
+	if page.Students == nil {
+		page.Students = []Student{}
 	}
-	return students, nil
+	return page, nil
+}
+
+// StreamStudents calls fn for every student matching opts, in order, without
+// holding the listing in memory. opts.Limit and opts.After are honoured but
+// optional; a zero limit streams every match.
+func (s *Service) StreamStudents(ctx context.Context, opts ListOptions, fn func(Student) error) error {
+	if opts.Sort == "" {
+		opts.Sort = SortByID
+	}
+	if opts.After != nil && (opts.After.Sort != opts.Sort || opts.After.Desc != opts.Desc) {
+		return ErrInvalidCursor
+	}
+	if err := s.Store.StreamStudents(ctx, opts, fn); err != nil {
+		log.Errorf("Error streaming students: %v", err)
+		return err
+	}
+	return nil
 }
 
 func (s *Service) GetStudent(ctx context.Context, userID int32) (Student, error) {
@@ -80,35 +188,125 @@ func (s *Service) GetLastInsertedStudentID() (int32, error) {
 
 func (s *Service) AddStudent(ctx context.Context, student Student) (Student, error) {
 	log.Error("User type from context in service layer", ctx.Value("userType"))
+	if err := denyInstructor(ctx); err != nil {
+		return Student{}, err
+	}
+	if err := s.resolveCourse(ctx, &student); err != nil {
+		log.Warnf("Rejected student with course %q: %v", student.Course, err)
+		return Student{}, err
+	}
+	if err := s.validateGrade(ctx, student.CourseID, student.Grade); err != nil {
+		return Student{}, err
+	}
 	student, err := s.Store.AddStudent(ctx, student)
+	entry := AuditEntry{
+		Action:   AuditStudentCreate,
+		TargetID: student.ID,
+		Diff:     diffStudents(Student{}, student),
+		Outcome:  outcomeOf(err),
+		Message:  messageOf(err),
+	}
+	if _, ok := ctx.Value("userID").(int32); !ok {
+		// Self-registration: the new student is the actor.
+		entry.ActorID = student.ID
+	}
+	s.RecordAudit(ctx, entry)
 	if err != nil {
 		log.Errorf("Failed to insert student with user ID: %d, Error: %v", student.ID, err)
 		return Student{}, err
 	}
+	s.reindexStudent(ctx, student.ID)
 	log.Infof("Successfully added new student with user ID: %d", student.ID)
 	return student, nil
 }
 
 func (s *Service) UpdateStudent(ctx context.Context, userID int32, student Student) (Student, error) {
+	if err := denyInstructor(ctx); err != nil {
+		return Student{}, err
+	}
+	if err := s.resolveCourse(ctx, &student); err != nil {
+		log.Warnf("Rejected update of student %d with course %q: %v", userID, student.Course, err)
+		return Student{}, err
+	}
+	if err := s.validateGrade(ctx, student.CourseID, student.Grade); err != nil {
+		return Student{}, err
+	}
+	before, _ := s.Store.GetStudent(ctx, userID)
 	student, err := s.Store.UpdateStudent(ctx, userID, student)
+	s.RecordAudit(ctx, AuditEntry{
+		Action:   AuditStudentUpdate,
+		TargetID: userID,
+		Diff:     diffStudents(before, student),
+		Outcome:  outcomeOf(err),
+		Message:  messageOf(err),
+	})
 	if err != nil {
 		log.Errorf("Failed to update student with user ID: %d, Error: %v", userID, err)
 		return Student{}, err
 	}
+	s.reindexStudent(ctx, userID)
 	log.Infof("Successfully updated student with user ID: %d", userID)
 	return student, nil
 }
 
 func (s *Service) DeleteStudent(ctx context.Context, userID int32) error {
+	if err := denyInstructor(ctx); err != nil {
+		return err
+	}
+	before, _ := s.Store.GetStudent(ctx, userID)
+	courses := s.seatCourses(ctx, userID)
+	files := s.submissionFiles(ctx, userID)
 	err := s.Store.DeleteStudent(ctx, userID)
+	entry := AuditEntry{
+		Action:   AuditStudentDelete,
+		TargetID: userID,
+		Outcome:  outcomeOf(err),
+		Message:  messageOf(err),
+	}
+	if err == nil {
+		entry.Diff = diffStudents(before, Student{})
+	}
+	s.RecordAudit(ctx, entry)
 	if err != nil {
 		log.Errorf("Failed to delete student with user ID: %d, Error: %v", userID, err)
 		return err
 	}
+	s.Search.Remove(userID)
+	for _, courseID := range courses {
+		s.promoteWaitlist(ctx, courseID)
+	}
+	for _, key := range files {
+		s.removeFile(ctx, key)
+	}
 	log.Infof("Successfully deleted student with user ID: %d", userID)
 	return nil
 }
 
+// PatchStudent updates only the fields a partial update touches. patch gets
+// the stored student and returns it with the changes applied; everything it
+// leaves alone keeps its stored value. Patching the course text of a student
+// re-resolves the course rather than keeping the old course ID.
+func (s *Service) PatchStudent(ctx context.Context, userID int32, patch func(Student) (Student, error)) (Student, error) {
+	if err := denyInstructor(ctx); err != nil {
+		return Student{}, err
+	}
+	current, err := s.Store.GetStudent(ctx, userID)
+	if err != nil {
+		return Student{}, err
+	}
+	patched, err := patch(current)
+	if err != nil {
+		return Student{}, err
+	}
+	if patched.ID != current.ID {
+		return Student{}, fmt.Errorf("%w: id cannot be changed", ErrInvalidPatch)
+	}
+	if patched.Course != current.Course && patched.CourseID == current.CourseID {
+		patched.CourseID = 0
+	}
+	return s.UpdateStudent(ctx, userID, patched)
+}
+
 func (s *Service) ReadyCheck(ctx context.Context) error {
 	log.Info("Performing readiness check")
 	return s.Store.Ping(ctx)
@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DateLayout is the format of term start and end dates.
+const DateLayout = "2006-01-02"
+
+// Term is an academic term such as a semester. The current term is the one
+// whose dates contain today.
+type Term struct {
+	ID        int32  `json:"id"`
+	Code      string `json:"code" validate:"required,max=20"`
+	Name      string `json:"name" validate:"required,max=100"`
+	StartDate string `json:"start_date" validate:"required,datetime=2006-01-02"`
+	EndDate   string `json:"end_date" validate:"required,datetime=2006-01-02"`
+	IsCurrent bool   `json:"is_current"`
+	CreatedBy string `json:"created_by"`
+	CreatedOn string `json:"created_on"`
+	UpdatedBy string `json:"updated_by"`
+	UpdatedOn string `json:"updated_on"`
+}
+
+var (
+	ErrTermNotFound  = errors.New("term not found")
+	ErrNoCurrentTerm = errors.New("no term is in progress today")
+	ErrTermCodeTaken = errors.New("a term with that code already exists")
+	ErrTermInUse     = errors.New("term is still referenced by enrollments")
+	ErrInvalidTerm   = errors.New("invalid term")
+)
+
+const (
+	AuditTermCreate = "term.create"
+	AuditTermUpdate = "term.update"
+	AuditTermDelete = "term.delete"
+)
+
+// CurrentTermRef selects the current term wherever a term can be named.
+const CurrentTermRef = "current"
+
+type TermStore interface {
+	GetAllTerms(context.Context) ([]Term, error)
+	GetTerm(context.Context, int32) (Term, error)
+	GetTermByCode(context.Context, string) (Term, error)
+	// GetTermOn returns the term in progress on the given day, preferring
+	// the latest start when terms overlap.
+	GetTermOn(context.Context, time.Time) (Term, error)
+	AddTerm(context.Context, Term) (Term, error)
+	UpdateTerm(context.Context, int32, Term) (Term, error)
+	DeleteTerm(context.Context, int32) error
+}
+
+func validateTerm(t Term) error {
+	if strings.TrimSpace(t.Code) == "" {
+		return fmt.Errorf("%w: code is required", ErrInvalidTerm)
+	}
+	if strings.EqualFold(t.Code, CurrentTermRef) {
+		return fmt.Errorf("%w: %q is reserved", ErrInvalidTerm, CurrentTermRef)
+	}
+	if strings.TrimSpace(t.Name) == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidTerm)
+	}
+	start, err := time.Parse(DateLayout, t.StartDate)
+	if err != nil {
+		return fmt.Errorf("%w: start_date must be YYYY-MM-DD", ErrInvalidTerm)
+	}
+	end, err := time.Parse(DateLayout, t.EndDate)
+	if err != nil {
+		return fmt.Errorf("%w: end_date must be YYYY-MM-DD", ErrInvalidTerm)
+	}
+	if end.Before(start) {
+		return fmt.Errorf("%w: end_date is before start_date", ErrInvalidTerm)
+	}
+	return nil
+}
+
+func today() time.Time {
+	return time.Now().UTC().Truncate(24 * time.Hour)
+}
+
+// markCurrent sets IsCurrent on a term fetched from the store.
+func markCurrent(t Term) Term {
+	start, errStart := time.Parse(DateLayout, t.StartDate)
+	end, errEnd := time.Parse(DateLayout, t.EndDate)
+	now := today()
+	t.IsCurrent = errStart == nil && errEnd == nil && !now.Before(start) && !now.After(end)
+	return t
+}
+
+// ResolveTerm finds a term by ID, or by a reference that is "current", a
+// term code or a numeric ID.
+func (s *Service) ResolveTerm(ctx context.Context, termID int32, ref string) (Term, error) {
+	ref = strings.TrimSpace(ref)
+	var (
+		term Term
+		err  error
+	)
+	switch {
+	case termID != 0:
+		term, err = s.Store.GetTerm(ctx, termID)
+	case strings.EqualFold(ref, CurrentTermRef):
+		term, err = s.Store.GetTermOn(ctx, today())
+		if errors.Is(err, ErrTermNotFound) {
+			err = ErrNoCurrentTerm
+		}
+	case ref != "":
+		if id, convErr := strconv.ParseInt(ref, 10, 32); convErr == nil {
+			term, err = s.Store.GetTerm(ctx, int32(id))
+		} else {
+			term, err = s.Store.GetTermByCode(ctx, ref)
+		}
+	default:
+		return Term{}, fmt.Errorf("%w: no term given", ErrInvalidTerm)
+	}
+	if err != nil {
+		return Term{}, err
+	}
+	return markCurrent(term), nil
+}
+
+func (s *Service) GetCurrentTerm(ctx context.Context) (Term, error) {
+	return s.ResolveTerm(ctx, 0, CurrentTermRef)
+}
+
+func (s *Service) GetAllTerms(ctx context.Context) ([]Term, error) {
+	terms, err := s.Store.GetAllTerms(ctx)
+	if err != nil {
+		log.Errorf("Error fetching terms: %v", err)
+		return nil, err
+	}
+	for i := range terms {
+		terms[i] = markCurrent(terms[i])
+	}
+	return terms, nil
+}
+
+func (s *Service) GetTerm(ctx context.Context, termID int32) (Term, error) {
+	return s.ResolveTerm(ctx, termID, "")
+}
+
+func (s *Service) AddTerm(ctx context.Context, term Term) (Term, error) {
+	if err := validateTerm(term); err != nil {
+		return Term{}, err
+	}
+	created, err := s.Store.AddTerm(ctx, term)
+	s.RecordAudit(ctx, AuditEntry{
+		Action:   AuditTermCreate,
+		TargetID: created.ID,
+		Diff:     diffTerms(Term{}, term),
+		Outcome:  outcomeOf(err),
+		Message:  messageOf(err),
+	})
+	if err != nil {
+		log.Errorf("Failed to add term %s, Error: %v", term.Code, err)
+		return Term{}, err
+	}
+	return markCurrent(created), nil
+}
+
+func (s *Service) UpdateTerm(ctx context.Context, termID int32, term Term) (Term, error) {
+	if err := validateTerm(term); err != nil {
+		return Term{}, err
+	}
+	before, _ := s.Store.GetTerm(ctx, termID)
+	updated, err := s.Store.UpdateTerm(ctx, termID, term)
+	s.RecordAudit(ctx, AuditEntry{
+		Action:   AuditTermUpdate,
+		TargetID: termID,
+		Diff:     diffTerms(before, term),
+		Outcome:  outcomeOf(err),
+		Message:  messageOf(err),
+	})
+	if err != nil {
+		log.Errorf("Failed to update term %d, Error: %v", termID, err)
+		return Term{}, err
+	}
+	return markCurrent(updated), nil
+}
+
+func (s *Service) DeleteTerm(ctx context.Context, termID int32) error {
+	before, _ := s.Store.GetTerm(ctx, termID)
+	err := s.Store.DeleteTerm(ctx, termID)
+	entry := AuditEntry{
+		Action:   AuditTermDelete,
+		TargetID: termID,
+		Outcome:  outcomeOf(err),
+		Message:  messageOf(err),
+	}
+	if err == nil {
+		entry.Diff = diffTerms(before, Term{})
+	}
+	s.RecordAudit(ctx, entry)
+	if err != nil {
+		log.Errorf("Failed to delete term %d, Error: %v", termID, err)
+		return err
+	}
+	return nil
+}
+
+func diffTerms(before, after Term) map[string]FieldChange {
+	diff := map[string]FieldChange{}
+	add := func(field, from, to string) {
+		if from != to {
+			diff[field] = FieldChange{From: from, To: to}
+		}
+	}
+	add("code", before.Code, after.Code)
+	add("name", before.Name, after.Name)
+	add("start_date", before.StartDate, after.StartDate)
+	add("end_date", before.EndDate, after.EndDate)
+	return diff
+}
@@ -0,0 +1,330 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// TranscriptCourse is one enrollment as it appears on a transcript. Points
+// is only set for grades that count towards the GPA.
+type TranscriptCourse struct {
+	EnrollmentID int32    `json:"enrollment_id"`
+	CourseCode   string   `json:"course_code"`
+	CourseTitle  string   `json:"course_title"`
+	Credits      float64  `json:"credits"`
+	Grade        string   `json:"grade"`
+	Status       string   `json:"status"`
+	Points       *float64 `json:"points,omitempty"`
+}
+
+// TranscriptTerm groups the courses of one term.
+type TranscriptTerm struct {
+	TermID    int32              `json:"term_id"`
+	TermCode  string             `json:"term_code,omitempty"`
+	TermName  string             `json:"term_name,omitempty"`
+	StartDate string             `json:"start_date,omitempty"`
+	EndDate   string             `json:"end_date,omitempty"`
+	Courses   []TranscriptCourse `json:"courses"`
+	GPA       float64            `json:"gpa"`
+	Credits   float64            `json:"credits"`
+}
+
+// Transcript is the academic record of a student: every course, grade and
+// credit by term, with the GPA per term and cumulatively.
+type Transcript struct {
+	StudentID        int32            `json:"student_id"`
+	StudentName      string           `json:"student_name"`
+	Program          string           `json:"program,omitempty"`
+	Terms            []TranscriptTerm `json:"terms"`
+	GPA              float64          `json:"gpa"`
+	Credits          float64          `json:"credits"`
+	QualityPoints    float64          `json:"quality_points"`
+	IssuedOn         string           `json:"issued_on"`
+	VerificationCode string           `json:"verification_code,omitempty"`
+}
+
+// TranscriptRecord is what is kept of an issued transcript so its
+// verification code can be checked later. Body is the transcript as issued,
+// in JSON, and Digest its HMAC-SHA256 under the service's TranscriptKey.
+type TranscriptRecord struct {
+	Code        string  `json:"verification_code"`
+	StudentID   int32   `json:"student_id"`
+	StudentName string  `json:"student_name"`
+	GPA         float64 `json:"gpa"`
+	Credits     float64 `json:"credits"`
+	Digest      string  `json:"digest"`
+	IssuedBy    string  `json:"issued_by"`
+	IssuedOn    string  `json:"issued_on"`
+	Body        string  `json:"-"`
+}
+
+var (
+	ErrTranscriptNotFound = errors.New("no transcript with that verification code")
+	ErrTranscriptAltered  = errors.New("the transcript record does not match its digest")
+	ErrNoTranscriptKey    = errors.New("no key is configured to sign transcripts")
+)
+
+const AuditTranscriptIssue = "transcript.issue"
+
+type TranscriptStore interface {
+	AddTranscript(context.Context, TranscriptRecord) error
+	GetTranscriptByCode(context.Context, string) (TranscriptRecord, error)
+}
+
+// GetTranscript builds the transcript of a student from their enrollments.
+// It is not recorded and carries no verification code. For an instructor
+// it only lists, and its GPA only covers, the courses they teach.
+func (s *Service) GetTranscript(ctx context.Context, studentID int32) (Transcript, error) {
+	if err := checkTranscriptReader(ctx, studentID); err != nil {
+		return Transcript{}, err
+	}
+	enrollments, err := s.GetStudentEnrollments(ctx, studentID, "")
+	if err != nil {
+		return Transcript{}, err
+	}
+	student, err := s.Store.GetStudent(ctx, studentID)
+	if err != nil {
+		return Transcript{}, ErrStudentNotFound
+	}
+	gpa, err := s.GetStudentGPA(ctx, studentID)
+	if err != nil {
+		return Transcript{}, err
+	}
+	terms, err := s.Store.GetAllTerms(ctx)
+	if err != nil {
+		return Transcript{}, err
+	}
+
+	termsByID := make(map[int32]Term, len(terms))
+	for _, t := range terms {
+		termsByID[t.ID] = t
+	}
+	points := make(map[int32]float64, len(gpa.Courses))
+	for _, c := range gpa.Courses {
+		points[c.EnrollmentID] = c.Points
+	}
+	termGPAs := make(map[int32]TermGPA, len(gpa.Terms))
+	for _, t := range gpa.Terms {
+		termGPAs[t.TermID] = t
+	}
+
+	transcript := Transcript{
+		StudentID:     student.ID,
+		StudentName:   student.Name,
+		Program:       student.Course,
+		Terms:         []TranscriptTerm{},
+		GPA:           gpa.GPA,
+		Credits:       gpa.Credits,
+		QualityPoints: gpa.QualityPoints,
+		IssuedOn:      time.Now().UTC().Format(time.RFC3339),
+	}
+	termIndex := map[int32]int{}
+	for _, e := range enrollments {
+		i, seen := termIndex[e.TermID]
+		if !seen {
+			i = len(transcript.Terms)
+			termIndex[e.TermID] = i
+			term := termsByID[e.TermID]
+			transcript.Terms = append(transcript.Terms, TranscriptTerm{
+				TermID:    e.TermID,
+				TermCode:  e.TermCode,
+				TermName:  term.Name,
+				StartDate: term.StartDate,
+				EndDate:   term.EndDate,
+				Courses:   []TranscriptCourse{},
+				GPA:       termGPAs[e.TermID].GPA,
+				Credits:   termGPAs[e.TermID].Credits,
+			})
+		}
+		course := TranscriptCourse{
+			EnrollmentID: e.ID,
+			CourseCode:   e.CourseCode,
+			CourseTitle:  e.CourseTitle,
+			Credits:      e.Credits,
+			Grade:        e.Grade,
+			Status:       e.Status,
+		}
+		if p, ok := points[e.ID]; ok {
+			course.Points = &p
+		}
+		transcript.Terms[i].Courses = append(transcript.Terms[i].Courses, course)
+	}
+	return transcript, nil
+}
+
+// checkTranscriptReader lets the student, the admin and instructors read a
+// transcript; GetStudentEnrollments scopes instructors to their courses.
+func checkTranscriptReader(ctx context.Context, studentID int32) error {
+	if callerRole(ctx) == RoleInstructor {
+		return nil
+	}
+	if err := checkTranscriptIssuer(ctx, studentID); err != nil {
+		return fmt.Errorf("%w: only the student and staff can read a transcript", ErrForbidden)
+	}
+	return nil
+}
+
+// checkTranscriptIssuer lets only the student and the admin issue official
+// transcripts.
+func checkTranscriptIssuer(ctx context.Context, studentID int32) error {
+	switch callerRole(ctx) {
+	case RoleAdmin:
+		return nil
+	case RoleUser:
+		if callerID(ctx) == studentID {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: only the student and the admin can issue a transcript", ErrForbidden)
+}
+
+// IssueTranscript builds an official transcript, gives it a verification
+// code and records it so the code can be checked with VerifyTranscript.
+func (s *Service) IssueTranscript(ctx context.Context, studentID int32) (Transcript, error) {
+	if err := checkTranscriptIssuer(ctx, studentID); err != nil {
+		return Transcript{}, err
+	}
+	if len(s.TranscriptKey) == 0 {
+		return Transcript{}, ErrNoTranscriptKey
+	}
+	transcript, err := s.GetTranscript(ctx, studentID)
+	if err != nil {
+		return Transcript{}, err
+	}
+	code, err := newVerificationCode()
+	if err != nil {
+		return Transcript{}, err
+	}
+	transcript.VerificationCode = code
+
+	body, err := json.Marshal(transcript)
+	if err != nil {
+		return Transcript{}, err
+	}
+	issuedBy, _ := ctx.Value("userType").(string)
+
+	err = s.Store.AddTranscript(ctx, TranscriptRecord{
+		Code:        code,
+		StudentID:   transcript.StudentID,
+		StudentName: transcript.StudentName,
+		GPA:         transcript.GPA,
+		Credits:     transcript.Credits,
+		Digest:      hex.EncodeToString(s.transcriptDigest(body)),
+		IssuedBy:    issuedBy,
+		IssuedOn:    transcript.IssuedOn,
+		Body:        string(body),
+	})
+	s.RecordAudit(ctx, AuditEntry{
+		Action:   AuditTranscriptIssue,
+		TargetID: studentID,
+		Diff:     map[string]FieldChange{"verification_code": {To: code}},
+		Outcome:  outcomeOf(err),
+		Message:  messageOf(err),
+	})
+	if err != nil {
+		log.Errorf("Failed to record transcript of student %d, Error: %v", studentID, err)
+		return Transcript{}, err
+	}
+	log.Infof("Issued transcript %s for student %d", code, studentID)
+	return transcript, nil
+}
+
+// VerifyTranscript looks up an issued transcript by its verification code.
+// Codes are compared without regard to case, spaces or dashes. The stored
+// transcript must still match its digest and the record; a record that was
+// changed since it was issued gives ErrTranscriptAltered.
+func (s *Service) VerifyTranscript(ctx context.Context, code string) (TranscriptRecord, error) {
+	code = normalizeVerificationCode(code)
+	if code == "" {
+		return TranscriptRecord{}, ErrTranscriptNotFound
+	}
+	record, err := s.Store.GetTranscriptByCode(ctx, formatVerificationCode(code))
+	if err != nil {
+		return TranscriptRecord{}, err
+	}
+	if err := s.checkTranscriptDigest(record); err != nil {
+		log.Warnf("Transcript %s failed verification: %v", record.Code, err)
+		return TranscriptRecord{}, err
+	}
+	return record, nil
+}
+
+// transcriptDigest signs the body of an issued transcript. The digest is
+// keyed, so that whoever can write to the transcripts table cannot forge
+// one for a changed body.
+func (s *Service) transcriptDigest(body []byte) []byte {
+	mac := hmac.New(sha256.New, s.TranscriptKey)
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+// checkTranscriptDigest recomputes the digest of the stored transcript and
+// compares it, and the fields the record repeats, with the record.
+// Transcripts issued before their body was kept have nothing to recompute
+// the digest from and are taken as they are.
+func (s *Service) checkTranscriptDigest(record TranscriptRecord) error {
+	if record.Body == "" {
+		return nil
+	}
+	if len(s.TranscriptKey) == 0 {
+		return ErrNoTranscriptKey
+	}
+	stored, err := hex.DecodeString(record.Digest)
+	if err != nil || !hmac.Equal(s.transcriptDigest([]byte(record.Body)), stored) {
+		return ErrTranscriptAltered
+	}
+	var issued Transcript
+	if err := json.Unmarshal([]byte(record.Body), &issued); err != nil {
+		return ErrTranscriptAltered
+	}
+	// The record keeps the GPA and credits rounded to the columns.
+	if issued.VerificationCode != record.Code ||
+		issued.StudentID != record.StudentID ||
+		issued.StudentName != record.StudentName ||
+		issued.IssuedOn != record.IssuedOn ||
+		fmt.Sprintf("%.2f", issued.GPA) != fmt.Sprintf("%.2f", record.GPA) ||
+		fmt.Sprintf("%.1f", issued.Credits) != fmt.Sprintf("%.1f", record.Credits) {
+		return ErrTranscriptAltered
+	}
+	return nil
+}
+
+// newVerificationCode returns 80 random bits as four groups of four base32
+// characters, e.g. "K3QF-7ZPA-M2XD-LR5T".
+func newVerificationCode() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return formatVerificationCode(base32.StdEncoding.EncodeToString(b)), nil
+}
+
+func normalizeVerificationCode(code string) string {
+	code = strings.ToUpper(code)
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, code)
+}
+
+func formatVerificationCode(code string) string {
+	var groups []string
+	for len(code) > 4 {
+		groups = append(groups, code[:4])
+		code = code[4:]
+	}
+	return strings.Join(append(groups, code), "-")
+}
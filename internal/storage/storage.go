@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	service "GO_Assignment_3/internal/service"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// NewStorage sets up file storage from the environment. STORAGE_DRIVER is
+// "local" (the default), which keeps files under STORAGE_DIR, or "s3",
+// which uses an S3-compatible bucket configured by the S3_* variables.
+func NewStorage() (service.FileStorage, error) {
+	driver := strings.ToLower(os.Getenv("STORAGE_DRIVER"))
+	log.Infof("Setting up %q file storage", driver)
+
+	switch driver {
+	case "", "local":
+		dir := os.Getenv("STORAGE_DIR")
+		if dir == "" {
+			dir = "uploads"
+		}
+		return NewLocal(dir)
+	case "s3":
+		return NewS3(S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Region:    os.Getenv("S3_REGION"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+			UseSSL:    os.Getenv("S3_USE_SSL") != "false",
+		})
+	}
+	return nil, fmt.Errorf("unknown STORAGE_DRIVER %q", driver)
+}
@@ -0,0 +1,248 @@
+package Grpc
+
+import (
+	service "GO_Assignment_3/internal/service"
+	"GO_Assignment_3/internal/transport/Grpc/pb"
+	"GO_Assignment_3/internal/transport/Htt"
+	"context"
+	"net"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// access is who may call a method, following the route groups of the REST
+// API.
+type access int
+
+const (
+	// accessPublic needs no token.
+	accessPublic access = iota
+	// accessToken needs any valid token; the service may still refuse the
+	// caller.
+	accessToken
+	// accessOwner is accessToken, but a user may only change their own
+	// student record, named by student_id in the request.
+	accessOwner
+	accessAdmin
+)
+
+// methodAccess lists every method of the API. Methods missing from it are
+// refused.
+var methodAccess = map[string]access{
+	pb.StudentService_ListStudents_FullMethodName:   accessToken,
+	pb.StudentService_StreamStudents_FullMethodName: accessToken,
+	pb.StudentService_SearchStudents_FullMethodName: accessToken,
+	pb.StudentService_GetStudent_FullMethodName:     accessToken,
+	pb.StudentService_CreateStudent_FullMethodName:  accessOwner,
+	pb.StudentService_UpdateStudent_FullMethodName:  accessOwner,
+	pb.StudentService_DeleteStudent_FullMethodName:  accessOwner,
+
+	pb.CourseService_ListCourses_FullMethodName:  accessToken,
+	pb.CourseService_GetCourse_FullMethodName:    accessToken,
+	pb.CourseService_CreateCourse_FullMethodName: accessAdmin,
+	pb.CourseService_UpdateCourse_FullMethodName: accessAdmin,
+	pb.CourseService_DeleteCourse_FullMethodName: accessAdmin,
+
+	pb.EnrollmentService_ListStudentEnrollments_FullMethodName: accessToken,
+	pb.EnrollmentService_GetEnrollment_FullMethodName:          accessToken,
+	pb.EnrollmentService_Enroll_FullMethodName:                 accessOwner,
+	pb.EnrollmentService_UpdateEnrollment_FullMethodName:       accessOwner,
+	pb.EnrollmentService_DeleteEnrollment_FullMethodName:       accessOwner,
+	pb.EnrollmentService_ListCourseRoster_FullMethodName:       accessToken,
+	pb.EnrollmentService_ListCourseWaitlist_FullMethodName:     accessToken,
+
+	pb.TermService_ListTerms_FullMethodName:      accessToken,
+	pb.TermService_GetCurrentTerm_FullMethodName: accessToken,
+	pb.TermService_GetTerm_FullMethodName:        accessToken,
+	pb.TermService_CreateTerm_FullMethodName:     accessAdmin,
+	pb.TermService_UpdateTerm_FullMethodName:     accessAdmin,
+	pb.TermService_DeleteTerm_FullMethodName:     accessAdmin,
+
+	pb.GradeService_GetStudentGPA_FullMethodName:    accessToken,
+	pb.GradeService_GetTranscript_FullMethodName:    accessToken,
+	pb.GradeService_VerifyTranscript_FullMethodName: accessPublic,
+}
+
+// publicServices need no token: health checks and server reflection.
+var publicServices = []string{
+	"/grpc.health.v1.Health/",
+	"/grpc.reflection.v1.ServerReflection/",
+	"/grpc.reflection.v1alpha.ServerReflection/",
+}
+
+func accessFor(method string) (access, bool) {
+	for _, prefix := range publicServices {
+		if strings.HasPrefix(method, prefix) {
+			return accessPublic, true
+		}
+	}
+	a, ok := methodAccess[method]
+	return a, ok
+}
+
+// studentScoped is implemented by requests that name a student.
+type studentScoped interface {
+	GetStudentId() int32
+}
+
+func (s *Server) authUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := s.authorize(ctx, info.FullMethod, req)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (s *Server) authStream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	// Server streams are authorized on their metadata alone; none of the
+	// streaming methods is owner-scoped.
+	ctx, err := s.authorize(ss.Context(), info.FullMethod, nil)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &contextStream{ss, ctx})
+}
+
+// authorize resolves the token in the authorization metadata, as "Token
+// <jwt>" like the REST Authorization header, and applies the access rule
+// of the method. It returns the context carrying userType and userID.
+func (s *Server) authorize(ctx context.Context, method string, req interface{}) (context.Context, error) {
+	rule, ok := accessFor(method)
+	if !ok {
+		return nil, status.Error(codes.PermissionDenied, "Forbidden")
+	}
+	if rule == accessPublic {
+		return ctx, nil
+	}
+
+	authHeader := firstMetadata(ctx, "authorization")
+	if authHeader == "" {
+		s.auditAuth(ctx, service.AuditAuthRejected, 0, "authorization metadata missing")
+		return nil, status.Error(codes.Unauthenticated, "Authorization metadata missing")
+	}
+	tokenString := strings.TrimSpace(strings.Replace(authHeader, "Token", "", 1))
+	userType, userID, err := Htt.AuthenticateJWT(tokenString)
+	if err != nil {
+		s.auditAuth(ctx, service.AuditAuthRejected, 0, err.Error())
+		return nil, status.Error(codes.Unauthenticated, "Unauthorized")
+	}
+	ctx = context.WithValue(ctx, "userType", userType)
+	ctx = context.WithValue(ctx, "userID", userID)
+
+	// Guardian tokens only open contacts, which are not served over gRPC.
+	if userType == service.RoleGuardian {
+		s.auditAuth(ctx, service.AuditAuthDenied, 0, "guardian token used for "+method)
+		return nil, status.Error(codes.PermissionDenied, "Forbidden")
+	}
+
+	switch rule {
+	case accessAdmin:
+		if userType != service.RoleAdmin {
+			s.auditAuth(ctx, service.AuditAuthDenied, 0, "admin role required for "+method)
+			return nil, status.Error(codes.PermissionDenied, "Forbidden")
+		}
+	case accessOwner:
+		// Instructors are scoped by the service layer, as over REST.
+		if userType != service.RoleUser {
+			break
+		}
+		scoped, ok := req.(studentScoped)
+		if !ok || scoped.GetStudentId() != userID {
+			var target int32
+			if ok {
+				target = scoped.GetStudentId()
+			}
+			s.auditAuth(ctx, service.AuditAuthDenied, target, "user may only modify their own record")
+			return nil, status.Error(codes.PermissionDenied, "Forbidden")
+		}
+	}
+	return ctx, nil
+}
+
+func (s *Server) auditAuth(ctx context.Context, action string, targetID int32, message string) {
+	outcome := service.OutcomeFailure
+	if action == service.AuditAuthDenied {
+		outcome = service.OutcomeDenied
+	}
+	s.Service.RecordAudit(ctx, service.AuditEntry{
+		Action:   action,
+		TargetID: targetID,
+		Outcome:  outcome,
+		Message:  message,
+	})
+}
+
+// requestContext tags the call with a request ID, from x-request-id or
+// new, and the client IP, like Htt.RequestContextMiddleware.
+func requestContext(ctx context.Context) context.Context {
+	requestID := firstMetadata(ctx, "x-request-id")
+	if requestID == "" || len(requestID) > 64 {
+		requestID = Htt.NewRequestID()
+	}
+	grpc.SetHeader(ctx, metadata.Pairs("x-request-id", requestID))
+
+	ctx = context.WithValue(ctx, "requestID", requestID)
+	if p, ok := peer.FromContext(ctx); ok {
+		host := p.Addr.String()
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		ctx = context.WithValue(ctx, "clientIP", host)
+	}
+	return ctx
+}
+
+func requestContextUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx = requestContext(ctx)
+	logrus.WithFields(logrus.Fields{"Method": info.FullMethod}).Info("handled gRPC call")
+	return handler(ctx, req)
+}
+
+func requestContextStream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx := requestContext(ss.Context())
+	logrus.WithFields(logrus.Fields{"Method": info.FullMethod}).Info("handled gRPC stream")
+	return handler(srv, &contextStream{ss, ctx})
+}
+
+func recoverUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logrus.Error("Recovered from panic: ", r)
+			err = status.Error(codes.Internal, "Internal Server Error")
+		}
+	}()
+	return handler(ctx, req)
+}
+
+func recoverStream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logrus.Error("Recovered from panic: ", r)
+			err = status.Error(codes.Internal, "Internal Server Error")
+		}
+	}()
+	return handler(srv, ss)
+}
+
+// contextStream replaces the context of a server stream.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (c *contextStream) Context() context.Context {
+	return c.ctx
+}
+
+func firstMetadata(ctx context.Context, key string) string {
+	if values := metadata.ValueFromIncomingContext(ctx, key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
@@ -0,0 +1,223 @@
+package Grpc
+
+import (
+	service "GO_Assignment_3/internal/service"
+	"GO_Assignment_3/internal/transport/Grpc/pb"
+)
+
+// Conversions between the protobuf messages and the service types. The
+// FromPB functions read through the getters, so a missing message becomes
+// the zero value and fails validation like an empty JSON body.
+
+// studentToPB leaves the password out, like the JSON responses do.
+func studentToPB(s service.Student) *pb.Student {
+	return &pb.Student{
+		Id:        s.ID,
+		Name:      s.Name,
+		Course:    s.Course,
+		CourseId:  s.CourseID,
+		Grade:     s.Grade,
+		CreatedBy: s.CreatedBy,
+		CreatedOn: s.CreatedOn,
+		UpdatedBy: s.UpdatedBy,
+		UpdatedOn: s.UpdatedOn,
+	}
+}
+
+func studentFromPB(p *pb.Student) service.Student {
+	return service.Student{
+		ID:       p.GetId(),
+		Password: p.GetPassword(),
+		Name:     p.GetName(),
+		Course:   p.GetCourse(),
+		CourseID: p.GetCourseId(),
+		Grade:    p.GetGrade(),
+	}
+}
+
+func studentsToPB(students []service.Student) []*pb.Student {
+	out := make([]*pb.Student, len(students))
+	for i, s := range students {
+		out[i] = studentToPB(s)
+	}
+	return out
+}
+
+func courseToPB(c service.Course) *pb.Course {
+	return &pb.Course{
+		Id:             c.ID,
+		Code:           c.Code,
+		Title:          c.Title,
+		Credits:        c.Credits,
+		Capacity:       c.Capacity,
+		Department:     c.Department,
+		GradingScaleId: c.GradingScaleID,
+		CreatedBy:      c.CreatedBy,
+		CreatedOn:      c.CreatedOn,
+		UpdatedBy:      c.UpdatedBy,
+		UpdatedOn:      c.UpdatedOn,
+	}
+}
+
+func courseFromPB(p *pb.Course) service.Course {
+	return service.Course{
+		ID:             p.GetId(),
+		Code:           p.GetCode(),
+		Title:          p.GetTitle(),
+		Credits:        p.GetCredits(),
+		Capacity:       p.GetCapacity(),
+		Department:     p.GetDepartment(),
+		GradingScaleID: p.GetGradingScaleId(),
+	}
+}
+
+func enrollmentToPB(e service.Enrollment) *pb.Enrollment {
+	return &pb.Enrollment{
+		Id:               e.ID,
+		StudentId:        e.StudentID,
+		StudentName:      e.StudentName,
+		CourseId:         e.CourseID,
+		CourseCode:       e.CourseCode,
+		CourseTitle:      e.CourseTitle,
+		Credits:          e.Credits,
+		TermId:           e.TermID,
+		TermCode:         e.TermCode,
+		Term:             e.Term,
+		Course:           e.Course,
+		Grade:            e.Grade,
+		Status:           e.Status,
+		WaitlistPosition: e.WaitlistPosition,
+		CreatedBy:        e.CreatedBy,
+		CreatedOn:        e.CreatedOn,
+		UpdatedBy:        e.UpdatedBy,
+		UpdatedOn:        e.UpdatedOn,
+	}
+}
+
+func enrollmentFromPB(p *pb.Enrollment) service.Enrollment {
+	return service.Enrollment{
+		ID:        p.GetId(),
+		StudentID: p.GetStudentId(),
+		CourseID:  p.GetCourseId(),
+		TermID:    p.GetTermId(),
+		Term:      p.GetTerm(),
+		Course:    p.GetCourse(),
+		Grade:     p.GetGrade(),
+		Status:    p.GetStatus(),
+	}
+}
+
+func enrollmentsToPB(enrollments []service.Enrollment) *pb.ListEnrollmentsResponse {
+	out := &pb.ListEnrollmentsResponse{Enrollments: make([]*pb.Enrollment, len(enrollments))}
+	for i, e := range enrollments {
+		out.Enrollments[i] = enrollmentToPB(e)
+	}
+	return out
+}
+
+func termToPB(t service.Term) *pb.Term {
+	return &pb.Term{
+		Id:        t.ID,
+		Code:      t.Code,
+		Name:      t.Name,
+		StartDate: t.StartDate,
+		EndDate:   t.EndDate,
+		IsCurrent: t.IsCurrent,
+		CreatedBy: t.CreatedBy,
+		CreatedOn: t.CreatedOn,
+		UpdatedBy: t.UpdatedBy,
+		UpdatedOn: t.UpdatedOn,
+	}
+}
+
+func termFromPB(p *pb.Term) service.Term {
+	return service.Term{
+		ID:        p.GetId(),
+		Code:      p.GetCode(),
+		Name:      p.GetName(),
+		StartDate: p.GetStartDate(),
+		EndDate:   p.GetEndDate(),
+	}
+}
+
+func gpaToPB(g service.GPA) *pb.GPA {
+	out := &pb.GPA{
+		StudentId:           g.StudentID,
+		Gpa:                 g.GPA,
+		Credits:             g.Credits,
+		QualityPoints:       g.QualityPoints,
+		UnscoredEnrollments: g.Unscored,
+	}
+	for _, t := range g.Terms {
+		out.Terms = append(out.Terms, &pb.TermGPA{
+			TermId:        t.TermID,
+			TermCode:      t.TermCode,
+			Gpa:           t.GPA,
+			Credits:       t.Credits,
+			QualityPoints: t.QualityPoints,
+		})
+	}
+	for _, c := range g.Courses {
+		out.Courses = append(out.Courses, &pb.CourseGrade{
+			EnrollmentId: c.EnrollmentID,
+			CourseId:     c.CourseID,
+			CourseCode:   c.CourseCode,
+			TermId:       c.TermID,
+			TermCode:     c.TermCode,
+			Credits:      c.Credits,
+			Grade:        c.Grade,
+			Points:       c.Points,
+		})
+	}
+	return out
+}
+
+func transcriptToPB(t service.Transcript) *pb.Transcript {
+	out := &pb.Transcript{
+		StudentId:        t.StudentID,
+		StudentName:      t.StudentName,
+		Program:          t.Program,
+		Gpa:              t.GPA,
+		Credits:          t.Credits,
+		QualityPoints:    t.QualityPoints,
+		IssuedOn:         t.IssuedOn,
+		VerificationCode: t.VerificationCode,
+	}
+	for _, term := range t.Terms {
+		pt := &pb.TranscriptTerm{
+			TermId:    term.TermID,
+			TermCode:  term.TermCode,
+			TermName:  term.TermName,
+			StartDate: term.StartDate,
+			EndDate:   term.EndDate,
+			Gpa:       term.GPA,
+			Credits:   term.Credits,
+		}
+		for _, c := range term.Courses {
+			pt.Courses = append(pt.Courses, &pb.TranscriptCourse{
+				EnrollmentId: c.EnrollmentID,
+				CourseCode:   c.CourseCode,
+				CourseTitle:  c.CourseTitle,
+				Credits:      c.Credits,
+				Grade:        c.Grade,
+				Status:       c.Status,
+				Points:       c.Points,
+			})
+		}
+		out.Terms = append(out.Terms, pt)
+	}
+	return out
+}
+
+func transcriptRecordToPB(r service.TranscriptRecord) *pb.TranscriptRecord {
+	return &pb.TranscriptRecord{
+		VerificationCode: r.Code,
+		StudentId:        r.StudentID,
+		StudentName:      r.StudentName,
+		Gpa:              r.GPA,
+		Credits:          r.Credits,
+		Digest:           r.Digest,
+		IssuedBy:         r.IssuedBy,
+		IssuedOn:         r.IssuedOn,
+	}
+}
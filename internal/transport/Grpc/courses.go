@@ -0,0 +1,64 @@
+package Grpc
+
+import (
+	"GO_Assignment_3/internal/transport/Grpc/pb"
+	"context"
+
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+type courseServer struct {
+	pb.UnimplementedCourseServiceServer
+	*Server
+}
+
+func (s *courseServer) ListCourses(ctx context.Context, _ *emptypb.Empty) (*pb.ListCoursesResponse, error) {
+	courses, err := s.Service.GetAllCourses(ctx)
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	out := &pb.ListCoursesResponse{Courses: make([]*pb.Course, len(courses))}
+	for i, c := range courses {
+		out.Courses[i] = courseToPB(c)
+	}
+	return out, nil
+}
+
+func (s *courseServer) GetCourse(ctx context.Context, req *pb.GetCourseRequest) (*pb.Course, error) {
+	course, err := s.Service.GetCourse(ctx, req.GetCourseId())
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return courseToPB(course), nil
+}
+
+func (s *courseServer) CreateCourse(ctx context.Context, req *pb.CreateCourseRequest) (*pb.Course, error) {
+	course := courseFromPB(req.GetCourse())
+	if err := validateMessage(ctx, &course); err != nil {
+		return nil, err
+	}
+	created, err := s.Service.AddCourse(ctx, course)
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return courseToPB(created), nil
+}
+
+func (s *courseServer) UpdateCourse(ctx context.Context, req *pb.UpdateCourseRequest) (*pb.Course, error) {
+	course := courseFromPB(req.GetCourse())
+	if err := validateMessage(ctx, &course); err != nil {
+		return nil, err
+	}
+	updated, err := s.Service.UpdateCourse(ctx, req.GetCourseId(), course)
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return courseToPB(updated), nil
+}
+
+func (s *courseServer) DeleteCourse(ctx context.Context, req *pb.DeleteCourseRequest) (*emptypb.Empty, error) {
+	if err := s.Service.DeleteCourse(ctx, req.GetCourseId()); err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return &emptypb.Empty{}, nil
+}
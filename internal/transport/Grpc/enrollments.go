@@ -0,0 +1,76 @@
+package Grpc
+
+import (
+	"GO_Assignment_3/internal/transport/Grpc/pb"
+	"context"
+
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+type enrollmentServer struct {
+	pb.UnimplementedEnrollmentServiceServer
+	*Server
+}
+
+func (s *enrollmentServer) ListStudentEnrollments(ctx context.Context, req *pb.ListStudentEnrollmentsRequest) (*pb.ListEnrollmentsResponse, error) {
+	enrollments, err := s.Service.GetStudentEnrollments(ctx, req.GetStudentId(), req.GetTerm())
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return enrollmentsToPB(enrollments), nil
+}
+
+func (s *enrollmentServer) GetEnrollment(ctx context.Context, req *pb.GetEnrollmentRequest) (*pb.Enrollment, error) {
+	enrollment, err := s.Service.GetEnrollment(ctx, req.GetStudentId(), req.GetEnrollmentId())
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return enrollmentToPB(enrollment), nil
+}
+
+func (s *enrollmentServer) Enroll(ctx context.Context, req *pb.EnrollRequest) (*pb.Enrollment, error) {
+	enrollment := enrollmentFromPB(req.GetEnrollment())
+	if err := validateMessage(ctx, &enrollment); err != nil {
+		return nil, err
+	}
+	created, err := s.Service.Enroll(ctx, req.GetStudentId(), enrollment)
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return enrollmentToPB(created), nil
+}
+
+func (s *enrollmentServer) UpdateEnrollment(ctx context.Context, req *pb.UpdateEnrollmentRequest) (*pb.Enrollment, error) {
+	enrollment := enrollmentFromPB(req.GetEnrollment())
+	if err := validateMessage(ctx, &enrollment); err != nil {
+		return nil, err
+	}
+	updated, err := s.Service.UpdateEnrollment(ctx, req.GetStudentId(), req.GetEnrollmentId(), enrollment)
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return enrollmentToPB(updated), nil
+}
+
+func (s *enrollmentServer) DeleteEnrollment(ctx context.Context, req *pb.DeleteEnrollmentRequest) (*emptypb.Empty, error) {
+	if err := s.Service.DeleteEnrollment(ctx, req.GetStudentId(), req.GetEnrollmentId()); err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *enrollmentServer) ListCourseRoster(ctx context.Context, req *pb.ListCourseEnrollmentsRequest) (*pb.ListEnrollmentsResponse, error) {
+	roster, err := s.Service.GetCourseRoster(ctx, req.GetCourseId(), req.GetTerm())
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return enrollmentsToPB(roster), nil
+}
+
+func (s *enrollmentServer) ListCourseWaitlist(ctx context.Context, req *pb.ListCourseEnrollmentsRequest) (*pb.ListEnrollmentsResponse, error) {
+	waitlist, err := s.Service.GetCourseWaitlist(ctx, req.GetCourseId(), req.GetTerm())
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return enrollmentsToPB(waitlist), nil
+}
@@ -0,0 +1,101 @@
+package Grpc
+
+import (
+	service "GO_Assignment_3/internal/service"
+	"GO_Assignment_3/internal/transport/Htt"
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+)
+
+// errorDomain is the domain of the ErrorInfo details sent with errors.
+const errorDomain = "studentapi"
+
+// alreadyExists are the conflicts that mean the thing being created is
+// already there; other conflicts are failed preconditions.
+var alreadyExists = []error{
+	service.ErrCourseCodeTaken,
+	service.ErrTermCodeTaken,
+	service.ErrAlreadyEnrolled,
+	service.ErrGradingScaleTaken,
+	service.ErrOverrideExists,
+	service.ErrInstructorEmailUsed,
+	service.ErrGuardianEmailUsed,
+}
+
+// grpcCodes translates the statuses of Htt.ProblemFor.
+var grpcCodes = map[int]codes.Code{
+	http.StatusBadRequest:            codes.InvalidArgument,
+	http.StatusUnauthorized:          codes.Unauthenticated,
+	http.StatusForbidden:             codes.PermissionDenied,
+	http.StatusNotFound:              codes.NotFound,
+	http.StatusConflict:              codes.FailedPrecondition,
+	http.StatusPreconditionFailed:    codes.FailedPrecondition,
+	http.StatusUnprocessableEntity:   codes.FailedPrecondition,
+	http.StatusRequestEntityTooLarge: codes.ResourceExhausted,
+	http.StatusServiceUnavailable:    codes.Unavailable,
+}
+
+// toStatus turns an error of the service into a gRPC status. It carries
+// the same code as the REST problem, as ErrorInfo.Reason, and the failed
+// rules of invalid requests as BadRequest field violations. The text of
+// internal errors is logged but not sent.
+func toStatus(ctx context.Context, err error) error {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+
+	var invalid Htt.ValidationErrors
+	if errors.As(err, &invalid) {
+		violations := make([]*errdetails.BadRequest_FieldViolation, len(invalid))
+		for i, fe := range invalid {
+			violations[i] = &errdetails.BadRequest_FieldViolation{Field: fe.Field, Description: fe.Message}
+		}
+		return withDetails(codes.InvalidArgument, "The request failed validation", "validation_failed",
+			&errdetails.BadRequest{FieldViolations: violations})
+	}
+
+	httpStatus, reason := Htt.ProblemFor(err)
+	if httpStatus == http.StatusInternalServerError {
+		logrus.Errorf("gRPC call %v failed: %v", ctx.Value("requestID"), err)
+		return withDetails(codes.Internal, "An internal error occurred", reason)
+	}
+	code, ok := grpcCodes[httpStatus]
+	if !ok {
+		code = codes.Unknown
+	}
+	for _, target := range alreadyExists {
+		if errors.Is(err, target) {
+			code = codes.AlreadyExists
+		}
+	}
+	return withDetails(code, err.Error(), reason)
+}
+
+// invalidArgument is toStatus for a request the server could not make
+// sense of, keeping the code of err when it is a known service error.
+func invalidArgument(err error) error {
+	httpStatus, reason := Htt.ProblemFor(err)
+	if httpStatus != http.StatusBadRequest {
+		reason = "bad_request"
+	}
+	return withDetails(codes.InvalidArgument, err.Error(), reason)
+}
+
+func withDetails(code codes.Code, message, reason string, details ...protoadapt.MessageV1) error {
+	info := &errdetails.ErrorInfo{Reason: reason, Domain: errorDomain}
+	st, err := status.New(code, message).WithDetails(append([]protoadapt.MessageV1{info}, details...)...)
+	if err != nil {
+		return status.Error(code, message)
+	}
+	return st.Err()
+}
@@ -0,0 +1,37 @@
+package Grpc
+
+import (
+	"GO_Assignment_3/internal/transport/Grpc/pb"
+	"context"
+)
+
+type gradeServer struct {
+	pb.UnimplementedGradeServiceServer
+	*Server
+}
+
+func (s *gradeServer) GetStudentGPA(ctx context.Context, req *pb.GetStudentGradesRequest) (*pb.GPA, error) {
+	gpa, err := s.Service.GetStudentGPA(ctx, req.GetStudentId())
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return gpaToPB(gpa), nil
+}
+
+// GetTranscript returns the unofficial transcript; official, verifiable
+// ones are issued with POST over REST.
+func (s *gradeServer) GetTranscript(ctx context.Context, req *pb.GetStudentGradesRequest) (*pb.Transcript, error) {
+	transcript, err := s.Service.GetTranscript(ctx, req.GetStudentId())
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return transcriptToPB(transcript), nil
+}
+
+func (s *gradeServer) VerifyTranscript(ctx context.Context, req *pb.VerifyTranscriptRequest) (*pb.TranscriptRecord, error) {
+	record, err := s.Service.VerifyTranscript(ctx, req.GetVerificationCode())
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return transcriptRecordToPB(record), nil
+}
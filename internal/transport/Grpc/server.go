@@ -0,0 +1,108 @@
+// Package Grpc serves service.Service over gRPC, next to the REST API in
+// package Htt. It uses the same tokens, authorization rules, validation and
+// error codes.
+package Grpc
+
+import (
+	service "GO_Assignment_3/internal/service"
+	"GO_Assignment_3/internal/transport/Grpc/pb"
+	"context"
+	"net"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// Server - the gRPC counterpart of Htt.Handler
+type Server struct {
+	Service *service.Service
+}
+
+// NewServer - creates a new Server instance
+func NewServer(service *service.Service) *Server {
+	return &Server{
+		Service: service,
+	}
+}
+
+// healthInterval is how often the health service checks the database.
+const healthInterval = 10 * time.Second
+
+// Listen opens the listener of the gRPC API, so that an address that cannot
+// be used fails startup before anything is served.
+func (s *Server) Listen(addr string) (net.Listener, error) {
+	return net.Listen("tcp", addr)
+}
+
+// Serve serves the gRPC API, the health service and server reflection on
+// listener until the listener fails or ctx is done, when the server stops
+// gracefully and Serve returns nil.
+func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	server, healthServer := s.newGRPCServer()
+	go s.watchHealth(ctx, healthServer, server)
+	go func() {
+		<-ctx.Done()
+		server.GracefulStop()
+	}()
+
+	logrus.Infof("Starting gRPC server on %s", listener.Addr())
+	return server.Serve(listener)
+}
+
+// newGRPCServer registers every service on a new grpc.Server and returns
+// it with its health service.
+func (s *Server) newGRPCServer() (*grpc.Server, *health.Server) {
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(requestContextUnary, recoverUnary, s.authUnary),
+		grpc.ChainStreamInterceptor(requestContextStream, recoverStream, s.authStream),
+	)
+
+	pb.RegisterStudentServiceServer(server, &studentServer{Server: s})
+	pb.RegisterCourseServiceServer(server, &courseServer{Server: s})
+	pb.RegisterEnrollmentServiceServer(server, &enrollmentServer{Server: s})
+	pb.RegisterTermServiceServer(server, &termServer{Server: s})
+	pb.RegisterGradeServiceServer(server, &gradeServer{Server: s})
+
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(server, healthServer)
+	reflection.Register(server)
+	return server, healthServer
+}
+
+// watchHealth reports every service as serving while the database answers,
+// until ctx is done.
+func (s *Server) watchHealth(ctx context.Context, healthServer *health.Server, server *grpc.Server) {
+	var names []string
+	for name := range server.GetServiceInfo() {
+		names = append(names, name)
+	}
+	ticker := time.NewTicker(healthInterval)
+	defer ticker.Stop()
+	for {
+		status := healthpb.HealthCheckResponse_SERVING
+		checkCtx, cancel := context.WithTimeout(ctx, healthInterval/2)
+		if err := s.Service.ReadyCheck(checkCtx); err != nil {
+			logrus.Warnf("gRPC health check failed: %v", err)
+			status = healthpb.HealthCheckResponse_NOT_SERVING
+		}
+		cancel()
+		// "" is the health of the server as a whole.
+		healthServer.SetServingStatus("", status)
+		for _, name := range names {
+			healthServer.SetServingStatus(name, status)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
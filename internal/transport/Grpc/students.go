@@ -0,0 +1,120 @@
+package Grpc
+
+import (
+	service "GO_Assignment_3/internal/service"
+	"GO_Assignment_3/internal/transport/Grpc/pb"
+	"GO_Assignment_3/internal/transport/Htt"
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+type studentServer struct {
+	pb.UnimplementedStudentServiceServer
+	*Server
+}
+
+// validateMessage validates a request converted to its service type, like
+// the REST handlers validate JSON bodies, with messages in the languages of
+// the accept-language metadata.
+func validateMessage(ctx context.Context, v interface{}) error {
+	if err := Htt.Validate(v, firstMetadata(ctx, "accept-language")); err != nil {
+		return toStatus(ctx, err)
+	}
+	return nil
+}
+
+// listOptions reads the paging, sorting and filtering of a list request as
+// the query parameters of GET /students.
+func listOptions(req *pb.ListStudentsRequest) (service.ListOptions, error) {
+	params := url.Values{}
+	params.Set("filter", req.GetFilter())
+	params.Set("limit", strconv.Itoa(int(req.GetLimit())))
+	params.Set("cursor", req.GetCursor())
+	params.Set("sort", req.GetSort())
+	return Htt.ParseListOptions(params)
+}
+
+func (s *studentServer) ListStudents(ctx context.Context, req *pb.ListStudentsRequest) (*pb.ListStudentsResponse, error) {
+	opts, err := listOptions(req)
+	if err != nil {
+		return nil, invalidArgument(err)
+	}
+	page, err := s.Service.GetAllStudents(ctx, opts)
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return &pb.ListStudentsResponse{Students: studentsToPB(page.Students), NextCursor: page.NextCursor}, nil
+}
+
+// StreamStudents sends each student as soon as the store scans it. A
+// client that goes away cancels the context, which stops the query.
+func (s *studentServer) StreamStudents(req *pb.ListStudentsRequest, stream pb.StudentService_StreamStudentsServer) error {
+	ctx := stream.Context()
+	opts, err := listOptions(req)
+	if err != nil {
+		return invalidArgument(err)
+	}
+	err = s.Service.StreamStudents(ctx, opts, func(student service.Student) error {
+		return stream.Send(studentToPB(student))
+	})
+	if err != nil {
+		return toStatus(ctx, err)
+	}
+	return nil
+}
+
+func (s *studentServer) SearchStudents(ctx context.Context, req *pb.SearchStudentsRequest) (*pb.SearchStudentsResponse, error) {
+	if req.GetQ() == "" {
+		return nil, invalidArgument(fmt.Errorf("q is required"))
+	}
+	results := s.Service.SearchStudents(ctx, req.GetQ(), int(req.GetLimit()))
+
+	out := &pb.SearchStudentsResponse{Results: make([]*pb.SearchResult, len(results))}
+	for i, r := range results {
+		out.Results[i] = &pb.SearchResult{Student: studentToPB(r.Student), Score: r.Score, Highlights: r.Highlights}
+	}
+	return out, nil
+}
+
+func (s *studentServer) GetStudent(ctx context.Context, req *pb.GetStudentRequest) (*pb.Student, error) {
+	student, err := s.Service.GetStudent(ctx, req.GetStudentId())
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return studentToPB(student), nil
+}
+
+func (s *studentServer) CreateStudent(ctx context.Context, req *pb.CreateStudentRequest) (*pb.Student, error) {
+	student := studentFromPB(req.GetStudent())
+	if err := validateMessage(ctx, &student); err != nil {
+		return nil, err
+	}
+	created, err := s.Service.AddStudent(ctx, student)
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return studentToPB(created), nil
+}
+
+func (s *studentServer) UpdateStudent(ctx context.Context, req *pb.UpdateStudentRequest) (*pb.Student, error) {
+	student := studentFromPB(req.GetStudent())
+	if err := validateMessage(ctx, &student); err != nil {
+		return nil, err
+	}
+	updated, err := s.Service.UpdateStudent(ctx, req.GetStudentId(), student)
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return studentToPB(updated), nil
+}
+
+func (s *studentServer) DeleteStudent(ctx context.Context, req *pb.DeleteStudentRequest) (*emptypb.Empty, error) {
+	if err := s.Service.DeleteStudent(ctx, req.GetStudentId()); err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return &emptypb.Empty{}, nil
+}
@@ -0,0 +1,72 @@
+package Grpc
+
+import (
+	"GO_Assignment_3/internal/transport/Grpc/pb"
+	"context"
+
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+type termServer struct {
+	pb.UnimplementedTermServiceServer
+	*Server
+}
+
+func (s *termServer) ListTerms(ctx context.Context, _ *emptypb.Empty) (*pb.ListTermsResponse, error) {
+	terms, err := s.Service.GetAllTerms(ctx)
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	out := &pb.ListTermsResponse{Terms: make([]*pb.Term, len(terms))}
+	for i, t := range terms {
+		out.Terms[i] = termToPB(t)
+	}
+	return out, nil
+}
+
+func (s *termServer) GetCurrentTerm(ctx context.Context, _ *emptypb.Empty) (*pb.Term, error) {
+	term, err := s.Service.GetCurrentTerm(ctx)
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return termToPB(term), nil
+}
+
+func (s *termServer) GetTerm(ctx context.Context, req *pb.GetTermRequest) (*pb.Term, error) {
+	term, err := s.Service.GetTerm(ctx, req.GetTermId())
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return termToPB(term), nil
+}
+
+func (s *termServer) CreateTerm(ctx context.Context, req *pb.CreateTermRequest) (*pb.Term, error) {
+	term := termFromPB(req.GetTerm())
+	if err := validateMessage(ctx, &term); err != nil {
+		return nil, err
+	}
+	created, err := s.Service.AddTerm(ctx, term)
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return termToPB(created), nil
+}
+
+func (s *termServer) UpdateTerm(ctx context.Context, req *pb.UpdateTermRequest) (*pb.Term, error) {
+	term := termFromPB(req.GetTerm())
+	if err := validateMessage(ctx, &term); err != nil {
+		return nil, err
+	}
+	updated, err := s.Service.UpdateTerm(ctx, req.GetTermId(), term)
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return termToPB(updated), nil
+}
+
+func (s *termServer) DeleteTerm(ctx context.Context, req *pb.DeleteTermRequest) (*emptypb.Empty, error) {
+	if err := s.Service.DeleteTerm(ctx, req.GetTermId()); err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return &emptypb.Empty{}, nil
+}
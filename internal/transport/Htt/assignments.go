@@ -0,0 +1,294 @@
+package Htt
+
+import (
+	service "GO_Assignment_3/internal/service"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// parseAssignmentPath reads the course and assignment IDs from
+// /courses/{id}/assignments/{assignmentID}.
+func parseAssignmentPath(r *http.Request) (int32, int32, error) {
+	courseID, err := parseCourseID(r)
+	if err != nil {
+		return 0, 0, err
+	}
+	assignmentID, err := strconv.ParseInt(mux.Vars(r)["assignmentID"], 10, 32)
+	if err != nil {
+		return 0, 0, err
+	}
+	return courseID, int32(assignmentID), nil
+}
+
+// parseSubmissionPath reads the student and submission IDs from
+// /students/{id}/submissions/{submissionID}.
+func parseSubmissionPath(r *http.Request) (int32, int32, error) {
+	studentID, _, err := parseEnrollmentPath(r)
+	if err != nil {
+		return 0, 0, err
+	}
+	submissionID, err := strconv.ParseInt(mux.Vars(r)["submissionID"], 10, 32)
+	if err != nil {
+		return 0, 0, err
+	}
+	return studentID, int32(submissionID), nil
+}
+
+// GetAssignments - GET /courses/{id}/assignments[?term=current]
+func (h *Handler) GetAssignments(w http.ResponseWriter, r *http.Request) {
+	courseID, err := parseCourseID(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid course ID format")
+		return
+	}
+
+	assignments, err := h.Service.GetAssignments(r.Context(), courseID, r.URL.Query().Get("term"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": assignments})
+}
+
+func (h *Handler) GetAssignment(w http.ResponseWriter, r *http.Request) {
+	courseID, assignmentID, err := parseAssignmentPath(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid course or assignment ID format")
+		return
+	}
+
+	assignment, err := h.Service.GetAssignment(r.Context(), courseID, assignmentID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(assignment)
+}
+
+// CreateAssignment - POST /courses/{id}/assignments, for the admin and the
+// course's instructors.
+func (h *Handler) CreateAssignment(w http.ResponseWriter, r *http.Request) {
+	courseID, err := parseCourseID(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid course ID format")
+		return
+	}
+
+	var assignment service.Assignment
+	if !decodeRequest(w, r, &assignment) {
+		return
+	}
+
+	created, err := h.Service.AddAssignment(r.Context(), courseID, assignment)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+func (h *Handler) UpdateAssignment(w http.ResponseWriter, r *http.Request) {
+	courseID, assignmentID, err := parseAssignmentPath(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid course or assignment ID format")
+		return
+	}
+
+	var assignment service.Assignment
+	if !decodeRequest(w, r, &assignment) {
+		return
+	}
+
+	updated, err := h.Service.UpdateAssignment(r.Context(), courseID, assignmentID, assignment)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+func (h *Handler) DeleteAssignment(w http.ResponseWriter, r *http.Request) {
+	courseID, assignmentID, err := parseAssignmentPath(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid course or assignment ID format")
+		return
+	}
+
+	if err := h.Service.DeleteAssignment(r.Context(), courseID, assignmentID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) GetAssignmentSubmissions(w http.ResponseWriter, r *http.Request) {
+	courseID, assignmentID, err := parseAssignmentPath(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid course or assignment ID format")
+		return
+	}
+
+	submissions, err := h.Service.GetAssignmentSubmissions(r.Context(), courseID, assignmentID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": submissions})
+}
+
+func (h *Handler) GetStudentSubmissions(w http.ResponseWriter, r *http.Request) {
+	studentID, _, err := parseEnrollmentPath(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid student ID format")
+		return
+	}
+
+	submissions, err := h.Service.GetStudentSubmissions(r.Context(), studentID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": submissions})
+}
+
+// CreateSubmission - POST /students/{id}/submissions
+// A multipart form with the assignment_id field and the uploaded file in
+// the file field.
+func (h *Handler) CreateSubmission(w http.ResponseWriter, r *http.Request) {
+	studentID, _, err := parseEnrollmentPath(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid student ID format")
+		return
+	}
+
+	// Leave room for the other form fields on top of the file.
+	r.Body = http.MaxBytesReader(w, r.Body, service.MaxSubmissionSize+1<<20)
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeProblem(w, r, http.StatusRequestEntityTooLarge, fmt.Sprintf("Upload is larger than %d MB", service.MaxSubmissionSize>>20))
+			return
+		}
+		writeProblem(w, r, http.StatusBadRequest, "Expected a multipart/form-data upload")
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	assignmentID, err := strconv.ParseInt(r.FormValue("assignment_id"), 10, 32)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid assignment ID format")
+		return
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "The file field is required")
+		return
+	}
+	defer file.Close()
+
+	contentType := header.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	submission, err := h.Service.Submit(r.Context(), studentID, int32(assignmentID), service.Upload{
+		FileName:    header.Filename,
+		ContentType: contentType,
+		Size:        header.Size,
+		Body:        file,
+	})
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(submission)
+}
+
+func (h *Handler) GetSubmission(w http.ResponseWriter, r *http.Request) {
+	studentID, submissionID, err := parseSubmissionPath(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid student or submission ID format")
+		return
+	}
+
+	submission, err := h.Service.GetSubmission(r.Context(), studentID, submissionID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(submission)
+}
+
+// GetSubmissionFile - GET /students/{id}/submissions/{submissionID}/file
+// Downloads the uploaded file as it was submitted.
+func (h *Handler) GetSubmissionFile(w http.ResponseWriter, r *http.Request) {
+	studentID, submissionID, err := parseSubmissionPath(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid student or submission ID format")
+		return
+	}
+
+	submission, file, err := h.Service.OpenSubmissionFile(r.Context(), studentID, submissionID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", submission.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(submission.Size, 10))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", submission.FileName))
+	if _, err := io.Copy(w, file); err != nil {
+		logrus.Errorf("Failed to send file of submission %d: %v", submissionID, err)
+	}
+}
+
+// GradeSubmission - PUT /students/{id}/submissions/{submissionID}/grade
+// Records {"points": 8.5, "feedback": "..."}; admin and the course's
+// instructors only.
+func (h *Handler) GradeSubmission(w http.ResponseWriter, r *http.Request) {
+	studentID, submissionID, err := parseSubmissionPath(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid student or submission ID format")
+		return
+	}
+
+	var grade service.SubmissionGrade
+	if !decodeRequest(w, r, &grade) {
+		return
+	}
+
+	submission, err := h.Service.GradeSubmission(r.Context(), studentID, submissionID, grade)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(submission)
+}
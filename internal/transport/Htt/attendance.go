@@ -0,0 +1,215 @@
+package Htt
+
+import (
+	service "GO_Assignment_3/internal/service"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// parseSessionPath reads the course and session IDs from
+// /courses/{id}/sessions/{sessionID}.
+func parseSessionPath(r *http.Request) (int32, int32, error) {
+	courseID, err := parseCourseID(r)
+	if err != nil {
+		return 0, 0, err
+	}
+	sessionID, err := strconv.ParseInt(mux.Vars(r)["sessionID"], 10, 32)
+	if err != nil {
+		return 0, 0, err
+	}
+	return courseID, int32(sessionID), nil
+}
+
+// GetSessions - GET /courses/{id}/sessions[?term=current]
+func (h *Handler) GetSessions(w http.ResponseWriter, r *http.Request) {
+	courseID, err := parseCourseID(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid course ID format")
+		return
+	}
+
+	sessions, err := h.Service.GetSessions(r.Context(), courseID, r.URL.Query().Get("term"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": sessions})
+}
+
+func (h *Handler) GetSession(w http.ResponseWriter, r *http.Request) {
+	courseID, sessionID, err := parseSessionPath(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid course or session ID format")
+		return
+	}
+
+	session, err := h.Service.GetSession(r.Context(), courseID, sessionID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(session)
+}
+
+// CreateSession - POST /courses/{id}/sessions, for the admin and the
+// course's instructors.
+func (h *Handler) CreateSession(w http.ResponseWriter, r *http.Request) {
+	courseID, err := parseCourseID(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid course ID format")
+		return
+	}
+
+	var session service.ClassSession
+	if !decodeRequest(w, r, &session) {
+		return
+	}
+
+	created, err := h.Service.AddSession(r.Context(), courseID, session)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+func (h *Handler) UpdateSession(w http.ResponseWriter, r *http.Request) {
+	courseID, sessionID, err := parseSessionPath(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid course or session ID format")
+		return
+	}
+
+	var session service.ClassSession
+	if !decodeRequest(w, r, &session) {
+		return
+	}
+
+	updated, err := h.Service.UpdateSession(r.Context(), courseID, sessionID, session)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+func (h *Handler) DeleteSession(w http.ResponseWriter, r *http.Request) {
+	courseID, sessionID, err := parseSessionPath(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid course or session ID format")
+		return
+	}
+
+	if err := h.Service.DeleteSession(r.Context(), courseID, sessionID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) GetSessionAttendance(w http.ResponseWriter, r *http.Request) {
+	courseID, sessionID, err := parseSessionPath(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid course or session ID format")
+		return
+	}
+
+	marks, err := h.Service.GetSessionAttendance(r.Context(), courseID, sessionID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": marks})
+}
+
+// MarkAttendance - PUT /courses/{id}/sessions/{sessionID}/attendance
+// Marks a whole session at once, e.g. {"default": "present", "marks":
+// [{"student_id": 3, "mark": "absent"}]}.
+func (h *Handler) MarkAttendance(w http.ResponseWriter, r *http.Request) {
+	courseID, sessionID, err := parseSessionPath(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid course or session ID format")
+		return
+	}
+
+	var bulk service.BulkAttendance
+	if !decodeRequest(w, r, &bulk) {
+		return
+	}
+
+	marks, err := h.Service.MarkAttendance(r.Context(), courseID, sessionID, bulk)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": marks})
+}
+
+// GetStudentAttendance - GET /students/{id}/attendance[?term=current]
+func (h *Handler) GetStudentAttendance(w http.ResponseWriter, r *http.Request) {
+	studentID, _, err := parseEnrollmentPath(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid student ID format")
+		return
+	}
+
+	attendance, err := h.Service.GetStudentAttendance(r.Context(), studentID, r.URL.Query().Get("term"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(attendance)
+}
+
+// GetAttendanceReport - GET /attendance/report[?threshold=80&course=3&term=current]
+// Lists students whose attendance is below the threshold.
+func (h *Handler) GetAttendanceReport(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var threshold float64
+	if v := query.Get("threshold"); v != "" {
+		t, err := strconv.ParseFloat(v, 64)
+		if err != nil || t <= 0 || t > 100 {
+			writeProblem(w, r, http.StatusBadRequest, "threshold must be a percentage between 0 and 100")
+			return
+		}
+		threshold = t
+	}
+	var courseID int32
+	if v := query.Get("course"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 32)
+		if err != nil {
+			writeProblem(w, r, http.StatusBadRequest, "Invalid course ID format")
+			return
+		}
+		courseID = int32(id)
+	}
+
+	report, err := h.Service.GetAttendanceReport(r.Context(), courseID, query.Get("term"), threshold)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": report})
+}
@@ -0,0 +1,121 @@
+package Htt
+
+import (
+	service "GO_Assignment_3/internal/service"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+type auditResponse struct {
+	Data   []service.AuditEntry `json:"data"`
+	Limit  int                  `json:"limit"`
+	Offset int                  `json:"offset"`
+}
+
+// GetAuditEntries - GET /audit, admin only. Supports filtering on actor_id,
+// actor_role, action, target_id, outcome, request_id and a from/to time range,
+// paginated with limit and offset.
+func (h *Handler) GetAuditEntries(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAuditFilter(r.URL.Query())
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+
+	entries, err := h.Service.GetAuditEntries(r.Context(), filter)
+	if err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, "Error fetching audit entries")
+		return
+	}
+	if entries == nil {
+		entries = []service.AuditEntry{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(auditResponse{
+		Data:   entries,
+		Limit:  filter.Limit,
+		Offset: filter.Offset,
+	})
+}
+
+func parseAuditFilter(q url.Values) (service.AuditFilter, error) {
+	filter := service.AuditFilter{
+		ActorRole: q.Get("actor_role"),
+		Action:    q.Get("action"),
+		Outcome:   q.Get("outcome"),
+		RequestID: q.Get("request_id"),
+	}
+
+	var err error
+	if filter.ActorID, err = parseInt32Param(q, "actor_id"); err != nil {
+		return filter, err
+	}
+	if filter.TargetID, err = parseInt32Param(q, "target_id"); err != nil {
+		return filter, err
+	}
+	if filter.From, err = parseTimeParam(q, "from"); err != nil {
+		return filter, err
+	}
+	if filter.To, err = parseTimeParam(q, "to"); err != nil {
+		return filter, err
+	}
+	if filter.Limit, err = parseIntParam(q, "limit"); err != nil {
+		return filter, err
+	}
+	if filter.Offset, err = parseIntParam(q, "offset"); err != nil {
+		return filter, err
+	}
+	// A limit above MaxAuditLimit is clamped by the service.
+	if filter.Limit <= 0 {
+		filter.Limit = service.DefaultAuditLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	return filter, nil
+}
+
+func parseIntParam(q url.Values, name string) (int, error) {
+	v := q.Get(name)
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: must be an integer", name)
+	}
+	return n, nil
+}
+
+func parseInt32Param(q url.Values, name string) (int32, error) {
+	v := q.Get(name)
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(v, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: must be an integer", name)
+	}
+	return int32(n), nil
+}
+
+// parseTimeParam accepts either an RFC 3339 timestamp or a plain date.
+func parseTimeParam(q url.Values, name string) (time.Time, error) {
+	v := q.Get(name)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s: expected RFC 3339 timestamp or YYYY-MM-DD", name)
+	}
+	return t, nil
+}
@@ -1,6 +1,7 @@
 package Htt
 
 import (
+	service "GO_Assignment_3/internal/service"
 	"errors"
 	"fmt"
 	"os"
@@ -13,10 +14,13 @@ import (
 type JWTClaims struct {
 	UserID int32  `json:"user_id"`
 	Name   string `json:"name"`
+	// Role is "user", "instructor" or "guardian"; tokens issued before
+	// roles existed have none and are user tokens.
+	Role string `json:"role,omitempty"`
 	jwt.StandardClaims
 }
 
-func GenerateJWT(userID int32, name string) (string, error) {
+func GenerateJWT(userID int32, name string, role string) (string, error) {
 
 	jwtSecret := os.Getenv("JWT_SECRET")
 	if jwtSecret == "" {
@@ -27,6 +31,7 @@ func GenerateJWT(userID int32, name string) (string, error) {
 	claims := JWTClaims{
 		UserID: userID,
 		Name:   name,
+		Role:   role,
 		StandardClaims: jwt.StandardClaims{
 			ExpiresAt: time.Now().Add(time.Hour * 72).Unix(), // Token expires in 72 hours
 			IssuedAt:  time.Now().Unix(),
@@ -71,8 +76,15 @@ func AuthenticateJWT(tokenString string) (string, int32, error) {
 	}
 
 	if claims, ok := token.Claims.(*JWTClaims); ok && token.Valid {
-		log.Infof("User token authenticated, UserID: %d", claims.UserID)
-		return "user", claims.UserID, nil
+		role := claims.Role
+		if role == "" {
+			role = service.RoleUser
+		}
+		if role != service.RoleUser && role != service.RoleInstructor && role != service.RoleGuardian {
+			return "", 0, fmt.Errorf("invalid token role %q", role)
+		}
+		log.Infof("Token authenticated, role: %s, ID: %d", role, claims.UserID)
+		return role, claims.UserID, nil
 	} else {
 		log.Warn("Invalid JWT token")
 		return "", 0, errors.New("invalid token")
@@ -0,0 +1,117 @@
+package Htt
+
+import (
+	service "GO_Assignment_3/internal/service"
+	"encoding/json"
+	"net/http"
+)
+
+// maxBatchBody bounds the body of a batch, which is read whole before its
+// operations are counted.
+const maxBatchBody = 4 << 20
+
+// batchRequest is the body of POST /students/batch. The operations are
+// validated one by one, so that a bad one only fails itself.
+type batchRequest struct {
+	Atomic     bool                `json:"atomic"`
+	Operations []service.StudentOp `json:"operations" validate:"required,min=1"`
+}
+
+// batchResponse reports every operation of a batch, in request order.
+type batchResponse struct {
+	Atomic    bool          `json:"atomic"`
+	Succeeded int           `json:"succeeded"`
+	Failed    int           `json:"failed"`
+	Results   []batchResult `json:"results"`
+}
+
+// batchResult is the outcome of one operation: the status the single
+// request would have answered with, and its student, without the password,
+// or its problem.
+type batchResult struct {
+	Index   int              `json:"index"`
+	Op      string           `json:"op"`
+	Status  int              `json:"status"`
+	ID      int32            `json:"id,omitempty"`
+	Student *service.Student `json:"student,omitempty"`
+	Error   *Problem         `json:"error,omitempty"`
+}
+
+// batchStatus is the success status of each operation, as answered by
+// POST, PUT and DELETE /students.
+var batchStatus = map[string]int{
+	service.BatchCreate: http.StatusCreated,
+	service.BatchUpdate: http.StatusOK,
+	service.BatchDelete: http.StatusNoContent,
+}
+
+// BatchStudents - POST /students/batch
+// Creates, updates and deletes up to service.MaxBatchSize students with
+// one request. Each operation gets its own result; the response is 200 even
+// when some failed. With "atomic": true the batch runs in one transaction
+// and either every operation succeeds or none is applied.
+func (h *Handler) BatchStudents(w http.ResponseWriter, r *http.Request) {
+	var req batchRequest
+	r.Body = http.MaxBytesReader(w, r.Body, maxBatchBody)
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+	if len(req.Operations) > service.MaxBatchSize {
+		writeError(w, r, service.ErrBatchTooLarge)
+		return
+	}
+
+	acceptLanguage := r.Header.Get("Accept-Language")
+	errs := make([]error, len(req.Operations))
+	var valid []service.StudentOp
+	var positions []int
+	for i, op := range req.Operations {
+		if errs[i] = Validate(op, acceptLanguage); errs[i] == nil {
+			valid = append(valid, op)
+			positions = append(positions, i)
+		}
+	}
+
+	ran := make([]*service.StudentOpResult, len(req.Operations))
+	if req.Atomic && len(valid) < len(req.Operations) {
+		// An invalid operation aborts an atomic batch before it runs.
+		for _, i := range positions {
+			errs[i] = service.ErrBatchAborted
+		}
+	} else {
+		results, err := h.Service.ApplyStudentBatch(r.Context(), valid, req.Atomic)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		for n, i := range positions {
+			ran[i] = &results[n]
+		}
+	}
+
+	response := batchResponse{Atomic: req.Atomic, Results: make([]batchResult, len(req.Operations))}
+	for i, op := range req.Operations {
+		result := batchResult{Index: i, Op: op.Op, ID: op.ID}
+		err := errs[i]
+		if ran[i] != nil {
+			err = ran[i].Err
+		}
+		if err != nil {
+			result.Error = itemProblem(r, err)
+			result.Status = result.Error.Status
+			response.Failed++
+		} else {
+			result.Status = batchStatus[op.Op]
+			if op.Op != service.BatchDelete {
+				student := ran[i].Student
+				student.Password = ""
+				result.ID, result.Student = student.ID, &student
+			}
+			response.Succeeded++
+		}
+		response.Results[i] = result
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
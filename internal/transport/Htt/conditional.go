@@ -0,0 +1,45 @@
+package Htt
+
+import (
+	service "GO_Assignment_3/internal/service"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// studentETag is the entity tag of a stored student: a hash of its JSON
+// form, so that any change to the record changes the tag.
+func studentETag(s service.Student) string {
+	b, _ := json.Marshal(s)
+	sum := sha256.Sum256(b)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// withStudentIfMatch makes a write to a student conditional on the If-Match
+// header. The tags are compared by the store, against the student as locked
+// by the write, so that nothing can change it in between; an outdated tag
+// fails the write with ErrPreconditionFailed. Without the header the write
+// goes ahead unconditionally.
+func withStudentIfMatch(r *http.Request) context.Context {
+	ifMatch := strings.TrimSpace(r.Header.Get("If-Match"))
+	if ifMatch == "" {
+		return r.Context()
+	}
+	match := service.Precondition(func(current service.Student) error {
+		if ifMatch == "*" {
+			return nil
+		}
+		etag := studentETag(current)
+		for _, tag := range strings.Split(ifMatch, ",") {
+			// If-Match uses the strong comparison, so weak tags never match.
+			if strings.TrimSpace(tag) == etag {
+				return nil
+			}
+		}
+		return service.ErrPreconditionFailed
+	})
+	return context.WithValue(r.Context(), "precondition", match)
+}
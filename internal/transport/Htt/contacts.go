@@ -0,0 +1,276 @@
+package Htt
+
+import (
+	service "GO_Assignment_3/internal/service"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// guardianPaths are the only routes a guardian token may reach, with the
+// method it may use. GraphQL checks guardians field by field.
+var guardianPaths = map[string]string{
+	"/students/{id}/contacts":             http.MethodGet,
+	"/students/{id}/contacts/{contactID}": http.MethodGet,
+	"/guardians/{id}/students":            http.MethodGet,
+	"/graphql":                            http.MethodPost,
+}
+
+// versionPrefix matches the prefix of a versioned route template, such as
+// /v1/.
+var versionPrefix = regexp.MustCompile(`^/v[0-9]+/`)
+
+// guardianAllowed reports whether a guardian may call the matched route,
+// under any version prefix.
+func guardianAllowed(r *http.Request) bool {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return false
+	}
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return false
+	}
+	return guardianPaths[versionPrefix.ReplaceAllString(template, "/")] == r.Method
+}
+
+// parseContactPath reads the student and contact IDs from
+// /students/{id}/contacts/{contactID}.
+func parseContactPath(r *http.Request) (int32, int32, error) {
+	studentID, _, err := parseEnrollmentPath(r)
+	if err != nil {
+		return 0, 0, err
+	}
+	contactID, err := strconv.ParseInt(mux.Vars(r)["contactID"], 10, 32)
+	if err != nil {
+		return 0, 0, err
+	}
+	return studentID, int32(contactID), nil
+}
+
+func (h *Handler) GetStudentContacts(w http.ResponseWriter, r *http.Request) {
+	studentID, _, err := parseEnrollmentPath(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid student ID format")
+		return
+	}
+
+	contacts, err := h.Service.GetStudentContacts(r.Context(), studentID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": contacts})
+}
+
+func (h *Handler) GetContact(w http.ResponseWriter, r *http.Request) {
+	studentID, contactID, err := parseContactPath(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid student or contact ID format")
+		return
+	}
+
+	contact, err := h.Service.GetContact(r.Context(), studentID, contactID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(contact)
+}
+
+func (h *Handler) CreateContact(w http.ResponseWriter, r *http.Request) {
+	studentID, _, err := parseEnrollmentPath(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid student ID format")
+		return
+	}
+
+	var contact service.Contact
+	if !decodeRequest(w, r, &contact) {
+		return
+	}
+
+	created, err := h.Service.AddContact(r.Context(), studentID, contact)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+func (h *Handler) UpdateContact(w http.ResponseWriter, r *http.Request) {
+	studentID, contactID, err := parseContactPath(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid student or contact ID format")
+		return
+	}
+
+	var contact service.Contact
+	if !decodeRequest(w, r, &contact) {
+		return
+	}
+
+	updated, err := h.Service.UpdateContact(r.Context(), studentID, contactID, contact)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+func (h *Handler) DeleteContact(w http.ResponseWriter, r *http.Request) {
+	studentID, contactID, err := parseContactPath(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid student or contact ID format")
+		return
+	}
+
+	if err := h.Service.DeleteContact(r.Context(), studentID, contactID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GuardianLogin - POST /guardians/login, exchanging an email and password
+// for a guardian token.
+func (h *Handler) GuardianLogin(w http.ResponseWriter, r *http.Request) {
+	var credentials loginRequest
+	if !decodeRequest(w, r, &credentials) {
+		return
+	}
+
+	guardian, err := h.Service.AuthenticateGuardian(r.Context(), credentials.Email, credentials.Password)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	token, err := GenerateJWT(guardian.ID, guardian.Name, service.RoleGuardian)
+	if err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, "Error generating token")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokenResponse{Token: token})
+}
+
+func parseGuardianID(r *http.Request) (int32, error) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 32)
+	return int32(id), err
+}
+
+func (h *Handler) GetAllGuardians(w http.ResponseWriter, r *http.Request) {
+	guardians, err := h.Service.GetAllGuardians(r.Context())
+	if err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, "Error fetching guardians")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": guardians})
+}
+
+func (h *Handler) GetGuardian(w http.ResponseWriter, r *http.Request) {
+	id, err := parseGuardianID(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid guardian ID format")
+		return
+	}
+
+	guardian, err := h.Service.GetGuardian(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(guardian)
+}
+
+func (h *Handler) CreateGuardian(w http.ResponseWriter, r *http.Request) {
+	var guardian service.Guardian
+	if !decodeRequest(w, r, &guardian) {
+		return
+	}
+
+	created, err := h.Service.AddGuardian(r.Context(), guardian)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+func (h *Handler) UpdateGuardian(w http.ResponseWriter, r *http.Request) {
+	id, err := parseGuardianID(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid guardian ID format")
+		return
+	}
+
+	var guardian service.Guardian
+	if !decodeRequest(w, r, &guardian) {
+		return
+	}
+
+	updated, err := h.Service.UpdateGuardian(r.Context(), id, guardian)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+func (h *Handler) DeleteGuardian(w http.ResponseWriter, r *http.Request) {
+	id, err := parseGuardianID(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid guardian ID format")
+		return
+	}
+
+	if err := h.Service.DeleteGuardian(r.Context(), id); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetGuardianStudents - GET /guardians/{id}/students, for the admin and the
+// guardian themselves.
+func (h *Handler) GetGuardianStudents(w http.ResponseWriter, r *http.Request) {
+	id, err := parseGuardianID(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid guardian ID format")
+		return
+	}
+
+	students, err := h.Service.GetGuardianStudents(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": students})
+}
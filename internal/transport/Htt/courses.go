@@ -0,0 +1,97 @@
+package Htt
+
+import (
+	service "GO_Assignment_3/internal/service"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+func parseCourseID(r *http.Request) (int32, error) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 32)
+	return int32(id), err
+}
+
+func (h *Handler) GetAllCourses(w http.ResponseWriter, r *http.Request) {
+	courses, err := h.Service.GetAllCourses(r.Context())
+	if err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, "Error fetching courses")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": courses})
+}
+
+func (h *Handler) GetCourse(w http.ResponseWriter, r *http.Request) {
+	id, err := parseCourseID(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid course ID format")
+		return
+	}
+
+	course, err := h.Service.GetCourse(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(course)
+}
+
+func (h *Handler) CreateCourse(w http.ResponseWriter, r *http.Request) {
+	var course service.Course
+	if !decodeRequest(w, r, &course) {
+		return
+	}
+
+	created, err := h.Service.AddCourse(r.Context(), course)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+func (h *Handler) UpdateCourse(w http.ResponseWriter, r *http.Request) {
+	id, err := parseCourseID(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid course ID format")
+		return
+	}
+
+	var course service.Course
+	if !decodeRequest(w, r, &course) {
+		return
+	}
+
+	updated, err := h.Service.UpdateCourse(r.Context(), id, course)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+func (h *Handler) DeleteCourse(w http.ResponseWriter, r *http.Request) {
+	id, err := parseCourseID(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid course ID format")
+		return
+	}
+
+	if err := h.Service.DeleteCourse(r.Context(), id); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,158 @@
+package Htt
+
+import (
+	service "GO_Assignment_3/internal/service"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// parseEnrollmentPath reads the student ID and, when present, the
+// enrollment ID from /students/{id}/enrollments/{enrollmentID}.
+func parseEnrollmentPath(r *http.Request) (int32, int32, error) {
+	vars := mux.Vars(r)
+	studentID, err := strconv.ParseInt(vars["id"], 10, 32)
+	if err != nil {
+		return 0, 0, err
+	}
+	if vars["enrollmentID"] == "" {
+		return int32(studentID), 0, nil
+	}
+	enrollmentID, err := strconv.ParseInt(vars["enrollmentID"], 10, 32)
+	if err != nil {
+		return 0, 0, err
+	}
+	return int32(studentID), int32(enrollmentID), nil
+}
+
+func (h *Handler) GetStudentEnrollments(w http.ResponseWriter, r *http.Request) {
+	studentID, _, err := parseEnrollmentPath(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid student ID format")
+		return
+	}
+
+	enrollments, err := h.Service.GetStudentEnrollments(r.Context(), studentID, r.URL.Query().Get("term"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": enrollments})
+}
+
+func (h *Handler) GetEnrollment(w http.ResponseWriter, r *http.Request) {
+	studentID, enrollmentID, err := parseEnrollmentPath(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid ID format")
+		return
+	}
+
+	enrollment, err := h.Service.GetEnrollment(r.Context(), studentID, enrollmentID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(enrollment)
+}
+
+func (h *Handler) CreateEnrollment(w http.ResponseWriter, r *http.Request) {
+	studentID, _, err := parseEnrollmentPath(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid student ID format")
+		return
+	}
+
+	var enrollment service.Enrollment
+	if !decodeRequest(w, r, &enrollment) {
+		return
+	}
+
+	created, err := h.Service.Enroll(r.Context(), studentID, enrollment)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+func (h *Handler) UpdateEnrollment(w http.ResponseWriter, r *http.Request) {
+	studentID, enrollmentID, err := parseEnrollmentPath(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid ID format")
+		return
+	}
+
+	var enrollment service.Enrollment
+	if !decodeRequest(w, r, &enrollment) {
+		return
+	}
+
+	updated, err := h.Service.UpdateEnrollment(r.Context(), studentID, enrollmentID, enrollment)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+func (h *Handler) DeleteEnrollment(w http.ResponseWriter, r *http.Request) {
+	studentID, enrollmentID, err := parseEnrollmentPath(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid ID format")
+		return
+	}
+
+	if err := h.Service.DeleteEnrollment(r.Context(), studentID, enrollmentID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetCourseStudents - GET /courses/{id}/students, the course roster.
+func (h *Handler) GetCourseStudents(w http.ResponseWriter, r *http.Request) {
+	courseID, err := parseCourseID(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid course ID format")
+		return
+	}
+
+	roster, err := h.Service.GetCourseRoster(r.Context(), courseID, r.URL.Query().Get("term"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": roster})
+}
+
+// GetCourseWaitlist - GET /courses/{id}/waitlist, in promotion order.
+func (h *Handler) GetCourseWaitlist(w http.ResponseWriter, r *http.Request) {
+	courseID, err := parseCourseID(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid course ID format")
+		return
+	}
+
+	waitlist, err := h.Service.GetCourseWaitlist(r.Context(), courseID, r.URL.Query().Get("term"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": waitlist})
+}
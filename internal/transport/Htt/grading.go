@@ -0,0 +1,115 @@
+package Htt
+
+import (
+	service "GO_Assignment_3/internal/service"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+func parseScaleID(r *http.Request) (int32, error) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 32)
+	return int32(id), err
+}
+
+func (h *Handler) GetAllGradingScales(w http.ResponseWriter, r *http.Request) {
+	scales, err := h.Service.GetAllGradingScales(r.Context())
+	if err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, "Error fetching grading scales")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": scales})
+}
+
+func (h *Handler) GetGradingScale(w http.ResponseWriter, r *http.Request) {
+	id, err := parseScaleID(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid grading scale ID format")
+		return
+	}
+
+	scale, err := h.Service.GetGradingScale(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(scale)
+}
+
+func (h *Handler) CreateGradingScale(w http.ResponseWriter, r *http.Request) {
+	var scale service.GradingScale
+	if !decodeRequest(w, r, &scale) {
+		return
+	}
+
+	created, err := h.Service.AddGradingScale(r.Context(), scale)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+func (h *Handler) UpdateGradingScale(w http.ResponseWriter, r *http.Request) {
+	id, err := parseScaleID(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid grading scale ID format")
+		return
+	}
+
+	var scale service.GradingScale
+	if !decodeRequest(w, r, &scale) {
+		return
+	}
+
+	updated, err := h.Service.UpdateGradingScale(r.Context(), id, scale)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+func (h *Handler) DeleteGradingScale(w http.ResponseWriter, r *http.Request) {
+	id, err := parseScaleID(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid grading scale ID format")
+		return
+	}
+
+	if err := h.Service.DeleteGradingScale(r.Context(), id); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetStudentGPA - GET /students/{id}/gpa
+func (h *Handler) GetStudentGPA(w http.ResponseWriter, r *http.Request) {
+	studentID, _, err := parseEnrollmentPath(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid student ID format")
+		return
+	}
+
+	gpa, err := h.Service.GetStudentGPA(r.Context(), studentID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(gpa)
+}
@@ -0,0 +1,245 @@
+package Htt
+
+import (
+	service "GO_Assignment_3/internal/service"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// graphqlSchema is the GraphQL view of the API. It only offers what the
+// REST routes offer, and its resolvers apply the same rules.
+//
+// Fields that can be refused or fail are nullable, so that an error only
+// nulls the field it belongs to rather than the whole response.
+const graphqlSchema = `
+schema {
+	query: Query
+	mutation: Mutation
+}
+
+type Query {
+	student(id: Int!): Student
+	# students is a page of GET /students; filter, cursor and sort work the same.
+	students(filter: String, limit: Int, cursor: String, sort: String): StudentPage
+	searchStudents(q: String!, limit: Int): [SearchResult!]
+	course(id: Int!): Course
+	courses: [Course!]
+	term(id: Int!): Term
+	terms: [Term!]
+	currentTerm: Term
+	# guardianStudents is open to the admin and to the guardian themselves.
+	guardianStudents(guardianId: Int!): [Student!]
+}
+
+type Mutation {
+	createStudent(input: StudentInput!): Student
+	updateStudent(id: Int!, input: StudentInput!): Student
+	deleteStudent(id: Int!): Boolean
+	# enroll puts the student on the waitlist when the course is full.
+	enroll(studentId: Int!, input: EnrollmentInput!): Enrollment
+}
+
+type StudentPage {
+	students: [Student!]!
+	nextCursor: String
+}
+
+type Student {
+	id: Int!
+	name: String!
+	course: String!
+	courseId: Int!
+	grade: String!
+	createdBy: String!
+	createdOn: String!
+	updatedBy: String!
+	updatedOn: String!
+	# courseDetails is the catalog entry of courseId, if the student has one.
+	courseDetails: Course
+	# term is current, a term code or an ID; every term when left out.
+	enrollments(term: String): [Enrollment!]
+	# contacts are visible to the student, staff and linked guardians.
+	contacts: [Contact!]
+}
+
+type SearchResult {
+	student: Student!
+	score: Float!
+	highlights: [Highlight!]!
+}
+
+type Highlight {
+	field: String!
+	text: String!
+}
+
+type Course {
+	id: Int!
+	code: String!
+	title: String!
+	credits: Float!
+	capacity: Int!
+	department: String!
+	gradingScaleId: Int!
+	createdBy: String!
+	createdOn: String!
+	updatedBy: String!
+	updatedOn: String!
+}
+
+type Enrollment {
+	id: Int!
+	studentId: Int!
+	courseId: Int!
+	courseCode: String!
+	courseTitle: String!
+	credits: Float!
+	termId: Int!
+	termCode: String!
+	grade: String!
+	status: String!
+	waitlistPosition: Int!
+	createdBy: String!
+	createdOn: String!
+	updatedBy: String!
+	updatedOn: String!
+	course: Course
+}
+
+type Term {
+	id: Int!
+	code: String!
+	name: String!
+	startDate: String!
+	endDate: String!
+	isCurrent: Boolean!
+}
+
+type Contact {
+	id: Int!
+	studentId: Int!
+	name: String!
+	relationship: String!
+	phone: String!
+	email: String!
+	isPrimary: Boolean!
+	isEmergency: Boolean!
+	guardianId: Int!
+}
+
+input StudentInput {
+	password: String
+	name: String!
+	course: String
+	courseId: Int
+	grade: String
+}
+
+input EnrollmentInput {
+	courseId: Int
+	course: String
+	termId: Int
+	term: String
+	grade: String
+	status: String
+}
+`
+
+// graphqlMaxDepth bounds how deeply queries may nest.
+const graphqlMaxDepth = 8
+
+// graphqlParallelism is how many resolvers run at once. It matches the
+// largest page so that a nested field of every student on a page waits
+// for the same batch of its loader.
+const graphqlParallelism = service.MaxPageLimit
+
+// graphqlRequest is the body of POST /graphql.
+type graphqlRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// graphqlResponse documents the response of POST /graphql.
+type graphqlResponse struct {
+	Data   map[string]interface{} `json:"data"`
+	Errors []graphqlError         `json:"errors,omitempty"`
+}
+
+type graphqlError struct {
+	Message    string                 `json:"message"`
+	Path       []interface{}          `json:"path,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+func (h *Handler) schema() *graphql.Schema {
+	h.graphqlOnce.Do(func() {
+		h.graphql = graphql.MustParseSchema(graphqlSchema, &graphqlRoot{h: h},
+			graphql.UseFieldResolvers(),
+			graphql.MaxDepth(graphqlMaxDepth),
+			graphql.MaxParallelism(graphqlParallelism),
+		)
+	})
+	return h.graphql
+}
+
+// GraphQL - POST /graphql
+// Answers 200 with data and errors as GraphQL does; only a body that is not
+// a GraphQL request gets a problem. Each request has its own loaders, so
+// nested fields are fetched in batches and never across callers.
+func (h *Handler) GraphQL(w http.ResponseWriter, r *http.Request) {
+	var req graphqlRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Query == "" {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	ctx := newGraphQLContext(r.Context(), h.Service, r.Header.Get("Accept-Language"))
+	response := h.schema().Exec(ctx, req.Query, req.OperationName, req.Variables)
+	for _, qe := range response.Errors {
+		graphqlExtensions(r, qe)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// badArgument is an argument the resolver could not make sense of. It is
+// reported like writeBadRequest reports a bad query parameter.
+type badArgument struct {
+	error
+}
+
+func (b badArgument) Unwrap() error {
+	return b.error
+}
+
+// graphqlExtensions gives resolver errors the status and code of the REST
+// problem for the same error, so clients can branch on them alike. The
+// text of internal errors is logged but not sent.
+func graphqlExtensions(r *http.Request, qe *gqlerrors.QueryError) {
+	if qe.ResolverError == nil {
+		return
+	}
+	var invalid ValidationErrors
+	if errors.As(qe.ResolverError, &invalid) {
+		qe.Message = "The input failed validation"
+		qe.Extensions = map[string]interface{}{"status": http.StatusBadRequest, "code": "validation_failed", "errors": invalid}
+		return
+	}
+	status, code := ProblemFor(qe.ResolverError)
+	var bad badArgument
+	if errors.As(qe.ResolverError, &bad) && status != http.StatusBadRequest {
+		status, code = http.StatusBadRequest, statusCodes[http.StatusBadRequest]
+	}
+	if status == http.StatusInternalServerError {
+		logrus.Errorf("GraphQL field %v of request %v failed: %v", qe.Path, r.Context().Value("requestID"), qe.ResolverError)
+		qe.Message = "An internal error occurred"
+	}
+	qe.Extensions = map[string]interface{}{"status": status, "code": code}
+}
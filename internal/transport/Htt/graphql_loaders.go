@@ -0,0 +1,111 @@
+package Htt
+
+import (
+	service "GO_Assignment_3/internal/service"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/graph-gophers/dataloader/v7"
+)
+
+// graphqlBatchWait is how long a loader collects keys before it asks the
+// service for all of them at once.
+const graphqlBatchWait = 2 * time.Millisecond
+
+// graphqlContext is what the resolvers of one GraphQL request share: the
+// language of validation messages and the loaders of nested fields.
+type graphqlContext struct {
+	acceptLanguage string
+	courses        *dataloader.Loader[int32, *service.Course]
+	enrollments    *dataloader.Loader[enrollmentsKey, []service.Enrollment]
+	contacts       *dataloader.Loader[int32, []service.Contact]
+}
+
+// enrollmentsKey asks for the enrollments of a student in a term.
+type enrollmentsKey struct {
+	studentID int32
+	term      string
+}
+
+func newGraphQLContext(ctx context.Context, svc *service.Service, acceptLanguage string) context.Context {
+	gc := &graphqlContext{
+		acceptLanguage: acceptLanguage,
+		courses: dataloader.NewBatchedLoader(loadCourses(svc),
+			dataloader.WithWait[int32, *service.Course](graphqlBatchWait)),
+		enrollments: dataloader.NewBatchedLoader(loadEnrollments(svc),
+			dataloader.WithWait[enrollmentsKey, []service.Enrollment](graphqlBatchWait)),
+		contacts: dataloader.NewBatchedLoader(loadContacts(svc),
+			dataloader.WithWait[int32, []service.Contact](graphqlBatchWait)),
+	}
+	return context.WithValue(ctx, "graphql", gc)
+}
+
+func graphqlFrom(ctx context.Context) *graphqlContext {
+	gc, _ := ctx.Value("graphql").(*graphqlContext)
+	return gc
+}
+
+// loadCourses fetches courses by ID; an ID without a course loads as nil.
+func loadCourses(svc *service.Service) dataloader.BatchFunc[int32, *service.Course] {
+	return func(ctx context.Context, ids []int32) []*dataloader.Result[*service.Course] {
+		results := make([]*dataloader.Result[*service.Course], len(ids))
+		courses, err := svc.GetCoursesByIDs(ctx, ids)
+		for i, id := range ids {
+			results[i] = &dataloader.Result[*service.Course]{Error: err}
+			if c, ok := courses[id]; ok {
+				results[i].Data = &c
+			}
+		}
+		return results
+	}
+}
+
+// loadEnrollments fetches enrollments with one call per term asked for.
+// Students whose records the caller may not read load as forbidden.
+func loadEnrollments(svc *service.Service) dataloader.BatchFunc[enrollmentsKey, []service.Enrollment] {
+	return func(ctx context.Context, keys []enrollmentsKey) []*dataloader.Result[[]service.Enrollment] {
+		byTerm := map[string][]int32{}
+		for _, k := range keys {
+			byTerm[k.term] = append(byTerm[k.term], k.studentID)
+		}
+		loaded := map[enrollmentsKey]*dataloader.Result[[]service.Enrollment]{}
+		for term, ids := range byTerm {
+			enrollments, err := svc.GetEnrollmentsOfStudents(ctx, ids, term)
+			for _, id := range ids {
+				result := &dataloader.Result[[]service.Enrollment]{Data: enrollments[id], Error: err}
+				if _, ok := enrollments[id]; !ok && err == nil {
+					result.Error = fmt.Errorf("%w: students can only read their own records", service.ErrForbidden)
+				}
+				loaded[enrollmentsKey{id, term}] = result
+			}
+		}
+		results := make([]*dataloader.Result[[]service.Enrollment], len(keys))
+		for i, k := range keys {
+			results[i] = loaded[k]
+		}
+		return results
+	}
+}
+
+// loadContacts fetches contacts by student ID. The service leaves out the
+// students whose contacts the caller may not read, which load as
+// forbidden.
+func loadContacts(svc *service.Service) dataloader.BatchFunc[int32, []service.Contact] {
+	return func(ctx context.Context, ids []int32) []*dataloader.Result[[]service.Contact] {
+		results := make([]*dataloader.Result[[]service.Contact], len(ids))
+		contacts, err := svc.GetContactsOfStudents(ctx, ids)
+		for i, id := range ids {
+			results[i] = &dataloader.Result[[]service.Contact]{Error: err}
+			if err != nil {
+				continue
+			}
+			c, ok := contacts[id]
+			if !ok {
+				results[i].Error = fmt.Errorf("%w: contacts are only visible to the student, their instructors, the admin and linked guardians", service.ErrForbidden)
+			}
+			results[i].Data = c
+		}
+		return results
+	}
+}
@@ -0,0 +1,435 @@
+package Htt
+
+import (
+	service "GO_Assignment_3/internal/service"
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+)
+
+// The resolvers of the GraphQL schema. Scalar fields are read from the
+// embedded service types; methods add the nested objects.
+//
+// Authorization follows the REST routes. Every query field needs a
+// non-guardian token, as the routes behind TokenAuthMiddleware do, except
+// guardianStudents and Student.contacts, which the service checks like
+// guardianPaths. Mutations follow JWTAuthMiddleware: a user may only write
+// their own student record.
+
+type graphqlRoot struct {
+	h *Handler
+}
+
+var errGraphQLForbidden = fmt.Errorf("%w: not available to this token", service.ErrForbidden)
+
+// denyGuardian refuses guardian tokens the fields they cannot reach over
+// REST.
+func (h *Handler) denyGuardian(ctx context.Context, field string) error {
+	if userType, _ := ctx.Value("userType").(string); userType == service.RoleGuardian {
+		h.auditAuth(ctx, service.AuditAuthDenied, 0, "guardian token used for GraphQL field "+field)
+		return errGraphQLForbidden
+	}
+	return nil
+}
+
+// checkOwner lets a user write only their own student record. The admin
+// passes, and instructors are scoped by the service.
+func (h *Handler) checkOwner(ctx context.Context, studentID int32, field string) error {
+	userType, _ := ctx.Value("userType").(string)
+	userID, _ := ctx.Value("userID").(int32)
+	switch userType {
+	case service.RoleAdmin, service.RoleInstructor:
+		return nil
+	case service.RoleUser:
+		if studentID != 0 && studentID == userID {
+			return nil
+		}
+	}
+	h.auditAuth(ctx, service.AuditAuthDenied, studentID, "user may only modify their own record ("+field+")")
+	return errGraphQLForbidden
+}
+
+// Queries
+
+func (q *graphqlRoot) Student(ctx context.Context, args struct{ ID int32 }) (*studentResolver, error) {
+	if err := q.h.denyGuardian(ctx, "student"); err != nil {
+		return nil, err
+	}
+	student, err := q.h.Service.GetStudent(ctx, args.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &studentResolver{student}, nil
+}
+
+type studentsArgs struct {
+	Filter *string
+	Limit  *int32
+	Cursor *string
+	Sort   *string
+}
+
+func (q *graphqlRoot) Students(ctx context.Context, args studentsArgs) (*studentPageResolver, error) {
+	if err := q.h.denyGuardian(ctx, "students"); err != nil {
+		return nil, err
+	}
+	// The arguments are read like the query parameters of GET /students.
+	params := url.Values{}
+	for name, value := range map[string]*string{"filter": args.Filter, "cursor": args.Cursor, "sort": args.Sort} {
+		if value != nil {
+			params.Set(name, *value)
+		}
+	}
+	if args.Limit != nil {
+		params.Set("limit", strconv.Itoa(int(*args.Limit)))
+	}
+	opts, err := ParseListOptions(params)
+	if err != nil {
+		return nil, badArgument{err}
+	}
+	page, err := q.h.Service.GetAllStudents(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &studentPageResolver{page}, nil
+}
+
+func (q *graphqlRoot) SearchStudents(ctx context.Context, args struct {
+	Q     string
+	Limit *int32
+}) (*[]*searchResultResolver, error) {
+	if err := q.h.denyGuardian(ctx, "searchStudents"); err != nil {
+		return nil, err
+	}
+	limit := 0
+	if args.Limit != nil {
+		limit = int(*args.Limit)
+	}
+	results := q.h.Service.SearchStudents(ctx, args.Q, limit)
+	out := make([]*searchResultResolver, len(results))
+	for i, r := range results {
+		out[i] = &searchResultResolver{r}
+	}
+	return &out, nil
+}
+
+func (q *graphqlRoot) Course(ctx context.Context, args struct{ ID int32 }) (*courseResolver, error) {
+	if err := q.h.denyGuardian(ctx, "course"); err != nil {
+		return nil, err
+	}
+	course, err := q.h.Service.GetCourse(ctx, args.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &courseResolver{course}, nil
+}
+
+func (q *graphqlRoot) Courses(ctx context.Context) (*[]*courseResolver, error) {
+	if err := q.h.denyGuardian(ctx, "courses"); err != nil {
+		return nil, err
+	}
+	courses, err := q.h.Service.GetAllCourses(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*courseResolver, len(courses))
+	for i, c := range courses {
+		out[i] = &courseResolver{c}
+	}
+	return &out, nil
+}
+
+func (q *graphqlRoot) Term(ctx context.Context, args struct{ ID int32 }) (*termResolver, error) {
+	if err := q.h.denyGuardian(ctx, "term"); err != nil {
+		return nil, err
+	}
+	term, err := q.h.Service.GetTerm(ctx, args.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &termResolver{term}, nil
+}
+
+func (q *graphqlRoot) Terms(ctx context.Context) (*[]*termResolver, error) {
+	if err := q.h.denyGuardian(ctx, "terms"); err != nil {
+		return nil, err
+	}
+	terms, err := q.h.Service.GetAllTerms(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*termResolver, len(terms))
+	for i, t := range terms {
+		out[i] = &termResolver{t}
+	}
+	return &out, nil
+}
+
+func (q *graphqlRoot) CurrentTerm(ctx context.Context) (*termResolver, error) {
+	if err := q.h.denyGuardian(ctx, "currentTerm"); err != nil {
+		return nil, err
+	}
+	term, err := q.h.Service.GetCurrentTerm(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &termResolver{term}, nil
+}
+
+func (q *graphqlRoot) GuardianStudents(ctx context.Context, args struct{ GuardianID int32 }) (*[]*studentResolver, error) {
+	students, err := q.h.Service.GetGuardianStudents(ctx, args.GuardianID)
+	if err != nil {
+		return nil, err
+	}
+	out := studentResolvers(students)
+	return &out, nil
+}
+
+// Mutations
+
+type studentInput struct {
+	Password *string
+	Name     string
+	Course   *string
+	CourseID *int32
+	Grade    *string
+}
+
+func (in studentInput) student() service.Student {
+	s := service.Student{Name: in.Name}
+	if in.Password != nil {
+		s.Password = *in.Password
+	}
+	if in.Course != nil {
+		s.Course = *in.Course
+	}
+	if in.CourseID != nil {
+		s.CourseID = *in.CourseID
+	}
+	if in.Grade != nil {
+		s.Grade = *in.Grade
+	}
+	return s
+}
+
+type enrollmentInput struct {
+	CourseID *int32
+	Course   *string
+	TermID   *int32
+	Term     *string
+	Grade    *string
+	Status   *string
+}
+
+func (in enrollmentInput) enrollment() service.Enrollment {
+	var e service.Enrollment
+	if in.CourseID != nil {
+		e.CourseID = *in.CourseID
+	}
+	if in.Course != nil {
+		e.Course = *in.Course
+	}
+	if in.TermID != nil {
+		e.TermID = *in.TermID
+	}
+	if in.Term != nil {
+		e.Term = *in.Term
+	}
+	if in.Grade != nil {
+		e.Grade = *in.Grade
+	}
+	if in.Status != nil {
+		e.Status = *in.Status
+	}
+	return e
+}
+
+func (q *graphqlRoot) CreateStudent(ctx context.Context, args struct{ Input studentInput }) (*studentResolver, error) {
+	// POST /students names no student, so only staff get past the owner
+	// check.
+	if err := q.h.checkOwner(ctx, 0, "createStudent"); err != nil {
+		return nil, err
+	}
+	student := args.Input.student()
+	if err := Validate(&student, graphqlFrom(ctx).acceptLanguage); err != nil {
+		return nil, err
+	}
+	created, err := q.h.Service.AddStudent(ctx, student)
+	if err != nil {
+		return nil, err
+	}
+	return &studentResolver{created}, nil
+}
+
+func (q *graphqlRoot) UpdateStudent(ctx context.Context, args struct {
+	ID    int32
+	Input studentInput
+}) (*studentResolver, error) {
+	if err := q.h.checkOwner(ctx, args.ID, "updateStudent"); err != nil {
+		return nil, err
+	}
+	student := args.Input.student()
+	if err := Validate(&student, graphqlFrom(ctx).acceptLanguage); err != nil {
+		return nil, err
+	}
+	updated, err := q.h.Service.UpdateStudent(ctx, args.ID, student)
+	if err != nil {
+		return nil, err
+	}
+	return &studentResolver{updated}, nil
+}
+
+func (q *graphqlRoot) DeleteStudent(ctx context.Context, args struct{ ID int32 }) (*bool, error) {
+	if err := q.h.checkOwner(ctx, args.ID, "deleteStudent"); err != nil {
+		return nil, err
+	}
+	if err := q.h.Service.DeleteStudent(ctx, args.ID); err != nil {
+		return nil, err
+	}
+	deleted := true
+	return &deleted, nil
+}
+
+func (q *graphqlRoot) Enroll(ctx context.Context, args struct {
+	StudentID int32
+	Input     enrollmentInput
+}) (*enrollmentResolver, error) {
+	if err := q.h.checkOwner(ctx, args.StudentID, "enroll"); err != nil {
+		return nil, err
+	}
+	enrollment := args.Input.enrollment()
+	if err := Validate(&enrollment, graphqlFrom(ctx).acceptLanguage); err != nil {
+		return nil, err
+	}
+	created, err := q.h.Service.Enroll(ctx, args.StudentID, enrollment)
+	if err != nil {
+		return nil, err
+	}
+	return &enrollmentResolver{created}, nil
+}
+
+// Objects
+
+type studentPageResolver struct {
+	page service.StudentPage
+}
+
+func (p *studentPageResolver) Students() []*studentResolver {
+	return studentResolvers(p.page.Students)
+}
+
+func (p *studentPageResolver) NextCursor() *string {
+	if p.page.NextCursor == "" {
+		return nil
+	}
+	return &p.page.NextCursor
+}
+
+type studentResolver struct {
+	service.Student
+}
+
+func studentResolvers(students []service.Student) []*studentResolver {
+	out := make([]*studentResolver, len(students))
+	for i, s := range students {
+		out[i] = &studentResolver{s}
+	}
+	return out
+}
+
+func (s *studentResolver) CourseDetails(ctx context.Context) (*courseResolver, error) {
+	if userType, _ := ctx.Value("userType").(string); userType == service.RoleGuardian {
+		return nil, errGraphQLForbidden
+	}
+	if s.CourseID == 0 {
+		return nil, nil
+	}
+	return loadCourse(ctx, s.CourseID)
+}
+
+func (s *studentResolver) Enrollments(ctx context.Context, args struct{ Term *string }) (*[]*enrollmentResolver, error) {
+	if userType, _ := ctx.Value("userType").(string); userType == service.RoleGuardian {
+		return nil, errGraphQLForbidden
+	}
+	key := enrollmentsKey{studentID: s.ID}
+	if args.Term != nil {
+		key.term = *args.Term
+	}
+	enrollments, err := graphqlFrom(ctx).enrollments.Load(ctx, key)()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*enrollmentResolver, len(enrollments))
+	for i, e := range enrollments {
+		out[i] = &enrollmentResolver{e}
+	}
+	return &out, nil
+}
+
+func (s *studentResolver) Contacts(ctx context.Context) (*[]*contactResolver, error) {
+	contacts, err := graphqlFrom(ctx).contacts.Load(ctx, s.ID)()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*contactResolver, len(contacts))
+	for i, c := range contacts {
+		out[i] = &contactResolver{c}
+	}
+	return &out, nil
+}
+
+type searchResultResolver struct {
+	service.SearchResult
+}
+
+func (r *searchResultResolver) Student() *studentResolver {
+	return &studentResolver{r.SearchResult.Student}
+}
+
+func (r *searchResultResolver) Highlights() []*highlightResolver {
+	fields := make([]string, 0, len(r.SearchResult.Highlights))
+	for field := range r.SearchResult.Highlights {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	out := make([]*highlightResolver, len(fields))
+	for i, field := range fields {
+		out[i] = &highlightResolver{Field: field, Text: r.SearchResult.Highlights[field]}
+	}
+	return out
+}
+
+type highlightResolver struct {
+	Field string
+	Text  string
+}
+
+type courseResolver struct {
+	service.Course
+}
+
+func loadCourse(ctx context.Context, courseID int32) (*courseResolver, error) {
+	course, err := graphqlFrom(ctx).courses.Load(ctx, courseID)()
+	if err != nil || course == nil {
+		return nil, err
+	}
+	return &courseResolver{*course}, nil
+}
+
+type enrollmentResolver struct {
+	service.Enrollment
+}
+
+func (e *enrollmentResolver) Course(ctx context.Context) (*courseResolver, error) {
+	return loadCourse(ctx, e.CourseID)
+}
+
+type termResolver struct {
+	service.Term
+}
+
+type contactResolver struct {
+	service.Contact
+}
@@ -4,16 +4,28 @@ import (
 	service "GO_Assignment_3/internal/service"
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/gorilla/mux"
+	graphql "github.com/graph-gophers/graphql-go"
 	"github.com/sirupsen/logrus"
 )
 
 // Handler - struct to handle HTTP requests
 type Handler struct {
 	Service *service.Service
+	// PublicURL is the base URL clients reach the API at, printed in the
+	// verification link of official transcripts.
+	PublicURL string
+
+	graphqlOnce sync.Once
+	graphql     *graphql.Schema
 }
 
 // NewHandler - creates a new Handler instance
@@ -25,24 +37,241 @@ func NewHandler(service *service.Service) *Handler {
 
 func (h *Handler) RegisterRoutes(router *mux.Router) {
 	router.HandleFunc("/students", h.GetAllStudents).Methods("GET")
+	router.HandleFunc("/students/search", h.SearchStudents).Methods("GET")
 	router.HandleFunc("/students/{id}", h.GetStudent).Methods("GET")
 	router.HandleFunc("/students", h.CreateStudent).Methods("POST")
 	router.HandleFunc("/students/{id}", h.UpdateStudent).Methods("PUT")
+	router.HandleFunc("/students/{id}", h.PatchStudent).Methods("PATCH")
 	router.HandleFunc("/students/{id}", h.DeleteStudent).Methods("DELETE")
+	router.HandleFunc("/students/batch", h.BatchStudents).Methods("POST")
+	router.HandleFunc("/students/import", h.ImportStudents).Methods("POST")
 	router.HandleFunc("/register", h.RegisterUser).Methods("POST")
+	router.HandleFunc("/audit", h.GetAuditEntries).Methods("GET")
+	router.HandleFunc("/courses", h.GetAllCourses).Methods("GET")
+	router.HandleFunc("/courses/{id}", h.GetCourse).Methods("GET")
+	router.HandleFunc("/courses", h.CreateCourse).Methods("POST")
+	router.HandleFunc("/courses/{id}", h.UpdateCourse).Methods("PUT")
+	router.HandleFunc("/courses/{id}", h.DeleteCourse).Methods("DELETE")
+	router.HandleFunc("/courses/{id}/students", h.GetCourseStudents).Methods("GET")
+	router.HandleFunc("/courses/{id}/waitlist", h.GetCourseWaitlist).Methods("GET")
+	router.HandleFunc("/courses/{id}/prerequisites", h.GetPrerequisites).Methods("GET")
+	router.HandleFunc("/courses/{id}/prerequisites", h.SetPrerequisites).Methods("PUT")
+	router.HandleFunc("/students/{id}/prerequisite-overrides", h.GetStudentOverrides).Methods("GET")
+	router.HandleFunc("/students/{id}/prerequisite-overrides", h.GrantOverride).Methods("POST")
+	router.HandleFunc("/students/{id}/prerequisite-overrides/{overrideID}", h.RevokeOverride).Methods("DELETE")
+	router.HandleFunc("/students/{id}/enrollments", h.GetStudentEnrollments).Methods("GET")
+	router.HandleFunc("/students/{id}/enrollments/{enrollmentID}", h.GetEnrollment).Methods("GET")
+	router.HandleFunc("/students/{id}/enrollments", h.CreateEnrollment).Methods("POST")
+	router.HandleFunc("/students/{id}/enrollments/{enrollmentID}", h.UpdateEnrollment).Methods("PUT")
+	router.HandleFunc("/students/{id}/enrollments/{enrollmentID}", h.DeleteEnrollment).Methods("DELETE")
+	router.HandleFunc("/students/{id}/gpa", h.GetStudentGPA).Methods("GET")
+	router.HandleFunc("/students/{id}/transcript", h.GetTranscript).Methods("GET")
+	router.HandleFunc("/students/{id}/transcript", h.IssueTranscript).Methods("POST")
+	router.HandleFunc("/transcripts/verify/{code}", h.VerifyTranscript).Methods("GET")
+	router.HandleFunc("/grading-scales", h.GetAllGradingScales).Methods("GET")
+	router.HandleFunc("/grading-scales/{id}", h.GetGradingScale).Methods("GET")
+	router.HandleFunc("/grading-scales", h.CreateGradingScale).Methods("POST")
+	router.HandleFunc("/grading-scales/{id}", h.UpdateGradingScale).Methods("PUT")
+	router.HandleFunc("/grading-scales/{id}", h.DeleteGradingScale).Methods("DELETE")
+	router.HandleFunc("/instructors/login", h.InstructorLogin).Methods("POST")
+	router.HandleFunc("/instructors", h.GetAllInstructors).Methods("GET")
+	router.HandleFunc("/instructors/{id}", h.GetInstructor).Methods("GET")
+	router.HandleFunc("/instructors", h.CreateInstructor).Methods("POST")
+	router.HandleFunc("/instructors/{id}", h.UpdateInstructor).Methods("PUT")
+	router.HandleFunc("/instructors/{id}", h.DeleteInstructor).Methods("DELETE")
+	router.HandleFunc("/instructors/{id}/courses", h.GetInstructorCourses).Methods("GET")
+	router.HandleFunc("/instructors/{id}/courses/{courseID}", h.AssignCourse).Methods("PUT")
+	router.HandleFunc("/instructors/{id}/courses/{courseID}", h.UnassignCourse).Methods("DELETE")
+	router.HandleFunc("/terms", h.GetAllTerms).Methods("GET")
+	router.HandleFunc("/terms/current", h.GetCurrentTerm).Methods("GET")
+	router.HandleFunc("/terms/{id}", h.GetTerm).Methods("GET")
+	router.HandleFunc("/terms", h.CreateTerm).Methods("POST")
+	router.HandleFunc("/terms/{id}", h.UpdateTerm).Methods("PUT")
+	router.HandleFunc("/terms/{id}", h.DeleteTerm).Methods("DELETE")
+	router.HandleFunc("/courses/{id}/sessions", h.GetSessions).Methods("GET")
+	router.HandleFunc("/courses/{id}/sessions", h.CreateSession).Methods("POST")
+	router.HandleFunc("/courses/{id}/sessions/{sessionID}", h.GetSession).Methods("GET")
+	router.HandleFunc("/courses/{id}/sessions/{sessionID}", h.UpdateSession).Methods("PUT")
+	router.HandleFunc("/courses/{id}/sessions/{sessionID}", h.DeleteSession).Methods("DELETE")
+	router.HandleFunc("/courses/{id}/sessions/{sessionID}/attendance", h.GetSessionAttendance).Methods("GET")
+	router.HandleFunc("/courses/{id}/sessions/{sessionID}/attendance", h.MarkAttendance).Methods("PUT")
+	router.HandleFunc("/students/{id}/attendance", h.GetStudentAttendance).Methods("GET")
+	router.HandleFunc("/attendance/report", h.GetAttendanceReport).Methods("GET")
+	router.HandleFunc("/courses/{id}/assignments", h.GetAssignments).Methods("GET")
+	router.HandleFunc("/courses/{id}/assignments", h.CreateAssignment).Methods("POST")
+	router.HandleFunc("/courses/{id}/assignments/{assignmentID}", h.GetAssignment).Methods("GET")
+	router.HandleFunc("/courses/{id}/assignments/{assignmentID}", h.UpdateAssignment).Methods("PUT")
+	router.HandleFunc("/courses/{id}/assignments/{assignmentID}", h.DeleteAssignment).Methods("DELETE")
+	router.HandleFunc("/courses/{id}/assignments/{assignmentID}/submissions", h.GetAssignmentSubmissions).Methods("GET")
+	router.HandleFunc("/students/{id}/submissions", h.GetStudentSubmissions).Methods("GET")
+	router.HandleFunc("/students/{id}/submissions", h.CreateSubmission).Methods("POST")
+	router.HandleFunc("/students/{id}/submissions/{submissionID}", h.GetSubmission).Methods("GET")
+	router.HandleFunc("/students/{id}/submissions/{submissionID}/file", h.GetSubmissionFile).Methods("GET")
+	router.HandleFunc("/students/{id}/submissions/{submissionID}/grade", h.GradeSubmission).Methods("PUT")
+	router.HandleFunc("/students/{id}/contacts", h.GetStudentContacts).Methods("GET")
+	router.HandleFunc("/students/{id}/contacts", h.CreateContact).Methods("POST")
+	router.HandleFunc("/students/{id}/contacts/{contactID}", h.GetContact).Methods("GET")
+	router.HandleFunc("/students/{id}/contacts/{contactID}", h.UpdateContact).Methods("PUT")
+	router.HandleFunc("/students/{id}/contacts/{contactID}", h.DeleteContact).Methods("DELETE")
+	router.HandleFunc("/guardians/login", h.GuardianLogin).Methods("POST")
+	router.HandleFunc("/guardians", h.GetAllGuardians).Methods("GET")
+	router.HandleFunc("/guardians/{id}", h.GetGuardian).Methods("GET")
+	router.HandleFunc("/guardians", h.CreateGuardian).Methods("POST")
+	router.HandleFunc("/guardians/{id}", h.UpdateGuardian).Methods("PUT")
+	router.HandleFunc("/guardians/{id}", h.DeleteGuardian).Methods("DELETE")
+	router.HandleFunc("/guardians/{id}/students", h.GetGuardianStudents).Methods("GET")
+	router.HandleFunc("/graphql", h.GraphQL).Methods("POST")
+	router.HandleFunc("/openapi.json", h.OpenAPI).Methods("GET")
+	router.HandleFunc("/docs", h.Docs).Methods("GET")
+	router.PathPrefix("/docs/assets/").Handler(DocsAssets).Methods("GET")
 }
 
+// GetAllStudents - GET /students?filter=&limit=&cursor=&sort=
+// filter is an expression parsed by service.ParseFilter. sort is one of id,
+// name, created_on or updated_on, prefixed with - for descending order.
+// next_cursor in the response fetches the following page.
+// With "Accept: application/x-ndjson" the matching students are streamed one
+// JSON object per line instead, and limit becomes optional.
 func (h *Handler) GetAllStudents(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	students, err := h.Service.GetAllStudents(ctx)
+	opts, err := ParseListOptions(r.URL.Query())
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+
+	if acceptsNDJSON(r) {
+		h.streamStudents(w, r, opts)
+		return
+	}
+
+	page, err := h.Service.GetAllStudents(ctx, opts)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+const ndjsonContentType = "application/x-ndjson"
+
+func acceptsNDJSON(r *http.Request) bool {
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType := strings.TrimSpace(strings.Split(accept, ";")[0])
+		if strings.EqualFold(mediaType, ndjsonContentType) {
+			return true
+		}
+	}
+	return false
+}
+
+// streamWriteTimeout bounds each line of a student stream, so a client that
+// stops reading cannot hold the query and its connection open indefinitely.
+const streamWriteTimeout = 30 * time.Second
+
+// streamStudents writes each student as soon as the store scans it and
+// flushes after every line. If the client goes away the request context is
+// cancelled, which stops the query, and a client too slow to take a line
+// within streamWriteTimeout fails the write the same way. An error after the
+// first line can no longer change the status code, so it is reported as a
+// final {"error": "..."} line.
+func (h *Handler) streamStudents(w http.ResponseWriter, r *http.Request, opts service.ListOptions) {
+	ctx := r.Context()
+	flusher, _ := w.(http.Flusher)
+	controller := http.NewResponseController(w)
+	encoder := json.NewEncoder(w)
+	started := false
+
+	err := h.Service.StreamStudents(ctx, opts, func(student service.Student) error {
+		if !started {
+			w.Header().Set("Content-Type", ndjsonContentType)
+			w.Header().Set("X-Content-Type-Options", "nosniff")
+			w.WriteHeader(http.StatusOK)
+			started = true
+		}
+		controller.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+		student.Password = ""
+		if err := encoder.Encode(student); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+
+	if err != nil {
+		if ctx.Err() != nil {
+			logrus.Warnf("Student stream stopped: %v", ctx.Err())
+		}
+		if !started {
+			writeError(w, r, err)
+			return
+		}
+		encoder.Encode(map[string]string{"error": "stream interrupted"})
+		return
+	}
+
+	if !started {
+		w.Header().Set("Content-Type", ndjsonContentType)
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// ParseListOptions reads the filter, limit, cursor and sort parameters of
+// GET /students. The other transports pass their arguments through it so
+// that every listing accepts the same values.
+func ParseListOptions(q url.Values) (service.ListOptions, error) {
+	var opts service.ListOptions
+
+	limit, err := parseIntParam(q, "limit")
+	if err != nil {
+		return opts, err
+	}
+	// 0, like no limit, is the default page size, or every student when
+	// streaming.
+	if limit < 0 || limit > service.MaxPageLimit {
+		return opts, fmt.Errorf("invalid limit: must be between 0 and %d, 0 meaning the default", service.MaxPageLimit)
+	}
+	opts.Limit = limit
+
+	if opts.Sort, opts.Desc, err = service.ParseSort(q.Get("sort")); err != nil {
+		return opts, err
+	}
+
+	if opts.Filter, err = service.ParseFilter(q.Get("filter")); err != nil {
+		return opts, err
+	}
+
+	if cursor := q.Get("cursor"); cursor != "" {
+		if opts.After, err = service.DecodeCursor(cursor); err != nil {
+			return opts, err
+		}
+	}
+	return opts, nil
+}
+
+// SearchStudents - GET /students/search?q=&limit=
+// Ranked, typo-tolerant search over names and courses.
+func (h *Handler) SearchStudents(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query().Get("q")
+	if q == "" {
+		writeProblem(w, r, http.StatusBadRequest, "Query parameter q is required")
+		return
+	}
+	limit, err := parseIntParam(r.URL.Query(), "limit")
 	if err != nil {
-		http.Error(w, "Error fetching students", http.StatusInternalServerError)
+		writeBadRequest(w, r, err)
 		return
 	}
 
+	results := h.Service.SearchStudents(r.Context(), q, limit)
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(students)
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": results})
 }
 
 func (h *Handler) GetStudent(w http.ResponseWriter, r *http.Request) {
@@ -52,17 +281,18 @@ func (h *Handler) GetStudent(w http.ResponseWriter, r *http.Request) {
 
 	id, err := strconv.ParseInt(idStr, 10, 32)
 	if err != nil {
-		http.Error(w, "Invalid student ID format", http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, "Invalid student ID format")
 		return
 	}
 
 	student, err := h.Service.GetStudent(ctx, int32(id))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		writeError(w, r, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", studentETag(student))
 	json.NewEncoder(w).Encode(student)
 }
 
@@ -70,14 +300,13 @@ func (h *Handler) CreateStudent(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	var student service.Student
 
-	if err := json.NewDecoder(r.Body).Decode(&student); err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+	if !decodeRequest(w, r, &student) {
 		return
 	}
 
 	createdStudent, err := h.Service.AddStudent(ctx, student)
 	if err != nil {
-		http.Error(w, "Error adding student", http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
@@ -87,25 +316,23 @@ func (h *Handler) CreateStudent(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) UpdateStudent(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	ctx := withStudentIfMatch(r)
 	vars := mux.Vars(r)
 	idStr := vars["id"]
 
 	id, err := strconv.ParseInt(idStr, 10, 32)
 	if err != nil {
-		http.Error(w, "Invalid student ID format", http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, "Invalid student ID format")
 		return
 	}
 
 	var updatedStudent service.Student
-	if err := json.NewDecoder(r.Body).Decode(&updatedStudent); err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+	if !decodeRequest(w, r, &updatedStudent) {
 		return
 	}
-
 	student, err := h.Service.UpdateStudent(ctx, int32(id), updatedStudent)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
@@ -114,19 +341,19 @@ func (h *Handler) UpdateStudent(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) DeleteStudent(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
+	ctx := withStudentIfMatch(r)
 	vars := mux.Vars(r)
 	idStr := vars["id"]
 
 	id, err := strconv.ParseInt(idStr, 10, 32)
 	if err != nil {
-		http.Error(w, "Invalid student ID format", http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, "Invalid student ID format")
 		return
 	}
 
 	err = h.Service.DeleteStudent(ctx, int32(id))
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
@@ -138,57 +365,231 @@ func (h *Handler) RegisterUser(w http.ResponseWriter, r *http.Request) {
 
 	var student service.Student
 
-	if err := json.NewDecoder(r.Body).Decode(&student); err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+	if !decodeRequest(w, r, &student) {
 		return
 	}
 
-	_, err := h.Service.AddStudent(ctx, student)
+	created, err := h.Service.AddStudent(ctx, student)
 	if err != nil {
-		http.Error(w, "Error registering user", http.StatusInternalServerError)
+		h.auditAuth(ctx, service.AuditAuthRegister, 0, err.Error())
+		writeError(w, r, err)
 		return
 	}
 
-	user_ID, err := h.Service.GetLastInsertedStudentID()
-	if err != nil {
-		http.Error(w, "Error retrieving student ID", http.StatusInternalServerError)
-		return
-	}
+	// AddStudent returns the ID of the inserted row. LAST_INSERT_ID() is not
+	// reliable here because the pool may hand back a connection that has
+	// since written an audit entry or belongs to another request.
+	user_ID := created.ID
 
-	token, err := GenerateJWT(user_ID, student.Name)
+	token, err := GenerateJWT(user_ID, student.Name, service.RoleUser)
 	if err != nil {
-		http.Error(w, "Error generating token", http.StatusInternalServerError)
+		h.auditAuth(ctx, service.AuditAuthRegister, user_ID, err.Error())
+		writeProblem(w, r, http.StatusInternalServerError, "Error generating token")
 		return
 	}
 
+	h.Service.RecordAudit(ctx, service.AuditEntry{
+		ActorID:  user_ID,
+		Action:   service.AuditAuthRegister,
+		TargetID: user_ID,
+		Outcome:  service.OutcomeSuccess,
+	})
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"token": token})
+	json.NewEncoder(w).Encode(tokenResponse{Token: token})
 }
 
-func (h *Handler) Serve() error {
-	router := mux.NewRouter()
-
-	router.Use(JSONMiddleware)
-	router.Use(TimeoutMiddleware)
-	router.Use(LoggingMiddleware)
-	router.Use(CORSHandler)
-	router.Use(RecoverMiddleware)
-
+// routes registers the endpoints of the API on router, grouped by how
+// callers authenticate.
+func (h *Handler) routes(router *mux.Router) {
 	protectedRoutes := router.PathPrefix("/").Subrouter()
-	protectedRoutes.Use(JWTAuthMiddleware)
+	protectedRoutes.Use(h.JWTAuthMiddleware)
 	protectedRoutes.HandleFunc("/students", h.GetAllStudents).Methods("GET")
+	protectedRoutes.HandleFunc("/students/search", h.SearchStudents).Methods("GET")
 	protectedRoutes.HandleFunc("/students/{id}", h.GetStudent).Methods("GET")
 	protectedRoutes.HandleFunc("/students", h.CreateStudent).Methods("POST")
 	protectedRoutes.HandleFunc("/students/{id}", h.UpdateStudent).Methods("PUT")
+	protectedRoutes.HandleFunc("/students/{id}", h.PatchStudent).Methods("PATCH")
 	protectedRoutes.HandleFunc("/students/{id}", h.DeleteStudent).Methods("DELETE")
+	protectedRoutes.HandleFunc("/students/{id}/enrollments", h.GetStudentEnrollments).Methods("GET")
+	protectedRoutes.HandleFunc("/students/{id}/enrollments/{enrollmentID}", h.GetEnrollment).Methods("GET")
+	protectedRoutes.HandleFunc("/students/{id}/enrollments", h.CreateEnrollment).Methods("POST")
+	protectedRoutes.HandleFunc("/students/{id}/enrollments/{enrollmentID}", h.UpdateEnrollment).Methods("PUT")
+	protectedRoutes.HandleFunc("/students/{id}/enrollments/{enrollmentID}", h.DeleteEnrollment).Methods("DELETE")
+	protectedRoutes.HandleFunc("/students/{id}/gpa", h.GetStudentGPA).Methods("GET")
+	protectedRoutes.HandleFunc("/students/{id}/transcript", h.GetTranscript).Methods("GET")
+	protectedRoutes.HandleFunc("/students/{id}/transcript", h.IssueTranscript).Methods("POST")
+	protectedRoutes.HandleFunc("/students/{id}/prerequisite-overrides", h.GetStudentOverrides).Methods("GET")
+	protectedRoutes.HandleFunc("/students/{id}/attendance", h.GetStudentAttendance).Methods("GET")
+	protectedRoutes.HandleFunc("/students/{id}/submissions", h.GetStudentSubmissions).Methods("GET")
+	protectedRoutes.HandleFunc("/students/{id}/submissions", h.CreateSubmission).Methods("POST")
+	protectedRoutes.HandleFunc("/students/{id}/submissions/{submissionID}", h.GetSubmission).Methods("GET")
+	protectedRoutes.HandleFunc("/students/{id}/submissions/{submissionID}/file", h.GetSubmissionFile).Methods("GET")
+	protectedRoutes.HandleFunc("/students/{id}/submissions/{submissionID}/grade", h.GradeSubmission).Methods("PUT")
+	protectedRoutes.HandleFunc("/students/{id}/contacts", h.GetStudentContacts).Methods("GET")
+	protectedRoutes.HandleFunc("/students/{id}/contacts", h.CreateContact).Methods("POST")
+	protectedRoutes.HandleFunc("/students/{id}/contacts/{contactID}", h.GetContact).Methods("GET")
+	protectedRoutes.HandleFunc("/students/{id}/contacts/{contactID}", h.UpdateContact).Methods("PUT")
+	protectedRoutes.HandleFunc("/students/{id}/contacts/{contactID}", h.DeleteContact).Methods("DELETE")
+
+	// Routes that authenticate the caller but do not tie them to a student ID.
+	authRoutes := router.PathPrefix("/").Subrouter()
+	authRoutes.Use(h.TokenAuthMiddleware)
+	authRoutes.HandleFunc("/courses", h.GetAllCourses).Methods("GET")
+	authRoutes.HandleFunc("/courses/{id}", h.GetCourse).Methods("GET")
+	authRoutes.HandleFunc("/courses/{id}/students", h.GetCourseStudents).Methods("GET")
+	authRoutes.HandleFunc("/courses/{id}/waitlist", h.GetCourseWaitlist).Methods("GET")
+	authRoutes.HandleFunc("/courses/{id}/prerequisites", h.GetPrerequisites).Methods("GET")
+	authRoutes.HandleFunc("/grading-scales", h.GetAllGradingScales).Methods("GET")
+	authRoutes.HandleFunc("/grading-scales/{id}", h.GetGradingScale).Methods("GET")
+	authRoutes.HandleFunc("/terms", h.GetAllTerms).Methods("GET")
+	authRoutes.HandleFunc("/instructors", h.GetAllInstructors).Methods("GET")
+	authRoutes.HandleFunc("/instructors/{id}", h.GetInstructor).Methods("GET")
+	authRoutes.HandleFunc("/instructors/{id}/courses", h.GetInstructorCourses).Methods("GET")
+	authRoutes.HandleFunc("/terms/current", h.GetCurrentTerm).Methods("GET")
+	authRoutes.HandleFunc("/terms/{id}", h.GetTerm).Methods("GET")
+	// Session and attendance writes are limited to the admin and the
+	// course's instructors by the service.
+	authRoutes.HandleFunc("/courses/{id}/sessions", h.GetSessions).Methods("GET")
+	authRoutes.HandleFunc("/courses/{id}/sessions", h.CreateSession).Methods("POST")
+	authRoutes.HandleFunc("/courses/{id}/sessions/{sessionID}", h.GetSession).Methods("GET")
+	authRoutes.HandleFunc("/courses/{id}/sessions/{sessionID}", h.UpdateSession).Methods("PUT")
+	authRoutes.HandleFunc("/courses/{id}/sessions/{sessionID}", h.DeleteSession).Methods("DELETE")
+	authRoutes.HandleFunc("/courses/{id}/sessions/{sessionID}/attendance", h.GetSessionAttendance).Methods("GET")
+	authRoutes.HandleFunc("/courses/{id}/sessions/{sessionID}/attendance", h.MarkAttendance).Methods("PUT")
+	authRoutes.HandleFunc("/attendance/report", h.GetAttendanceReport).Methods("GET")
+	authRoutes.HandleFunc("/courses/{id}/assignments", h.GetAssignments).Methods("GET")
+	authRoutes.HandleFunc("/courses/{id}/assignments", h.CreateAssignment).Methods("POST")
+	authRoutes.HandleFunc("/courses/{id}/assignments/{assignmentID}", h.GetAssignment).Methods("GET")
+	authRoutes.HandleFunc("/courses/{id}/assignments/{assignmentID}", h.UpdateAssignment).Methods("PUT")
+	authRoutes.HandleFunc("/courses/{id}/assignments/{assignmentID}", h.DeleteAssignment).Methods("DELETE")
+	authRoutes.HandleFunc("/courses/{id}/assignments/{assignmentID}/submissions", h.GetAssignmentSubmissions).Methods("GET")
+	authRoutes.HandleFunc("/guardians/{id}/students", h.GetGuardianStudents).Methods("GET")
+	// GraphQL authorizes each field with the rules of the matching route.
+	authRoutes.HandleFunc("/graphql", h.GraphQL).Methods("POST")
+
+	adminRoutes := authRoutes.PathPrefix("/").Subrouter()
+	adminRoutes.Use(h.AdminOnlyMiddleware)
+	adminRoutes.HandleFunc("/audit", h.GetAuditEntries).Methods("GET")
+	adminRoutes.HandleFunc("/students/batch", h.BatchStudents).Methods("POST")
+	adminRoutes.HandleFunc("/students/import", h.ImportStudents).Methods("POST")
+	adminRoutes.HandleFunc("/courses", h.CreateCourse).Methods("POST")
+	adminRoutes.HandleFunc("/courses/{id}", h.UpdateCourse).Methods("PUT")
+	adminRoutes.HandleFunc("/courses/{id}", h.DeleteCourse).Methods("DELETE")
+	adminRoutes.HandleFunc("/courses/{id}/prerequisites", h.SetPrerequisites).Methods("PUT")
+	adminRoutes.HandleFunc("/students/{id}/prerequisite-overrides", h.GrantOverride).Methods("POST")
+	adminRoutes.HandleFunc("/students/{id}/prerequisite-overrides/{overrideID}", h.RevokeOverride).Methods("DELETE")
+	adminRoutes.HandleFunc("/grading-scales", h.CreateGradingScale).Methods("POST")
+	adminRoutes.HandleFunc("/grading-scales/{id}", h.UpdateGradingScale).Methods("PUT")
+	adminRoutes.HandleFunc("/grading-scales/{id}", h.DeleteGradingScale).Methods("DELETE")
+	adminRoutes.HandleFunc("/instructors", h.CreateInstructor).Methods("POST")
+	adminRoutes.HandleFunc("/instructors/{id}", h.UpdateInstructor).Methods("PUT")
+	adminRoutes.HandleFunc("/instructors/{id}", h.DeleteInstructor).Methods("DELETE")
+	adminRoutes.HandleFunc("/instructors/{id}/courses/{courseID}", h.AssignCourse).Methods("PUT")
+	adminRoutes.HandleFunc("/instructors/{id}/courses/{courseID}", h.UnassignCourse).Methods("DELETE")
+	adminRoutes.HandleFunc("/terms", h.CreateTerm).Methods("POST")
+	adminRoutes.HandleFunc("/terms/{id}", h.UpdateTerm).Methods("PUT")
+	adminRoutes.HandleFunc("/terms/{id}", h.DeleteTerm).Methods("DELETE")
+	adminRoutes.HandleFunc("/guardians", h.GetAllGuardians).Methods("GET")
+	adminRoutes.HandleFunc("/guardians/{id}", h.GetGuardian).Methods("GET")
+	adminRoutes.HandleFunc("/guardians", h.CreateGuardian).Methods("POST")
+	adminRoutes.HandleFunc("/guardians/{id}", h.UpdateGuardian).Methods("PUT")
+	adminRoutes.HandleFunc("/guardians/{id}", h.DeleteGuardian).Methods("DELETE")
 
 	router.HandleFunc("/register", h.RegisterUser).Methods("POST")
+	router.HandleFunc("/transcripts/verify/{code}", h.VerifyTranscript).Methods("GET")
+	router.HandleFunc("/instructors/login", h.InstructorLogin).Methods("POST")
+	router.HandleFunc("/guardians/login", h.GuardianLogin).Methods("POST")
+}
+
+func (h *Handler) Serve() error {
+	proxies, err := loadTrustedProxies()
+	if err != nil {
+		return err
+	}
+	trustedProxies = proxies
+	if !docsAvailable() {
+		logrus.Warn(docsMissing + "; /docs answers 503 until then")
+	}
+	// The unversioned routes are kept as deprecated aliases of /v1.
+	legacy, err := loadLegacyDeprecation()
+	if err != nil {
+		return err
+	}
 
 	server := &http.Server{
 		Addr:    ":8080",
-		Handler: router,
+		Handler: h.newRouter(legacy),
 	}
 
 	logrus.Info("Starting server on :8080")
 	return server.ListenAndServe()
 }
+
+// newRouter mounts every API version, and the unversioned aliases that
+// legacy deprecates, behind the common middleware.
+func (h *Handler) newRouter(legacy Deprecation) *mux.Router {
+	router := mux.NewRouter()
+
+	router.Use(JSONMiddleware)
+	router.Use(TimeoutMiddleware)
+	router.Use(LoggingMiddleware)
+	router.Use(CORSHandler)
+	router.Use(RecoverMiddleware)
+	router.Use(RequestContextMiddleware)
+	router.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if allowed := allowedMethods(router, r); len(allowed) > 0 {
+			methodNotAllowed(w, r, allowed)
+			return
+		}
+		writeProblem(w, r, http.StatusNotFound, "No route matches "+r.URL.Path)
+	})
+	router.MethodNotAllowedHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methodNotAllowed(w, r, allowedMethods(router, r))
+	})
+
+	router.HandleFunc("/openapi.json", h.OpenAPI).Methods("GET")
+	router.HandleFunc("/docs", h.Docs).Methods("GET")
+	router.PathPrefix("/docs/assets/").Handler(DocsAssets).Methods("GET")
+
+	for _, v := range apiVersions {
+		versioned := router.PathPrefix(v.prefix).Subrouter()
+		versioned.Use(versionMiddleware(v.version))
+		v.mount(h, versioned)
+	}
+
+	aliases := router.PathPrefix("/").Subrouter()
+	aliases.Use(deprecated(legacy))
+	h.routes(aliases)
+
+	return router
+}
+
+// routeMethods are the methods the API has routes for.
+var routeMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE"}
+
+// allowedMethods lists the methods router has a route for at the path of r.
+// mux forgets a method mismatch as soon as the prefix of a later subrouter
+// matches, which every path does with the "/" subrouters of routes, so a
+// request that only got the method wrong would look unrouted; the router is
+// asked again with each method instead.
+func allowedMethods(router *mux.Router, r *http.Request) []string {
+	var allowed []string
+	for _, method := range routeMethods {
+		if method == r.Method {
+			continue
+		}
+		probe := *r
+		probe.Method = method
+		var match mux.RouteMatch
+		if router.Match(&probe, &match) && match.MatchErr == nil {
+			allowed = append(allowed, method)
+		}
+	}
+	return allowed
+}
+
+func methodNotAllowed(w http.ResponseWriter, r *http.Request, allowed []string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	writeProblem(w, r, http.StatusMethodNotAllowed, r.Method+" is not allowed on "+r.URL.Path)
+}
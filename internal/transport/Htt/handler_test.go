@@ -0,0 +1,39 @@
+package Htt
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestMethodNotAllowed checks that a path the API has routes for answers 405
+// with the methods it does have, under every prefix, and that unknown paths
+// still answer 404.
+func TestMethodNotAllowed(t *testing.T) {
+	router := (&Handler{}).newRouter(legacyDeprecation)
+
+	tests := []struct {
+		method, path string
+		status       int
+		allow        string
+	}{
+		{"TRACE", "/v1/students", http.StatusMethodNotAllowed, "GET, POST"},
+		{"TRACE", "/students", http.StatusMethodNotAllowed, "GET, POST"},
+		{"POST", "/v1/students/7", http.StatusMethodNotAllowed, "GET, PUT, PATCH, DELETE"},
+		{"DELETE", "/v1/courses", http.StatusMethodNotAllowed, "GET, POST"},
+		{"PATCH", "/courses/3", http.StatusMethodNotAllowed, "GET, PUT, DELETE"},
+		{"POST", "/openapi.json", http.StatusMethodNotAllowed, "GET"},
+		{"GET", "/v1/nothing-here", http.StatusNotFound, ""},
+		{"GET", "/nothing-here", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+		if rec.Code != tt.status {
+			t.Errorf("%s %s: status %d, want %d", tt.method, tt.path, rec.Code, tt.status)
+		}
+		if got := rec.Header().Get("Allow"); got != tt.allow {
+			t.Errorf("%s %s: Allow %q, want %q", tt.method, tt.path, got, tt.allow)
+		}
+	}
+}
@@ -0,0 +1,363 @@
+package Htt
+
+import (
+	service "GO_Assignment_3/internal/service"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	csvType = "text/csv"
+	// maxImportSize bounds the CSV file of an import.
+	maxImportSize = 10 << 20
+	// maxImportRows bounds the rows of an import; they are applied
+	// service.MaxBatchSize at a time.
+	maxImportRows = 10000
+)
+
+// importFields are the fields of Student a CSV file can set, by JSON name.
+// Each is read from the column of the same name unless ?map says otherwise.
+var importFields = []string{"id", "password", "name", "course", "course_id", "grade"}
+
+// importCells are the numeric cells of a row, checked before they are
+// parsed into the student.
+type importCells struct {
+	ID       string `json:"id" validate:"omitempty,number"`
+	CourseID string `json:"course_id" validate:"omitempty,number"`
+}
+
+// importReport is the outcome of an import, a row per data row of the file.
+type importReport struct {
+	DryRun  bool           `json:"dry_run"`
+	Created int            `json:"created"`
+	Updated int            `json:"updated"`
+	Failed  int            `json:"failed"`
+	Rows    []importResult `json:"rows"`
+}
+
+// importResult is the outcome of one row. Row is the row number in the
+// spreadsheet, the header being row 1; Student is the student as stored,
+// without its password.
+type importResult struct {
+	Row     int              `json:"row"`
+	Action  string           `json:"action"`
+	Status  int              `json:"status"`
+	ID      int32            `json:"id,omitempty"`
+	Student *service.Student `json:"student,omitempty"`
+	Error   *Problem         `json:"error,omitempty"`
+}
+
+// wantsCSV reports whether the client asked for a CSV report, either with
+// ?format=csv or through the Accept header.
+func wantsCSV(r *http.Request) bool {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return strings.EqualFold(format, "csv")
+	}
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		if strings.TrimSpace(strings.SplitN(accept, ";", 2)[0]) == csvType {
+			return true
+		}
+	}
+	return false
+}
+
+// parseColumnMap reads ?map=field:Column parameters into the column header
+// of each import field.
+func parseColumnMap(values []string) (map[string]string, error) {
+	columns := make(map[string]string, len(importFields))
+	for _, field := range importFields {
+		columns[field] = field
+	}
+	for _, value := range values {
+		field, column, ok := strings.Cut(value, ":")
+		field = strings.TrimSpace(field)
+		if _, known := columns[field]; !ok || !known || strings.TrimSpace(column) == "" {
+			return nil, fmt.Errorf("invalid map %q: expected field:Column with field one of %s", value, strings.Join(importFields, ", "))
+		}
+		columns[field] = strings.TrimSpace(column)
+	}
+	return columns, nil
+}
+
+// ImportStudents - POST /students/import?dry_run=&map=field:Column&format=
+// Creates and updates students from a CSV file with a header row. Rows with
+// an id update that student, changing only the cells filled in; the others
+// create a student. Creates are validated like POST /students, updates on
+// the cells they fill in, and each row stands on its own. With dry_run=true nothing is written and the report tells what
+// would happen. The report is JSON, or a CSV download with ?format=csv or
+// "Accept: text/csv".
+func (h *Handler) ImportStudents(w http.ResponseWriter, r *http.Request) {
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if contentType != csvType {
+		writeProblem(w, r, http.StatusUnsupportedMediaType, "An import needs Content-Type "+csvType)
+		return
+	}
+	query := r.URL.Query()
+	dryRun := false
+	if value := query.Get("dry_run"); value != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(value); err != nil {
+			writeProblem(w, r, http.StatusBadRequest, "dry_run must be true or false")
+			return
+		}
+	}
+	columns, err := parseColumnMap(query["map"])
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	reader := csv.NewReader(r.Body)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	rows, err := reader.ReadAll()
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		writeProblem(w, r, http.StatusRequestEntityTooLarge, fmt.Sprintf("The file is larger than %d bytes", maxImportSize))
+		return
+	case err != nil:
+		writeProblem(w, r, http.StatusBadRequest, "Invalid CSV: "+err.Error())
+		return
+	case len(rows) == 0:
+		writeProblem(w, r, http.StatusBadRequest, "The file has no header row")
+		return
+	case len(rows)-1 > maxImportRows:
+		writeProblem(w, r, http.StatusRequestEntityTooLarge, fmt.Sprintf("An import can have at most %d rows", maxImportRows))
+		return
+	}
+
+	index, err := columnIndex(rows[0], columns)
+	if err != nil {
+		writeBadRequest(w, r, err)
+		return
+	}
+
+	// Rows are numbered as in the spreadsheet; blank rows are skipped.
+	acceptLanguage := r.Header.Get("Accept-Language")
+	report := importReport{DryRun: dryRun}
+	var ops []service.StudentOp
+	var positions []int
+	for n, row := range rows[1:] {
+		cells := map[string]string{}
+		for field, i := range index {
+			if i < len(row) {
+				cells[field] = strings.TrimSpace(row[i])
+			}
+		}
+		if isBlank(cells) {
+			continue
+		}
+
+		op, err := importOp(cells, acceptLanguage)
+		result := importResult{Row: n + 2, Action: op.Op, ID: op.ID}
+		if err != nil {
+			result.Error = itemProblem(r, err)
+			result.Status = result.Error.Status
+		} else {
+			ops = append(ops, op)
+			positions = append(positions, len(report.Rows))
+		}
+		report.Rows = append(report.Rows, result)
+	}
+
+	for start := 0; start < len(ops); start += service.MaxBatchSize {
+		end := min(start+service.MaxBatchSize, len(ops))
+		var results []service.StudentOpResult
+		if dryRun {
+			results, err = h.Service.CheckStudentBatch(r.Context(), ops[start:end])
+		} else {
+			results, err = h.Service.ApplyStudentBatch(r.Context(), ops[start:end], false)
+		}
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		for n, res := range results {
+			result := &report.Rows[positions[start+n]]
+			if res.Err != nil {
+				result.Error = itemProblem(r, res.Err)
+				result.Status = result.Error.Status
+				continue
+			}
+			student := res.Student
+			student.Password = ""
+			result.Status = batchStatus[result.Action]
+			result.ID, result.Student = student.ID, &student
+		}
+	}
+	for _, result := range report.Rows {
+		switch {
+		case result.Error != nil:
+			report.Failed++
+		case result.Action == service.BatchCreate:
+			report.Created++
+		default:
+			report.Updated++
+		}
+	}
+
+	if wantsCSV(r) {
+		writeImportCSV(w, report)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// columnIndex finds the column of each import field in the header,
+// ignoring case. Fields without a column are left out; every column named in
+// ?map must be there, and the name column unless the file has ids to update.
+func columnIndex(header []string, columns map[string]string) (map[string]int, error) {
+	positions := map[string]int{}
+	for i, title := range header {
+		title = strings.TrimSpace(strings.TrimPrefix(title, "\ufeff"))
+		positions[strings.ToLower(title)] = i
+	}
+	index := map[string]int{}
+	for _, field := range importFields {
+		if i, ok := positions[strings.ToLower(columns[field])]; ok {
+			index[field] = i
+		} else if columns[field] != field {
+			return nil, fmt.Errorf("column %q mapped to %s is not in the header", columns[field], field)
+		}
+	}
+	_, hasName := index["name"]
+	_, hasID := index["id"]
+	if !hasName && !hasID {
+		return nil, fmt.Errorf("the header has no %q column; map another one with map=name:Column", columns["name"])
+	}
+	return index, nil
+}
+
+// importOp turns the cells of a row into a create, or into an update when
+// the row has an id. An update only changes, and only checks, the fields of
+// the cells that are filled in, so that a blank password cell keeps the
+// password and a row without a name keeps the name.
+func importOp(cells map[string]string, acceptLanguage string) (service.StudentOp, error) {
+	op := service.StudentOp{Op: service.BatchCreate}
+	if cells["id"] != "" {
+		op.Op = service.BatchUpdate
+		op.Fields = []string{}
+		for field, cell := range cells {
+			if field != "id" && cell != "" {
+				op.Fields = append(op.Fields, field)
+			}
+		}
+	}
+	student := service.Student{
+		Password: cells["password"],
+		Name:     cells["name"],
+		Course:   cells["course"],
+		Grade:    cells["grade"],
+	}
+
+	var invalid ValidationErrors
+	numbers := importCells{ID: cells["id"], CourseID: cells["course_id"]}
+	if err := Validate(numbers, acceptLanguage); err != nil && !errors.As(err, &invalid) {
+		return op, err
+	}
+	if len(invalid) == 0 {
+		for field, target := range map[string]*int32{"id": &op.ID, "course_id": &student.CourseID} {
+			if cells[field] == "" {
+				continue
+			}
+			n, err := strconv.ParseInt(cells[field], 10, 32)
+			if err != nil {
+				invalid = append(invalid, FieldError{Field: field, Rule: "number", Message: field + " is out of range"})
+			}
+			*target = int32(n)
+		}
+	}
+
+	var failed ValidationErrors
+	if err := Validate(student, acceptLanguage); err != nil && !errors.As(err, &failed) {
+		return op, err
+	}
+	if op.Op == service.BatchUpdate {
+		failed = onlyFields(failed, op.Fields)
+	}
+	if invalid = append(invalid, failed...); len(invalid) > 0 {
+		return op, invalid
+	}
+	op.Student = &student
+	return op, nil
+}
+
+// onlyFields keeps the failed rules of the given fields.
+func onlyFields(failed ValidationErrors, fields []string) ValidationErrors {
+	var kept ValidationErrors
+	for _, fe := range failed {
+		for _, field := range fields {
+			if fe.Field == field {
+				kept = append(kept, fe)
+				break
+			}
+		}
+	}
+	return kept
+}
+
+// isBlank reports whether a row has nothing in the imported columns.
+func isBlank(cells map[string]string) bool {
+	for _, cell := range cells {
+		if cell != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// writeImportCSV sends the report as a CSV download, a line per row of the
+// import. Failed rows carry the problem code and the messages.
+func writeImportCSV(w http.ResponseWriter, report importReport) {
+	filename := "import-results.csv"
+	if report.DryRun {
+		filename = "import-dry-run.csv"
+	}
+	w.Header().Set("Content-Type", csvType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	out := csv.NewWriter(w)
+	out.Write([]string{"row", "action", "status", "id", "name", "code", "error"})
+	for _, result := range report.Rows {
+		line := []string{strconv.Itoa(result.Row), result.Action, strconv.Itoa(result.Status), "", "", "", ""}
+		if result.ID != 0 {
+			line[3] = strconv.Itoa(int(result.ID))
+		}
+		if result.Student != nil {
+			line[4] = result.Student.Name
+		}
+		if result.Error != nil {
+			line[5], line[6] = result.Error.Code, result.Error.Detail
+			if len(result.Error.Errors) > 0 {
+				messages := make([]string, len(result.Error.Errors))
+				for i, fe := range result.Error.Errors {
+					messages[i] = fe.Message
+				}
+				line[6] = strings.Join(messages, "; ")
+			}
+		}
+		for i := range line {
+			line[i] = csvCell(line[i])
+		}
+		out.Write(line)
+	}
+	out.Flush()
+}
+
+// csvCell quotes a cell that a spreadsheet would otherwise run as a
+// formula, such as a name starting with "=", with a leading "'".
+func csvCell(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
@@ -0,0 +1,34 @@
+package Htt
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"GO_Assignment_3/internal/service"
+)
+
+// TestWriteImportCSVEscapesFormulas checks that cells a spreadsheet would run
+// as formulas come out quoted.
+func TestWriteImportCSVEscapesFormulas(t *testing.T) {
+	report := importReport{Rows: []importResult{
+		{Row: 2, Action: service.BatchCreate, Status: 201, ID: 7, Student: &service.Student{ID: 7, Name: "=HYPERLINK(\"x\")"}},
+		{Row: 3, Action: service.BatchCreate, Status: 201, ID: 8, Student: &service.Student{ID: 8, Name: "@SUM(A1)"}},
+		{Row: 4, Action: service.BatchCreate, Status: 201, ID: 9, Student: &service.Student{ID: 9, Name: "Jo-Ann"}},
+		{Row: 5, Action: service.BatchUpdate, Status: 400, Error: &Problem{Code: "invalid", Detail: "+1 is not a course"}},
+		{Row: 6, Action: service.BatchUpdate, Status: 400, Error: &Problem{Code: "invalid", Detail: "-x"}},
+	}}
+	rec := httptest.NewRecorder()
+	writeImportCSV(rec, report)
+
+	want := `row,action,status,id,name,code,error
+2,create,201,7,"'=HYPERLINK(""x"")",,
+3,create,201,8,'@SUM(A1),,
+4,create,201,9,Jo-Ann,,
+5,update,400,,,invalid,'+1 is not a course
+6,update,400,,,invalid,'-x
+`
+	if got := rec.Body.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, strings.TrimSpace(want))
+	}
+}
@@ -0,0 +1,191 @@
+package Htt
+
+import (
+	service "GO_Assignment_3/internal/service"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+func parseInstructorID(r *http.Request) (int32, error) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 32)
+	return int32(id), err
+}
+
+// loginRequest is the body of the instructor and guardian logins.
+type loginRequest struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
+
+// tokenResponse is the body of a successful login or registration.
+type tokenResponse struct {
+	Token string `json:"token"`
+}
+
+// InstructorLogin - POST /instructors/login, exchanging an email and
+// password for an instructor token.
+func (h *Handler) InstructorLogin(w http.ResponseWriter, r *http.Request) {
+	var credentials loginRequest
+	if !decodeRequest(w, r, &credentials) {
+		return
+	}
+
+	instructor, err := h.Service.AuthenticateInstructor(r.Context(), credentials.Email, credentials.Password)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	token, err := GenerateJWT(instructor.ID, instructor.Name, service.RoleInstructor)
+	if err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, "Error generating token")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tokenResponse{Token: token})
+}
+
+func (h *Handler) GetAllInstructors(w http.ResponseWriter, r *http.Request) {
+	instructors, err := h.Service.GetAllInstructors(r.Context())
+	if err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, "Error fetching instructors")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": instructors})
+}
+
+func (h *Handler) GetInstructor(w http.ResponseWriter, r *http.Request) {
+	id, err := parseInstructorID(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid instructor ID format")
+		return
+	}
+
+	instructor, err := h.Service.GetInstructor(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(instructor)
+}
+
+func (h *Handler) CreateInstructor(w http.ResponseWriter, r *http.Request) {
+	var instructor service.Instructor
+	if !decodeRequest(w, r, &instructor) {
+		return
+	}
+
+	created, err := h.Service.AddInstructor(r.Context(), instructor)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+func (h *Handler) UpdateInstructor(w http.ResponseWriter, r *http.Request) {
+	id, err := parseInstructorID(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid instructor ID format")
+		return
+	}
+
+	var instructor service.Instructor
+	if !decodeRequest(w, r, &instructor) {
+		return
+	}
+
+	updated, err := h.Service.UpdateInstructor(r.Context(), id, instructor)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+func (h *Handler) DeleteInstructor(w http.ResponseWriter, r *http.Request) {
+	id, err := parseInstructorID(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid instructor ID format")
+		return
+	}
+
+	if err := h.Service.DeleteInstructor(r.Context(), id); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) GetInstructorCourses(w http.ResponseWriter, r *http.Request) {
+	id, err := parseInstructorID(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid instructor ID format")
+		return
+	}
+
+	courses, err := h.Service.GetInstructorCourses(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": courses})
+}
+
+// parseInstructorCourse reads /instructors/{id}/courses/{courseID}.
+func parseInstructorCourse(r *http.Request) (int32, int32, error) {
+	instructorID, err := parseInstructorID(r)
+	if err != nil {
+		return 0, 0, err
+	}
+	courseID, err := strconv.ParseInt(mux.Vars(r)["courseID"], 10, 32)
+	return instructorID, int32(courseID), err
+}
+
+// AssignCourse - PUT /instructors/{id}/courses/{courseID}
+func (h *Handler) AssignCourse(w http.ResponseWriter, r *http.Request) {
+	instructorID, courseID, err := parseInstructorCourse(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid ID format")
+		return
+	}
+
+	if err := h.Service.AssignCourse(r.Context(), instructorID, courseID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UnassignCourse - DELETE /instructors/{id}/courses/{courseID}
+func (h *Handler) UnassignCourse(w http.ResponseWriter, r *http.Request) {
+	instructorID, courseID, err := parseInstructorCourse(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid ID format")
+		return
+	}
+
+	if err := h.Service.UnassignCourse(r.Context(), instructorID, courseID); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
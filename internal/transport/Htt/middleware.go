@@ -2,11 +2,18 @@ package Htt
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
 	"net/http"
+	"os"
 	"strconv"
 	"strings"
 	"time"
 
+	service "GO_Assignment_3/internal/service"
+
 	"github.com/gorilla/mux"
 
 	log "github.com/sirupsen/logrus"
@@ -19,9 +26,31 @@ func JSONMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// requestTimeout bounds every request but the long-running ones.
+const requestTimeout = 15 * time.Second
+
+// untimed reports whether a request may outlive requestTimeout: NDJSON
+// exports of GET /students and CSV imports, which take as long as the data
+// they carry. They end when the client goes away instead; imports are
+// bounded by maxImportSize and maxImportRows.
+func untimed(r *http.Request) bool {
+	path := versionPrefix.ReplaceAllString(r.URL.Path, "/")
+	switch {
+	case r.Method == http.MethodGet && path == "/students":
+		return acceptsNDJSON(r)
+	case r.Method == http.MethodPost && path == "/students/import":
+		return true
+	}
+	return false
+}
+
 func TimeoutMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
+		if untimed(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
 		defer cancel()
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
@@ -40,8 +69,9 @@ func LoggingMiddleware(next http.Handler) http.Handler {
 func CORSHandler(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, If-Match")
+		w.Header().Set("Access-Control-Expose-Headers", "ETag, API-Version, Deprecation, Sunset, Link")
 		if r.Method == http.MethodOptions {
 			return
 		}
@@ -54,31 +84,160 @@ func RecoverMiddleware(next http.Handler) http.Handler {
 		defer func() {
 			if err := recover(); err != nil {
 				log.Error("Recovered from panic: ", err)
-				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				writeProblem(w, r, http.StatusInternalServerError, "Internal Server Error")
 			}
 		}()
 		next.ServeHTTP(w, r)
 	})
 }
 
-func JWTAuthMiddleware(next http.Handler) http.Handler {
+// RequestContextMiddleware tags every request with a request ID and the
+// client IP so that logs and audit entries can be correlated.
+func RequestContextMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			http.Error(w, "Authorization header missing", http.StatusUnauthorized)
-			return
+		requestID := r.Header.Get("X-Request-ID")
+		if requestID == "" || len(requestID) > 64 {
+			requestID = NewRequestID()
 		}
+		w.Header().Set("X-Request-ID", requestID)
 
-		tokenString := strings.TrimSpace(strings.Replace(authHeader, "Token", "", 1))
+		ctx := context.WithValue(r.Context(), "requestID", requestID)
+		ctx = context.WithValue(ctx, "clientIP", clientIP(r))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
 
-		userType, userID, err := AuthenticateJWT(tokenString)
+// NewRequestID returns a random ID for a request that did not bring one.
+func NewRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		log.Errorf("Failed to generate request ID: %v", err)
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// trustedProxies are the reverse proxies whose X-Forwarded-For is
+// believed, from TRUSTED_PROXIES. Without them the header is ignored.
+var trustedProxies []*net.IPNet
+
+// loadTrustedProxies reads TRUSTED_PROXIES, a comma separated list of IPs
+// and CIDR ranges.
+func loadTrustedProxies() ([]*net.IPNet, error) {
+	var proxies []*net.IPNet
+	for _, entry := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			if ip := net.ParseIP(entry); ip != nil && ip.To4() != nil {
+				entry += "/32"
+			} else {
+				entry += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(entry)
 		if err != nil {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return nil, fmt.Errorf("invalid TRUSTED_PROXIES entry %q: %v", entry, err)
+		}
+		proxies = append(proxies, network)
+	}
+	return proxies, nil
+}
+
+func isTrustedProxy(host string) bool {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, network := range trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP is the address the request came from. X-Forwarded-For is only
+// followed through trusted proxies: the client is the last address in it
+// that is not one of them, so a client cannot forge its own.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if !isTrustedProxy(host) {
+		return host
+	}
+	hops := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+		if !isTrustedProxy(hop) {
+			return hop
+		}
+		host = hop
+	}
+	return host
+}
+
+// authenticate resolves the token in the Authorization header and returns
+// the request context carrying userType and userID. On failure it has
+// already written the response.
+func (h *Handler) authenticate(w http.ResponseWriter, r *http.Request) (context.Context, bool) {
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		h.auditAuth(r.Context(), service.AuditAuthRejected, 0, "authorization header missing")
+		writeProblem(w, r, http.StatusUnauthorized, "Authorization header missing")
+		return nil, false
+	}
+
+	tokenString := strings.TrimSpace(strings.Replace(authHeader, "Token", "", 1))
+
+	userType, userID, err := AuthenticateJWT(tokenString)
+	if err != nil {
+		h.auditAuth(r.Context(), service.AuditAuthRejected, 0, err.Error())
+		writeProblem(w, r, http.StatusUnauthorized, "Unauthorized")
+		return nil, false
+	}
+
+	ctx := context.WithValue(r.Context(), "userType", userType)
+	ctx = context.WithValue(ctx, "userID", userID)
+
+	// Guardian tokens only open their linked students' contacts.
+	if userType == service.RoleGuardian && !guardianAllowed(r) {
+		h.auditAuth(ctx, service.AuditAuthDenied, 0, "guardian token used for "+r.URL.Path)
+		writeProblem(w, r, http.StatusForbidden, "Forbidden")
+		return nil, false
+	}
+	return ctx, true
+}
+
+// TokenAuthMiddleware only authenticates the caller. Routes behind it do
+// their own authorization, e.g. with AdminOnlyMiddleware.
+func (h *Handler) TokenAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := h.authenticate(w, r)
+		if !ok {
 			return
 		}
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
 
-		ctx := context.WithValue(r.Context(), "userType", userType)
-		ctx = context.WithValue(ctx, "userID", userID)
+// JWTAuthMiddleware authenticates the caller and lets a user modify only the
+// student record whose ID is in the path.
+func (h *Handler) JWTAuthMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, ok := h.authenticate(w, r)
+		if !ok {
+			return
+		}
+		userType, _ := ctx.Value("userType").(string)
+		userID, _ := ctx.Value("userID").(int32)
 
 		// Bypass userID verification for GET requests
 		if r.Method == http.MethodGet {
@@ -92,6 +251,13 @@ func JWTAuthMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
+		// Instructors are scoped to the courses they teach by the service
+		// layer, which also refuses them everything but grading.
+		if userType == service.RoleInstructor {
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
 		// For user, check if the userID matches the ID in the request
 		if userType == "user" {
 			vars := mux.Vars(r)
@@ -102,23 +268,50 @@ func JWTAuthMiddleware(next http.Handler) http.Handler {
 			}
 
 			if requestedUserIDStr == "" {
-				http.Error(w, "User ID not provided in the request", http.StatusBadRequest)
+				writeProblem(w, r, http.StatusBadRequest, "User ID not provided in the request")
 				return
 			}
 
 			requestedUserID, err := strconv.ParseInt(requestedUserIDStr, 10, 32)
 			if err != nil {
-				http.Error(w, "Invalid User ID format", http.StatusBadRequest)
+				writeProblem(w, r, http.StatusBadRequest, "Invalid User ID format")
 				return
 			}
 
 			if int32(requestedUserID) == userID {
 				next.ServeHTTP(w, r.WithContext(ctx))
 			} else {
-				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				h.auditAuth(ctx, service.AuditAuthDenied, int32(requestedUserID), "user may only modify their own record")
+				writeProblem(w, r, http.StatusForbidden, "Users can only modify their own record")
 			}
 		} else {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			h.auditAuth(ctx, service.AuditAuthDenied, 0, "unknown user type")
+			writeProblem(w, r, http.StatusForbidden, "Forbidden")
 		}
 	})
 }
+
+// AdminOnlyMiddleware must run after JWTAuthMiddleware or TokenAuthMiddleware.
+func (h *Handler) AdminOnlyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if userType, _ := r.Context().Value("userType").(string); userType != "admin" {
+			h.auditAuth(r.Context(), service.AuditAuthDenied, 0, "admin role required for "+r.URL.Path)
+			writeProblem(w, r, http.StatusForbidden, "Forbidden")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h *Handler) auditAuth(ctx context.Context, action string, targetID int32, message string) {
+	outcome := service.OutcomeFailure
+	if action == service.AuditAuthDenied {
+		outcome = service.OutcomeDenied
+	}
+	h.Service.RecordAudit(ctx, service.AuditEntry{
+		Action:   action,
+		TargetID: targetID,
+		Outcome:  outcome,
+		Message:  message,
+	})
+}
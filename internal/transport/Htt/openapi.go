@@ -0,0 +1,561 @@
+package Htt
+
+import (
+	service "GO_Assignment_3/internal/service"
+	"GO_Assignment_3/internal/transport/Htt/swaggerui"
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// access is who may call an operation.
+type access int
+
+const (
+	accessPublic access = iota
+	// accessToken needs any valid token; the service may still refuse the
+	// caller with 403.
+	accessToken
+	accessAdmin
+)
+
+// apiParam is a query parameter of an operation.
+type apiParam struct {
+	Name        string
+	Type        string
+	Description string
+	Required    bool
+}
+
+// apiOperation describes one route for the OpenAPI document.
+type apiOperation struct {
+	Method  string
+	Path    string
+	Tag     string
+	Summary string
+	Access  access
+	Query   []apiParam
+	// Body is a value of the request body type; BodyTypes overrides its
+	// media types, which default to application/json.
+	Body      interface{}
+	BodyTypes []string
+	// Status is the success status, 200 unless set.
+	Status int
+	// Result is a value of the response body type, nil for no body. List
+	// wraps it as {"data": [...]}. ResultType overrides the media type.
+	Result     interface{}
+	List       bool
+	ResultType string
+	// Errors are the statuses the operation can fail with besides the ones
+	// every operation of its kind can.
+	Errors []int
+}
+
+func termParam() apiParam {
+	return apiParam{Name: "term", Type: "string", Description: "Term code, or current for the term in progress"}
+}
+
+// apiOperations lists every route of the latest API version, relative to
+// its prefix. TestSpecCoverage keeps the two in step.
+var apiOperations = []apiOperation{
+	{Method: "GET", Path: "/students", Tag: "Students", Summary: "List students a page at a time, or stream them as NDJSON", Access: accessToken,
+		Query: []apiParam{
+			{Name: "filter", Type: "string", Description: "Filter expression, e.g. grade eq 'A' and course co 'data'"},
+			{Name: "limit", Type: "integer", Description: "Page size, at most 100"},
+			{Name: "cursor", Type: "string", Description: "next_cursor of the previous page"},
+			{Name: "sort", Type: "string", Description: "id, name, created_on or updated_on, prefixed with - for descending order"},
+		},
+		Result: service.StudentPage{}},
+	{Method: "GET", Path: "/students/search", Tag: "Students", Summary: "Ranked, typo-tolerant search over names and courses", Access: accessToken,
+		Query: []apiParam{
+			{Name: "q", Type: "string", Description: "Search text", Required: true},
+			{Name: "limit", Type: "integer", Description: "Maximum number of results"},
+		},
+		Result: service.SearchResult{}, List: true},
+	{Method: "GET", Path: "/students/{id}", Tag: "Students", Summary: "Get a student, with its ETag", Access: accessToken, Result: service.Student{}},
+	{Method: "POST", Path: "/students", Tag: "Students", Summary: "Create a student", Access: accessToken, Body: service.Student{}, Status: http.StatusCreated, Result: service.Student{}},
+	{Method: "PUT", Path: "/students/{id}", Tag: "Students", Summary: "Replace a student; conditional with If-Match", Access: accessToken, Body: service.Student{}, Result: service.Student{},
+		Errors: []int{http.StatusPreconditionFailed}},
+	{Method: "PATCH", Path: "/students/{id}", Tag: "Students", Summary: "Update some fields of a student with a JSON Merge Patch or a JSON Patch", Access: accessToken,
+		Body: json.RawMessage{}, BodyTypes: []string{mergePatchType, jsonPatchType}, Result: service.Student{},
+		Errors: []int{http.StatusPreconditionFailed, http.StatusUnsupportedMediaType, http.StatusUnprocessableEntity}},
+	{Method: "DELETE", Path: "/students/{id}", Tag: "Students", Summary: "Delete a student; conditional with If-Match", Access: accessToken, Status: http.StatusNoContent,
+		Errors: []int{http.StatusPreconditionFailed}},
+	{Method: "POST", Path: "/students/batch", Tag: "Students", Summary: "Create, update and delete students in one request, with a result per operation; all or nothing with atomic", Access: accessAdmin,
+		Body: batchRequest{}, Result: batchResponse{}, Errors: []int{http.StatusRequestEntityTooLarge}},
+	{Method: "POST", Path: "/students/import", Tag: "Students", Summary: "Create and update students from a CSV file, or check it with dry_run; the report is JSON or a CSV download", Access: accessAdmin,
+		Query: []apiParam{
+			{Name: "map", Type: "string", Description: "field:Column, repeated, to read a field (id, password, name, course, course_id or grade) from another column"},
+			{Name: "dry_run", Type: "boolean", Description: "true to only report what would be created or updated"},
+			{Name: "format", Type: "string", Description: "csv for the report as a CSV download"},
+		},
+		Body: "", BodyTypes: []string{csvType}, Result: importReport{},
+		Errors: []int{http.StatusRequestEntityTooLarge, http.StatusUnsupportedMediaType}},
+	{Method: "POST", Path: "/register", Tag: "Students", Summary: "Register as a student and get a token", Access: accessPublic, Body: service.Student{}, Result: tokenResponse{}},
+
+	{Method: "GET", Path: "/students/{id}/enrollments", Tag: "Enrollments", Summary: "List the enrollments of a student", Access: accessToken, Query: []apiParam{termParam()}, Result: service.Enrollment{}, List: true},
+	{Method: "GET", Path: "/students/{id}/enrollments/{enrollmentID}", Tag: "Enrollments", Summary: "Get an enrollment", Access: accessToken, Result: service.Enrollment{}},
+	{Method: "POST", Path: "/students/{id}/enrollments", Tag: "Enrollments", Summary: "Enroll a student in a course, or put them on its waitlist", Access: accessToken, Body: service.Enrollment{}, Status: http.StatusCreated, Result: service.Enrollment{},
+		Errors: []int{http.StatusConflict, http.StatusUnprocessableEntity}},
+	{Method: "PUT", Path: "/students/{id}/enrollments/{enrollmentID}", Tag: "Enrollments", Summary: "Update the grade or status of an enrollment", Access: accessToken, Body: service.Enrollment{}, Result: service.Enrollment{}},
+	{Method: "DELETE", Path: "/students/{id}/enrollments/{enrollmentID}", Tag: "Enrollments", Summary: "Delete an enrollment", Access: accessToken, Status: http.StatusNoContent},
+	{Method: "GET", Path: "/courses/{id}/students", Tag: "Enrollments", Summary: "Roster of a course", Access: accessToken, Query: []apiParam{termParam()}, Result: service.Enrollment{}, List: true},
+	{Method: "GET", Path: "/courses/{id}/waitlist", Tag: "Enrollments", Summary: "Waitlist of a course, in order", Access: accessToken, Query: []apiParam{termParam()}, Result: service.Enrollment{}, List: true},
+
+	{Method: "GET", Path: "/students/{id}/gpa", Tag: "Grades", Summary: "GPA of a student, overall and per term", Access: accessToken, Result: service.GPA{}},
+	{Method: "GET", Path: "/students/{id}/transcript", Tag: "Transcripts", Summary: "Transcript as JSON, or as an unofficial PDF with Accept: application/pdf or ?format=pdf", Access: accessToken,
+		Query: []apiParam{{Name: "format", Type: "string", Description: "pdf for an unofficial PDF"}}, Result: service.Transcript{}},
+	{Method: "POST", Path: "/students/{id}/transcript", Tag: "Transcripts", Summary: "Issue an official transcript with a verification code, as JSON or as a PDF; only the student and the admin", Access: accessToken,
+		Query: []apiParam{{Name: "format", Type: "string", Description: "pdf for the official PDF"}}, Status: http.StatusCreated, Result: service.Transcript{}},
+	{Method: "GET", Path: "/transcripts/verify/{code}", Tag: "Transcripts", Summary: "Check that a transcript was issued here and is unchanged", Access: accessPublic, Result: verifyResponse{},
+		Errors: []int{http.StatusConflict}},
+
+	{Method: "GET", Path: "/students/{id}/prerequisite-overrides", Tag: "Prerequisites", Summary: "List the prerequisite overrides of a student", Access: accessToken, Result: service.PrerequisiteOverride{}, List: true},
+	{Method: "POST", Path: "/students/{id}/prerequisite-overrides", Tag: "Prerequisites", Summary: "Let a student skip the prerequisites of a course", Access: accessAdmin, Body: service.PrerequisiteOverride{}, Status: http.StatusCreated, Result: service.PrerequisiteOverride{},
+		Errors: []int{http.StatusConflict}},
+	{Method: "DELETE", Path: "/students/{id}/prerequisite-overrides/{overrideID}", Tag: "Prerequisites", Summary: "Revoke a prerequisite override", Access: accessAdmin, Status: http.StatusNoContent},
+	{Method: "GET", Path: "/courses/{id}/prerequisites", Tag: "Prerequisites", Summary: "Prerequisites of a course", Access: accessToken, Result: prerequisiteRules{}},
+	{Method: "PUT", Path: "/courses/{id}/prerequisites", Tag: "Prerequisites", Summary: "Replace the prerequisites of a course", Access: accessAdmin, Body: prerequisiteRules{}, Result: prerequisiteRules{}},
+
+	{Method: "GET", Path: "/students/{id}/attendance", Tag: "Attendance", Summary: "Attendance of a student per course and overall", Access: accessToken, Query: []apiParam{termParam()}, Result: service.StudentAttendance{}},
+	{Method: "GET", Path: "/courses/{id}/sessions", Tag: "Attendance", Summary: "List the class sessions of a course", Access: accessToken, Query: []apiParam{termParam()}, Result: service.ClassSession{}, List: true},
+	{Method: "POST", Path: "/courses/{id}/sessions", Tag: "Attendance", Summary: "Schedule a class session", Access: accessToken, Body: service.ClassSession{}, Status: http.StatusCreated, Result: service.ClassSession{}},
+	{Method: "GET", Path: "/courses/{id}/sessions/{sessionID}", Tag: "Attendance", Summary: "Get a class session", Access: accessToken, Result: service.ClassSession{}},
+	{Method: "PUT", Path: "/courses/{id}/sessions/{sessionID}", Tag: "Attendance", Summary: "Update a class session", Access: accessToken, Body: service.ClassSession{}, Result: service.ClassSession{}},
+	{Method: "DELETE", Path: "/courses/{id}/sessions/{sessionID}", Tag: "Attendance", Summary: "Delete a class session and its attendance", Access: accessToken, Status: http.StatusNoContent},
+	{Method: "GET", Path: "/courses/{id}/sessions/{sessionID}/attendance", Tag: "Attendance", Summary: "Attendance marks of a session", Access: accessToken, Result: service.AttendanceMark{}, List: true},
+	{Method: "PUT", Path: "/courses/{id}/sessions/{sessionID}/attendance", Tag: "Attendance", Summary: "Mark the attendance of a session", Access: accessToken, Body: service.BulkAttendance{}, Result: service.AttendanceMark{}, List: true},
+	{Method: "GET", Path: "/attendance/report", Tag: "Attendance", Summary: "Enrollments with attendance below a threshold", Access: accessToken,
+		Query: []apiParam{
+			{Name: "threshold", Type: "number", Description: "Percentage, default ATTENDANCE_THRESHOLD or 75"},
+			{Name: "course", Type: "integer", Description: "Only this course"},
+			termParam(),
+		},
+		Result: service.AttendanceSummary{}, List: true},
+
+	{Method: "GET", Path: "/students/{id}/submissions", Tag: "Assignments", Summary: "List the submissions of a student", Access: accessToken, Result: service.Submission{}, List: true},
+	{Method: "POST", Path: "/students/{id}/submissions", Tag: "Assignments", Summary: "Hand in work for an assignment", Access: accessToken,
+		Body: submissionUpload{}, BodyTypes: []string{"multipart/form-data"}, Status: http.StatusCreated, Result: service.Submission{},
+		Errors: []int{http.StatusConflict, http.StatusRequestEntityTooLarge, http.StatusServiceUnavailable}},
+	{Method: "GET", Path: "/students/{id}/submissions/{submissionID}", Tag: "Assignments", Summary: "Get a submission", Access: accessToken, Result: service.Submission{}},
+	{Method: "GET", Path: "/students/{id}/submissions/{submissionID}/file", Tag: "Assignments", Summary: "Download the file of a submission", Access: accessToken, Result: fileDownload{}, ResultType: "application/octet-stream"},
+	{Method: "PUT", Path: "/students/{id}/submissions/{submissionID}/grade", Tag: "Assignments", Summary: "Grade a submission", Access: accessToken, Body: service.SubmissionGrade{}, Result: service.Submission{}},
+	{Method: "GET", Path: "/courses/{id}/assignments", Tag: "Assignments", Summary: "List the assignments of a course", Access: accessToken, Query: []apiParam{termParam()}, Result: service.Assignment{}, List: true},
+	{Method: "POST", Path: "/courses/{id}/assignments", Tag: "Assignments", Summary: "Create an assignment", Access: accessToken, Body: service.Assignment{}, Status: http.StatusCreated, Result: service.Assignment{}},
+	{Method: "GET", Path: "/courses/{id}/assignments/{assignmentID}", Tag: "Assignments", Summary: "Get an assignment", Access: accessToken, Result: service.Assignment{}},
+	{Method: "PUT", Path: "/courses/{id}/assignments/{assignmentID}", Tag: "Assignments", Summary: "Update an assignment", Access: accessToken, Body: service.Assignment{}, Result: service.Assignment{}},
+	{Method: "DELETE", Path: "/courses/{id}/assignments/{assignmentID}", Tag: "Assignments", Summary: "Delete an assignment and its submissions", Access: accessToken, Status: http.StatusNoContent},
+	{Method: "GET", Path: "/courses/{id}/assignments/{assignmentID}/submissions", Tag: "Assignments", Summary: "List the submissions for an assignment", Access: accessToken, Result: service.Submission{}, List: true},
+
+	{Method: "GET", Path: "/students/{id}/contacts", Tag: "Contacts", Summary: "List the contacts of a student, primary first", Access: accessToken, Result: service.Contact{}, List: true},
+	{Method: "POST", Path: "/students/{id}/contacts", Tag: "Contacts", Summary: "Add a contact", Access: accessToken, Body: service.Contact{}, Status: http.StatusCreated, Result: service.Contact{}},
+	{Method: "GET", Path: "/students/{id}/contacts/{contactID}", Tag: "Contacts", Summary: "Get a contact", Access: accessToken, Result: service.Contact{}},
+	{Method: "PUT", Path: "/students/{id}/contacts/{contactID}", Tag: "Contacts", Summary: "Update a contact", Access: accessToken, Body: service.Contact{}, Result: service.Contact{}},
+	{Method: "DELETE", Path: "/students/{id}/contacts/{contactID}", Tag: "Contacts", Summary: "Delete a contact", Access: accessToken, Status: http.StatusNoContent},
+	{Method: "POST", Path: "/guardians/login", Tag: "Contacts", Summary: "Log in as a guardian", Access: accessPublic, Body: loginRequest{}, Result: tokenResponse{}, Errors: []int{http.StatusUnauthorized}},
+	{Method: "GET", Path: "/guardians", Tag: "Contacts", Summary: "List guardian accounts", Access: accessAdmin, Result: service.Guardian{}, List: true},
+	{Method: "GET", Path: "/guardians/{id}", Tag: "Contacts", Summary: "Get a guardian account", Access: accessAdmin, Result: service.Guardian{}},
+	{Method: "POST", Path: "/guardians", Tag: "Contacts", Summary: "Create a guardian account", Access: accessAdmin, Body: service.Guardian{}, Status: http.StatusCreated, Result: service.Guardian{}, Errors: []int{http.StatusConflict}},
+	{Method: "PUT", Path: "/guardians/{id}", Tag: "Contacts", Summary: "Update a guardian account", Access: accessAdmin, Body: service.Guardian{}, Result: service.Guardian{}, Errors: []int{http.StatusConflict}},
+	{Method: "DELETE", Path: "/guardians/{id}", Tag: "Contacts", Summary: "Delete a guardian account", Access: accessAdmin, Status: http.StatusNoContent},
+	{Method: "GET", Path: "/guardians/{id}/students", Tag: "Contacts", Summary: "Students a guardian is linked to", Access: accessToken, Result: service.Student{}, List: true},
+
+	{Method: "GET", Path: "/courses", Tag: "Courses", Summary: "List the course catalog", Access: accessToken, Result: service.Course{}, List: true},
+	{Method: "GET", Path: "/courses/{id}", Tag: "Courses", Summary: "Get a course", Access: accessToken, Result: service.Course{}},
+	{Method: "POST", Path: "/courses", Tag: "Courses", Summary: "Create a course", Access: accessAdmin, Body: service.Course{}, Status: http.StatusCreated, Result: service.Course{}, Errors: []int{http.StatusConflict}},
+	{Method: "PUT", Path: "/courses/{id}", Tag: "Courses", Summary: "Update a course", Access: accessAdmin, Body: service.Course{}, Result: service.Course{}, Errors: []int{http.StatusConflict}},
+	{Method: "DELETE", Path: "/courses/{id}", Tag: "Courses", Summary: "Delete a course", Access: accessAdmin, Status: http.StatusNoContent, Errors: []int{http.StatusConflict}},
+
+	{Method: "GET", Path: "/grading-scales", Tag: "Grades", Summary: "List grading scales", Access: accessToken, Result: service.GradingScale{}, List: true},
+	{Method: "GET", Path: "/grading-scales/{id}", Tag: "Grades", Summary: "Get a grading scale", Access: accessToken, Result: service.GradingScale{}},
+	{Method: "POST", Path: "/grading-scales", Tag: "Grades", Summary: "Create a grading scale", Access: accessAdmin, Body: service.GradingScale{}, Status: http.StatusCreated, Result: service.GradingScale{}, Errors: []int{http.StatusConflict}},
+	{Method: "PUT", Path: "/grading-scales/{id}", Tag: "Grades", Summary: "Update a grading scale", Access: accessAdmin, Body: service.GradingScale{}, Result: service.GradingScale{}, Errors: []int{http.StatusConflict}},
+	{Method: "DELETE", Path: "/grading-scales/{id}", Tag: "Grades", Summary: "Delete a grading scale", Access: accessAdmin, Status: http.StatusNoContent, Errors: []int{http.StatusConflict}},
+
+	{Method: "GET", Path: "/terms", Tag: "Terms", Summary: "List terms", Access: accessToken, Result: service.Term{}, List: true},
+	{Method: "GET", Path: "/terms/current", Tag: "Terms", Summary: "The term in progress today", Access: accessToken, Result: service.Term{}},
+	{Method: "GET", Path: "/terms/{id}", Tag: "Terms", Summary: "Get a term", Access: accessToken, Result: service.Term{}},
+	{Method: "POST", Path: "/terms", Tag: "Terms", Summary: "Create a term", Access: accessAdmin, Body: service.Term{}, Status: http.StatusCreated, Result: service.Term{}, Errors: []int{http.StatusConflict}},
+	{Method: "PUT", Path: "/terms/{id}", Tag: "Terms", Summary: "Update a term", Access: accessAdmin, Body: service.Term{}, Result: service.Term{}, Errors: []int{http.StatusConflict}},
+	{Method: "DELETE", Path: "/terms/{id}", Tag: "Terms", Summary: "Delete a term", Access: accessAdmin, Status: http.StatusNoContent, Errors: []int{http.StatusConflict}},
+
+	{Method: "POST", Path: "/instructors/login", Tag: "Instructors", Summary: "Log in as an instructor", Access: accessPublic, Body: loginRequest{}, Result: tokenResponse{}, Errors: []int{http.StatusUnauthorized}},
+	{Method: "GET", Path: "/instructors", Tag: "Instructors", Summary: "List instructors", Access: accessToken, Result: service.Instructor{}, List: true},
+	{Method: "GET", Path: "/instructors/{id}", Tag: "Instructors", Summary: "Get an instructor", Access: accessToken, Result: service.Instructor{}},
+	{Method: "POST", Path: "/instructors", Tag: "Instructors", Summary: "Create an instructor account", Access: accessAdmin, Body: service.Instructor{}, Status: http.StatusCreated, Result: service.Instructor{}, Errors: []int{http.StatusConflict}},
+	{Method: "PUT", Path: "/instructors/{id}", Tag: "Instructors", Summary: "Update an instructor account", Access: accessAdmin, Body: service.Instructor{}, Result: service.Instructor{}, Errors: []int{http.StatusConflict}},
+	{Method: "DELETE", Path: "/instructors/{id}", Tag: "Instructors", Summary: "Delete an instructor account", Access: accessAdmin, Status: http.StatusNoContent},
+	{Method: "GET", Path: "/instructors/{id}/courses", Tag: "Instructors", Summary: "Courses an instructor teaches", Access: accessToken, Result: service.Course{}, List: true},
+	{Method: "PUT", Path: "/instructors/{id}/courses/{courseID}", Tag: "Instructors", Summary: "Assign a course to an instructor", Access: accessAdmin, Status: http.StatusNoContent},
+	{Method: "DELETE", Path: "/instructors/{id}/courses/{courseID}", Tag: "Instructors", Summary: "Take a course away from an instructor", Access: accessAdmin, Status: http.StatusNoContent},
+
+	{Method: "POST", Path: "/graphql", Tag: "GraphQL", Summary: "Query students, courses, enrollments, terms and contacts in one request, or create, update and delete students and enroll them", Access: accessToken,
+		Body: graphqlRequest{}, Result: graphqlResponse{}},
+
+	{Method: "GET", Path: "/audit", Tag: "Audit", Summary: "Search the audit log", Access: accessAdmin,
+		Query: []apiParam{
+			{Name: "actor_id", Type: "integer"},
+			{Name: "actor_role", Type: "string"},
+			{Name: "action", Type: "string", Description: "e.g. student.update"},
+			{Name: "target_id", Type: "integer"},
+			{Name: "outcome", Type: "string", Description: "success or failure"},
+			{Name: "request_id", Type: "string"},
+			{Name: "from", Type: "string", Description: "RFC 3339 time"},
+			{Name: "to", Type: "string", Description: "RFC 3339 time"},
+			{Name: "limit", Type: "integer"},
+			{Name: "offset", Type: "integer"},
+		},
+		Result: auditResponse{}},
+}
+
+// Types that only exist to describe bodies that are not JSON.
+type (
+	submissionUpload struct {
+		AssignmentID int32  `json:"assignment_id" validate:"required"`
+		File         []byte `json:"file" validate:"required"`
+	}
+	fileDownload []byte
+)
+
+var pathParamPattern = regexp.MustCompile(`\{([^}]+)\}`)
+
+// openAPIBuilder collects the component schemas while the paths are built.
+type openAPIBuilder struct {
+	schemas map[string]interface{}
+}
+
+// schemaName is the component name of a named type.
+func schemaName(t reflect.Type) string {
+	name := t.Name()
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// schema returns the JSON schema of t, adding named struct types to the
+// components and referring to them.
+func (b *openAPIBuilder) schema(t reflect.Type) map[string]interface{} {
+	switch t {
+	case reflect.TypeOf(json.RawMessage{}):
+		return map[string]interface{}{}
+	case reflect.TypeOf(fileDownload{}):
+		return map[string]interface{}{"type": "string", "format": "binary"}
+	case reflect.TypeOf(time.Time{}):
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		s := b.schema(t.Elem())
+		s["nullable"] = true
+		return s
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Int32:
+		return map[string]interface{}{"type": "integer", "format": "int32"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "binary"}
+		}
+		return map[string]interface{}{"type": "array", "items": b.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": b.schema(t.Elem())}
+	case reflect.Struct:
+		name := schemaName(t)
+		if _, ok := b.schemas[name]; !ok {
+			b.schemas[name] = nil // guards against recursive types
+			b.schemas[name] = b.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	}
+	return map[string]interface{}{}
+}
+
+func (b *openAPIBuilder) structSchema(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+		if !f.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		s := b.schema(f.Type)
+		if rules := f.Tag.Get("validate"); rules != "" {
+			if applyRules(s, rules) {
+				required = append(required, name)
+			}
+		}
+		properties[name] = s
+	}
+	s := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+// applyRules adds the constraints of validate tags to a property schema and
+// reports whether the property is required.
+func applyRules(s map[string]interface{}, rules string) bool {
+	if _, isRef := s["$ref"]; isRef {
+		return strings.Contains(","+rules+",", ",required,")
+	}
+	required := false
+	str := s["type"] == "string"
+	arr := s["type"] == "array"
+	for _, rule := range strings.Split(rules, ",") {
+		key, arg, _ := strings.Cut(rule, "=")
+		n, _ := strconv.ParseFloat(arg, 64)
+		switch {
+		case key == "required":
+			required = true
+		case key == "email":
+			s["format"] = "email"
+		case key == "grade":
+			s["pattern"] = gradePattern.String()
+		case key == "oneof":
+			s["enum"] = strings.Fields(arg)
+		case key == "datetime" && arg == "2006-01-02":
+			s["format"] = "date"
+		case key == "datetime":
+			s["format"] = "date-time"
+		case key == "max" && str:
+			s["maxLength"] = int(n)
+		case key == "min" && str:
+			s["minLength"] = int(n)
+		case key == "min" && arr:
+			s["minItems"] = int(n)
+		case (key == "max" || key == "lte") && !arr:
+			s["maximum"] = n
+		case key == "gte" || key == "min":
+			s["minimum"] = n
+		case key == "gt":
+			s["minimum"] = n
+			s["exclusiveMinimum"] = true
+		}
+	}
+	return required
+}
+
+func (b *openAPIBuilder) content(mediaTypes []string, v interface{}, list bool) map[string]interface{} {
+	s := b.schema(reflect.TypeOf(v))
+	if list {
+		s = map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"data": map[string]interface{}{"type": "array", "items": s}},
+		}
+	}
+	content := map[string]interface{}{}
+	for _, mt := range mediaTypes {
+		content[mt] = map[string]interface{}{"schema": s}
+	}
+	return content
+}
+
+func problemResponse(status int) map[string]interface{} {
+	return map[string]interface{}{
+		"description": http.StatusText(status),
+		"content": map[string]interface{}{
+			problemContentType: map[string]interface{}{"schema": map[string]interface{}{"$ref": "#/components/schemas/Problem"}},
+		},
+	}
+}
+
+func (b *openAPIBuilder) operation(op apiOperation) map[string]interface{} {
+	var params []interface{}
+	for _, m := range pathParamPattern.FindAllStringSubmatch(op.Path, -1) {
+		typ := "integer"
+		if m[1] == "code" {
+			typ = "string"
+		}
+		params = append(params, map[string]interface{}{
+			"name": m[1], "in": "path", "required": true, "schema": map[string]interface{}{"type": typ},
+		})
+	}
+	for _, q := range op.Query {
+		p := map[string]interface{}{"name": q.Name, "in": "query", "schema": map[string]interface{}{"type": q.Type}}
+		if q.Description != "" {
+			p["description"] = q.Description
+		}
+		if q.Required {
+			p["required"] = true
+		}
+		params = append(params, p)
+	}
+
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := map[string]interface{}{"description": http.StatusText(status)}
+	if op.Result != nil {
+		resultType := op.ResultType
+		if resultType == "" {
+			resultType = "application/json"
+		}
+		success["content"] = b.content([]string{resultType}, op.Result, op.List)
+	}
+	responses := map[string]interface{}{strconv.Itoa(status): success}
+
+	errs := append([]int{http.StatusInternalServerError}, op.Errors...)
+	if len(params) > 0 || op.Body != nil {
+		errs = append(errs, http.StatusBadRequest)
+	}
+	if op.Access != accessPublic {
+		errs = append(errs, http.StatusUnauthorized, http.StatusForbidden)
+	}
+	if strings.Contains(op.Path, "{") {
+		errs = append(errs, http.StatusNotFound)
+	}
+	for _, e := range errs {
+		responses[strconv.Itoa(e)] = problemResponse(e)
+	}
+
+	o := map[string]interface{}{
+		"summary":     op.Summary,
+		"tags":        []string{op.Tag},
+		"operationId": operationID(op),
+		"responses":   responses,
+	}
+	if len(params) > 0 {
+		o["parameters"] = params
+	}
+	if op.Body != nil {
+		bodyTypes := op.BodyTypes
+		if len(bodyTypes) == 0 {
+			bodyTypes = []string{"application/json"}
+		}
+		o["requestBody"] = map[string]interface{}{"required": true, "content": b.content(bodyTypes, op.Body, false)}
+	}
+	switch op.Access {
+	case accessPublic:
+		o["security"] = []interface{}{}
+	case accessAdmin:
+		o["description"] = "Admin only."
+	}
+	return o
+}
+
+// operationID is a stable identifier such as get_students_id_enrollments.
+func operationID(op apiOperation) string {
+	path := strings.NewReplacer("{", "", "}", "", "-", "_", ".", "_").Replace(op.Path)
+	parts := strings.FieldsFunc(path, func(r rune) bool { return r == '/' })
+	return strings.ToLower(op.Method) + "_" + strings.Join(parts, "_")
+}
+
+// buildOpenAPI assembles the OpenAPI 3 document from apiOperations.
+func buildOpenAPI() map[string]interface{} {
+	b := &openAPIBuilder{schemas: map[string]interface{}{}}
+	b.schema(reflect.TypeOf(Problem{}))
+
+	paths := map[string]interface{}{}
+	for _, op := range apiOperations {
+		item, ok := paths[op.Path].(map[string]interface{})
+		if !ok {
+			item = map[string]interface{}{}
+			paths[op.Path] = item
+		}
+		item[strings.ToLower(op.Method)] = b.operation(op)
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "Student API",
+			"version":     "1.0.0",
+			"description": "Students, courses, enrollments, grades, attendance, assignments and contacts. Errors are RFC 7807 problem documents. The same routes without the version prefix are deprecated aliases, announced with the Deprecation and Sunset headers.",
+		},
+		"servers": []interface{}{map[string]interface{}{"url": apiVersions[latestVersion-1].prefix}},
+		"paths":   paths,
+		"components": map[string]interface{}{
+			"schemas": b.schemas,
+			"securitySchemes": map[string]interface{}{
+				"tokenAuth": map[string]interface{}{
+					"type":        "apiKey",
+					"in":          "header",
+					"name":        "Authorization",
+					"description": "A JWT from /register or a login, sent as \"Token <jwt>\".",
+				},
+			},
+		},
+		"security": []interface{}{map[string]interface{}{"tokenAuth": []string{}}},
+	}
+}
+
+var (
+	openAPIOnce sync.Once
+	openAPIJSON []byte
+)
+
+// OpenAPI - GET /openapi.json
+func (h *Handler) OpenAPI(w http.ResponseWriter, r *http.Request) {
+	openAPIOnce.Do(func() {
+		openAPIJSON, _ = json.MarshalIndent(buildOpenAPI(), "", "  ")
+	})
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPIJSON)
+}
+
+// docsPage is Swagger UI pointed at /openapi.json. Its scripts and styles
+// are embedded in the binary and served under /docs/assets/.
+const docsPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Student API</title>
+  <link rel="stylesheet" href="/docs/assets/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="/docs/assets/swagger-ui-bundle.js"></script>
+  <script>
+    window.onload = function () {
+      window.ui = SwaggerUIBundle({ url: "/openapi.json", dom_id: "#swagger-ui" });
+    };
+  </script>
+</body>
+</html>
+`
+
+// docsAssets are the embedded Swagger UI files.
+var docsAssets, _ = fs.Sub(swaggerui.Files, "dist")
+
+// docsMissing is the reason /docs cannot be served, when the Swagger UI
+// files were not generated before the build.
+const docsMissing = "The Swagger UI files are missing; run go generate ./internal/transport/Htt/swaggerui"
+
+// docsAvailable reports whether the Swagger UI files are embedded.
+func docsAvailable() bool {
+	for _, name := range []string{"swagger-ui.css", "swagger-ui-bundle.js"} {
+		if _, err := fs.Stat(docsAssets, name); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Docs - GET /docs, interactive documentation of the API.
+func (h *Handler) Docs(w http.ResponseWriter, r *http.Request) {
+	if !docsAvailable() {
+		writeProblem(w, r, http.StatusServiceUnavailable, docsMissing)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(docsPage))
+}
+
+// DocsAssets - GET /docs/assets/{file}, the files of Swagger UI.
+var DocsAssets = http.StripPrefix("/docs/assets/", http.FileServer(http.FS(docsAssets)))
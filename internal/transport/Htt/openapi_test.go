@@ -0,0 +1,66 @@
+package Htt
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// TestSpecCoverage fails when the routes of the latest API version and
+// apiOperations have drifted apart.
+func TestSpecCoverage(t *testing.T) {
+	router := mux.NewRouter()
+	apiVersions[latestVersion-1].mount(&Handler{}, router)
+	if err := checkSpecCoverage(router); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// checkSpecCoverage compares the routes mounted on router with
+// apiOperations and fails when a route is missing from the document, or the
+// document lists a route that does not exist, so the two cannot drift apart.
+func checkSpecCoverage(router *mux.Router) error {
+	documented := map[string]bool{}
+	for _, op := range apiOperations {
+		documented[op.Method+" "+op.Path] = true
+	}
+
+	registered := map[string]bool{}
+	err := router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil // a subrouter's prefix, not an endpoint
+		}
+		for _, m := range methods {
+			registered[m+" "+path] = true
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var problems []string
+	for route := range registered {
+		if !documented[route] {
+			problems = append(problems, "not in the OpenAPI document: "+route)
+		}
+	}
+	for route := range documented {
+		if !registered[route] {
+			problems = append(problems, "documented but not registered: "+route)
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("OpenAPI document is out of date:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package Htt
+
+import (
+	service "GO_Assignment_3/internal/service"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/gorilla/mux"
+)
+
+// Patch document media types.
+const (
+	mergePatchType = "application/merge-patch+json"
+	jsonPatchType  = "application/json-patch+json"
+)
+
+// acceptPatch is advertised with the Accept-Patch header (RFC 5789).
+const acceptPatch = mergePatchType + ", " + jsonPatchType
+
+// studentPatcher turns a patch document into the function
+// service.PatchStudent applies to the stored student. Malformed documents
+// are reported before anything is read from the store; the patched student
+// is validated like the body of a PUT.
+func studentPatcher(r *http.Request, contentType string, body []byte) (func(service.Student) (service.Student, error), error) {
+	var apply func([]byte) ([]byte, error)
+	switch contentType {
+	case mergePatchType:
+		if !json.Valid(body) {
+			return nil, errors.New("merge patch is not valid JSON")
+		}
+		apply = func(doc []byte) ([]byte, error) { return jsonpatch.MergePatch(doc, body) }
+	case jsonPatchType:
+		patch, err := jsonpatch.DecodePatch(body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSON patch: %v", err)
+		}
+		apply = patch.Apply
+	default:
+		return nil, fmt.Errorf("unsupported patch type %q", contentType)
+	}
+
+	return func(current service.Student) (service.Student, error) {
+		doc, err := json.Marshal(current)
+		if err != nil {
+			return service.Student{}, err
+		}
+		doc, err = apply(doc)
+		if err != nil {
+			return service.Student{}, fmt.Errorf("%w: %v", service.ErrInvalidPatch, err)
+		}
+		var patched service.Student
+		if err := json.Unmarshal(doc, &patched); err != nil {
+			return service.Student{}, fmt.Errorf("%w: %v", service.ErrInvalidPatch, err)
+		}
+		if err := validateRequest(r, &patched); err != nil {
+			return service.Student{}, err
+		}
+		return patched, nil
+	}, nil
+}
+
+// PatchStudent - PATCH /students/{id}
+// Accepts a JSON Merge Patch (RFC 7396) or a JSON Patch (RFC 6902), chosen
+// by Content-Type, and updates only the fields it touches.
+func (h *Handler) PatchStudent(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 32)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid student ID format")
+		return
+	}
+
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if contentType != mergePatchType && contentType != jsonPatchType {
+		w.Header().Set("Accept-Patch", acceptPatch)
+		writeProblem(w, r, http.StatusUnsupportedMediaType, "PATCH needs Content-Type "+mergePatchType+" or "+jsonPatchType)
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	patch, err := studentPatcher(r, contentType, body)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+	student, err := h.Service.PatchStudent(withStudentIfMatch(r), int32(id), patch)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(student)
+}
@@ -0,0 +1,116 @@
+package Htt
+
+import (
+	service "GO_Assignment_3/internal/service"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// prerequisiteRules is the body of GET and PUT /courses/{id}/prerequisites:
+// every group of all_of is required, any one course of a group meets it.
+type prerequisiteRules struct {
+	AllOf []service.PrerequisiteGroup `json:"all_of" validate:"dive"`
+}
+
+func (h *Handler) GetPrerequisites(w http.ResponseWriter, r *http.Request) {
+	courseID, err := parseCourseID(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid course ID format")
+		return
+	}
+
+	groups, err := h.Service.GetPrerequisites(r.Context(), courseID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(prerequisiteRules{AllOf: groups})
+}
+
+// SetPrerequisites - PUT /courses/{id}/prerequisites, replacing all rules.
+func (h *Handler) SetPrerequisites(w http.ResponseWriter, r *http.Request) {
+	courseID, err := parseCourseID(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid course ID format")
+		return
+	}
+
+	var rules prerequisiteRules
+	if !decodeRequest(w, r, &rules) {
+		return
+	}
+
+	groups, err := h.Service.SetPrerequisites(r.Context(), courseID, rules.AllOf)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(prerequisiteRules{AllOf: groups})
+}
+
+func (h *Handler) GetStudentOverrides(w http.ResponseWriter, r *http.Request) {
+	studentID, _, err := parseEnrollmentPath(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid student ID format")
+		return
+	}
+
+	overrides, err := h.Service.GetStudentOverrides(r.Context(), studentID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": overrides})
+}
+
+func (h *Handler) GrantOverride(w http.ResponseWriter, r *http.Request) {
+	studentID, _, err := parseEnrollmentPath(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid student ID format")
+		return
+	}
+
+	var override service.PrerequisiteOverride
+	if !decodeRequest(w, r, &override) {
+		return
+	}
+
+	created, err := h.Service.GrantOverride(r.Context(), studentID, override)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+func (h *Handler) RevokeOverride(w http.ResponseWriter, r *http.Request) {
+	studentID, _, err := parseEnrollmentPath(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid student ID format")
+		return
+	}
+	overrideID, err := strconv.ParseInt(mux.Vars(r)["overrideID"], 10, 32)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid override ID format")
+		return
+	}
+
+	if err := h.Service.RevokeOverride(r.Context(), studentID, int32(overrideID)); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,189 @@
+package Htt
+
+import (
+	service "GO_Assignment_3/internal/service"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+)
+
+// problemContentType is the media type of error responses (RFC 7807).
+const problemContentType = "application/problem+json"
+
+// Problem is the body of every error response (RFC 7807). Code is a stable,
+// machine-readable identifier of the error; clients should branch on it
+// rather than on Detail, which is meant for people.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Code      string       `json:"code"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// problemMapping ties a service error to its status and code.
+type problemMapping struct {
+	err    error
+	status int
+	code   string
+}
+
+// problemMappings is the single place where service errors become HTTP
+// responses. The first entry the error matches with errors.Is wins.
+var problemMappings = []problemMapping{
+	{service.ErrStudentNotFound, http.StatusNotFound, "student_not_found"},
+	{service.ErrCourseNotFound, http.StatusNotFound, "course_not_found"},
+	{service.ErrTermNotFound, http.StatusNotFound, "term_not_found"},
+	{service.ErrNoCurrentTerm, http.StatusNotFound, "no_current_term"},
+	{service.ErrEnrollmentNotFound, http.StatusNotFound, "enrollment_not_found"},
+	{service.ErrGradingScaleNotFound, http.StatusNotFound, "grading_scale_not_found"},
+	{service.ErrOverrideNotFound, http.StatusNotFound, "override_not_found"},
+	{service.ErrInstructorNotFound, http.StatusNotFound, "instructor_not_found"},
+	{service.ErrSessionNotFound, http.StatusNotFound, "session_not_found"},
+	{service.ErrAssignmentNotFound, http.StatusNotFound, "assignment_not_found"},
+	{service.ErrSubmissionNotFound, http.StatusNotFound, "submission_not_found"},
+	{service.ErrFileNotFound, http.StatusNotFound, "file_not_found"},
+	{service.ErrContactNotFound, http.StatusNotFound, "contact_not_found"},
+	{service.ErrGuardianNotFound, http.StatusNotFound, "guardian_not_found"},
+	{service.ErrTranscriptNotFound, http.StatusNotFound, "transcript_not_found"},
+
+	{service.ErrCourseCodeTaken, http.StatusConflict, "course_code_taken"},
+	{service.ErrCourseInUse, http.StatusConflict, "course_in_use"},
+	{service.ErrTermCodeTaken, http.StatusConflict, "term_code_taken"},
+	{service.ErrTermInUse, http.StatusConflict, "term_in_use"},
+	{service.ErrAlreadyEnrolled, http.StatusConflict, "already_enrolled"},
+	{service.ErrGradingScaleTaken, http.StatusConflict, "grading_scale_taken"},
+	{service.ErrGradingScaleInUse, http.StatusConflict, "grading_scale_in_use"},
+	{service.ErrOverrideExists, http.StatusConflict, "override_exists"},
+	{service.ErrInstructorEmailUsed, http.StatusConflict, "instructor_email_used"},
+	{service.ErrGuardianEmailUsed, http.StatusConflict, "guardian_email_used"},
+	{service.ErrSubmissionClosed, http.StatusConflict, "submission_closed"},
+	{service.ErrAlreadyGraded, http.StatusConflict, "already_graded"},
+	{service.ErrTranscriptAltered, http.StatusConflict, "transcript_altered"},
+
+	{service.ErrInvalidCourse, http.StatusBadRequest, "invalid_course"},
+	{service.ErrUnknownCourse, http.StatusBadRequest, "unknown_course"},
+	{service.ErrInvalidTerm, http.StatusBadRequest, "invalid_term"},
+	{service.ErrInvalidEnrollment, http.StatusBadRequest, "invalid_enrollment"},
+	{service.ErrInvalidGrade, http.StatusBadRequest, "invalid_grade"},
+	{service.ErrInvalidGradingScale, http.StatusBadRequest, "invalid_grading_scale"},
+	{service.ErrInvalidPrerequisites, http.StatusBadRequest, "invalid_prerequisites"},
+	{service.ErrInvalidInstructor, http.StatusBadRequest, "invalid_instructor"},
+	{service.ErrInvalidSession, http.StatusBadRequest, "invalid_session"},
+	{service.ErrInvalidAttendance, http.StatusBadRequest, "invalid_attendance"},
+	{service.ErrInvalidAssignment, http.StatusBadRequest, "invalid_assignment"},
+	{service.ErrInvalidSubmission, http.StatusBadRequest, "invalid_submission"},
+	{service.ErrInvalidContact, http.StatusBadRequest, "invalid_contact"},
+	{service.ErrInvalidGuardian, http.StatusBadRequest, "invalid_guardian"},
+	{service.ErrInvalidCursor, http.StatusBadRequest, "invalid_cursor"},
+	{service.ErrInvalidSort, http.StatusBadRequest, "invalid_sort"},
+	{service.ErrInvalidFilter, http.StatusBadRequest, "invalid_filter"},
+
+	{service.ErrInvalidCredentials, http.StatusUnauthorized, "invalid_credentials"},
+	{service.ErrForbidden, http.StatusForbidden, "forbidden"},
+	{service.ErrPreconditionFailed, http.StatusPreconditionFailed, "precondition_failed"},
+	{service.ErrPrerequisitesNotMet, http.StatusUnprocessableEntity, "prerequisites_not_met"},
+	{service.ErrInvalidPatch, http.StatusUnprocessableEntity, "invalid_patch"},
+	{service.ErrBatchTooLarge, http.StatusRequestEntityTooLarge, "batch_too_large"},
+	{service.ErrBatchAborted, http.StatusFailedDependency, "batch_aborted"},
+	{service.ErrStorageUnavailable, http.StatusServiceUnavailable, "storage_unavailable"},
+	{service.ErrNoTranscriptKey, http.StatusServiceUnavailable, "transcripts_unavailable"},
+}
+
+// statusCodes are the codes of problems that have no service error behind
+// them, such as a malformed ID in the path.
+var statusCodes = map[int]string{
+	http.StatusBadRequest:            "bad_request",
+	http.StatusUnauthorized:          "unauthorized",
+	http.StatusForbidden:             "forbidden",
+	http.StatusNotFound:              "not_found",
+	http.StatusMethodNotAllowed:      "method_not_allowed",
+	http.StatusConflict:              "conflict",
+	http.StatusGone:                  "gone",
+	http.StatusPreconditionFailed:    "precondition_failed",
+	http.StatusRequestEntityTooLarge: "payload_too_large",
+	http.StatusUnsupportedMediaType:  "unsupported_media_type",
+	http.StatusUnprocessableEntity:   "unprocessable_entity",
+	http.StatusInternalServerError:   "internal_error",
+	http.StatusServiceUnavailable:    "service_unavailable",
+}
+
+// ProblemFor returns the status and code for err. Errors that are not
+// listed in problemMappings are internal errors. The gRPC transport maps
+// errors with it too, so both report the same codes.
+func ProblemFor(err error) (int, string) {
+	for _, m := range problemMappings {
+		if errors.Is(err, m.err) {
+			return m.status, m.code
+		}
+	}
+	return http.StatusInternalServerError, statusCodes[http.StatusInternalServerError]
+}
+
+// writeProblem answers with a problem of the given status and the generic
+// code of that status.
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	code, ok := statusCodes[status]
+	if !ok {
+		code = "error"
+	}
+	sendProblem(w, r, Problem{Status: status, Code: code, Detail: detail})
+}
+
+// writeError answers with the problem matching err.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	sendProblem(w, r, problemOf(r, err))
+}
+
+// problemOf is the problem matching err, without the fields sendProblem
+// fills in. The text of internal errors is logged but not sent, as it may
+// describe the database.
+func problemOf(r *http.Request, err error) Problem {
+	var invalid ValidationErrors
+	if errors.As(err, &invalid) {
+		return validationProblem(invalid)
+	}
+	status, code := ProblemFor(err)
+	detail := err.Error()
+	if status == http.StatusInternalServerError {
+		logrus.Errorf("%s %s failed: %v", r.Method, r.URL.Path, err)
+		detail = "An internal error occurred"
+	}
+	return Problem{Status: status, Code: code, Detail: detail}
+}
+
+// itemProblem is the problem of one item of a bulk request, which is
+// reported inside the response rather than as the response.
+func itemProblem(r *http.Request, err error) *Problem {
+	p := problemOf(r, err)
+	p.Type = "about:blank"
+	p.Title = http.StatusText(p.Status)
+	return &p
+}
+
+// writeBadRequest answers 400 for a request the handler could not make sense
+// of, keeping the code of err when it is a known service error.
+func writeBadRequest(w http.ResponseWriter, r *http.Request, err error) {
+	status, code := ProblemFor(err)
+	if status != http.StatusBadRequest {
+		code = statusCodes[http.StatusBadRequest]
+	}
+	sendProblem(w, r, Problem{Status: http.StatusBadRequest, Code: code, Detail: err.Error()})
+}
+
+func sendProblem(w http.ResponseWriter, r *http.Request, p Problem) {
+	p.Type = "about:blank"
+	p.Title = http.StatusText(p.Status)
+	p.Instance = r.URL.Path
+	p.RequestID, _ = r.Context().Value("requestID").(string)
+
+	w.Header().Set("Content-Type", problemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
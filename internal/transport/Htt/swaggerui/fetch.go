@@ -0,0 +1,118 @@
+//go:build ignore
+
+// fetch downloads the swagger-ui-dist release named by swaggerui.Version
+// from the npm registry, checks the package against the sha512 integrity
+// hash of the registry and writes the files /docs needs into dist.
+package main
+
+import (
+	"GO_Assignment_3/internal/transport/Htt/swaggerui"
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// files are the files of the package that are kept; the first two are
+// required.
+var files = []string{"swagger-ui.css", "swagger-ui-bundle.js", "LICENSE", "NOTICE"}
+
+func main() {
+	if err := fetch(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func fetch() error {
+	var release struct {
+		Dist struct {
+			Tarball   string `json:"tarball"`
+			Integrity string `json:"integrity"`
+		} `json:"dist"`
+	}
+	meta, err := get("https://registry.npmjs.org/swagger-ui-dist/" + swaggerui.Version)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(meta, &release); err != nil {
+		return fmt.Errorf("invalid registry answer: %w", err)
+	}
+
+	algorithm, hash, _ := strings.Cut(release.Dist.Integrity, "-")
+	want, err := base64.StdEncoding.DecodeString(hash)
+	if algorithm != "sha512" || err != nil {
+		return fmt.Errorf("unexpected integrity %q", release.Dist.Integrity)
+	}
+	tarball, err := get(release.Dist.Tarball)
+	if err != nil {
+		return err
+	}
+	if got := sha512.Sum512(tarball); !bytes.Equal(got[:], want) {
+		return fmt.Errorf("%s does not match its integrity hash", release.Dist.Tarball)
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(tarball))
+	if err != nil {
+		return err
+	}
+	archive := tar.NewReader(gz)
+	written := map[string]bool{}
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		name := strings.TrimPrefix(header.Name, "package/")
+		if !wanted(name) {
+			continue
+		}
+		body, err := io.ReadAll(archive)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join("dist", name), body, 0o644); err != nil {
+			return err
+		}
+		written[name] = true
+	}
+	for _, name := range files[:2] {
+		if !written[name] {
+			return fmt.Errorf("swagger-ui-dist %s has no %s", swaggerui.Version, name)
+		}
+	}
+	log.Printf("Wrote swagger-ui-dist %s to dist", swaggerui.Version)
+	return nil
+}
+
+func wanted(name string) bool {
+	for _, f := range files {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+func get(url string) ([]byte, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
@@ -0,0 +1,18 @@
+// Package swaggerui embeds the Swagger UI files served by /docs, so that
+// the documentation page loads nothing from other hosts. The files in dist
+// come from the swagger-ui-dist release named by Version; go generate
+// fetches them from the npm registry and checks them against the integrity
+// hash the registry publishes. Commit dist after regenerating.
+package swaggerui
+
+import "embed"
+
+//go:generate go run fetch.go
+
+// Version is the swagger-ui-dist release the files in dist come from.
+const Version = "5.17.14"
+
+// Files holds dist/swagger-ui.css and dist/swagger-ui-bundle.js.
+//
+//go:embed dist
+var Files embed.FS
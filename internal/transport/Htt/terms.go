@@ -0,0 +1,109 @@
+package Htt
+
+import (
+	service "GO_Assignment_3/internal/service"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+func parseTermID(r *http.Request) (int32, error) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 32)
+	return int32(id), err
+}
+
+func (h *Handler) GetAllTerms(w http.ResponseWriter, r *http.Request) {
+	terms, err := h.Service.GetAllTerms(r.Context())
+	if err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, "Error fetching terms")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"data": terms})
+}
+
+// GetCurrentTerm - GET /terms/current, the term whose dates contain today.
+func (h *Handler) GetCurrentTerm(w http.ResponseWriter, r *http.Request) {
+	term, err := h.Service.GetCurrentTerm(r.Context())
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(term)
+}
+
+func (h *Handler) GetTerm(w http.ResponseWriter, r *http.Request) {
+	id, err := parseTermID(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid term ID format")
+		return
+	}
+
+	term, err := h.Service.GetTerm(r.Context(), id)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(term)
+}
+
+func (h *Handler) CreateTerm(w http.ResponseWriter, r *http.Request) {
+	var term service.Term
+	if !decodeRequest(w, r, &term) {
+		return
+	}
+
+	created, err := h.Service.AddTerm(r.Context(), term)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+func (h *Handler) UpdateTerm(w http.ResponseWriter, r *http.Request) {
+	id, err := parseTermID(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid term ID format")
+		return
+	}
+
+	var term service.Term
+	if !decodeRequest(w, r, &term) {
+		return
+	}
+
+	updated, err := h.Service.UpdateTerm(r.Context(), id, term)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+func (h *Handler) DeleteTerm(w http.ResponseWriter, r *http.Request) {
+	id, err := parseTermID(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid term ID format")
+		return
+	}
+
+	if err := h.Service.DeleteTerm(r.Context(), id); err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
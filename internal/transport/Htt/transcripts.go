@@ -0,0 +1,195 @@
+package Htt
+
+import (
+	service "GO_Assignment_3/internal/service"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// wantsPDF reports whether the client asked for a PDF, either with
+// ?format=pdf or through the Accept header.
+func wantsPDF(r *http.Request) bool {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return strings.EqualFold(format, "pdf")
+	}
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		if strings.TrimSpace(strings.SplitN(accept, ";", 2)[0]) == "application/pdf" {
+			return true
+		}
+	}
+	return false
+}
+
+// GetTranscript - GET /students/{id}/transcript
+// Returns the transcript as JSON, or as an unofficial PDF when the client
+// asks for application/pdf. Nothing is recorded; official transcripts are
+// issued with POST.
+func (h *Handler) GetTranscript(w http.ResponseWriter, r *http.Request) {
+	studentID, _, err := parseEnrollmentPath(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid student ID format")
+		return
+	}
+
+	transcript, err := h.Service.GetTranscript(r.Context(), studentID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	h.writeTranscript(w, r, http.StatusOK, transcript)
+}
+
+// IssueTranscript - POST /students/{id}/transcript
+// Issues an official transcript with a verification code, as JSON or as a
+// PDF when the client asks for application/pdf. Only the student and the
+// admin can issue one.
+func (h *Handler) IssueTranscript(w http.ResponseWriter, r *http.Request) {
+	studentID, _, err := parseEnrollmentPath(r)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, "Invalid student ID format")
+		return
+	}
+
+	transcript, err := h.Service.IssueTranscript(r.Context(), studentID)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	h.writeTranscript(w, r, http.StatusCreated, transcript)
+}
+
+// writeTranscript answers with the transcript as JSON, or as a PDF when
+// the client asks for one.
+func (h *Handler) writeTranscript(w http.ResponseWriter, r *http.Request, status int, transcript service.Transcript) {
+	if !wantsPDF(r) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(transcript)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := renderTranscriptPDF(&buf, transcript, h.verifyURL(transcript.VerificationCode)); err != nil {
+		logrus.Errorf("Failed to render transcript of student %d: %v", transcript.StudentID, err)
+		writeProblem(w, r, http.StatusInternalServerError, "Error rendering transcript")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"transcript-%d.pdf\"", transcript.StudentID))
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
+
+// verifyURL is where a verification code can be checked, under the public
+// base URL of the API. Unofficial transcripts have no code and no URL.
+func (h *Handler) verifyURL(code string) string {
+	if code == "" {
+		return ""
+	}
+	return strings.TrimSuffix(h.PublicURL, "/") + "/transcripts/verify/" + code
+}
+
+// verifyResponse is the body of a successful transcript verification.
+type verifyResponse struct {
+	Valid      bool                     `json:"valid"`
+	Transcript service.TranscriptRecord `json:"transcript"`
+}
+
+// VerifyTranscript - GET /transcripts/verify/{code}
+// Public: anyone holding a transcript can check that it was issued here.
+func (h *Handler) VerifyTranscript(w http.ResponseWriter, r *http.Request) {
+	record, err := h.Service.VerifyTranscript(r.Context(), mux.Vars(r)["code"])
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(verifyResponse{Valid: true, Transcript: record})
+}
+
+// renderTranscriptPDF lays out a transcript on A4 pages: a header with the
+// student, one table per term and the verification code in the footer of
+// every page. A transcript without a code is rendered as unofficial.
+func renderTranscriptPDF(buf *bytes.Buffer, t service.Transcript, verifyURL string) error {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	title, notice := "Official Transcript", "Verification code: "+t.VerificationCode+"  -  verify at "+verifyURL
+	if t.VerificationCode == "" {
+		title, notice = "Unofficial Transcript", "Unofficial copy - it cannot be verified"
+	}
+	pdf.SetTitle(title, true)
+	pdf.SetAutoPageBreak(true, 20)
+	pdf.SetFooterFunc(func() {
+		const pageNumberWidth = 20
+		pageWidth, _ := pdf.GetPageSize()
+		left, _, right, _ := pdf.GetMargins()
+		pdf.SetY(-15)
+		pdf.SetFont("Helvetica", "", 8)
+		pdf.CellFormat(pageWidth-left-right-pageNumberWidth, 5, tr(notice), "", 0, "L", false, 0, "")
+		pdf.CellFormat(pageNumberWidth, 5, fmt.Sprintf("Page %d", pdf.PageNo()), "", 0, "R", false, 0, "")
+	})
+	pdf.AddPage()
+
+	pdf.SetFont("Helvetica", "B", 16)
+	pdf.CellFormat(0, 10, title, "", 1, "C", false, 0, "")
+	pdf.Ln(2)
+
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.CellFormat(0, 6, tr(fmt.Sprintf("Student: %s (ID %d)", t.StudentName, t.StudentID)), "", 1, "L", false, 0, "")
+	if t.Program != "" {
+		pdf.CellFormat(0, 6, tr("Program: "+t.Program), "", 1, "L", false, 0, "")
+	}
+	pdf.CellFormat(0, 6, "Issued: "+t.IssuedOn, "", 1, "L", false, 0, "")
+	pdf.Ln(4)
+
+	widths := []float64{25, 85, 20, 20, 20}
+	for _, term := range t.Terms {
+		title := term.TermCode
+		if term.TermName != "" {
+			title = fmt.Sprintf("%s - %s (%s to %s)", term.TermCode, term.TermName, term.StartDate, term.EndDate)
+		}
+		pdf.SetFont("Helvetica", "B", 11)
+		pdf.CellFormat(0, 7, tr(title), "", 1, "L", false, 0, "")
+
+		pdf.SetFont("Helvetica", "B", 9)
+		pdf.SetFillColor(230, 230, 230)
+		for i, heading := range []string{"Code", "Course", "Credits", "Grade", "Points"} {
+			pdf.CellFormat(widths[i], 6, heading, "1", 0, "L", true, 0, "")
+		}
+		pdf.Ln(-1)
+
+		pdf.SetFont("Helvetica", "", 9)
+		for _, c := range term.Courses {
+			grade := c.Grade
+			if grade == "" {
+				grade = c.Status
+			}
+			points := ""
+			if c.Points != nil {
+				points = fmt.Sprintf("%.2f", *c.Points)
+			}
+			cells := []string{c.CourseCode, c.CourseTitle, fmt.Sprintf("%.1f", c.Credits), grade, points}
+			for i, cell := range cells {
+				pdf.CellFormat(widths[i], 6, tr(cell), "1", 0, "L", false, 0, "")
+			}
+			pdf.Ln(-1)
+		}
+		pdf.SetFont("Helvetica", "I", 9)
+		pdf.CellFormat(0, 6, fmt.Sprintf("Term GPA %.2f over %.1f credits", term.GPA, term.Credits), "", 1, "R", false, 0, "")
+		pdf.Ln(3)
+	}
+
+	pdf.SetFont("Helvetica", "B", 11)
+	pdf.CellFormat(0, 8, fmt.Sprintf("Cumulative GPA %.2f over %.1f credits", t.GPA, t.Credits), "T", 1, "R", false, 0, "")
+
+	return pdf.Output(buf)
+}
@@ -0,0 +1,172 @@
+package Htt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/es"
+	"github.com/go-playground/locales/fr"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+	en_translations "github.com/go-playground/validator/v10/translations/en"
+	es_translations "github.com/go-playground/validator/v10/translations/es"
+	fr_translations "github.com/go-playground/validator/v10/translations/fr"
+	"github.com/sirupsen/logrus"
+)
+
+// FieldError is one failed validation rule of a request body.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// ValidationErrors is the list of rules a request body failed.
+type ValidationErrors []FieldError
+
+func (v ValidationErrors) Error() string {
+	messages := make([]string, len(v))
+	for i, fe := range v {
+		messages[i] = fe.Message
+	}
+	return "invalid request: " + strings.Join(messages, "; ")
+}
+
+// gradePattern is the shape of a letter grade: up to 10 letters, digits,
+// plus or minus signs. Whether the grade is on the course's scale is checked
+// by the service.
+var gradePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9+-]{0,9}$`)
+
+// customMessages holds the messages of the rules registered here, and of
+// built-in rules the validator has no translation for, per locale.
+var customMessages = map[string]map[string]string{
+	"en": {"grade": "{0} must be a letter grade such as A, B+ or 10", "required_unless": "{0} is a required field"},
+	"es": {"grade": "{0} debe ser una calificación como A, B+ o 10", "required_unless": "{0} es un campo requerido"},
+	"fr": {"grade": "{0} doit être une note telle que A, B+ ou 10", "required_unless": "{0} est un champ obligatoire"},
+}
+
+var (
+	validate   *validator.Validate
+	translator *ut.UniversalTranslator
+)
+
+func init() {
+	validate = validator.New(validator.WithRequiredStructEnabled())
+	// Report fields by their JSON names, as the client sent them.
+	validate.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	validate.RegisterValidation("grade", func(fl validator.FieldLevel) bool {
+		return gradePattern.MatchString(fl.Field().String())
+	})
+
+	english := en.New()
+	translator = ut.New(english, english, es.New(), fr.New())
+	register := map[string]func(*validator.Validate, ut.Translator) error{
+		"en": en_translations.RegisterDefaultTranslations,
+		"es": es_translations.RegisterDefaultTranslations,
+		"fr": fr_translations.RegisterDefaultTranslations,
+	}
+	for locale, registerDefaults := range register {
+		trans, _ := translator.GetTranslator(locale)
+		if err := registerDefaults(validate, trans); err != nil {
+			logrus.Fatalf("Failed to register %s validation messages: %v", locale, err)
+		}
+		for rule, message := range customMessages[locale] {
+			rule, message := rule, message
+			err := validate.RegisterTranslation(rule, trans,
+				func(t ut.Translator) error { return t.Add(rule, message, true) },
+				func(t ut.Translator, fe validator.FieldError) string {
+					msg, _ := t.T(rule, fe.Field())
+					return msg
+				})
+			if err != nil {
+				logrus.Fatalf("Failed to register %s message for %s: %v", locale, rule, err)
+			}
+		}
+	}
+}
+
+// translatorFor picks the translator for the languages in an
+// Accept-Language header, falling back to English.
+func translatorFor(acceptLanguage string) ut.Translator {
+	var locales []string
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag := strings.TrimSpace(strings.SplitN(part, ";", 2)[0])
+		if tag == "" {
+			continue
+		}
+		// "fr-CA" falls back to "fr".
+		locales = append(locales, strings.ReplaceAll(tag, "-", "_"), strings.SplitN(tag, "-", 2)[0])
+	}
+	trans, _ := translator.FindTranslator(locales...)
+	return trans
+}
+
+// validateRequest checks v against its validate tags and returns the
+// failures as ValidationErrors, with messages in the caller's language.
+func validateRequest(r *http.Request, v interface{}) error {
+	return Validate(v, r.Header.Get("Accept-Language"))
+}
+
+// Validate checks v against its validate tags, with messages in the
+// languages of acceptLanguage. It is validateRequest for transports other
+// than HTTP.
+func Validate(v interface{}, acceptLanguage string) error {
+	err := validate.Struct(v)
+	var failed validator.ValidationErrors
+	if !errors.As(err, &failed) {
+		return err
+	}
+	trans := translatorFor(acceptLanguage)
+	errs := make(ValidationErrors, len(failed))
+	for i, fe := range failed {
+		field := fe.Namespace()
+		// Drop the name of the request type.
+		if dot := strings.IndexByte(field, '.'); dot >= 0 {
+			field = field[dot+1:]
+		}
+		errs[i] = FieldError{Field: field, Rule: fe.Tag(), Message: fe.Translate(trans)}
+	}
+	return errs
+}
+
+// validationProblem is the 400 problem listing the failed rules.
+func validationProblem(errs ValidationErrors) Problem {
+	return Problem{
+		Status: http.StatusBadRequest,
+		Code:   "validation_failed",
+		Detail: "The request body failed validation",
+		Errors: errs,
+	}
+}
+
+// decodeRequest reads a JSON request body into v and validates it. It
+// answers the request itself and returns false when the body is malformed
+// or invalid.
+func decodeRequest(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeProblem(w, r, http.StatusRequestEntityTooLarge, fmt.Sprintf("The request body is larger than %d bytes", tooLarge.Limit))
+			return false
+		}
+		writeProblem(w, r, http.StatusBadRequest, "Invalid request payload")
+		return false
+	}
+	if err := validateRequest(r, v); err != nil {
+		writeError(w, r, err)
+		return false
+	}
+	return true
+}
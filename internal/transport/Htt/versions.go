@@ -0,0 +1,102 @@
+package Htt
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// apiVersions are the versions of the API, each served under its own
+// prefix. A new version gets an entry here: its mount registers the routes
+// that change in it before the ones it shares with the previous version
+// (the first route that matches wins), and handlers that only change the
+// shape of a response check apiVersion(r).
+var apiVersions = []struct {
+	prefix  string
+	version int
+	mount   func(h *Handler, router *mux.Router)
+}{
+	{"/v1", 1, (*Handler).routes},
+}
+
+// latestVersion is the version described by /openapi.json.
+const latestVersion = 1
+
+// apiVersion returns the version of the API the request was made to. The
+// unversioned aliases count as version 1.
+func apiVersion(r *http.Request) int {
+	if v, ok := r.Context().Value("apiVersion").(int); ok {
+		return v
+	}
+	return 1
+}
+
+func versionMiddleware(version int) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("API-Version", strconv.Itoa(version))
+			ctx := context.WithValue(r.Context(), "apiVersion", version)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Deprecation is the retirement plan of an endpoint or a group of them.
+type Deprecation struct {
+	// Since is when the endpoints were deprecated.
+	Since time.Time
+	// Sunset is when they stop working. After it they answer 410.
+	Sunset time.Time
+	// Successor is the prefix of the version replacing them, e.g. /v1.
+	Successor string
+}
+
+// legacyDeprecation retires the unversioned routes in favour of /v1. The
+// sunset can be moved with LEGACY_API_SUNSET (YYYY-MM-DD).
+var legacyDeprecation = Deprecation{
+	Since:     time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC),
+	Sunset:    time.Date(2027, time.April, 19, 0, 0, 0, 0, time.UTC),
+	Successor: "/v1",
+}
+
+// loadLegacyDeprecation applies LEGACY_API_SUNSET to legacyDeprecation.
+func loadLegacyDeprecation() (Deprecation, error) {
+	d := legacyDeprecation
+	if v := os.Getenv("LEGACY_API_SUNSET"); v != "" {
+		sunset, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return d, fmt.Errorf("invalid LEGACY_API_SUNSET %q: %v", v, err)
+		}
+		d.Sunset = sunset
+	}
+	return d, nil
+}
+
+// deprecated announces the retirement of the routes it wraps with the
+// Deprecation (RFC 9745) and Sunset (RFC 8594) headers and a Link to the
+// successor, and answers 410 once the sunset has passed.
+func deprecated(d Deprecation) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", "@"+strconv.FormatInt(d.Since.Unix(), 10))
+			w.Header().Set("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+			if d.Successor != "" {
+				w.Header().Set("Link", "<"+d.Successor+r.URL.Path+`>; rel="successor-version"`)
+			}
+			if !time.Now().Before(d.Sunset) {
+				detail := r.URL.Path + " was retired on " + d.Sunset.Format("2006-01-02")
+				if d.Successor != "" {
+					detail += "; use " + d.Successor + r.URL.Path
+				}
+				writeProblem(w, r, http.StatusGone, detail)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}